@@ -172,6 +172,58 @@ func (TimeInForce) EnumDescriptor() ([]byte, []int) {
 	return file_exchange_v1_trading_proto_rawDescGZIP(), []int{2}
 }
 
+type SelfTradePrevention int32
+
+const (
+	SelfTradePrevention_SELF_TRADE_PREVENTION_UNSPECIFIED   SelfTradePrevention = 0
+	SelfTradePrevention_SELF_TRADE_PREVENTION_CANCEL_NEWEST SelfTradePrevention = 1
+	SelfTradePrevention_SELF_TRADE_PREVENTION_CANCEL_OLDEST SelfTradePrevention = 2
+	SelfTradePrevention_SELF_TRADE_PREVENTION_CANCEL_BOTH   SelfTradePrevention = 3
+)
+
+// Enum value maps for SelfTradePrevention.
+var (
+	SelfTradePrevention_name = map[int32]string{
+		0: "SELF_TRADE_PREVENTION_UNSPECIFIED",
+		1: "SELF_TRADE_PREVENTION_CANCEL_NEWEST",
+		2: "SELF_TRADE_PREVENTION_CANCEL_OLDEST",
+		3: "SELF_TRADE_PREVENTION_CANCEL_BOTH",
+	}
+	SelfTradePrevention_value = map[string]int32{
+		"SELF_TRADE_PREVENTION_UNSPECIFIED":   0,
+		"SELF_TRADE_PREVENTION_CANCEL_NEWEST": 1,
+		"SELF_TRADE_PREVENTION_CANCEL_OLDEST": 2,
+		"SELF_TRADE_PREVENTION_CANCEL_BOTH":   3,
+	}
+)
+
+func (x SelfTradePrevention) Enum() *SelfTradePrevention {
+	p := new(SelfTradePrevention)
+	*p = x
+	return p
+}
+
+func (x SelfTradePrevention) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SelfTradePrevention) Descriptor() protoreflect.EnumDescriptor {
+	return file_exchange_v1_trading_proto_enumTypes[3].Descriptor()
+}
+
+func (SelfTradePrevention) Type() protoreflect.EnumType {
+	return &file_exchange_v1_trading_proto_enumTypes[3]
+}
+
+func (x SelfTradePrevention) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SelfTradePrevention.Descriptor instead.
+func (SelfTradePrevention) EnumDescriptor() ([]byte, []int) {
+	return file_exchange_v1_trading_proto_rawDescGZIP(), []int{3}
+}
+
 type SymbolMode int32
 
 const (
@@ -211,11 +263,11 @@ func (x SymbolMode) String() string {
 }
 
 func (SymbolMode) Descriptor() protoreflect.EnumDescriptor {
-	return file_exchange_v1_trading_proto_enumTypes[3].Descriptor()
+	return file_exchange_v1_trading_proto_enumTypes[4].Descriptor()
 }
 
 func (SymbolMode) Type() protoreflect.EnumType {
-	return &file_exchange_v1_trading_proto_enumTypes[3]
+	return &file_exchange_v1_trading_proto_enumTypes[4]
 }
 
 func (x SymbolMode) Number() protoreflect.EnumNumber {
@@ -224,20 +276,24 @@ func (x SymbolMode) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use SymbolMode.Descriptor instead.
 func (SymbolMode) EnumDescriptor() ([]byte, []int) {
-	return file_exchange_v1_trading_proto_rawDescGZIP(), []int{3}
+	return file_exchange_v1_trading_proto_rawDescGZIP(), []int{4}
 }
 
 type PlaceOrderRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Meta          *CommandMetadata       `protobuf:"bytes,1,opt,name=meta,proto3" json:"meta,omitempty"`
-	OrderId       string                 `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
-	Side          Side                   `protobuf:"varint,3,opt,name=side,proto3,enum=exchange.v1.Side" json:"side,omitempty"`
-	OrderType     OrderType              `protobuf:"varint,4,opt,name=order_type,json=orderType,proto3,enum=exchange.v1.OrderType" json:"order_type,omitempty"`
-	Price         string                 `protobuf:"bytes,5,opt,name=price,proto3" json:"price,omitempty"`
-	Quantity      string                 `protobuf:"bytes,6,opt,name=quantity,proto3" json:"quantity,omitempty"`
-	TimeInForce   TimeInForce            `protobuf:"varint,7,opt,name=time_in_force,json=timeInForce,proto3,enum=exchange.v1.TimeInForce" json:"time_in_force,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Meta                *CommandMetadata       `protobuf:"bytes,1,opt,name=meta,proto3" json:"meta,omitempty"`
+	OrderId             string                 `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Side                Side                   `protobuf:"varint,3,opt,name=side,proto3,enum=exchange.v1.Side" json:"side,omitempty"`
+	OrderType           OrderType              `protobuf:"varint,4,opt,name=order_type,json=orderType,proto3,enum=exchange.v1.OrderType" json:"order_type,omitempty"`
+	Price               string                 `protobuf:"bytes,5,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity            string                 `protobuf:"bytes,6,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	TimeInForce         TimeInForce            `protobuf:"varint,7,opt,name=time_in_force,json=timeInForce,proto3,enum=exchange.v1.TimeInForce" json:"time_in_force,omitempty"`
+	PostOnly            bool                   `protobuf:"varint,8,opt,name=post_only,json=postOnly,proto3" json:"post_only,omitempty"`
+	SelfTradePrevention SelfTradePrevention    `protobuf:"varint,9,opt,name=self_trade_prevention,json=selfTradePrevention,proto3,enum=exchange.v1.SelfTradePrevention" json:"self_trade_prevention,omitempty"`
+	MakerFeeBps         string                 `protobuf:"bytes,10,opt,name=maker_fee_bps,json=makerFeeBps,proto3" json:"maker_fee_bps,omitempty"`
+	TakerFeeBps         string                 `protobuf:"bytes,11,opt,name=taker_fee_bps,json=takerFeeBps,proto3" json:"taker_fee_bps,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *PlaceOrderRequest) Reset() {
@@ -319,6 +375,34 @@ func (x *PlaceOrderRequest) GetTimeInForce() TimeInForce {
 	return TimeInForce_TIME_IN_FORCE_UNSPECIFIED
 }
 
+func (x *PlaceOrderRequest) GetPostOnly() bool {
+	if x != nil {
+		return x.PostOnly
+	}
+	return false
+}
+
+func (x *PlaceOrderRequest) GetSelfTradePrevention() SelfTradePrevention {
+	if x != nil {
+		return x.SelfTradePrevention
+	}
+	return SelfTradePrevention_SELF_TRADE_PREVENTION_UNSPECIFIED
+}
+
+func (x *PlaceOrderRequest) GetMakerFeeBps() string {
+	if x != nil {
+		return x.MakerFeeBps
+	}
+	return ""
+}
+
+func (x *PlaceOrderRequest) GetTakerFeeBps() string {
+	if x != nil {
+		return x.TakerFeeBps
+	}
+	return ""
+}
+
 type CancelOrderRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Meta          *CommandMetadata       `protobuf:"bytes,1,opt,name=meta,proto3" json:"meta,omitempty"`
@@ -484,17 +568,18 @@ func (x *CancelAllRequest) GetReason() string {
 }
 
 type PlaceOrderResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Accepted      bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
-	OrderId       string                 `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
-	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
-	Symbol        string                 `protobuf:"bytes,4,opt,name=symbol,proto3" json:"symbol,omitempty"`
-	Seq           uint64                 `protobuf:"varint,5,opt,name=seq,proto3" json:"seq,omitempty"`
-	AcceptedAt    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=accepted_at,json=acceptedAt,proto3" json:"accepted_at,omitempty"`
-	RejectCode    string                 `protobuf:"bytes,7,opt,name=reject_code,json=rejectCode,proto3" json:"reject_code,omitempty"`
-	CorrelationId string                 `protobuf:"bytes,8,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Accepted            bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	OrderId             string                 `protobuf:"bytes,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status              string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Symbol              string                 `protobuf:"bytes,4,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Seq                 uint64                 `protobuf:"varint,5,opt,name=seq,proto3" json:"seq,omitempty"`
+	AcceptedAt          *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=accepted_at,json=acceptedAt,proto3" json:"accepted_at,omitempty"`
+	RejectCode          string                 `protobuf:"bytes,7,opt,name=reject_code,json=rejectCode,proto3" json:"reject_code,omitempty"`
+	CorrelationId       string                 `protobuf:"bytes,8,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	StpCanceledOrderIds []string               `protobuf:"bytes,9,rep,name=stp_canceled_order_ids,json=stpCanceledOrderIds,proto3" json:"stp_canceled_order_ids,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *PlaceOrderResponse) Reset() {
@@ -583,6 +668,13 @@ func (x *PlaceOrderResponse) GetCorrelationId() string {
 	return ""
 }
 
+func (x *PlaceOrderResponse) GetStpCanceledOrderIds() []string {
+	if x != nil {
+		return x.StpCanceledOrderIds
+	}
+	return nil
+}
+
 type CancelOrderResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Accepted      bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
@@ -1327,7 +1419,7 @@ var File_exchange_v1_trading_proto protoreflect.FileDescriptor
 
 const file_exchange_v1_trading_proto_rawDesc = "" +
 	"\n" +
-	"\x19exchange/v1/trading.proto\x12\vexchange.v1\x1a\x18exchange/v1/common.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xae\x02\n" +
+	"\x19exchange/v1/trading.proto\x12\vexchange.v1\x1a\x18exchange/v1/common.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe9\x03\n" +
 	"\x11PlaceOrderRequest\x120\n" +
 	"\x04meta\x18\x01 \x01(\v2\x1c.exchange.v1.CommandMetadataR\x04meta\x12\x19\n" +
 	"\border_id\x18\x02 \x01(\tR\aorderId\x12%\n" +
@@ -1336,7 +1428,12 @@ const file_exchange_v1_trading_proto_rawDesc = "" +
 	"order_type\x18\x04 \x01(\x0e2\x16.exchange.v1.OrderTypeR\torderType\x12\x14\n" +
 	"\x05price\x18\x05 \x01(\tR\x05price\x12\x1a\n" +
 	"\bquantity\x18\x06 \x01(\tR\bquantity\x12<\n" +
-	"\rtime_in_force\x18\a \x01(\x0e2\x18.exchange.v1.TimeInForceR\vtimeInForce\"a\n" +
+	"\rtime_in_force\x18\a \x01(\x0e2\x18.exchange.v1.TimeInForceR\vtimeInForce\x12\x1b\n" +
+	"\tpost_only\x18\b \x01(\bR\bpostOnly\x12T\n" +
+	"\x15self_trade_prevention\x18\t \x01(\x0e2 .exchange.v1.SelfTradePreventionR\x13selfTradePrevention\x12\"\n" +
+	"\rmaker_fee_bps\x18\n" +
+	" \x01(\tR\vmakerFeeBps\x12\"\n" +
+	"\rtaker_fee_bps\x18\v \x01(\tR\vtakerFeeBps\"a\n" +
 	"\x12CancelOrderRequest\x120\n" +
 	"\x04meta\x18\x01 \x01(\v2\x1c.exchange.v1.CommandMetadataR\x04meta\x12\x19\n" +
 	"\border_id\x18\x02 \x01(\tR\aorderId\"\x8d\x01\n" +
@@ -1346,7 +1443,7 @@ const file_exchange_v1_trading_proto_rawDesc = "" +
 	"\x06reason\x18\x03 \x01(\tR\x06reason\"\\\n" +
 	"\x10CancelAllRequest\x120\n" +
 	"\x04meta\x18\x01 \x01(\v2\x1c.exchange.v1.CommandMetadataR\x04meta\x12\x16\n" +
-	"\x06reason\x18\x02 \x01(\tR\x06reason\"\x92\x02\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\xc7\x02\n" +
 	"\x12PlaceOrderResponse\x12\x1a\n" +
 	"\baccepted\x18\x01 \x01(\bR\baccepted\x12\x19\n" +
 	"\border_id\x18\x02 \x01(\tR\aorderId\x12\x16\n" +
@@ -1357,7 +1454,8 @@ const file_exchange_v1_trading_proto_rawDesc = "" +
 	"acceptedAt\x12\x1f\n" +
 	"\vreject_code\x18\a \x01(\tR\n" +
 	"rejectCode\x12%\n" +
-	"\x0ecorrelation_id\x18\b \x01(\tR\rcorrelationId\"\x93\x02\n" +
+	"\x0ecorrelation_id\x18\b \x01(\tR\rcorrelationId\x123\n" +
+	"\x16stp_canceled_order_ids\x18\t \x03(\tR\x13stpCanceledOrderIds\"\x93\x02\n" +
 	"\x13CancelOrderResponse\x12\x1a\n" +
 	"\baccepted\x18\x01 \x01(\bR\baccepted\x12\x19\n" +
 	"\border_id\x18\x02 \x01(\tR\aorderId\x12\x16\n" +
@@ -1439,7 +1537,12 @@ const file_exchange_v1_trading_proto_rawDesc = "" +
 	"\x19TIME_IN_FORCE_UNSPECIFIED\x10\x00\x12\x15\n" +
 	"\x11TIME_IN_FORCE_GTC\x10\x01\x12\x15\n" +
 	"\x11TIME_IN_FORCE_IOC\x10\x02\x12\x15\n" +
-	"\x11TIME_IN_FORCE_FOK\x10\x03*\x94\x01\n" +
+	"\x11TIME_IN_FORCE_FOK\x10\x03*\xb5\x01\n" +
+	"\x13SelfTradePrevention\x12%\n" +
+	"!SELF_TRADE_PREVENTION_UNSPECIFIED\x10\x00\x12'\n" +
+	"#SELF_TRADE_PREVENTION_CANCEL_NEWEST\x10\x01\x12'\n" +
+	"#SELF_TRADE_PREVENTION_CANCEL_OLDEST\x10\x02\x12%\n" +
+	"!SELF_TRADE_PREVENTION_CANCEL_BOTH\x10\x03*\x94\x01\n" +
 	"\n" +
 	"SymbolMode\x12\x1b\n" +
 	"\x17SYMBOL_MODE_UNSPECIFIED\x10\x00\x12\x16\n" +
@@ -1467,65 +1570,67 @@ func file_exchange_v1_trading_proto_rawDescGZIP() []byte {
 	return file_exchange_v1_trading_proto_rawDescData
 }
 
-var file_exchange_v1_trading_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_exchange_v1_trading_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
 var file_exchange_v1_trading_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_exchange_v1_trading_proto_goTypes = []any{
 	(Side)(0),                     // 0: exchange.v1.Side
 	(OrderType)(0),                // 1: exchange.v1.OrderType
 	(TimeInForce)(0),              // 2: exchange.v1.TimeInForce
-	(SymbolMode)(0),               // 3: exchange.v1.SymbolMode
-	(*PlaceOrderRequest)(nil),     // 4: exchange.v1.PlaceOrderRequest
-	(*CancelOrderRequest)(nil),    // 5: exchange.v1.CancelOrderRequest
-	(*SetSymbolModeRequest)(nil),  // 6: exchange.v1.SetSymbolModeRequest
-	(*CancelAllRequest)(nil),      // 7: exchange.v1.CancelAllRequest
-	(*PlaceOrderResponse)(nil),    // 8: exchange.v1.PlaceOrderResponse
-	(*CancelOrderResponse)(nil),   // 9: exchange.v1.CancelOrderResponse
-	(*SetSymbolModeResponse)(nil), // 10: exchange.v1.SetSymbolModeResponse
-	(*CancelAllResponse)(nil),     // 11: exchange.v1.CancelAllResponse
-	(*OrderAccepted)(nil),         // 12: exchange.v1.OrderAccepted
-	(*OrderRejected)(nil),         // 13: exchange.v1.OrderRejected
-	(*OrderCanceled)(nil),         // 14: exchange.v1.OrderCanceled
-	(*CancelRejected)(nil),        // 15: exchange.v1.CancelRejected
-	(*TradeExecuted)(nil),         // 16: exchange.v1.TradeExecuted
-	(*EngineCheckpoint)(nil),      // 17: exchange.v1.EngineCheckpoint
-	(*CommandMetadata)(nil),       // 18: exchange.v1.CommandMetadata
-	(*timestamppb.Timestamp)(nil), // 19: google.protobuf.Timestamp
-	(*EventEnvelope)(nil),         // 20: exchange.v1.EventEnvelope
+	(SelfTradePrevention)(0),      // 3: exchange.v1.SelfTradePrevention
+	(SymbolMode)(0),               // 4: exchange.v1.SymbolMode
+	(*PlaceOrderRequest)(nil),     // 5: exchange.v1.PlaceOrderRequest
+	(*CancelOrderRequest)(nil),    // 6: exchange.v1.CancelOrderRequest
+	(*SetSymbolModeRequest)(nil),  // 7: exchange.v1.SetSymbolModeRequest
+	(*CancelAllRequest)(nil),      // 8: exchange.v1.CancelAllRequest
+	(*PlaceOrderResponse)(nil),    // 9: exchange.v1.PlaceOrderResponse
+	(*CancelOrderResponse)(nil),   // 10: exchange.v1.CancelOrderResponse
+	(*SetSymbolModeResponse)(nil), // 11: exchange.v1.SetSymbolModeResponse
+	(*CancelAllResponse)(nil),     // 12: exchange.v1.CancelAllResponse
+	(*OrderAccepted)(nil),         // 13: exchange.v1.OrderAccepted
+	(*OrderRejected)(nil),         // 14: exchange.v1.OrderRejected
+	(*OrderCanceled)(nil),         // 15: exchange.v1.OrderCanceled
+	(*CancelRejected)(nil),        // 16: exchange.v1.CancelRejected
+	(*TradeExecuted)(nil),         // 17: exchange.v1.TradeExecuted
+	(*EngineCheckpoint)(nil),      // 18: exchange.v1.EngineCheckpoint
+	(*CommandMetadata)(nil),       // 19: exchange.v1.CommandMetadata
+	(*timestamppb.Timestamp)(nil), // 20: google.protobuf.Timestamp
+	(*EventEnvelope)(nil),         // 21: exchange.v1.EventEnvelope
 }
 var file_exchange_v1_trading_proto_depIdxs = []int32{
-	18, // 0: exchange.v1.PlaceOrderRequest.meta:type_name -> exchange.v1.CommandMetadata
+	19, // 0: exchange.v1.PlaceOrderRequest.meta:type_name -> exchange.v1.CommandMetadata
 	0,  // 1: exchange.v1.PlaceOrderRequest.side:type_name -> exchange.v1.Side
 	1,  // 2: exchange.v1.PlaceOrderRequest.order_type:type_name -> exchange.v1.OrderType
 	2,  // 3: exchange.v1.PlaceOrderRequest.time_in_force:type_name -> exchange.v1.TimeInForce
-	18, // 4: exchange.v1.CancelOrderRequest.meta:type_name -> exchange.v1.CommandMetadata
-	18, // 5: exchange.v1.SetSymbolModeRequest.meta:type_name -> exchange.v1.CommandMetadata
-	3,  // 6: exchange.v1.SetSymbolModeRequest.mode:type_name -> exchange.v1.SymbolMode
-	18, // 7: exchange.v1.CancelAllRequest.meta:type_name -> exchange.v1.CommandMetadata
-	19, // 8: exchange.v1.PlaceOrderResponse.accepted_at:type_name -> google.protobuf.Timestamp
-	19, // 9: exchange.v1.CancelOrderResponse.canceled_at:type_name -> google.protobuf.Timestamp
-	19, // 10: exchange.v1.SetSymbolModeResponse.acted_at:type_name -> google.protobuf.Timestamp
-	19, // 11: exchange.v1.CancelAllResponse.acted_at:type_name -> google.protobuf.Timestamp
-	20, // 12: exchange.v1.OrderAccepted.envelope:type_name -> exchange.v1.EventEnvelope
-	0,  // 13: exchange.v1.OrderAccepted.side:type_name -> exchange.v1.Side
-	1,  // 14: exchange.v1.OrderAccepted.order_type:type_name -> exchange.v1.OrderType
-	20, // 15: exchange.v1.OrderRejected.envelope:type_name -> exchange.v1.EventEnvelope
-	20, // 16: exchange.v1.OrderCanceled.envelope:type_name -> exchange.v1.EventEnvelope
-	20, // 17: exchange.v1.CancelRejected.envelope:type_name -> exchange.v1.EventEnvelope
-	20, // 18: exchange.v1.TradeExecuted.envelope:type_name -> exchange.v1.EventEnvelope
-	20, // 19: exchange.v1.EngineCheckpoint.envelope:type_name -> exchange.v1.EventEnvelope
-	4,  // 20: exchange.v1.TradingCoreService.PlaceOrder:input_type -> exchange.v1.PlaceOrderRequest
-	5,  // 21: exchange.v1.TradingCoreService.CancelOrder:input_type -> exchange.v1.CancelOrderRequest
-	6,  // 22: exchange.v1.TradingCoreService.SetSymbolMode:input_type -> exchange.v1.SetSymbolModeRequest
-	7,  // 23: exchange.v1.TradingCoreService.CancelAll:input_type -> exchange.v1.CancelAllRequest
-	8,  // 24: exchange.v1.TradingCoreService.PlaceOrder:output_type -> exchange.v1.PlaceOrderResponse
-	9,  // 25: exchange.v1.TradingCoreService.CancelOrder:output_type -> exchange.v1.CancelOrderResponse
-	10, // 26: exchange.v1.TradingCoreService.SetSymbolMode:output_type -> exchange.v1.SetSymbolModeResponse
-	11, // 27: exchange.v1.TradingCoreService.CancelAll:output_type -> exchange.v1.CancelAllResponse
-	24, // [24:28] is the sub-list for method output_type
-	20, // [20:24] is the sub-list for method input_type
-	20, // [20:20] is the sub-list for extension type_name
-	20, // [20:20] is the sub-list for extension extendee
-	0,  // [0:20] is the sub-list for field type_name
+	3,  // 4: exchange.v1.PlaceOrderRequest.self_trade_prevention:type_name -> exchange.v1.SelfTradePrevention
+	19, // 5: exchange.v1.CancelOrderRequest.meta:type_name -> exchange.v1.CommandMetadata
+	19, // 6: exchange.v1.SetSymbolModeRequest.meta:type_name -> exchange.v1.CommandMetadata
+	4,  // 7: exchange.v1.SetSymbolModeRequest.mode:type_name -> exchange.v1.SymbolMode
+	19, // 8: exchange.v1.CancelAllRequest.meta:type_name -> exchange.v1.CommandMetadata
+	20, // 9: exchange.v1.PlaceOrderResponse.accepted_at:type_name -> google.protobuf.Timestamp
+	20, // 10: exchange.v1.CancelOrderResponse.canceled_at:type_name -> google.protobuf.Timestamp
+	20, // 11: exchange.v1.SetSymbolModeResponse.acted_at:type_name -> google.protobuf.Timestamp
+	20, // 12: exchange.v1.CancelAllResponse.acted_at:type_name -> google.protobuf.Timestamp
+	21, // 13: exchange.v1.OrderAccepted.envelope:type_name -> exchange.v1.EventEnvelope
+	0,  // 14: exchange.v1.OrderAccepted.side:type_name -> exchange.v1.Side
+	1,  // 15: exchange.v1.OrderAccepted.order_type:type_name -> exchange.v1.OrderType
+	21, // 16: exchange.v1.OrderRejected.envelope:type_name -> exchange.v1.EventEnvelope
+	21, // 17: exchange.v1.OrderCanceled.envelope:type_name -> exchange.v1.EventEnvelope
+	21, // 18: exchange.v1.CancelRejected.envelope:type_name -> exchange.v1.EventEnvelope
+	21, // 19: exchange.v1.TradeExecuted.envelope:type_name -> exchange.v1.EventEnvelope
+	21, // 20: exchange.v1.EngineCheckpoint.envelope:type_name -> exchange.v1.EventEnvelope
+	5,  // 21: exchange.v1.TradingCoreService.PlaceOrder:input_type -> exchange.v1.PlaceOrderRequest
+	6,  // 22: exchange.v1.TradingCoreService.CancelOrder:input_type -> exchange.v1.CancelOrderRequest
+	7,  // 23: exchange.v1.TradingCoreService.SetSymbolMode:input_type -> exchange.v1.SetSymbolModeRequest
+	8,  // 24: exchange.v1.TradingCoreService.CancelAll:input_type -> exchange.v1.CancelAllRequest
+	9,  // 25: exchange.v1.TradingCoreService.PlaceOrder:output_type -> exchange.v1.PlaceOrderResponse
+	10, // 26: exchange.v1.TradingCoreService.CancelOrder:output_type -> exchange.v1.CancelOrderResponse
+	11, // 27: exchange.v1.TradingCoreService.SetSymbolMode:output_type -> exchange.v1.SetSymbolModeResponse
+	12, // 28: exchange.v1.TradingCoreService.CancelAll:output_type -> exchange.v1.CancelAllResponse
+	25, // [25:29] is the sub-list for method output_type
+	21, // [21:25] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
 }
 
 func init() { file_exchange_v1_trading_proto_init() }
@@ -1539,7 +1644,7 @@ func file_exchange_v1_trading_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_exchange_v1_trading_proto_rawDesc), len(file_exchange_v1_trading_proto_rawDesc)),
-			NumEnums:      4,
+			NumEnums:      5,
 			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
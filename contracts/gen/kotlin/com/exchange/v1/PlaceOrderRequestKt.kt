@@ -178,6 +178,81 @@ public object PlaceOrderRequestKt {
     public fun clearTimeInForce() {
       _builder.clearTimeInForce()
     }
+
+    /**
+     * `bool post_only = 8 [json_name = "postOnly"];`
+     */
+    public var postOnly: kotlin.Boolean
+      @kotlin.jvm.JvmName("getPostOnly")
+        get() = _builder.postOnly
+      @kotlin.jvm.JvmName("setPostOnly")
+        set(value) {
+        _builder.postOnly = value
+      }
+    /**
+     * `bool post_only = 8 [json_name = "postOnly"];`
+     */
+    public fun clearPostOnly() {
+      _builder.clearPostOnly()
+    }
+
+    /**
+     * `.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];`
+     */
+    public var selfTradePrevention: com.exchange.v1.SelfTradePrevention
+      @kotlin.jvm.JvmName("getSelfTradePrevention")
+        get() = _builder.selfTradePrevention
+      @kotlin.jvm.JvmName("setSelfTradePrevention")
+        set(value) {
+        _builder.selfTradePrevention = value
+      }
+    public var selfTradePreventionValue: kotlin.Int
+      @kotlin.jvm.JvmName("getSelfTradePreventionValue")
+        get() = _builder.selfTradePreventionValue
+      @kotlin.jvm.JvmName("setSelfTradePreventionValue")
+        set(value) {
+        _builder.selfTradePreventionValue = value
+      }
+    /**
+     * `.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];`
+     */
+    public fun clearSelfTradePrevention() {
+      _builder.clearSelfTradePrevention()
+    }
+
+    /**
+     * `string maker_fee_bps = 10 [json_name = "makerFeeBps"];`
+     */
+    public var makerFeeBps: kotlin.String
+      @kotlin.jvm.JvmName("getMakerFeeBps")
+        get() = _builder.makerFeeBps
+      @kotlin.jvm.JvmName("setMakerFeeBps")
+        set(value) {
+        _builder.makerFeeBps = value
+      }
+    /**
+     * `string maker_fee_bps = 10 [json_name = "makerFeeBps"];`
+     */
+    public fun clearMakerFeeBps() {
+      _builder.clearMakerFeeBps()
+    }
+
+    /**
+     * `string taker_fee_bps = 11 [json_name = "takerFeeBps"];`
+     */
+    public var takerFeeBps: kotlin.String
+      @kotlin.jvm.JvmName("getTakerFeeBps")
+        get() = _builder.takerFeeBps
+      @kotlin.jvm.JvmName("setTakerFeeBps")
+        set(value) {
+        _builder.takerFeeBps = value
+      }
+    /**
+     * `string taker_fee_bps = 11 [json_name = "takerFeeBps"];`
+     */
+    public fun clearTakerFeeBps() {
+      _builder.clearTakerFeeBps()
+    }
   }
 }
 @kotlin.jvm.JvmSynthetic
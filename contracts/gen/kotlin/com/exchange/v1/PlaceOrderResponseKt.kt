@@ -174,6 +174,78 @@ public object PlaceOrderResponseKt {
     public fun clearCorrelationId() {
       _builder.clearCorrelationId()
     }
+
+    /**
+     * An uninstantiable, behaviorless type to represent the field in
+     * generics.
+     */
+    @kotlin.OptIn(com.google.protobuf.kotlin.OnlyForUseByGeneratedProtoCode::class)
+    public class StpCanceledOrderIdsProxy private constructor() : com.google.protobuf.kotlin.DslProxy()
+    /**
+     * `repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];`
+     * @return A list containing the stpCanceledOrderIds.
+     */
+    public val stpCanceledOrderIds: com.google.protobuf.kotlin.DslList<kotlin.String, StpCanceledOrderIdsProxy>
+      @kotlin.jvm.JvmSynthetic
+      get() = com.google.protobuf.kotlin.DslList(
+        _builder.stpCanceledOrderIdsList
+      )
+    /**
+     * `repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];`
+     * @param value The stpCanceledOrderIds to add.
+     */
+    @kotlin.jvm.JvmSynthetic
+    @kotlin.jvm.JvmName("addStpCanceledOrderIds")
+    public fun com.google.protobuf.kotlin.DslList<kotlin.String, StpCanceledOrderIdsProxy>.add(value: kotlin.String) {
+      _builder.addStpCanceledOrderIds(value)
+    }
+    /**
+     * `repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];`
+     * @param value The stpCanceledOrderIds to add.
+     */
+    @kotlin.jvm.JvmSynthetic
+    @kotlin.jvm.JvmName("plusAssignStpCanceledOrderIds")
+    @Suppress("NOTHING_TO_INLINE")
+    public inline operator fun com.google.protobuf.kotlin.DslList<kotlin.String, StpCanceledOrderIdsProxy>.plusAssign(value: kotlin.String) {
+      add(value)
+    }
+    /**
+     * `repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];`
+     * @param values The stpCanceledOrderIds to add.
+     */
+    @kotlin.jvm.JvmSynthetic
+    @kotlin.jvm.JvmName("addAllStpCanceledOrderIds")
+    public fun com.google.protobuf.kotlin.DslList<kotlin.String, StpCanceledOrderIdsProxy>.addAll(values: kotlin.collections.Iterable<kotlin.String>) {
+      _builder.addAllStpCanceledOrderIds(values)
+    }
+    /**
+     * `repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];`
+     * @param values The stpCanceledOrderIds to add.
+     */
+    @kotlin.jvm.JvmSynthetic
+    @kotlin.jvm.JvmName("plusAssignAllStpCanceledOrderIds")
+    @Suppress("NOTHING_TO_INLINE")
+    public inline operator fun com.google.protobuf.kotlin.DslList<kotlin.String, StpCanceledOrderIdsProxy>.plusAssign(values: kotlin.collections.Iterable<kotlin.String>) {
+      addAll(values)
+    }
+    /**
+     * `repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];`
+     * @param index The index to set the value at.
+     * @param value The stpCanceledOrderIds to set.
+     */
+    @kotlin.jvm.JvmSynthetic
+    @kotlin.jvm.JvmName("setStpCanceledOrderIds")
+    public operator fun com.google.protobuf.kotlin.DslList<kotlin.String, StpCanceledOrderIdsProxy>.set(index: kotlin.Int, value: kotlin.String) {
+      _builder.setStpCanceledOrderIds(index, value)
+    }
+    /**
+     * `repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];`
+     */
+    @kotlin.jvm.JvmSynthetic
+    @kotlin.jvm.JvmName("clearStpCanceledOrderIds")
+    public fun com.google.protobuf.kotlin.DslList<kotlin.String, StpCanceledOrderIdsProxy>.clear() {
+      _builder.clearStpCanceledOrderIds()
+    }
   }
 }
 @kotlin.jvm.JvmSynthetic
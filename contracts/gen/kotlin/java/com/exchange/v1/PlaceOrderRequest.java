@@ -34,6 +34,9 @@ private static final long serialVersionUID = 0L;
     price_ = "";
     quantity_ = "";
     timeInForce_ = 0;
+    selfTradePrevention_ = 0;
+    makerFeeBps_ = "";
+    takerFeeBps_ = "";
   }
 
   public static final com.google.protobuf.Descriptors.Descriptor
@@ -247,6 +250,113 @@ private static final long serialVersionUID = 0L;
     return result == null ? com.exchange.v1.TimeInForce.UNRECOGNIZED : result;
   }
 
+  public static final int POST_ONLY_FIELD_NUMBER = 8;
+  private boolean postOnly_ = false;
+  /**
+   * <code>bool post_only = 8 [json_name = "postOnly"];</code>
+   * @return The postOnly.
+   */
+  @java.lang.Override
+  public boolean getPostOnly() {
+    return postOnly_;
+  }
+
+  public static final int SELF_TRADE_PREVENTION_FIELD_NUMBER = 9;
+  private int selfTradePrevention_ = 0;
+  /**
+   * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+   * @return The enum numeric value on the wire for selfTradePrevention.
+   */
+  @java.lang.Override public int getSelfTradePreventionValue() {
+    return selfTradePrevention_;
+  }
+  /**
+   * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+   * @return The selfTradePrevention.
+   */
+  @java.lang.Override public com.exchange.v1.SelfTradePrevention getSelfTradePrevention() {
+    com.exchange.v1.SelfTradePrevention result = com.exchange.v1.SelfTradePrevention.forNumber(selfTradePrevention_);
+    return result == null ? com.exchange.v1.SelfTradePrevention.UNRECOGNIZED : result;
+  }
+
+  public static final int MAKER_FEE_BPS_FIELD_NUMBER = 10;
+  @SuppressWarnings("serial")
+  private volatile java.lang.Object makerFeeBps_ = "";
+  /**
+   * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+   * @return The makerFeeBps.
+   */
+  @java.lang.Override
+  public java.lang.String getMakerFeeBps() {
+    java.lang.Object ref = makerFeeBps_;
+    if (ref instanceof java.lang.String) {
+      return (java.lang.String) ref;
+    } else {
+      com.google.protobuf.ByteString bs = 
+          (com.google.protobuf.ByteString) ref;
+      java.lang.String s = bs.toStringUtf8();
+      makerFeeBps_ = s;
+      return s;
+    }
+  }
+  /**
+   * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+   * @return The bytes for makerFeeBps.
+   */
+  @java.lang.Override
+  public com.google.protobuf.ByteString
+      getMakerFeeBpsBytes() {
+    java.lang.Object ref = makerFeeBps_;
+    if (ref instanceof java.lang.String) {
+      com.google.protobuf.ByteString b = 
+          com.google.protobuf.ByteString.copyFromUtf8(
+              (java.lang.String) ref);
+      makerFeeBps_ = b;
+      return b;
+    } else {
+      return (com.google.protobuf.ByteString) ref;
+    }
+  }
+
+  public static final int TAKER_FEE_BPS_FIELD_NUMBER = 11;
+  @SuppressWarnings("serial")
+  private volatile java.lang.Object takerFeeBps_ = "";
+  /**
+   * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+   * @return The takerFeeBps.
+   */
+  @java.lang.Override
+  public java.lang.String getTakerFeeBps() {
+    java.lang.Object ref = takerFeeBps_;
+    if (ref instanceof java.lang.String) {
+      return (java.lang.String) ref;
+    } else {
+      com.google.protobuf.ByteString bs = 
+          (com.google.protobuf.ByteString) ref;
+      java.lang.String s = bs.toStringUtf8();
+      takerFeeBps_ = s;
+      return s;
+    }
+  }
+  /**
+   * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+   * @return The bytes for takerFeeBps.
+   */
+  @java.lang.Override
+  public com.google.protobuf.ByteString
+      getTakerFeeBpsBytes() {
+    java.lang.Object ref = takerFeeBps_;
+    if (ref instanceof java.lang.String) {
+      com.google.protobuf.ByteString b = 
+          com.google.protobuf.ByteString.copyFromUtf8(
+              (java.lang.String) ref);
+      takerFeeBps_ = b;
+      return b;
+    } else {
+      return (com.google.protobuf.ByteString) ref;
+    }
+  }
+
   private byte memoizedIsInitialized = -1;
   @java.lang.Override
   public final boolean isInitialized() {
@@ -282,6 +392,18 @@ private static final long serialVersionUID = 0L;
     if (timeInForce_ != com.exchange.v1.TimeInForce.TIME_IN_FORCE_UNSPECIFIED.getNumber()) {
       output.writeEnum(7, timeInForce_);
     }
+    if (postOnly_ != false) {
+      output.writeBool(8, postOnly_);
+    }
+    if (selfTradePrevention_ != com.exchange.v1.SelfTradePrevention.SELF_TRADE_PREVENTION_UNSPECIFIED.getNumber()) {
+      output.writeEnum(9, selfTradePrevention_);
+    }
+    if (!com.google.protobuf.GeneratedMessage.isStringEmpty(makerFeeBps_)) {
+      com.google.protobuf.GeneratedMessage.writeString(output, 10, makerFeeBps_);
+    }
+    if (!com.google.protobuf.GeneratedMessage.isStringEmpty(takerFeeBps_)) {
+      com.google.protobuf.GeneratedMessage.writeString(output, 11, takerFeeBps_);
+    }
     getUnknownFields().writeTo(output);
   }
 
@@ -316,6 +438,20 @@ private static final long serialVersionUID = 0L;
       size += com.google.protobuf.CodedOutputStream
         .computeEnumSize(7, timeInForce_);
     }
+    if (postOnly_ != false) {
+      size += com.google.protobuf.CodedOutputStream
+        .computeBoolSize(8, postOnly_);
+    }
+    if (selfTradePrevention_ != com.exchange.v1.SelfTradePrevention.SELF_TRADE_PREVENTION_UNSPECIFIED.getNumber()) {
+      size += com.google.protobuf.CodedOutputStream
+        .computeEnumSize(9, selfTradePrevention_);
+    }
+    if (!com.google.protobuf.GeneratedMessage.isStringEmpty(makerFeeBps_)) {
+      size += com.google.protobuf.GeneratedMessage.computeStringSize(10, makerFeeBps_);
+    }
+    if (!com.google.protobuf.GeneratedMessage.isStringEmpty(takerFeeBps_)) {
+      size += com.google.protobuf.GeneratedMessage.computeStringSize(11, takerFeeBps_);
+    }
     size += getUnknownFields().getSerializedSize();
     memoizedSize = size;
     return size;
@@ -345,6 +481,13 @@ private static final long serialVersionUID = 0L;
     if (!getQuantity()
         .equals(other.getQuantity())) return false;
     if (timeInForce_ != other.timeInForce_) return false;
+    if (getPostOnly()
+        != other.getPostOnly()) return false;
+    if (selfTradePrevention_ != other.selfTradePrevention_) return false;
+    if (!getMakerFeeBps()
+        .equals(other.getMakerFeeBps())) return false;
+    if (!getTakerFeeBps()
+        .equals(other.getTakerFeeBps())) return false;
     if (!getUnknownFields().equals(other.getUnknownFields())) return false;
     return true;
   }
@@ -372,6 +515,15 @@ private static final long serialVersionUID = 0L;
     hash = (53 * hash) + getQuantity().hashCode();
     hash = (37 * hash) + TIME_IN_FORCE_FIELD_NUMBER;
     hash = (53 * hash) + timeInForce_;
+    hash = (37 * hash) + POST_ONLY_FIELD_NUMBER;
+    hash = (53 * hash) + com.google.protobuf.Internal.hashBoolean(
+        getPostOnly());
+    hash = (37 * hash) + SELF_TRADE_PREVENTION_FIELD_NUMBER;
+    hash = (53 * hash) + selfTradePrevention_;
+    hash = (37 * hash) + MAKER_FEE_BPS_FIELD_NUMBER;
+    hash = (53 * hash) + getMakerFeeBps().hashCode();
+    hash = (37 * hash) + TAKER_FEE_BPS_FIELD_NUMBER;
+    hash = (53 * hash) + getTakerFeeBps().hashCode();
     hash = (29 * hash) + getUnknownFields().hashCode();
     memoizedHashCode = hash;
     return hash;
@@ -520,6 +672,10 @@ private static final long serialVersionUID = 0L;
       price_ = "";
       quantity_ = "";
       timeInForce_ = 0;
+      postOnly_ = false;
+      selfTradePrevention_ = 0;
+      makerFeeBps_ = "";
+      takerFeeBps_ = "";
       return this;
     }
 
@@ -578,6 +734,18 @@ private static final long serialVersionUID = 0L;
       if (((from_bitField0_ & 0x00000040) != 0)) {
         result.timeInForce_ = timeInForce_;
       }
+      if (((from_bitField0_ & 0x00000080) != 0)) {
+        result.postOnly_ = postOnly_;
+      }
+      if (((from_bitField0_ & 0x00000100) != 0)) {
+        result.selfTradePrevention_ = selfTradePrevention_;
+      }
+      if (((from_bitField0_ & 0x00000200) != 0)) {
+        result.makerFeeBps_ = makerFeeBps_;
+      }
+      if (((from_bitField0_ & 0x00000400) != 0)) {
+        result.takerFeeBps_ = takerFeeBps_;
+      }
       result.bitField0_ |= to_bitField0_;
     }
 
@@ -620,6 +788,22 @@ private static final long serialVersionUID = 0L;
       if (other.timeInForce_ != 0) {
         setTimeInForceValue(other.getTimeInForceValue());
       }
+      if (other.getPostOnly() != false) {
+        setPostOnly(other.getPostOnly());
+      }
+      if (other.selfTradePrevention_ != 0) {
+        setSelfTradePreventionValue(other.getSelfTradePreventionValue());
+      }
+      if (!other.getMakerFeeBps().isEmpty()) {
+        makerFeeBps_ = other.makerFeeBps_;
+        bitField0_ |= 0x00000200;
+        onChanged();
+      }
+      if (!other.getTakerFeeBps().isEmpty()) {
+        takerFeeBps_ = other.takerFeeBps_;
+        bitField0_ |= 0x00000400;
+        onChanged();
+      }
       this.mergeUnknownFields(other.getUnknownFields());
       onChanged();
       return this;
@@ -683,6 +867,26 @@ private static final long serialVersionUID = 0L;
               bitField0_ |= 0x00000040;
               break;
             } // case 56
+            case 64: {
+              postOnly_ = input.readBool();
+              bitField0_ |= 0x00000080;
+              break;
+            } // case 64
+            case 72: {
+              selfTradePrevention_ = input.readEnum();
+              bitField0_ |= 0x00000100;
+              break;
+            } // case 72
+            case 82: {
+              makerFeeBps_ = input.readStringRequireUtf8();
+              bitField0_ |= 0x00000200;
+              break;
+            } // case 82
+            case 90: {
+              takerFeeBps_ = input.readStringRequireUtf8();
+              bitField0_ |= 0x00000400;
+              break;
+            } // case 90
             default: {
               if (!super.parseUnknownField(input, extensionRegistry, tag)) {
                 done = true; // was an endgroup tag
@@ -1190,6 +1394,233 @@ private static final long serialVersionUID = 0L;
       return this;
     }
 
+    private boolean postOnly_ ;
+    /**
+     * <code>bool post_only = 8 [json_name = "postOnly"];</code>
+     * @return The postOnly.
+     */
+    @java.lang.Override
+    public boolean getPostOnly() {
+      return postOnly_;
+    }
+    /**
+     * <code>bool post_only = 8 [json_name = "postOnly"];</code>
+     * @param value The postOnly to set.
+     * @return This builder for chaining.
+     */
+    public Builder setPostOnly(boolean value) {
+
+      postOnly_ = value;
+      bitField0_ |= 0x00000080;
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>bool post_only = 8 [json_name = "postOnly"];</code>
+     * @return This builder for chaining.
+     */
+    public Builder clearPostOnly() {
+      bitField0_ = (bitField0_ & ~0x00000080);
+      postOnly_ = false;
+      onChanged();
+      return this;
+    }
+
+    private int selfTradePrevention_ = 0;
+    /**
+     * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+     * @return The enum numeric value on the wire for selfTradePrevention.
+     */
+    @java.lang.Override public int getSelfTradePreventionValue() {
+      return selfTradePrevention_;
+    }
+    /**
+     * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+     * @param value The enum numeric value on the wire for selfTradePrevention to set.
+     * @return This builder for chaining.
+     */
+    public Builder setSelfTradePreventionValue(int value) {
+      selfTradePrevention_ = value;
+      bitField0_ |= 0x00000100;
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+     * @return The selfTradePrevention.
+     */
+    @java.lang.Override
+    public com.exchange.v1.SelfTradePrevention getSelfTradePrevention() {
+      com.exchange.v1.SelfTradePrevention result = com.exchange.v1.SelfTradePrevention.forNumber(selfTradePrevention_);
+      return result == null ? com.exchange.v1.SelfTradePrevention.UNRECOGNIZED : result;
+    }
+    /**
+     * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+     * @param value The selfTradePrevention to set.
+     * @return This builder for chaining.
+     */
+    public Builder setSelfTradePrevention(com.exchange.v1.SelfTradePrevention value) {
+      if (value == null) { throw new NullPointerException(); }
+      bitField0_ |= 0x00000100;
+      selfTradePrevention_ = value.getNumber();
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+     * @return This builder for chaining.
+     */
+    public Builder clearSelfTradePrevention() {
+      bitField0_ = (bitField0_ & ~0x00000100);
+      selfTradePrevention_ = 0;
+      onChanged();
+      return this;
+    }
+
+    private java.lang.Object makerFeeBps_ = "";
+    /**
+     * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+     * @return The makerFeeBps.
+     */
+    public java.lang.String getMakerFeeBps() {
+      java.lang.Object ref = makerFeeBps_;
+      if (!(ref instanceof java.lang.String)) {
+        com.google.protobuf.ByteString bs =
+            (com.google.protobuf.ByteString) ref;
+        java.lang.String s = bs.toStringUtf8();
+        makerFeeBps_ = s;
+        return s;
+      } else {
+        return (java.lang.String) ref;
+      }
+    }
+    /**
+     * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+     * @return The bytes for makerFeeBps.
+     */
+    public com.google.protobuf.ByteString
+        getMakerFeeBpsBytes() {
+      java.lang.Object ref = makerFeeBps_;
+      if (ref instanceof String) {
+        com.google.protobuf.ByteString b = 
+            com.google.protobuf.ByteString.copyFromUtf8(
+                (java.lang.String) ref);
+        makerFeeBps_ = b;
+        return b;
+      } else {
+        return (com.google.protobuf.ByteString) ref;
+      }
+    }
+    /**
+     * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+     * @param value The makerFeeBps to set.
+     * @return This builder for chaining.
+     */
+    public Builder setMakerFeeBps(
+        java.lang.String value) {
+      if (value == null) { throw new NullPointerException(); }
+      makerFeeBps_ = value;
+      bitField0_ |= 0x00000200;
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+     * @return This builder for chaining.
+     */
+    public Builder clearMakerFeeBps() {
+      makerFeeBps_ = getDefaultInstance().getMakerFeeBps();
+      bitField0_ = (bitField0_ & ~0x00000200);
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+     * @param value The bytes for makerFeeBps to set.
+     * @return This builder for chaining.
+     */
+    public Builder setMakerFeeBpsBytes(
+        com.google.protobuf.ByteString value) {
+      if (value == null) { throw new NullPointerException(); }
+      checkByteStringIsUtf8(value);
+      makerFeeBps_ = value;
+      bitField0_ |= 0x00000200;
+      onChanged();
+      return this;
+    }
+
+    private java.lang.Object takerFeeBps_ = "";
+    /**
+     * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+     * @return The takerFeeBps.
+     */
+    public java.lang.String getTakerFeeBps() {
+      java.lang.Object ref = takerFeeBps_;
+      if (!(ref instanceof java.lang.String)) {
+        com.google.protobuf.ByteString bs =
+            (com.google.protobuf.ByteString) ref;
+        java.lang.String s = bs.toStringUtf8();
+        takerFeeBps_ = s;
+        return s;
+      } else {
+        return (java.lang.String) ref;
+      }
+    }
+    /**
+     * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+     * @return The bytes for takerFeeBps.
+     */
+    public com.google.protobuf.ByteString
+        getTakerFeeBpsBytes() {
+      java.lang.Object ref = takerFeeBps_;
+      if (ref instanceof String) {
+        com.google.protobuf.ByteString b = 
+            com.google.protobuf.ByteString.copyFromUtf8(
+                (java.lang.String) ref);
+        takerFeeBps_ = b;
+        return b;
+      } else {
+        return (com.google.protobuf.ByteString) ref;
+      }
+    }
+    /**
+     * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+     * @param value The takerFeeBps to set.
+     * @return This builder for chaining.
+     */
+    public Builder setTakerFeeBps(
+        java.lang.String value) {
+      if (value == null) { throw new NullPointerException(); }
+      takerFeeBps_ = value;
+      bitField0_ |= 0x00000400;
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+     * @return This builder for chaining.
+     */
+    public Builder clearTakerFeeBps() {
+      takerFeeBps_ = getDefaultInstance().getTakerFeeBps();
+      bitField0_ = (bitField0_ & ~0x00000400);
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+     * @param value The bytes for takerFeeBps to set.
+     * @return This builder for chaining.
+     */
+    public Builder setTakerFeeBpsBytes(
+        com.google.protobuf.ByteString value) {
+      if (value == null) { throw new NullPointerException(); }
+      checkByteStringIsUtf8(value);
+      takerFeeBps_ = value;
+      bitField0_ |= 0x00000400;
+      onChanged();
+      return this;
+    }
+
     // @@protoc_insertion_point(builder_scope:exchange.v1.PlaceOrderRequest)
   }
 
@@ -93,4 +93,45 @@ public interface PlaceOrderRequestOrBuilder extends
    * @return The timeInForce.
    */
   com.exchange.v1.TimeInForce getTimeInForce();
+
+  /**
+   * <code>bool post_only = 8 [json_name = "postOnly"];</code>
+   * @return The postOnly.
+   */
+  boolean getPostOnly();
+
+  /**
+   * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+   * @return The enum numeric value on the wire for selfTradePrevention.
+   */
+  int getSelfTradePreventionValue();
+  /**
+   * <code>.exchange.v1.SelfTradePrevention self_trade_prevention = 9 [json_name = "selfTradePrevention"];</code>
+   * @return The selfTradePrevention.
+   */
+  com.exchange.v1.SelfTradePrevention getSelfTradePrevention();
+
+  /**
+   * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+   * @return The makerFeeBps.
+   */
+  java.lang.String getMakerFeeBps();
+  /**
+   * <code>string maker_fee_bps = 10 [json_name = "makerFeeBps"];</code>
+   * @return The bytes for makerFeeBps.
+   */
+  com.google.protobuf.ByteString
+      getMakerFeeBpsBytes();
+
+  /**
+   * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+   * @return The takerFeeBps.
+   */
+  java.lang.String getTakerFeeBps();
+  /**
+   * <code>string taker_fee_bps = 11 [json_name = "takerFeeBps"];</code>
+   * @return The bytes for takerFeeBps.
+   */
+  com.google.protobuf.ByteString
+      getTakerFeeBpsBytes();
 }
@@ -33,6 +33,8 @@ private static final long serialVersionUID = 0L;
     symbol_ = "";
     rejectCode_ = "";
     correlationId_ = "";
+    stpCanceledOrderIds_ =
+        com.google.protobuf.LazyStringArrayList.emptyList();
   }
 
   public static final com.google.protobuf.Descriptors.Descriptor
@@ -292,6 +294,43 @@ private static final long serialVersionUID = 0L;
     }
   }
 
+  public static final int STP_CANCELED_ORDER_IDS_FIELD_NUMBER = 9;
+  @SuppressWarnings("serial")
+  private com.google.protobuf.LazyStringArrayList stpCanceledOrderIds_ =
+      com.google.protobuf.LazyStringArrayList.emptyList();
+  /**
+   * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+   * @return A list containing the stpCanceledOrderIds.
+   */
+  public com.google.protobuf.ProtocolStringList
+      getStpCanceledOrderIdsList() {
+    return stpCanceledOrderIds_;
+  }
+  /**
+   * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+   * @return The count of stpCanceledOrderIds.
+   */
+  public int getStpCanceledOrderIdsCount() {
+    return stpCanceledOrderIds_.size();
+  }
+  /**
+   * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+   * @param index The index of the element to return.
+   * @return The stpCanceledOrderIds at the given index.
+   */
+  public java.lang.String getStpCanceledOrderIds(int index) {
+    return stpCanceledOrderIds_.get(index);
+  }
+  /**
+   * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+   * @param index The index of the value to return.
+   * @return The bytes of the stpCanceledOrderIds at the given index.
+   */
+  public com.google.protobuf.ByteString
+      getStpCanceledOrderIdsBytes(int index) {
+    return stpCanceledOrderIds_.getByteString(index);
+  }
+
   private byte memoizedIsInitialized = -1;
   @java.lang.Override
   public final boolean isInitialized() {
@@ -330,6 +369,9 @@ private static final long serialVersionUID = 0L;
     if (!com.google.protobuf.GeneratedMessage.isStringEmpty(correlationId_)) {
       com.google.protobuf.GeneratedMessage.writeString(output, 8, correlationId_);
     }
+    for (int i = 0; i < stpCanceledOrderIds_.size(); i++) {
+      com.google.protobuf.GeneratedMessage.writeString(output, 9, stpCanceledOrderIds_.getRaw(i));
+    }
     getUnknownFields().writeTo(output);
   }
 
@@ -366,6 +408,14 @@ private static final long serialVersionUID = 0L;
     if (!com.google.protobuf.GeneratedMessage.isStringEmpty(correlationId_)) {
       size += com.google.protobuf.GeneratedMessage.computeStringSize(8, correlationId_);
     }
+    {
+      int dataSize = 0;
+      for (int i = 0; i < stpCanceledOrderIds_.size(); i++) {
+        dataSize += computeStringSizeNoTag(stpCanceledOrderIds_.getRaw(i));
+      }
+      size += dataSize;
+      size += 1 * getStpCanceledOrderIdsList().size();
+    }
     size += getUnknownFields().getSerializedSize();
     memoizedSize = size;
     return size;
@@ -400,6 +450,8 @@ private static final long serialVersionUID = 0L;
         .equals(other.getRejectCode())) return false;
     if (!getCorrelationId()
         .equals(other.getCorrelationId())) return false;
+    if (!getStpCanceledOrderIdsList()
+        .equals(other.getStpCanceledOrderIdsList())) return false;
     if (!getUnknownFields().equals(other.getUnknownFields())) return false;
     return true;
   }
@@ -431,6 +483,10 @@ private static final long serialVersionUID = 0L;
     hash = (53 * hash) + getRejectCode().hashCode();
     hash = (37 * hash) + CORRELATION_ID_FIELD_NUMBER;
     hash = (53 * hash) + getCorrelationId().hashCode();
+    if (getStpCanceledOrderIdsCount() > 0) {
+      hash = (37 * hash) + STP_CANCELED_ORDER_IDS_FIELD_NUMBER;
+      hash = (53 * hash) + getStpCanceledOrderIdsList().hashCode();
+    }
     hash = (29 * hash) + getUnknownFields().hashCode();
     memoizedHashCode = hash;
     return hash;
@@ -580,6 +636,8 @@ private static final long serialVersionUID = 0L;
       }
       rejectCode_ = "";
       correlationId_ = "";
+      stpCanceledOrderIds_ =
+          com.google.protobuf.LazyStringArrayList.emptyList();
       return this;
     }
 
@@ -641,6 +699,10 @@ private static final long serialVersionUID = 0L;
       if (((from_bitField0_ & 0x00000080) != 0)) {
         result.correlationId_ = correlationId_;
       }
+      if (((from_bitField0_ & 0x00000100) != 0)) {
+        stpCanceledOrderIds_.makeImmutable();
+        result.stpCanceledOrderIds_ = stpCanceledOrderIds_;
+      }
       result.bitField0_ |= to_bitField0_;
     }
 
@@ -690,6 +752,16 @@ private static final long serialVersionUID = 0L;
         bitField0_ |= 0x00000080;
         onChanged();
       }
+      if (!other.stpCanceledOrderIds_.isEmpty()) {
+        if (stpCanceledOrderIds_.isEmpty()) {
+          stpCanceledOrderIds_ = other.stpCanceledOrderIds_;
+          bitField0_ |= 0x00000100;
+        } else {
+          ensureStpCanceledOrderIdsIsMutable();
+          stpCanceledOrderIds_.addAll(other.stpCanceledOrderIds_);
+        }
+        onChanged();
+      }
       this.mergeUnknownFields(other.getUnknownFields());
       onChanged();
       return this;
@@ -758,6 +830,12 @@ private static final long serialVersionUID = 0L;
               bitField0_ |= 0x00000080;
               break;
             } // case 66
+            case 74: {
+              java.lang.String s = input.readStringRequireUtf8();
+              ensureStpCanceledOrderIdsIsMutable();
+              stpCanceledOrderIds_.add(s);
+              break;
+            } // case 74
             default: {
               if (!super.parseUnknownField(input, extensionRegistry, tag)) {
                 done = true; // was an endgroup tag
@@ -1320,6 +1398,117 @@ private static final long serialVersionUID = 0L;
       return this;
     }
 
+    private com.google.protobuf.LazyStringArrayList stpCanceledOrderIds_ =
+        com.google.protobuf.LazyStringArrayList.emptyList();
+    private void ensureStpCanceledOrderIdsIsMutable() {
+      if (!stpCanceledOrderIds_.isModifiable()) {
+        stpCanceledOrderIds_ = new com.google.protobuf.LazyStringArrayList(stpCanceledOrderIds_);
+      }
+      bitField0_ |= 0x00000100;
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @return A list containing the stpCanceledOrderIds.
+     */
+    public com.google.protobuf.ProtocolStringList
+        getStpCanceledOrderIdsList() {
+      stpCanceledOrderIds_.makeImmutable();
+      return stpCanceledOrderIds_;
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @return The count of stpCanceledOrderIds.
+     */
+    public int getStpCanceledOrderIdsCount() {
+      return stpCanceledOrderIds_.size();
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @param index The index of the element to return.
+     * @return The stpCanceledOrderIds at the given index.
+     */
+    public java.lang.String getStpCanceledOrderIds(int index) {
+      return stpCanceledOrderIds_.get(index);
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @param index The index of the value to return.
+     * @return The bytes of the stpCanceledOrderIds at the given index.
+     */
+    public com.google.protobuf.ByteString
+        getStpCanceledOrderIdsBytes(int index) {
+      return stpCanceledOrderIds_.getByteString(index);
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @param index The index to set the value at.
+     * @param value The stpCanceledOrderIds to set.
+     * @return This builder for chaining.
+     */
+    public Builder setStpCanceledOrderIds(
+        int index, java.lang.String value) {
+      if (value == null) { throw new NullPointerException(); }
+      ensureStpCanceledOrderIdsIsMutable();
+      stpCanceledOrderIds_.set(index, value);
+      bitField0_ |= 0x00000100;
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @param value The stpCanceledOrderIds to add.
+     * @return This builder for chaining.
+     */
+    public Builder addStpCanceledOrderIds(
+        java.lang.String value) {
+      if (value == null) { throw new NullPointerException(); }
+      ensureStpCanceledOrderIdsIsMutable();
+      stpCanceledOrderIds_.add(value);
+      bitField0_ |= 0x00000100;
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @param values The stpCanceledOrderIds to add.
+     * @return This builder for chaining.
+     */
+    public Builder addAllStpCanceledOrderIds(
+        java.lang.Iterable<java.lang.String> values) {
+      ensureStpCanceledOrderIdsIsMutable();
+      com.google.protobuf.AbstractMessageLite.Builder.addAll(
+          values, stpCanceledOrderIds_);
+      bitField0_ |= 0x00000100;
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @return This builder for chaining.
+     */
+    public Builder clearStpCanceledOrderIds() {
+      stpCanceledOrderIds_ =
+        com.google.protobuf.LazyStringArrayList.emptyList();
+      bitField0_ = (bitField0_ & ~0x00000100);;
+      onChanged();
+      return this;
+    }
+    /**
+     * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+     * @param value The bytes of the stpCanceledOrderIds to add.
+     * @return This builder for chaining.
+     */
+    public Builder addStpCanceledOrderIdsBytes(
+        com.google.protobuf.ByteString value) {
+      if (value == null) { throw new NullPointerException(); }
+      checkByteStringIsUtf8(value);
+      ensureStpCanceledOrderIdsIsMutable();
+      stpCanceledOrderIds_.add(value);
+      bitField0_ |= 0x00000100;
+      onChanged();
+      return this;
+    }
+
     // @@protoc_insertion_point(builder_scope:exchange.v1.PlaceOrderResponse)
   }
 
@@ -96,4 +96,29 @@ public interface PlaceOrderResponseOrBuilder extends
    */
   com.google.protobuf.ByteString
       getCorrelationIdBytes();
+
+  /**
+   * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+   * @return A list containing the stpCanceledOrderIds.
+   */
+  java.util.List<java.lang.String>
+      getStpCanceledOrderIdsList();
+  /**
+   * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+   * @return The count of stpCanceledOrderIds.
+   */
+  int getStpCanceledOrderIdsCount();
+  /**
+   * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+   * @param index The index of the element to return.
+   * @return The stpCanceledOrderIds at the given index.
+   */
+  java.lang.String getStpCanceledOrderIds(int index);
+  /**
+   * <code>repeated string stp_canceled_order_ids = 9 [json_name = "stpCanceledOrderIds"];</code>
+   * @param index The index of the value to return.
+   * @return The bytes of the stpCanceledOrderIds at the given index.
+   */
+  com.google.protobuf.ByteString
+      getStpCanceledOrderIdsBytes(int index);
 }
@@ -124,7 +124,7 @@ public enum SymbolMode
   }
   public static com.google.protobuf.Descriptors.EnumDescriptor
       getDescriptor() {
-    return com.exchange.v1.TradingProto.getDescriptor().getEnumTypes().get(3);
+    return com.exchange.v1.TradingProto.getDescriptor().getEnumTypes().get(4);
   }
 
   private static final SymbolMode[] VALUES = values();
@@ -107,7 +107,7 @@ public final class TradingProto extends com.google.protobuf.GeneratedFile {
     java.lang.String[] descriptorData = {
       "\n\031exchange/v1/trading.proto\022\013exchange.v1" +
       "\032\030exchange/v1/common.proto\032\037google/proto" +
-      "buf/timestamp.proto\"\256\002\n\021PlaceOrderReques" +
+      "buf/timestamp.proto\"\351\003\n\021PlaceOrderReques" +
       "t\0220\n\004meta\030\001 \001(\0132\034.exchange.v1.CommandMet" +
       "adataR\004meta\022\031\n\010order_id\030\002 \001(\tR\007orderId\022%" +
       "\n\004side\030\003 \001(\0162\021.exchange.v1.SideR\004side\0225\n" +
@@ -115,22 +115,28 @@ public final class TradingProto extends com.google.protobuf.GeneratedFile {
       "eR\torderType\022\024\n\005price\030\005 \001(\tR\005price\022\032\n\010qu" +
       "antity\030\006 \001(\tR\010quantity\022<\n\rtime_in_force\030" +
       "\007 \001(\0162\030.exchange.v1.TimeInForceR\013timeInF" +
-      "orce\"a\n\022CancelOrderRequest\0220\n\004meta\030\001 \001(\013" +
-      "2\034.exchange.v1.CommandMetadataR\004meta\022\031\n\010" +
-      "order_id\030\002 \001(\tR\007orderId\"\215\001\n\024SetSymbolMod" +
-      "eRequest\0220\n\004meta\030\001 \001(\0132\034.exchange.v1.Com" +
-      "mandMetadataR\004meta\022+\n\004mode\030\002 \001(\0162\027.excha" +
-      "nge.v1.SymbolModeR\004mode\022\026\n\006reason\030\003 \001(\tR" +
-      "\006reason\"\\\n\020CancelAllRequest\0220\n\004meta\030\001 \001(" +
-      "\0132\034.exchange.v1.CommandMetadataR\004meta\022\026\n" +
-      "\006reason\030\002 \001(\tR\006reason\"\222\002\n\022PlaceOrderResp" +
-      "onse\022\032\n\010accepted\030\001 \001(\010R\010accepted\022\031\n\010orde" +
-      "r_id\030\002 \001(\tR\007orderId\022\026\n\006status\030\003 \001(\tR\006sta" +
-      "tus\022\026\n\006symbol\030\004 \001(\tR\006symbol\022\020\n\003seq\030\005 \001(\004" +
-      "R\003seq\022;\n\013accepted_at\030\006 \001(\0132\032.google.prot" +
-      "obuf.TimestampR\nacceptedAt\022\037\n\013reject_cod" +
-      "e\030\007 \001(\tR\nrejectCode\022%\n\016correlation_id\030\010 " +
-      "\001(\tR\rcorrelationId\"\223\002\n\023CancelOrderRespon" +
+      "orce\022\033\n\tpost_only\030\010 \001(\010R\010postOnly\022T\n\025sel" +
+      "f_trade_prevention\030\t \001(\0162 .exchange.v1.S" +
+      "elfTradePreventionR\023selfTradePrevention\022" +
+      "\"\n\rmaker_fee_bps\030\n \001(\tR\013makerFeeBps\022\"\n\rt" +
+      "aker_fee_bps\030\013 \001(\tR\013takerFeeBps\"a\n\022Cance" +
+      "lOrderRequest\0220\n\004meta\030\001 \001(\0132\034.exchange.v" +
+      "1.CommandMetadataR\004meta\022\031\n\010order_id\030\002 \001(" +
+      "\tR\007orderId\"\215\001\n\024SetSymbolModeRequest\0220\n\004m" +
+      "eta\030\001 \001(\0132\034.exchange.v1.CommandMetadataR" +
+      "\004meta\022+\n\004mode\030\002 \001(\0162\027.exchange.v1.Symbol" +
+      "ModeR\004mode\022\026\n\006reason\030\003 \001(\tR\006reason\"\\\n\020Ca" +
+      "ncelAllRequest\0220\n\004meta\030\001 \001(\0132\034.exchange." +
+      "v1.CommandMetadataR\004meta\022\026\n\006reason\030\002 \001(\t" +
+      "R\006reason\"\307\002\n\022PlaceOrderResponse\022\032\n\010accep" +
+      "ted\030\001 \001(\010R\010accepted\022\031\n\010order_id\030\002 \001(\tR\007o" +
+      "rderId\022\026\n\006status\030\003 \001(\tR\006status\022\026\n\006symbol" +
+      "\030\004 \001(\tR\006symbol\022\020\n\003seq\030\005 \001(\004R\003seq\022;\n\013acce" +
+      "pted_at\030\006 \001(\0132\032.google.protobuf.Timestam" +
+      "pR\nacceptedAt\022\037\n\013reject_code\030\007 \001(\tR\nreje" +
+      "ctCode\022%\n\016correlation_id\030\010 \001(\tR\rcorrelat" +
+      "ionId\0223\n\026stp_canceled_order_ids\030\t \003(\tR\023s" +
+      "tpCanceledOrderIds\"\223\002\n\023CancelOrderRespon" +
       "se\022\032\n\010accepted\030\001 \001(\010R\010accepted\022\031\n\010order_" +
       "id\030\002 \001(\tR\007orderId\022\026\n\006status\030\003 \001(\tR\006statu" +
       "s\022\026\n\006symbol\030\004 \001(\tR\006symbol\022\020\n\003seq\030\005 \001(\004R\003" +
@@ -185,24 +191,29 @@ public final class TradingProto extends com.google.protobuf.GeneratedFile {
       "\021ORDER_TYPE_MARKET\020\002*q\n\013TimeInForce\022\035\n\031T" +
       "IME_IN_FORCE_UNSPECIFIED\020\000\022\025\n\021TIME_IN_FO" +
       "RCE_GTC\020\001\022\025\n\021TIME_IN_FORCE_IOC\020\002\022\025\n\021TIME" +
-      "_IN_FORCE_FOK\020\003*\224\001\n\nSymbolMode\022\033\n\027SYMBOL" +
-      "_MODE_UNSPECIFIED\020\000\022\026\n\022SYMBOL_MODE_NORMA" +
-      "L\020\001\022\033\n\027SYMBOL_MODE_CANCEL_ONLY\020\002\022\031\n\025SYMB" +
-      "OL_MODE_SOFT_HALT\020\003\022\031\n\025SYMBOL_MODE_HARD_" +
-      "HALT\020\0042\331\002\n\022TradingCoreService\022M\n\nPlaceOr" +
-      "der\022\036.exchange.v1.PlaceOrderRequest\032\037.ex" +
-      "change.v1.PlaceOrderResponse\022P\n\013CancelOr" +
-      "der\022\037.exchange.v1.CancelOrderRequest\032 .e" +
-      "xchange.v1.CancelOrderResponse\022V\n\rSetSym" +
-      "bolMode\022!.exchange.v1.SetSymbolModeReque" +
-      "st\032\".exchange.v1.SetSymbolModeResponse\022J" +
-      "\n\tCancelAll\022\035.exchange.v1.CancelAllReque" +
-      "st\032\036.exchange.v1.CancelAllResponseB\302\001\n\017c" +
-      "om.exchange.v1B\014TradingProtoP\001ZTgithub.c" +
-      "om/quanta-exchange/exchange-platform/con" +
-      "tracts/gen/go/exchange/v1;exchangev1\242\002\003E" +
-      "XX\252\002\013Exchange.V1\312\002\013Exchange\\V1\342\002\027Exchang" +
-      "e\\V1\\GPBMetadata\352\002\014Exchange::V1b\006proto3"
+      "_IN_FORCE_FOK\020\003*\265\001\n\023SelfTradePrevention\022" +
+      "%\n!SELF_TRADE_PREVENTION_UNSPECIFIED\020\000\022\'" +
+      "\n#SELF_TRADE_PREVENTION_CANCEL_NEWEST\020\001\022" +
+      "\'\n#SELF_TRADE_PREVENTION_CANCEL_OLDEST\020\002" +
+      "\022%\n!SELF_TRADE_PREVENTION_CANCEL_BOTH\020\003*" +
+      "\224\001\n\nSymbolMode\022\033\n\027SYMBOL_MODE_UNSPECIFIE" +
+      "D\020\000\022\026\n\022SYMBOL_MODE_NORMAL\020\001\022\033\n\027SYMBOL_MO" +
+      "DE_CANCEL_ONLY\020\002\022\031\n\025SYMBOL_MODE_SOFT_HAL" +
+      "T\020\003\022\031\n\025SYMBOL_MODE_HARD_HALT\020\0042\331\002\n\022Tradi" +
+      "ngCoreService\022M\n\nPlaceOrder\022\036.exchange.v" +
+      "1.PlaceOrderRequest\032\037.exchange.v1.PlaceO" +
+      "rderResponse\022P\n\013CancelOrder\022\037.exchange.v" +
+      "1.CancelOrderRequest\032 .exchange.v1.Cance" +
+      "lOrderResponse\022V\n\rSetSymbolMode\022!.exchan" +
+      "ge.v1.SetSymbolModeRequest\032\".exchange.v1" +
+      ".SetSymbolModeResponse\022J\n\tCancelAll\022\035.ex" +
+      "change.v1.CancelAllRequest\032\036.exchange.v1" +
+      ".CancelAllResponseB\302\001\n\017com.exchange.v1B\014" +
+      "TradingProtoP\001ZTgithub.com/quanta-exchan" +
+      "ge/exchange-platform/contracts/gen/go/ex" +
+      "change/v1;exchangev1\242\002\003EXX\252\002\013Exchange.V1" +
+      "\312\002\013Exchange\\V1\342\002\027Exchange\\V1\\GPBMetadata" +
+      "\352\002\014Exchange::V1b\006proto3"
     };
     descriptor = com.google.protobuf.Descriptors.FileDescriptor
       .internalBuildGeneratedFileFrom(descriptorData,
@@ -215,7 +226,7 @@ public final class TradingProto extends com.google.protobuf.GeneratedFile {
     internal_static_exchange_v1_PlaceOrderRequest_fieldAccessorTable = new
       com.google.protobuf.GeneratedMessage.FieldAccessorTable(
         internal_static_exchange_v1_PlaceOrderRequest_descriptor,
-        new java.lang.String[] { "Meta", "OrderId", "Side", "OrderType", "Price", "Quantity", "TimeInForce", });
+        new java.lang.String[] { "Meta", "OrderId", "Side", "OrderType", "Price", "Quantity", "TimeInForce", "PostOnly", "SelfTradePrevention", "MakerFeeBps", "TakerFeeBps", });
     internal_static_exchange_v1_CancelOrderRequest_descriptor =
       getDescriptor().getMessageType(1);
     internal_static_exchange_v1_CancelOrderRequest_fieldAccessorTable = new
@@ -239,7 +250,7 @@ public final class TradingProto extends com.google.protobuf.GeneratedFile {
     internal_static_exchange_v1_PlaceOrderResponse_fieldAccessorTable = new
       com.google.protobuf.GeneratedMessage.FieldAccessorTable(
         internal_static_exchange_v1_PlaceOrderResponse_descriptor,
-        new java.lang.String[] { "Accepted", "OrderId", "Status", "Symbol", "Seq", "AcceptedAt", "RejectCode", "CorrelationId", });
+        new java.lang.String[] { "Accepted", "OrderId", "Status", "Symbol", "Seq", "AcceptedAt", "RejectCode", "CorrelationId", "StpCanceledOrderIds", });
     internal_static_exchange_v1_CancelOrderResponse_descriptor =
       getDescriptor().getMessageType(5);
     internal_static_exchange_v1_CancelOrderResponse_fieldAccessorTable = new
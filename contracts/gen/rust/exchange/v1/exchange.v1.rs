@@ -136,6 +136,14 @@ pub struct PlaceOrderRequest {
     pub quantity: ::prost::alloc::string::String,
     #[prost(enumeration="TimeInForce", tag="7")]
     pub time_in_force: i32,
+    #[prost(bool, tag="8")]
+    pub post_only: bool,
+    #[prost(enumeration="SelfTradePrevention", tag="9")]
+    pub self_trade_prevention: i32,
+    #[prost(string, tag="10")]
+    pub maker_fee_bps: ::prost::alloc::string::String,
+    #[prost(string, tag="11")]
+    pub taker_fee_bps: ::prost::alloc::string::String,
 }
 #[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
 pub struct CancelOrderRequest {
@@ -178,6 +186,8 @@ pub struct PlaceOrderResponse {
     pub reject_code: ::prost::alloc::string::String,
     #[prost(string, tag="8")]
     pub correlation_id: ::prost::alloc::string::String,
+    #[prost(string, repeated, tag="9")]
+    pub stp_canceled_order_ids: ::prost::alloc::vec::Vec<::prost::alloc::string::String>,
 }
 #[derive(Clone, PartialEq, Eq, Hash, ::prost::Message)]
 pub struct CancelOrderResponse {
@@ -402,6 +412,38 @@ impl TimeInForce {
 }
 #[derive(Clone, Copy, Debug, PartialEq, Eq, Hash, PartialOrd, Ord, ::prost::Enumeration)]
 #[repr(i32)]
+pub enum SelfTradePrevention {
+    Unspecified = 0,
+    CancelNewest = 1,
+    CancelOldest = 2,
+    CancelBoth = 3,
+}
+impl SelfTradePrevention {
+    /// String value of the enum field names used in the ProtoBuf definition.
+    ///
+    /// The values are not transformed in any way and thus are considered stable
+    /// (if the ProtoBuf definition does not change) and safe for programmatic use.
+    pub fn as_str_name(&self) -> &'static str {
+        match self {
+            Self::Unspecified => "SELF_TRADE_PREVENTION_UNSPECIFIED",
+            Self::CancelNewest => "SELF_TRADE_PREVENTION_CANCEL_NEWEST",
+            Self::CancelOldest => "SELF_TRADE_PREVENTION_CANCEL_OLDEST",
+            Self::CancelBoth => "SELF_TRADE_PREVENTION_CANCEL_BOTH",
+        }
+    }
+    /// Creates an enum from field names used in the ProtoBuf definition.
+    pub fn from_str_name(value: &str) -> ::core::option::Option<Self> {
+        match value {
+            "SELF_TRADE_PREVENTION_UNSPECIFIED" => Some(Self::Unspecified),
+            "SELF_TRADE_PREVENTION_CANCEL_NEWEST" => Some(Self::CancelNewest),
+            "SELF_TRADE_PREVENTION_CANCEL_OLDEST" => Some(Self::CancelOldest),
+            "SELF_TRADE_PREVENTION_CANCEL_BOTH" => Some(Self::CancelBoth),
+            _ => None,
+        }
+    }
+}
+#[derive(Clone, Copy, Debug, PartialEq, Eq, Hash, PartialOrd, Ord, ::prost::Enumeration)]
+#[repr(i32)]
 pub enum SymbolMode {
     Unspecified = 0,
     Normal = 1,
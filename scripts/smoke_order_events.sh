@@ -0,0 +1,201 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+ROOT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)"
+COMPOSE_FILE="${ROOT_DIR}/infra/compose/docker-compose.yml"
+PYTHON_BIN="${PYTHON_BIN:-python3}"
+CORE_ADDR="0.0.0.0:55061"
+EDGE_ADDR=":18091"
+EDGE_BASE_URL="http://localhost:18091"
+
+core_log="/tmp/trading-core-smoke-order-events.log"
+edge_log="/tmp/edge-gateway-smoke-order-events.log"
+kafka_log="/tmp/kafka-consume-smoke-order-events.log"
+
+require_cmd() {
+  local cmd="$1"
+  local hint="$2"
+  if ! command -v "$cmd" >/dev/null 2>&1; then
+    echo "missing required command: ${cmd}. ${hint}" >&2
+    exit 1
+  fi
+}
+
+require_cmd docker "Install Docker Desktop and ensure docker compose works."
+require_cmd curl "Install curl (usually preinstalled on macOS)."
+require_cmd cargo "Install Rust toolchain via rustup."
+require_cmd go "Install Go via brew install go."
+require_cmd "${PYTHON_BIN}" "Install Python 3 (brew install python)."
+
+cleanup() {
+  if [[ -n "${KAFKA_PID:-}" ]] && kill -0 "${KAFKA_PID}" >/dev/null 2>&1; then
+    kill "${KAFKA_PID}" >/dev/null 2>&1 || true
+  fi
+  if [[ -n "${CORE_PID:-}" ]] && kill -0 "${CORE_PID}" >/dev/null 2>&1; then
+    kill "${CORE_PID}" >/dev/null 2>&1 || true
+  fi
+  if [[ -n "${EDGE_PID:-}" ]] && kill -0 "${EDGE_PID}" >/dev/null 2>&1; then
+    kill "${EDGE_PID}" >/dev/null 2>&1 || true
+  fi
+}
+trap cleanup EXIT
+
+docker compose -f "${COMPOSE_FILE}" up -d redpanda redpanda-init redis
+
+echo "Waiting for Redpanda..."
+for _ in {1..40}; do
+  if docker compose -f "${COMPOSE_FILE}" exec -T redpanda rpk cluster info >/dev/null 2>&1; then
+    break
+  fi
+  sleep 1
+done
+
+RUN_ID="$(date +%s)"
+CORE_WAL_DIR="/tmp/trading-core/smoke-order-events-${RUN_ID}/wal"
+CORE_OUTBOX_DIR="/tmp/trading-core/smoke-order-events-${RUN_ID}/outbox"
+mkdir -p "${CORE_WAL_DIR}" "${CORE_OUTBOX_DIR}"
+
+CORE_CFLAGS=""
+CORE_CXXFLAGS=""
+if [[ "$(uname -s)" == "Darwin" ]]; then
+  SDKROOT="$(xcrun --show-sdk-path)"
+  CORE_CFLAGS="-isysroot ${SDKROOT}"
+  CORE_CXXFLAGS="-isysroot ${SDKROOT} -I${SDKROOT}/usr/include/c++/v1"
+fi
+
+echo "Starting trading-core..."
+CFLAGS="${CORE_CFLAGS}" \
+CXXFLAGS="${CORE_CXXFLAGS}" \
+CORE_GRPC_ADDR="${CORE_ADDR}" \
+CORE_SYMBOL="BTC-KRW" \
+CORE_WAL_DIR="${CORE_WAL_DIR}" \
+CORE_OUTBOX_DIR="${CORE_OUTBOX_DIR}" \
+CORE_KAFKA_BROKERS="localhost:29092" \
+CORE_KAFKA_TRADE_TOPIC="core.trade-events.v1" \
+CORE_KAFKA_ORDER_TOPIC="core.order-events.v1" \
+CORE_STUB_TRADES="false" \
+cargo run -p trading-core --bin trading-core >"${core_log}" 2>&1 &
+CORE_PID=$!
+
+echo "Waiting for trading-core gRPC port ${CORE_ADDR}..."
+for _ in {1..60}; do
+  if (echo > /dev/tcp/localhost/55061) >/dev/null 2>&1; then
+    break
+  fi
+  sleep 1
+done
+if ! (echo > /dev/tcp/localhost/55061) >/dev/null 2>&1; then
+  echo "trading-core gRPC is not ready" >&2
+  cat "${core_log}" >&2
+  exit 1
+fi
+
+echo "Starting edge-gateway..."
+EDGE_ADDR="${EDGE_ADDR}" \
+EDGE_DISABLE_DB="true" \
+EDGE_DISABLE_CORE="false" \
+EDGE_CORE_ADDR="localhost:55061" \
+EDGE_KAFKA_BROKERS="localhost:29092" \
+EDGE_KAFKA_TRADE_TOPIC="core.trade-events.v1" \
+EDGE_KAFKA_ORDER_TOPIC="core.order-events.v1" \
+EDGE_KAFKA_GROUP_ID="edge-smoke-order-events-${RUN_ID}" \
+EDGE_SEED_MARKET_DATA="false" \
+EDGE_API_SECRETS="" \
+go run ./services/edge-gateway/cmd/edge-gateway >"${edge_log}" 2>&1 &
+EDGE_PID=$!
+
+echo "Waiting for edge-gateway..."
+for _ in {1..40}; do
+  if curl -sf "${EDGE_BASE_URL}/readyz" >/dev/null; then
+    break
+  fi
+  sleep 1
+done
+if ! curl -sf "${EDGE_BASE_URL}/readyz" >/dev/null; then
+  echo "edge-gateway is not ready" >&2
+  cat "${edge_log}" >&2
+  exit 1
+fi
+
+# OrderAccepted for the resting order, then OrderCanceled for its cancel.
+KAFKA_CAPTURE="/tmp/smoke-order-events-${RUN_ID}.jsonl"
+docker compose -f "${COMPOSE_FILE}" exec -T redpanda \
+  rpk topic consume core.order-events.v1 -n 2 -o end -f '%v\n' >"${KAFKA_CAPTURE}" 2>"${kafka_log}" &
+KAFKA_PID=$!
+sleep 1
+
+EMAIL="smoke.order-events.${RUN_ID}@example.com"
+SIGNUP_RESP="$(curl -fsS -X POST "${EDGE_BASE_URL}/v1/auth/signup" \
+  -H 'Content-Type: application/json' \
+  -d "{\"email\":\"${EMAIL}\",\"password\":\"password1234\"}")"
+SESSION_TOKEN="$(
+SIGNUP_RESP="${SIGNUP_RESP}" "${PYTHON_BIN}" - <<'PY'
+import json, os
+print(json.loads(os.environ["SIGNUP_RESP"]).get("sessionToken", ""))
+PY
+)"
+if [[ -z "${SESSION_TOKEN}" ]]; then
+  echo "missing session token from signup response: ${SIGNUP_RESP}" >&2
+  exit 1
+fi
+
+ORDER_RESP="$(curl -fsS -X POST "${EDGE_BASE_URL}/v1/orders" \
+  -H "Authorization: Bearer ${SESSION_TOKEN}" \
+  -H "Idempotency-Key: smoke-order-events-${RUN_ID}" \
+  -H 'Content-Type: application/json' \
+  -d '{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"1000000","qty":"1","timeInForce":"GTC"}')"
+ORDER_ID="$(
+ORDER_RESP="${ORDER_RESP}" "${PYTHON_BIN}" - <<'PY'
+import json, os
+payload = json.loads(os.environ["ORDER_RESP"])
+print(payload.get("orderId", "") if payload.get("status") == "ACCEPTED" else "")
+PY
+)"
+if [[ -z "${ORDER_ID}" ]]; then
+  echo "expected a resting ACCEPTED order: ${ORDER_RESP}" >&2
+  exit 1
+fi
+
+curl -fsS -X DELETE "${EDGE_BASE_URL}/v1/orders/${ORDER_ID}" \
+  -H "Authorization: Bearer ${SESSION_TOKEN}" \
+  -H "Idempotency-Key: smoke-order-events-cancel-${RUN_ID}" >/dev/null
+
+echo "Waiting for OrderAccepted and OrderCanceled on topic core.order-events.v1"
+for _ in {1..30}; do
+  if ! kill -0 "${KAFKA_PID}" >/dev/null 2>&1; then
+    break
+  fi
+  sleep 1
+done
+if kill -0 "${KAFKA_PID}" >/dev/null 2>&1; then
+  echo "timed out waiting for order events on Kafka" >&2
+  cat "${kafka_log}" >&2 || true
+  exit 1
+fi
+wait "${KAFKA_PID}"
+
+ORDER_ID="${ORDER_ID}" KAFKA_CAPTURE="${KAFKA_CAPTURE}" "${PYTHON_BIN}" - <<'PY'
+import json, os, sys
+order_id = os.environ["ORDER_ID"]
+events = [json.loads(line) for line in open(os.environ["KAFKA_CAPTURE"]) if line.strip()]
+kinds = [e.get("eventType") for e in events if e.get("orderId") == order_id]
+if kinds != ["OrderAccepted", "OrderCanceled"]:
+    print(f"unexpected order events for {order_id}: {events}", file=sys.stderr)
+    sys.exit(1)
+for e in events:
+    envelope = e.get("envelope", {})
+    if not envelope.get("eventId", "").endswith("-" + e["eventType"]) or envelope.get("seq") != e.get("seq"):
+        print(f"malformed envelope: {e}", file=sys.stderr)
+        sys.exit(1)
+if events[1].get("remainingQuantity") is None:
+    print(f"OrderCanceled without remainingQuantity: {events[1]}", file=sys.stderr)
+    sys.exit(1)
+PY
+
+if grep -q "order_event_apply_failed" "${edge_log}"; then
+  echo "edge-gateway failed to apply order events:" >&2
+  grep "order_event_apply_failed" "${edge_log}" >&2
+  exit 1
+fi
+
+echo "Order events smoke OK: core published OrderAccepted and OrderCanceled, edge consumed them."
@@ -10,6 +10,18 @@ import (
 	"github.com/quanta-exchange/exchange-platform/services/edge-gateway/internal/gateway"
 )
 
+// defaultFeeTiers is name:minVolume:makerBps:takerBps per tier, volumes in
+// 30-day KRW quote volume.
+const defaultFeeTiers = "VIP0:0:10:15,VIP1:100000000:8:12,VIP2:1000000000:5:10,VIP3:10000000000:2:7"
+
+// defaultWithdrawalLimits is asset:amount, the most one user can withdraw
+// per UTC day.
+const defaultWithdrawalLimits = "KRW:100000000,BTC:2,ETH:50,SOL:2000,XRP:100000,BNB:200"
+
+// defaultTransferLimits is asset:amount, the most one user can send to
+// other users per UTC day.
+const defaultTransferLimits = "KRW:1000000000,BTC:20,ETH:500,SOL:20000,XRP:1000000,BNB:2000"
+
 func main() {
 	cfg := gateway.Config{
 		Addr:           getenv("EDGE_ADDR", ":8080"),
@@ -34,9 +46,22 @@ func main() {
 		OTelInsecure:       getenv("EDGE_OTEL_INSECURE", "true") == "true",
 		CoreAddr:           getenv("EDGE_CORE_ADDR", "localhost:50051"),
 		CoreTimeout:        time.Duration(getenvInt("EDGE_CORE_TIMEOUT_MS", 3000)) * time.Millisecond,
+		DBTimeout:          time.Duration(getenvInt("EDGE_DB_TIMEOUT_MS", 5000)) * time.Millisecond,
 		KafkaBrokers:       getenv("EDGE_KAFKA_BROKERS", ""),
 		KafkaTradeTopic:    getenv("EDGE_KAFKA_TRADE_TOPIC", "core.trade-events.v1"),
+		KafkaOrderTopic:    getenv("EDGE_KAFKA_ORDER_TOPIC", "core.order-events.v1"),
 		KafkaGroupID:       getenv("EDGE_KAFKA_GROUP_ID", "edge-trades-v1"),
+		FeeAccountID:       getenv("EDGE_FEE_ACCOUNT", "system:fees"),
+		FeeTiers:           parseFeeTiers(getenv("EDGE_FEE_TIERS", defaultFeeTiers)),
+		AdminToken:         getenv("EDGE_ADMIN_TOKEN", ""),
+		MaxBatchOrders:     getenvInt("EDGE_MAX_BATCH_ORDERS", 20),
+		RegistryFile:       getenv("EDGE_REGISTRY_FILE", ""),
+		PriceBandPercent:   getenvFloat("EDGE_PRICE_BAND_PCT", 0),
+
+		FundingConfirmations:   getenvInt("EDGE_FUNDING_CONFIRMATIONS", 3),
+		WithdrawalAddressDelay: time.Duration(getenvInt("EDGE_WITHDRAWAL_ADDRESS_DELAY_SEC", 86400)) * time.Second,
+		WithdrawalDailyLimits:  parseSecrets(getenv("EDGE_WITHDRAWAL_DAILY_LIMITS", defaultWithdrawalLimits)),
+		TransferDailyLimits:    parseSecrets(getenv("EDGE_TRANSFER_DAILY_LIMITS", defaultTransferLimits)),
 	}
 	srv, err := gateway.New(cfg)
 	if err != nil {
@@ -96,3 +121,11 @@ func parseSecrets(raw string) map[string]string {
 	}
 	return out
 }
+
+func parseFeeTiers(raw string) []gateway.FeeTier {
+	tiers, err := gateway.ParseFeeTiers(raw)
+	if err != nil {
+		log.Fatalf("invalid EDGE_FEE_TIERS: %v", err)
+	}
+	return tiers
+}
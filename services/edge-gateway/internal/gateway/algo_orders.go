@@ -0,0 +1,492 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	exchangev1 "github.com/quanta-exchange/exchange-platform/contracts/gen/go/exchange/v1"
+)
+
+const (
+	orderTypeTWAP    = "TWAP"
+	orderTypeIceberg = "ICEBERG"
+
+	defaultTWAPSlices  = 10
+	maxTWAPDurationSec = 24 * 60 * 60
+	algoTickInterval   = time.Second
+	algoJitterFraction = 0.2
+)
+
+// AlgoState is the working state of a TWAP or ICEBERG parent order. The
+// parent holds the whole reserve; SentQty is what its children have been
+// asked to trade so far, and the children themselves reserve nothing.
+type AlgoState struct {
+	VisibleQty      Decimal  `json:"visibleQty"`
+	DurationSeconds int64    `json:"durationSeconds,omitempty"`
+	Slices          int      `json:"slices,omitempty"`
+	SlicesSent      int      `json:"slicesSent"`
+	SentQty         Decimal  `json:"sentQty"`
+	EndsAt          int64    `json:"endsAt,omitempty"`
+	NextSliceAt     int64    `json:"nextSliceAt,omitempty"`
+	ActiveChildID   string   `json:"activeChildId,omitempty"`
+	ChildOrderIDs   []string `json:"childOrderIds,omitempty"`
+}
+
+func isAlgoOrderType(orderType string) bool {
+	switch strings.ToUpper(strings.TrimSpace(orderType)) {
+	case orderTypeTWAP, orderTypeIceberg:
+		return true
+	default:
+		return false
+	}
+}
+
+func hasAlgoFields(req OrderRequest) bool {
+	return strings.TrimSpace(req.VisibleQty) != "" || req.DurationSeconds != 0 || req.Slices != 0
+}
+
+// validateAlgoOrder checks a TWAP or ICEBERG parent. Children are LIMIT GTC
+// orders at the parent's price, so the price is required and quantities
+// must split into whole core units.
+func validateAlgoOrder(req OrderRequest) error {
+	if hasTriggerFields(req) {
+		return fmt.Errorf("trigger fields require STOP, STOP_LIMIT or TRAILING_STOP")
+	}
+	if strings.TrimSpace(req.Price) == "" {
+		return fmt.Errorf("price required for TWAP and ICEBERG")
+	}
+	if price, err := strconv.ParseInt(strings.TrimSpace(req.Price), 10, 64); err != nil || price <= 0 {
+		return fmt.Errorf("invalid price")
+	}
+	qty, err := strconv.ParseInt(strings.TrimSpace(req.Qty), 10, 64)
+	if err != nil || qty <= 0 {
+		return fmt.Errorf("qty must be a whole number for TWAP and ICEBERG")
+	}
+	if tif, _ := mapTimeInForce(req.TimeInForce); tif != exchangev1.TimeInForce_TIME_IN_FORCE_GTC {
+		return fmt.Errorf("TWAP and ICEBERG require GTC or GTD")
+	}
+
+	if strings.ToUpper(strings.TrimSpace(req.Type)) == orderTypeIceberg {
+		if req.DurationSeconds != 0 || req.Slices != 0 {
+			return fmt.Errorf("durationSeconds/slices only allowed for TWAP")
+		}
+		visible, err := strconv.ParseInt(strings.TrimSpace(req.VisibleQty), 10, 64)
+		if err != nil || visible <= 0 || visible >= qty {
+			return fmt.Errorf("invalid visibleQty")
+		}
+		return nil
+	}
+
+	if strings.TrimSpace(req.VisibleQty) != "" {
+		return fmt.Errorf("visibleQty only allowed for ICEBERG")
+	}
+	if req.DurationSeconds <= 0 || req.DurationSeconds > maxTWAPDurationSec {
+		return fmt.Errorf("invalid durationSeconds")
+	}
+	if req.Slices < 0 {
+		return fmt.Errorf("invalid slices")
+	}
+	if int64(req.Slices) > qty {
+		return fmt.Errorf("slices must not exceed qty")
+	}
+	if int64(req.Slices) > req.DurationSeconds {
+		return fmt.Errorf("slices must not exceed durationSeconds")
+	}
+	return nil
+}
+
+// twapSlices is the slice count for a validated TWAP: the request's, or
+// defaultTWAPSlices capped so every slice gets a unit and a second.
+func twapSlices(req OrderRequest) int {
+	if req.Slices > 0 {
+		return req.Slices
+	}
+	qty, _ := strconv.ParseInt(strings.TrimSpace(req.Qty), 10, 64)
+	slices := int64(defaultTWAPSlices)
+	if qty < slices {
+		slices = qty
+	}
+	if req.DurationSeconds < slices {
+		slices = req.DurationSeconds
+	}
+	return int(slices)
+}
+
+func algoJitter() float64 {
+	return 1 - algoJitterFraction + 2*algoJitterFraction*rand.Float64()
+}
+
+// nextAlgoSlice sizes the child an algo parent should send at nowMs and
+// returns when the one after it is due. TWAP slices spread what is left
+// over the slices and time left with some jitter, keeping a unit for each
+// later slice; the last one takes the remainder. An iceberg sends one
+// visible clip at a time. Algo quantities are whole units, so the split is
+// worked out in integers.
+func nextAlgoSlice(record OrderRecord, nowMs int64) (Decimal, int64) {
+	algo := record.Algo
+	remaining := record.Qty.Sub(algo.SentQty)
+	if remaining.Cmp(decimalFromInt(1)) < 0 {
+		return Decimal{}, 0
+	}
+	if record.Type == orderTypeIceberg {
+		if algo.ActiveChildID != "" {
+			return Decimal{}, 0
+		}
+		return minDecimal(algo.VisibleQty, remaining), 0
+	}
+
+	slicesLeft := algo.Slices - algo.SlicesSent
+	if slicesLeft <= 1 {
+		return remaining, 0
+	}
+	units := remaining.Floor(0).int().Int64()
+	qty := int64(math.Round(float64(units) / float64(slicesLeft) * algoJitter()))
+	if limit := units - int64(slicesLeft-1); qty > limit {
+		qty = limit
+	}
+	if qty < 1 {
+		qty = 1
+	}
+
+	next := nowMs + int64(float64(algo.EndsAt-nowMs)/float64(slicesLeft-1)*algoJitter())
+	if next > algo.EndsAt {
+		next = algo.EndsAt
+	}
+	return decimalFromInt(qty), next
+}
+
+// startAlgoOrder records a validated, reserved TWAP or ICEBERG parent and
+// sends its first child. A core error on that first child fails the whole
+// order the way it would fail a plain one.
+func (s *Server) startAlgoOrder(
+	ctx context.Context,
+	userID string,
+	idemKey string,
+	req OrderRequest,
+	reserveCurrency string,
+	reserveAmount Decimal,
+) (OrderResponse, error) {
+	orderID := fmt.Sprintf("ord_%s", idemKey)
+	if req.ClientOrderID != "" && !s.claimClientOrderID(userID, req.ClientOrderID, orderID) {
+		if reserveCurrency != "" && reserveAmount.Sign() > 0 {
+			s.releaseReserve(userID, reserveCurrency, reserveAmount, orderID)
+		}
+		return OrderResponse{}, errDuplicateClientOrderID
+	}
+
+	stp, _ := mapSelfTradePrevention(req.SelfTradePrevention)
+	qty, _ := parseDecimal(req.Qty)
+	now := time.Now().UnixMilli()
+	algo := &AlgoState{}
+	orderType := strings.ToUpper(strings.TrimSpace(req.Type))
+	if orderType == orderTypeIceberg {
+		algo.VisibleQty, _ = parseDecimal(req.VisibleQty)
+	} else {
+		algo.DurationSeconds = req.DurationSeconds
+		algo.Slices = twapSlices(req)
+		algo.EndsAt = now + req.DurationSeconds*1000
+	}
+	record := OrderRecord{
+		OrderID:         orderID,
+		ClientOrderID:   req.ClientOrderID,
+		Status:          "ACCEPTED",
+		Symbol:          strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		CreatedAt:       now,
+		AcceptedAt:      now,
+		OwnerUserID:     userID,
+		ReserveCurrency: reserveCurrency,
+		ReserveAmount:   reserveAmount,
+		Side:            strings.ToUpper(strings.TrimSpace(req.Side)),
+		Type:            orderType,
+		Price:           strings.TrimSpace(req.Price),
+		TimeInForce:     timeInForceLabel(req.TimeInForce, exchangev1.TimeInForce_TIME_IN_FORCE_GTC),
+		ExpireAt:        req.ExpireAt,
+		Qty:             qty,
+
+		SelfTradePrevention: selfTradePreventionLabel(stp),
+		Algo:                algo,
+	}
+	s.state.mu.Lock()
+	s.state.orders[orderID] = record
+	s.state.ordersTotal++
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, record)
+
+	if err := s.sendAlgoSlice(ctx, orderID, now); err != nil {
+		s.closeAlgoOrder(ctx, orderID, "REJECTED")
+		s.releaseClientOrderID(userID, req.ClientOrderID, orderID)
+		return OrderResponse{}, err
+	}
+	return s.orderResponseFor(orderID), nil
+}
+
+// runAlgoSlices sends every child that is due at nowMs: the next TWAP
+// slice, or an iceberg clip that could not be placed earlier. Parents on a
+// symbol that is not accepting orders wait until it reopens.
+func (s *Server) runAlgoSlices(ctx context.Context, nowMs int64) {
+	var due []OrderRecord
+	s.state.mu.Lock()
+	for _, record := range s.state.orders {
+		if record.Algo == nil || !isOpenOrderStatus(record.Status) || record.Algo.NextSliceAt > nowMs {
+			continue
+		}
+		if qty, _ := nextAlgoSlice(record, nowMs); qty.Sign() > 0 {
+			due = append(due, record)
+		}
+	}
+	s.state.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return orderLess(due[i], due[j]) })
+	for _, record := range due {
+		if code := s.orderEntryBlock(record.Symbol); code != "" {
+			continue
+		}
+		if err := s.sendAlgoSlice(ctx, record.OrderID, nowMs); err != nil {
+			log.Printf("service=edge-gateway msg=algo_slice_failed order_id=%s err=%v", record.OrderID, err)
+		}
+	}
+}
+
+// sendAlgoSlice places the parent's next child as ord_{key}:{n}. The slice
+// is booked on the parent before the core call so a concurrent tick cannot
+// send it twice; a child the core turns away is unbooked again, a rejected
+// one closes the parent, and one placed after the parent closed is pulled.
+func (s *Server) sendAlgoSlice(ctx context.Context, parentID string, nowMs int64) error {
+	s.state.mu.Lock()
+	parent, ok := s.state.orders[parentID]
+	if !ok || parent.Algo == nil || !isOpenOrderStatus(parent.Status) {
+		s.state.mu.Unlock()
+		return nil
+	}
+	qty, next := nextAlgoSlice(parent, nowMs)
+	if qty.Sign() <= 0 {
+		s.state.mu.Unlock()
+		return nil
+	}
+	algo := *parent.Algo
+	algo.SentQty = algo.SentQty.Add(qty)
+	algo.SlicesSent++
+	algo.NextSliceAt = next
+	childIdemKey := strings.TrimPrefix(parentID, "ord_") + ":" + strconv.Itoa(algo.SlicesSent)
+	childID := "ord_" + childIdemKey
+	algo.ChildOrderIDs = append(append([]string(nil), algo.ChildOrderIDs...), childID)
+	if parent.Type == orderTypeIceberg {
+		algo.ActiveChildID = childID
+	}
+	parent.Algo = &algo
+	s.state.orders[parentID] = parent
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, parent)
+
+	resp, err := s.placeOrderWithCore(ctx, parent.OwnerUserID, childIdemKey, OrderRequest{
+		Symbol:              parent.Symbol,
+		Side:                parent.Side,
+		Type:                "LIMIT",
+		Price:               parent.Price,
+		Qty:                 formatQty(qty),
+		TimeInForce:         "GTC",
+		SelfTradePrevention: parent.SelfTradePrevention,
+		parentOrderID:       parentID,
+	}, "", Decimal{})
+
+	placed := err == nil && resp.Status != "REJECTED" && resp.Status != "CANCELED"
+	s.state.mu.Lock()
+	parent = s.state.orders[parentID]
+	algo = *parent.Algo
+	if !placed {
+		algo.SentQty = algo.SentQty.Sub(qty)
+		if algo.ActiveChildID == childID {
+			algo.ActiveChildID = ""
+			algo.NextSliceAt = nowMs + algoTickInterval.Milliseconds()
+		}
+	}
+	if err != nil {
+		algo.ChildOrderIDs = removeString(algo.ChildOrderIDs, childID)
+	} else if resp.Seq > parent.Seq {
+		parent.Seq = resp.Seq
+	}
+	parent.Algo = &algo
+	s.state.orders[parentID] = parent
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, parent)
+
+	switch {
+	case err != nil:
+		return err
+	case resp.Status == "REJECTED":
+		log.Printf("service=edge-gateway msg=algo_child_rejected order_id=%s child_id=%s code=%s", parentID, childID, resp.RejectCode)
+		s.closeAlgoOrder(ctx, parentID, "REJECTED")
+	case placed && !isOpenOrderStatus(parent.Status):
+		s.cancelAlgoChildren(ctx, []string{childID})
+	}
+	return nil
+}
+
+// applyAlgoChildFill adds a child's fill to its parent. A filled iceberg
+// clip is replaced straight away with the next one.
+func (s *Server) applyAlgoChildFill(ctx context.Context, child OrderRecord, fillQty, fillPrice int64, seq uint64) {
+	filled := false
+	refill := false
+
+	s.state.mu.Lock()
+	parent, ok := s.state.orders[child.ParentOrderID]
+	if !ok || parent.Algo == nil {
+		s.state.mu.Unlock()
+		return
+	}
+	parent.FilledQty = parent.FilledQty.Add(decimalFromInt(fillQty))
+	switch parent.Side {
+	case "BUY":
+		parent.ReserveConsumed = parent.ReserveConsumed.Add(decimalFromInt(fillQty).MulInt(fillPrice))
+	case "SELL":
+		parent.ReserveConsumed = parent.ReserveConsumed.Add(decimalFromInt(fillQty))
+	}
+	if seq > parent.Seq {
+		parent.Seq = seq
+	}
+	if isOpenOrderStatus(parent.Status) {
+		if parent.FilledQty.Cmp(parent.Qty) >= 0 {
+			parent.FilledQty = parent.Qty
+			parent.Status = "FILLED"
+			filled = true
+		} else {
+			parent.Status = "PARTIALLY_FILLED"
+			if child.Status == "FILLED" && parent.Algo.ActiveChildID == child.OrderID {
+				algo := *parent.Algo
+				algo.ActiveChildID = ""
+				parent.Algo = &algo
+				refill = true
+			}
+		}
+	}
+	s.state.orders[parent.OrderID] = parent
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, parent)
+
+	if filled {
+		s.releaseOrderReserve(ctx, parent.OrderID)
+	}
+	if refill {
+		if err := s.sendAlgoSlice(ctx, parent.OrderID, time.Now().UnixMilli()); err != nil {
+			log.Printf("service=edge-gateway msg=algo_slice_failed order_id=%s err=%v", parent.OrderID, err)
+		}
+	}
+}
+
+// releaseAlgoChild hands the unfilled part of a child the core canceled on
+// its own (self-trade prevention) back to its parent's schedule. An
+// iceberg refills on the next scheduler tick rather than straight away.
+func (s *Server) releaseAlgoChild(ctx context.Context, childID string) {
+	s.state.mu.Lock()
+	child, ok := s.state.orders[childID]
+	if !ok || child.ParentOrderID == "" {
+		s.state.mu.Unlock()
+		return
+	}
+	parent, ok := s.state.orders[child.ParentOrderID]
+	if !ok || parent.Algo == nil || !isOpenOrderStatus(parent.Status) {
+		s.state.mu.Unlock()
+		return
+	}
+	algo := *parent.Algo
+	algo.SentQty = algo.SentQty.Sub(child.Qty.Sub(child.FilledQty))
+	if algo.ActiveChildID == childID {
+		algo.ActiveChildID = ""
+		algo.NextSliceAt = time.Now().UnixMilli()
+	}
+	parent.Algo = &algo
+	s.state.orders[parent.OrderID] = parent
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, parent)
+}
+
+// closeAlgoOrder moves an open parent to status, stops its schedule,
+// cancels its live children and then releases the reserve they did not
+// use. The release is worked out only once the children are off the book,
+// so fills that land while they are being canceled are paid from it first.
+// It reports false when the parent had already closed.
+func (s *Server) closeAlgoOrder(ctx context.Context, parentID, status string) bool {
+	s.state.mu.Lock()
+	parent, ok := s.state.orders[parentID]
+	if !ok || !isOpenOrderStatus(parent.Status) {
+		s.state.mu.Unlock()
+		return false
+	}
+	parent.Status = status
+	parent.CanceledAt = time.Now().UnixMilli()
+	var children []string
+	if parent.Algo != nil {
+		children = append(children, parent.Algo.ChildOrderIDs...)
+	}
+	s.state.orders[parentID] = parent
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, parent)
+
+	s.cancelAlgoChildren(ctx, children)
+	s.releaseOrderReserve(ctx, parentID)
+	return true
+}
+
+func (s *Server) cancelAlgoChildren(ctx context.Context, childIDs []string) {
+	for _, childID := range childIDs {
+		s.state.mu.Lock()
+		child, ok := s.state.orders[childID]
+		s.state.mu.Unlock()
+		if !ok || !isOpenOrderStatus(child.Status) {
+			continue
+		}
+		if _, err := s.cancelOnCore(ctx, child.OwnerUserID, childID+":algo-cancel", child, true); err != nil {
+			log.Printf("service=edge-gateway msg=algo_child_cancel_failed order_id=%s parent_id=%s err=%v", childID, child.ParentOrderID, err)
+		}
+	}
+}
+
+// cancelAlgoOrder cancels a TWAP or ICEBERG parent together with its live
+// children. A parent that already closed is reported with its status.
+func (s *Server) cancelAlgoOrder(ctx context.Context, record OrderRecord) OrderResponse {
+	closed := s.closeAlgoOrder(ctx, record.OrderID, "CANCELED")
+	resp := s.orderResponseFor(record.OrderID)
+	if !closed && resp.Status != "CANCELED" {
+		resp.RejectCode = "ORDER_NOT_OPEN"
+	}
+	return resp
+}
+
+func (s *Server) startAlgoScheduler() {
+	if s.coreClient == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.algoCancel = cancel
+	s.algoWG.Add(1)
+	go func() {
+		defer s.algoWG.Done()
+		ticker := time.NewTicker(algoTickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.runAlgoSlices(ctx, now.UnixMilli())
+			}
+		}
+	}()
+}
+
+func removeString(values []string, target string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != target {
+			out = append(out, v)
+		}
+	}
+	return out
+}
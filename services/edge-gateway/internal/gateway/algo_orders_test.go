@@ -0,0 +1,168 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func fillOrder(t *testing.T, s *Server, tradeID, orderID string, qty int64) {
+	t.Helper()
+	if err := s.consumeTradeMessage(context.Background(), []byte(fmt.Sprintf(
+		`{"tradeId":%q,"symbol":"BTC-KRW","seq":100,"makerOrderId":%q,"takerOrderId":"ord_x","buyerUserId":"test-key","price":100,"quantity":%d}`,
+		tradeID, orderID, qty,
+	))); err != nil {
+		t.Fatalf("consume trade %s: %v", tradeID, err)
+	}
+}
+
+func TestTWAPSlicesQuantityAcrossDuration(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"TWAP","price":"100","qty":"10","durationSeconds":600,"slices":5}`), "twap-1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create TWAP failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var resp OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Status != "ACCEPTED" {
+		t.Fatalf("unexpected TWAP response: %s", w.Body.String())
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "1000" {
+		t.Fatalf("expected parent to reserve 10 x 100 KRW, got %+v", krw)
+	}
+
+	parent := getOrderRecord(t, s, "ord_twap-1")
+	if parent.Algo == nil || parent.Algo.SlicesSent != 1 || parent.Algo.NextSliceAt > parent.Algo.EndsAt {
+		t.Fatalf("expected first slice sent at creation, got %+v", parent.Algo)
+	}
+	for i := 0; i < 10 && parent.Algo.SentQty.Cmp(parent.Qty) < 0; i++ {
+		s.runAlgoSlices(context.Background(), parent.Algo.NextSliceAt)
+		parent = getOrderRecord(t, s, "ord_twap-1")
+	}
+	if parent.Algo.SlicesSent != 5 || len(parent.Algo.ChildOrderIDs) != 5 {
+		t.Fatalf("expected 5 slices, got %+v", parent.Algo)
+	}
+	total := Decimal{}
+	for _, childID := range parent.Algo.ChildOrderIDs {
+		child := getOrderRecord(t, s, childID)
+		if child.ParentOrderID != "ord_twap-1" || child.Type != "LIMIT" || child.Price != "100" || child.Qty.Cmp(decimalFromInt(1)) < 0 {
+			t.Fatalf("unexpected child: %+v", child)
+		}
+		total = total.Add(child.Qty)
+	}
+	if total.Cmp(decimalFromInt(10)) != 0 {
+		t.Fatalf("expected slices to add up to 10, got %v", total)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "1000" {
+		t.Fatalf("children must not reserve on top of the parent, got %+v", krw)
+	}
+
+	for i, childID := range parent.Algo.ChildOrderIDs {
+		fillOrder(t, s, fmt.Sprintf("twap-fill-%d", i), childID, getOrderRecord(t, s, childID).Qty.int().Int64())
+	}
+	parent = getOrderRecord(t, s, "ord_twap-1")
+	if parent.Status != "FILLED" || parent.FilledQty.Cmp(decimalFromInt(10)) != 0 {
+		t.Fatalf("expected parent filled by its children, got %+v", parent)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "49999000" {
+		t.Fatalf("expected reserve settled, got %+v", krw)
+	}
+}
+
+func TestIcebergRefillsClipAndCancelCancelsChildren(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"ICEBERG","price":"100","qty":"5","visibleQty":"5"}`), "ice-bad"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected visibleQty >= qty to be rejected, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"ICEBERG","price":"100","qty":"5","visibleQty":"2"}`), "ice-1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create iceberg failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if clip := getOrderRecord(t, s, "ord_ice-1:1"); clip.Qty.Cmp(decimalFromInt(2)) != 0 || clip.Status != "ACCEPTED" {
+		t.Fatalf("expected first clip of 2, got %+v", clip)
+	}
+
+	fillOrder(t, s, "ice-fill-1", "ord_ice-1:1", 2)
+	parent := getOrderRecord(t, s, "ord_ice-1")
+	if parent.Status != "PARTIALLY_FILLED" || parent.FilledQty.Cmp(decimalFromInt(2)) != 0 || parent.Algo.ActiveChildID != "ord_ice-1:2" {
+		t.Fatalf("expected clip refilled after fill, got %+v algo=%+v", parent, parent.Algo)
+	}
+	fillOrder(t, s, "ice-fill-2", "ord_ice-1:2", 1)
+	if parent := getOrderRecord(t, s, "ord_ice-1"); parent.FilledQty.Cmp(decimalFromInt(3)) != 0 || len(parent.Algo.ChildOrderIDs) != 2 {
+		t.Fatalf("partial clip fill must not refill, got %+v", parent.Algo)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_ice-1:2", nil, "ice-child-cancel"))
+	var resp OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.RejectCode != "ORDER_MANAGED_BY_PARENT" {
+		t.Fatalf("expected child cancel to be refused, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_ice-1", nil, "ice-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel iceberg failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Status != "CANCELED" {
+		t.Fatalf("unexpected cancel response: %s", w.Body.String())
+	}
+	if clip := getOrderRecord(t, s, "ord_ice-1:2"); clip.Status != "CANCELED" {
+		t.Fatalf("expected live clip canceled with its parent, got %s", clip.Status)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "49999700" {
+		t.Fatalf("expected unused reserve released, got %+v", krw)
+	}
+}
+
+func TestAlgoCancelReleasesOnlyWhatChildFillsLeave(t *testing.T) {
+	core := &stubCore{}
+	s, cleanup := newTestServerWithCore(t, core)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"ICEBERG","price":"100","qty":"5","visibleQty":"2"}`), "ice-race"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create iceberg failed: %d body=%s", w.Code, w.Body.String())
+	}
+	// The live clip fills while the parent's cancel is pulling it.
+	core.beforeCancel = func(orderID string) {
+		if orderID == "ord_ice-race:1" {
+			fillOrder(t, s, "ice-race-fill", orderID, 2)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_ice-race", nil, "ice-race-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel iceberg failed: %d body=%s", w.Code, w.Body.String())
+	}
+	parent := getOrderRecord(t, s, "ord_ice-race")
+	if parent.Status != "CANCELED" || parent.FilledQty.Cmp(decimalFromInt(2)) != 0 {
+		t.Fatalf("expected the parent canceled with the clip's fill, got %+v", parent)
+	}
+	s.state.mu.Lock()
+	parent = s.state.orders["ord_ice-race"]
+	s.state.mu.Unlock()
+	if parent.ReserveAmount.String() != "200" || parent.ReserveConsumed.String() != "200" {
+		t.Fatalf("expected the fill paid from the reserve kept back, got reserve=%s consumed=%s", parent.ReserveAmount, parent.ReserveConsumed)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "49999800" {
+		t.Fatalf("expected only the unfilled reserve released, got %+v", krw)
+	}
+}
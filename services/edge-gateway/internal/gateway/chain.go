@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ChainTransfer is a transfer as a chain reports it. Confirmations counts
+// the blocks that include it, 0 while it is pending; a failed transfer
+// moved nothing.
+type ChainTransfer struct {
+	TxHash        string
+	Asset         string
+	Address       string
+	Amount        Decimal
+	Confirmations int
+	Failed        bool
+}
+
+// ChainAdapter is the gateway's view of the chains it holds custody on.
+// The funding watcher polls it; an implementation per network (or one for
+// a custody provider) plugs in through Config.Chain.
+type ChainAdapter interface {
+	// NewAddress issues a deposit address for asset.
+	NewAddress(ctx context.Context, asset string) (string, error)
+	// Deposits lists the inbound transfers of asset to issued addresses.
+	Deposits(ctx context.Context, asset string) ([]ChainTransfer, error)
+	// Send broadcasts a withdrawal and returns its transaction hash.
+	// reference is the withdrawal ID, for adapters that can deduplicate.
+	Send(ctx context.Context, asset, address string, amount Decimal, reference string) (string, error)
+	// Transfer looks up a transaction Send returned.
+	Transfer(ctx context.Context, asset, txHash string) (ChainTransfer, error)
+}
+
+// SimulatedChain is an in-process ChainAdapter for running the funding
+// flows offline. Nothing happens on it until told: SimulateDeposit plays an
+// inbound transfer, Mine adds blocks and FailTransfer drops a pending one.
+// Its state lives in memory; addresses and transaction hashes are random so
+// a restarted chain never reissues one the gateway stored, and restore
+// brings back what the gateway still expects of it.
+type SimulatedChain struct {
+	mu        sync.Mutex
+	height    int
+	addresses map[string]string
+	transfers map[string]*simulatedTransfer
+	order     []string
+	// sent maps a Send reference to its transaction, so a resend after a
+	// lost reply does not pay twice.
+	sent map[string]string
+}
+
+type simulatedTransfer struct {
+	transfer ChainTransfer
+	inbound  bool
+	// includedAt is the first block that confirms the transfer.
+	includedAt int
+}
+
+func NewSimulatedChain() *SimulatedChain {
+	return &SimulatedChain{
+		addresses: map[string]string{},
+		transfers: map[string]*simulatedTransfer{},
+		sent:      map[string]string{},
+	}
+}
+
+func (c *SimulatedChain) NewAddress(_ context.Context, asset string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	address := fmt.Sprintf("sim%s1%s", strings.ToLower(asset), simulatedID()[:16])
+	c.addresses[address] = asset
+	return address, nil
+}
+
+func (c *SimulatedChain) Deposits(_ context.Context, asset string) ([]ChainTransfer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]ChainTransfer, 0, len(c.order))
+	for _, txHash := range c.order {
+		tx := c.transfers[txHash]
+		if tx.inbound && tx.transfer.Asset == asset {
+			out = append(out, c.viewLocked(tx))
+		}
+	}
+	return out, nil
+}
+
+func (c *SimulatedChain) Send(_ context.Context, asset, address string, amount Decimal, reference string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if txHash, ok := c.sent[reference]; ok && reference != "" {
+		return txHash, nil
+	}
+	txHash := c.addLocked(ChainTransfer{Asset: asset, Address: address, Amount: amount}, false)
+	if reference != "" {
+		c.sent[reference] = txHash
+	}
+	return txHash, nil
+}
+
+func (c *SimulatedChain) Transfer(_ context.Context, _ string, txHash string) (ChainTransfer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx, ok := c.transfers[txHash]
+	if !ok {
+		return ChainTransfer{}, fmt.Errorf("unknown transaction %s", txHash)
+	}
+	return c.viewLocked(tx), nil
+}
+
+// SimulateDeposit plays an inbound transfer to an issued address; it is
+// confirmed by the next block.
+func (c *SimulatedChain) SimulateDeposit(asset, address string, amount Decimal) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.addresses[address] != asset {
+		return "", fmt.Errorf("address %s was not issued for %s", address, asset)
+	}
+	if amount.Sign() <= 0 {
+		return "", fmt.Errorf("amount must be > 0")
+	}
+	return c.addLocked(ChainTransfer{Asset: asset, Address: address, Amount: amount}, true), nil
+}
+
+// Mine adds blocks, confirming pending transfers.
+func (c *SimulatedChain) Mine(blocks int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if blocks > 0 {
+		c.height += blocks
+	}
+	return c.height
+}
+
+// FailTransfer drops a transfer that no block has confirmed yet.
+func (c *SimulatedChain) FailTransfer(txHash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tx, ok := c.transfers[txHash]
+	if !ok {
+		return fmt.Errorf("unknown transaction %s", txHash)
+	}
+	if c.height >= tx.includedAt {
+		return fmt.Errorf("transaction %s is already confirmed", txHash)
+	}
+	tx.transfer.Failed = true
+	return nil
+}
+
+func (c *SimulatedChain) addLocked(transfer ChainTransfer, inbound bool) string {
+	if transfer.TxHash == "" {
+		transfer.TxHash = "0xsim" + simulatedID()
+	}
+	c.transfers[transfer.TxHash] = &simulatedTransfer{transfer: transfer, inbound: inbound, includedAt: c.height + 1}
+	c.order = append(c.order, transfer.TxHash)
+	return transfer.TxHash
+}
+
+func simulatedID() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// restore puts back what the gateway stored from an earlier run: the
+// deposit addresses it issued, and the withdrawals it broadcast and still
+// waits on, which come back pending and confirm from the next block.
+func (c *SimulatedChain) restore(addresses []DepositAddress, withdrawals []WithdrawalRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, address := range addresses {
+		c.addresses[address.Address] = address.Asset
+	}
+	for _, record := range withdrawals {
+		if record.Status != withdrawalStatusBroadcast || record.TxHash == "" {
+			continue
+		}
+		if _, ok := c.transfers[record.TxHash]; ok {
+			continue
+		}
+		c.addLocked(ChainTransfer{TxHash: record.TxHash, Asset: record.Asset, Address: record.Address, Amount: record.Amount}, false)
+		c.sent[record.WithdrawalID] = record.TxHash
+	}
+}
+
+func (c *SimulatedChain) viewLocked(tx *simulatedTransfer) ChainTransfer {
+	out := tx.transfer
+	if !out.Failed && c.height >= tx.includedAt {
+		out.Confirmations = c.height - tx.includedAt + 1
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSimulatedChainConfirmsByBlockAndDedupesSends(t *testing.T) {
+	ctx := context.Background()
+	chain := NewSimulatedChain()
+
+	address, _ := chain.NewAddress(ctx, "BTC")
+	if _, err := chain.SimulateDeposit("ETH", address, decimalFromInt(1)); err == nil {
+		t.Fatalf("expected a deposit to an address of another asset rejected")
+	}
+	txHash, err := chain.SimulateDeposit("BTC", address, decimalFromInt(1))
+	if err != nil {
+		t.Fatalf("simulate deposit: %v", err)
+	}
+	if got, _ := chain.Deposits(ctx, "BTC"); len(got) != 1 || got[0].Confirmations != 0 {
+		t.Fatalf("expected an unconfirmed deposit, got %+v", got)
+	}
+	chain.Mine(2)
+	if got, _ := chain.Transfer(ctx, "BTC", txHash); got.Confirmations != 2 {
+		t.Fatalf("expected 2 confirmations, got %+v", got)
+	}
+	if err := chain.FailTransfer(txHash); err == nil {
+		t.Fatalf("expected a confirmed transfer not to fail")
+	}
+
+	first, _ := chain.Send(ctx, "BTC", "bc1dest", decimalFromInt(1), "wd_1")
+	second, _ := chain.Send(ctx, "BTC", "bc1dest", decimalFromInt(1), "wd_1")
+	if first != second {
+		t.Fatalf("expected a resend with the same reference deduplicated, got %s and %s", first, second)
+	}
+	if err := chain.FailTransfer(first); err != nil {
+		t.Fatalf("fail pending transfer: %v", err)
+	}
+	chain.Mine(1)
+	if got, _ := chain.Transfer(ctx, "BTC", first); !got.Failed || got.Confirmations != 0 {
+		t.Fatalf("expected the transfer failed, got %+v", got)
+	}
+}
+
+func TestSimulatedChainPicksUpStoredAddressesAndWithdrawalsAfterARestart(t *testing.T) {
+	ctx := context.Background()
+	before := NewSimulatedChain()
+	address, _ := before.NewAddress(ctx, "BTC")
+	txHash, _ := before.Send(ctx, "BTC", "bc1dest", decimalFromInt(1), "wd_1")
+
+	chain := NewSimulatedChain()
+	chain.restore(
+		[]DepositAddress{{Address: address, UserID: "u1", Asset: "BTC"}},
+		[]WithdrawalRecord{
+			{WithdrawalID: "wd_1", Asset: "BTC", Address: "bc1dest", Amount: decimalFromInt(1), Status: withdrawalStatusBroadcast, TxHash: txHash},
+			{WithdrawalID: "wd_0", Asset: "BTC", Address: "bc1dest", Amount: decimalFromInt(1), Status: withdrawalStatusConfirmed, TxHash: "0xsimold"},
+		},
+	)
+	if next, _ := chain.NewAddress(ctx, "BTC"); next == address {
+		t.Fatalf("expected a restarted chain not to reissue %s", address)
+	}
+	if _, err := chain.SimulateDeposit("BTC", address, decimalFromInt(1)); err != nil {
+		t.Fatalf("expected deposits to a stored address accepted: %v", err)
+	}
+	if got, err := chain.Transfer(ctx, "BTC", txHash); err != nil || got.Confirmations != 0 {
+		t.Fatalf("expected the broadcast withdrawal pending, got %+v %v", got, err)
+	}
+	chain.Mine(1)
+	if got, _ := chain.Transfer(ctx, "BTC", txHash); got.Confirmations != 1 {
+		t.Fatalf("expected the restored withdrawal confirmed, got %+v", got)
+	}
+	if resent, _ := chain.Send(ctx, "BTC", "bc1dest", decimalFromInt(1), "wd_1"); resent != txHash {
+		t.Fatalf("expected a resend of wd_1 deduplicated, got %s", resent)
+	}
+}
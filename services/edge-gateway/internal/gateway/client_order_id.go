@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxClientOrderIDLen = 64
+
+var errDuplicateClientOrderID = errors.New("DUPLICATE_CLIENT_ORDER_ID")
+
+func isValidClientOrderID(id string) bool {
+	if id == "" || len(id) > maxClientOrderIDLen {
+		return false
+	}
+	for _, ch := range id {
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-' || ch == '_' || ch == '.' || ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func clientOrderIndexKey(userID, clientOrderID string) string {
+	return userID + "|" + clientOrderID
+}
+
+// clientOrderIDHeldLocked reports whether the index entry for key still
+// points at an open or in-flight order. Closed orders keep their entry for
+// lookups but no longer block reuse of the ID.
+func (s *Server) clientOrderIDHeldLocked(key string) bool {
+	orderID, ok := s.state.clientOrderIDs[key]
+	if !ok {
+		return false
+	}
+	record, exists := s.state.orders[orderID]
+	if !exists {
+		return true
+	}
+	return isOpenOrderStatus(record.Status)
+}
+
+func (s *Server) clientOrderIDInUse(userID, clientOrderID string) bool {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	return s.clientOrderIDHeldLocked(clientOrderIndexKey(userID, clientOrderID))
+}
+
+// claimClientOrderID binds clientOrderID to orderID for the user unless an
+// open order already holds it.
+func (s *Server) claimClientOrderID(userID, clientOrderID, orderID string) bool {
+	key := clientOrderIndexKey(userID, clientOrderID)
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if current, ok := s.state.clientOrderIDs[key]; ok && current != orderID && s.clientOrderIDHeldLocked(key) {
+		return false
+	}
+	s.state.clientOrderIDs[key] = orderID
+	return true
+}
+
+func (s *Server) releaseClientOrderID(userID, clientOrderID, orderID string) {
+	if clientOrderID == "" {
+		return
+	}
+	key := clientOrderIndexKey(userID, clientOrderID)
+	s.state.mu.Lock()
+	if s.state.clientOrderIDs[key] == orderID {
+		delete(s.state.clientOrderIDs, key)
+	}
+	s.state.mu.Unlock()
+}
+
+func (s *Server) orderByClientID(userID, clientOrderID string) (OrderRecord, bool) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	orderID, ok := s.state.clientOrderIDs[clientOrderIndexKey(userID, clientOrderID)]
+	if !ok {
+		return OrderRecord{}, false
+	}
+	record, ok := s.state.orders[orderID]
+	return record, ok
+}
+
+func (s *Server) handleGetOrderByClientID(w http.ResponseWriter, r *http.Request) {
+	clientOrderID := chi.URLParam(r, "clientOrderId")
+	s.serveGetOrder(w, r, func(userID string) (OrderRecord, bool) {
+		return s.orderByClientID(userID, clientOrderID)
+	})
+}
+
+func (s *Server) handleCancelOrderByClientID(w http.ResponseWriter, r *http.Request) {
+	clientOrderID := chi.URLParam(r, "clientOrderId")
+	s.serveCancelOrder(w, r, "/v1/orders/by-client-id/"+clientOrderID, func(userID string) (OrderRecord, bool) {
+		return s.orderByClientID(userID, clientOrderID)
+	})
+}
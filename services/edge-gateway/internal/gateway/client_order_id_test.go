@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientOrderIDLookupCancelAndReuse(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	body := []byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","clientOrderId":"strat-7.leg-1"}`)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", body, "coid-1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var created OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode create: %v", err)
+	}
+	if created.ClientOrderID != "strat-7.leg-1" {
+		t.Fatalf("expected clientOrderId echoed, got %+v", created)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", body, "coid-2"))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected duplicate clientOrderId rejection, got %d body=%s", w.Code, w.Body.String())
+	}
+	if hold := s.snapshotWallet("test-key")["KRW"].Hold; hold.String() != "100" {
+		t.Fatalf("expected duplicate to leave reserve untouched, got hold=%v", hold)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/orders/by-client-id/strat-7.leg-1", nil, ""))
+	var fetched OrderRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &fetched); err != nil || fetched.OrderID != "ord_coid-1" {
+		t.Fatalf("lookup by clientOrderId failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/by-client-id/strat-7.leg-1", nil, "coid-cancel"))
+	var canceled OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &canceled); err != nil || canceled.Status != "CANCELED" || canceled.ClientOrderID != "strat-7.leg-1" {
+		t.Fatalf("cancel by clientOrderId failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", body, "coid-3"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected clientOrderId reusable after cancel, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","clientOrderId":"bad id!"}`), "coid-4"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid clientOrderId 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,597 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	orderTypeStop         = "STOP"
+	orderTypeStopLimit    = "STOP_LIMIT"
+	orderTypeTrailingStop = "TRAILING_STOP"
+
+	orderStatusPendingTrigger = "PENDING_TRIGGER"
+	orderStatusTriggered      = "TRIGGERED"
+
+	triggerAbove = "ABOVE"
+	triggerBelow = "BELOW"
+)
+
+// OrderTrigger is the condition a gateway-held order waits on. StopPrice is
+// the current trigger level; for trailing stops it follows Watermark, the
+// best price seen on the trigger symbol since the order was created.
+type OrderTrigger struct {
+	Symbol          string `json:"symbol"`
+	Direction       string `json:"direction"`
+	StopPrice       int64  `json:"stopPrice,string"`
+	TrailingOffset  int64  `json:"trailingOffset,string,omitempty"`
+	TrailingPercent string `json:"trailingPercent,omitempty"`
+	Watermark       int64  `json:"watermark,string,omitempty"`
+	TriggeredAt     int64  `json:"triggeredAt,omitempty"`
+	TriggeredPrice  int64  `json:"triggeredPrice,string,omitempty"`
+	ChildOrderID    string `json:"childOrderId,omitempty"`
+}
+
+func isConditionalOrderType(orderType string) bool {
+	switch strings.ToUpper(strings.TrimSpace(orderType)) {
+	case orderTypeStop, orderTypeStopLimit, orderTypeTrailingStop:
+		return true
+	default:
+		return false
+	}
+}
+
+func hasTriggerFields(req OrderRequest) bool {
+	return strings.TrimSpace(req.StopPrice) != "" ||
+		strings.TrimSpace(req.TriggerSymbol) != "" ||
+		strings.TrimSpace(req.TriggerDirection) != "" ||
+		strings.TrimSpace(req.TrailingOffset) != "" ||
+		strings.TrimSpace(req.TrailingPercent) != ""
+}
+
+// validateConditionalOrder checks the trigger half of a STOP, STOP_LIMIT or
+// TRAILING_STOP order; the child half goes through the usual order checks.
+func (s *Server) validateConditionalOrder(req OrderRequest) error {
+	trigger, err := parseOrderTrigger(req)
+	if err != nil {
+		return err
+	}
+	orderType := strings.ToUpper(strings.TrimSpace(req.Type))
+	if orderType == orderTypeStopLimit && strings.TrimSpace(req.Price) == "" {
+		return fmt.Errorf("price required for STOP_LIMIT")
+	}
+	if orderType != orderTypeStopLimit && strings.TrimSpace(req.Price) != "" {
+		return fmt.Errorf("price only allowed for STOP_LIMIT")
+	}
+	if orderType == orderTypeTrailingStop {
+		return nil
+	}
+	if last, ok := s.lastTradePrice(trigger.Symbol); ok && trigger.reached(last) {
+		return fmt.Errorf("stopPrice already reached")
+	}
+	return nil
+}
+
+// parseOrderTrigger builds the trigger for a conditional order request. The
+// direction defaults to the side's stop-loss sense: buys fire on a rise to
+// the stop, sells on a fall to it.
+func parseOrderTrigger(req OrderRequest) (OrderTrigger, error) {
+	trigger := OrderTrigger{
+		Symbol:    strings.ToUpper(strings.TrimSpace(req.TriggerSymbol)),
+		Direction: strings.ToUpper(strings.TrimSpace(req.TriggerDirection)),
+	}
+	if trigger.Symbol == "" {
+		trigger.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	}
+	if _, _, ok := parseSymbol(trigger.Symbol); !ok {
+		return OrderTrigger{}, fmt.Errorf("invalid triggerSymbol")
+	}
+	switch trigger.Direction {
+	case triggerAbove, triggerBelow:
+	case "":
+		trigger.Direction = triggerBelow
+		if strings.ToUpper(strings.TrimSpace(req.Side)) == "BUY" {
+			trigger.Direction = triggerAbove
+		}
+	default:
+		return OrderTrigger{}, fmt.Errorf("invalid triggerDirection")
+	}
+
+	stopRaw := strings.TrimSpace(req.StopPrice)
+	offsetRaw := strings.TrimSpace(req.TrailingOffset)
+	percentRaw := strings.TrimSpace(req.TrailingPercent)
+	if strings.ToUpper(strings.TrimSpace(req.Type)) != orderTypeTrailingStop {
+		if offsetRaw != "" || percentRaw != "" {
+			return OrderTrigger{}, fmt.Errorf("trailing offset only allowed for TRAILING_STOP")
+		}
+		stop, err := strconv.ParseInt(stopRaw, 10, 64)
+		if err != nil || stop <= 0 {
+			return OrderTrigger{}, fmt.Errorf("invalid stopPrice")
+		}
+		trigger.StopPrice = stop
+		return trigger, nil
+	}
+
+	if stopRaw != "" {
+		return OrderTrigger{}, fmt.Errorf("stopPrice not allowed for TRAILING_STOP")
+	}
+	if (offsetRaw == "") == (percentRaw == "") {
+		return OrderTrigger{}, fmt.Errorf("exactly one of trailingOffset/trailingPercent required")
+	}
+	if offsetRaw != "" {
+		offset, err := strconv.ParseInt(offsetRaw, 10, 64)
+		if err != nil || offset <= 0 {
+			return OrderTrigger{}, fmt.Errorf("invalid trailingOffset")
+		}
+		trigger.TrailingOffset = offset
+		return trigger, nil
+	}
+	percent, ok := parseDecimal(percentRaw)
+	if !ok || percent.Sign() <= 0 || percent.Cmp(decimalFromInt(100)) >= 0 {
+		return OrderTrigger{}, fmt.Errorf("invalid trailingPercent")
+	}
+	trigger.TrailingPercent = percentRaw
+	return trigger, nil
+}
+
+func (t OrderTrigger) trailing() bool {
+	return t.TrailingOffset > 0 || t.TrailingPercent != ""
+}
+
+// follow moves a trailing trigger's watermark to price when price is an
+// improvement, re-deriving StopPrice. It reports whether anything changed.
+func (t *OrderTrigger) follow(price int64) bool {
+	if !t.trailing() || price <= 0 {
+		return false
+	}
+	improved := t.Watermark == 0 ||
+		(t.Direction == triggerBelow && price > t.Watermark) ||
+		(t.Direction == triggerAbove && price < t.Watermark)
+	if !improved {
+		return false
+	}
+	t.Watermark = price
+
+	offset := t.TrailingOffset
+	if offset == 0 {
+		percent, _ := parseDecimal(t.TrailingPercent)
+		scaled, _ := decimalFromRat(new(big.Rat).Mul(percent.Rat(), big.NewRat(price, 100)), 0, false)
+		offset = scaled.int().Int64()
+	}
+	if t.Direction == triggerBelow {
+		t.StopPrice = price - offset
+	} else {
+		t.StopPrice = price + offset
+	}
+	return true
+}
+
+func (t OrderTrigger) reached(price int64) bool {
+	if t.StopPrice <= 0 || price <= 0 {
+		return false
+	}
+	if t.Direction == triggerAbove {
+		return price >= t.StopPrice
+	}
+	return price <= t.StopPrice
+}
+
+// conditionalChildRequest is the order a conditional order places once it
+// fires: LIMIT for STOP_LIMIT, MARKET otherwise.
+func conditionalChildRequest(req OrderRequest) OrderRequest {
+	child := OrderRequest{
+		Symbol:              req.Symbol,
+		Side:                req.Side,
+		Type:                "MARKET",
+		Qty:                 req.Qty,
+		TimeInForce:         req.TimeInForce,
+		ExpireAt:            req.ExpireAt,
+		SelfTradePrevention: req.SelfTradePrevention,
+	}
+	if strings.ToUpper(strings.TrimSpace(req.Type)) == orderTypeStopLimit {
+		child.Type = "LIMIT"
+		child.Price = req.Price
+	}
+	return child
+}
+
+// conditionalReserveRequest is what a conditional order reserves against.
+// A market buy that triggers on its own symbol is sized at the trigger
+// level rather than today's price, since that is where it will execute.
+func (s *Server) conditionalReserveRequest(req OrderRequest) OrderRequest {
+	child := conditionalChildRequest(req)
+	if child.Type != "MARKET" || strings.ToUpper(strings.TrimSpace(child.Side)) != "BUY" {
+		return child
+	}
+	trigger, err := parseOrderTrigger(req)
+	if err != nil || trigger.Symbol != strings.ToUpper(strings.TrimSpace(req.Symbol)) {
+		return child
+	}
+	if last, ok := s.lastTradePrice(trigger.Symbol); ok {
+		trigger.follow(last)
+	}
+	if trigger.StopPrice > 0 {
+		child.Type = "LIMIT"
+		child.Price = strconv.FormatInt(trigger.StopPrice, 10)
+	}
+	return child
+}
+
+func (s *Server) lastTradePrice(symbol string) (int64, bool) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	tape := s.state.tradeTape[strings.ToUpper(strings.TrimSpace(symbol))]
+	if len(tape) == 0 {
+		return 0, false
+	}
+	return tape[len(tape)-1].price, true
+}
+
+// holdConditionalOrder records a validated, reserved conditional order as
+// PENDING_TRIGGER. Nothing reaches the core until the trigger fires.
+func (s *Server) holdConditionalOrder(
+	ctx context.Context,
+	userID string,
+	idemKey string,
+	req OrderRequest,
+	reserveCurrency string,
+	reserveAmount Decimal,
+) (OrderResponse, error) {
+	return s.holdOrder(ctx, userID, idemKey, req, orderStatusPendingTrigger, reserveCurrency, reserveAmount)
+}
+
+// holdOrder stores an order the gateway keeps to itself under the given
+// held status, attaching the trigger for conditional types.
+func (s *Server) holdOrder(
+	ctx context.Context,
+	userID string,
+	idemKey string,
+	req OrderRequest,
+	status string,
+	reserveCurrency string,
+	reserveAmount Decimal,
+) (OrderResponse, error) {
+	orderID := fmt.Sprintf("ord_%s", idemKey)
+	if req.ClientOrderID != "" && !s.claimClientOrderID(userID, req.ClientOrderID, orderID) {
+		if reserveCurrency != "" && reserveAmount.Sign() > 0 {
+			s.releaseReserve(userID, reserveCurrency, reserveAmount, orderID)
+		}
+		return OrderResponse{}, errDuplicateClientOrderID
+	}
+
+	var trigger *OrderTrigger
+	if isConditionalOrderType(req.Type) {
+		parsed, _ := parseOrderTrigger(req)
+		if last, ok := s.lastTradePrice(parsed.Symbol); ok {
+			parsed.follow(last)
+		}
+		trigger = &parsed
+	}
+	tif, _ := mapTimeInForce(req.TimeInForce)
+	stp, _ := mapSelfTradePrevention(req.SelfTradePrevention)
+	qty, _ := parseDecimal(req.Qty)
+	now := time.Now().UnixMilli()
+	record := OrderRecord{
+		OrderID:         orderID,
+		ClientOrderID:   req.ClientOrderID,
+		Status:          status,
+		Symbol:          strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		CreatedAt:       now,
+		AcceptedAt:      now,
+		OwnerUserID:     userID,
+		ReserveCurrency: reserveCurrency,
+		ReserveAmount:   reserveAmount,
+		Side:            strings.ToUpper(strings.TrimSpace(req.Side)),
+		Type:            strings.ToUpper(strings.TrimSpace(req.Type)),
+		Price:           strings.TrimSpace(req.Price),
+		TimeInForce:     timeInForceLabel(req.TimeInForce, tif),
+		ExpireAt:        req.ExpireAt,
+		Qty:             qty,
+
+		SelfTradePrevention: selfTradePreventionLabel(stp),
+		Trigger:             trigger,
+		GroupID:             req.groupID,
+		GroupRole:           req.groupRole,
+	}
+	s.state.mu.Lock()
+	s.state.orders[orderID] = record
+	s.state.ordersTotal++
+	s.indexTriggerLocked(record)
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, record)
+
+	return OrderResponse{
+		OrderID:       orderID,
+		ClientOrderID: req.ClientOrderID,
+		Status:        status,
+		Symbol:        record.Symbol,
+		AcceptedAt:    now,
+	}, nil
+}
+
+// triggerFire is a conditional order whose trigger held at price, queued
+// for the trigger worker.
+type triggerFire struct {
+	orderID string
+	price   int64
+}
+
+// indexTriggerLocked files a held order with a trigger under its trigger
+// symbol. Entries are dropped lazily once the order is no longer held.
+// Callers hold s.state.mu.
+func (s *Server) indexTriggerLocked(record OrderRecord) {
+	if record.Trigger == nil || !isHeldOrderStatus(record.Status) {
+		return
+	}
+	orders := s.state.triggerIndex[record.Trigger.Symbol]
+	if orders == nil {
+		orders = map[string]struct{}{}
+		s.state.triggerIndex[record.Trigger.Symbol] = orders
+	}
+	orders[record.OrderID] = struct{}{}
+}
+
+// evaluateTriggers checks pending conditional orders watching symbol
+// against its latest trade price, trailing watermarks along the way, and
+// queues the ones whose condition now holds, in creation order, for the
+// trigger worker. It only looks at the orders indexed under symbol and never
+// calls the core, so the trade consumer is not held up by placements.
+func (s *Server) evaluateTriggers(ctx context.Context, symbol string, price int64) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if price <= 0 {
+		return
+	}
+
+	var moved, ready []OrderRecord
+	s.state.mu.Lock()
+	for orderID := range s.state.triggerIndex[symbol] {
+		record, ok := s.state.orders[orderID]
+		if !ok || record.Trigger == nil || !isHeldOrderStatus(record.Status) {
+			delete(s.state.triggerIndex[symbol], orderID)
+			continue
+		}
+		if record.Status != orderStatusPendingTrigger {
+			continue
+		}
+		trigger := *record.Trigger
+		if trigger.follow(price) {
+			record.Trigger = &trigger
+			s.state.orders[orderID] = record
+			moved = append(moved, record)
+		}
+		if trigger.reached(price) {
+			ready = append(ready, record)
+		}
+	}
+	if len(s.state.triggerIndex[symbol]) == 0 {
+		delete(s.state.triggerIndex, symbol)
+	}
+	s.state.mu.Unlock()
+
+	for _, record := range moved {
+		s.persistOrder(ctx, record)
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if ready[i].CreatedAt != ready[j].CreatedAt {
+			return ready[i].CreatedAt < ready[j].CreatedAt
+		}
+		return ready[i].OrderID < ready[j].OrderID
+	})
+	if len(ready) == 0 {
+		return
+	}
+	s.triggerMu.Lock()
+	for _, record := range ready {
+		s.triggerPending.Add(1)
+		s.triggerQueue = append(s.triggerQueue, triggerFire{orderID: record.OrderID, price: price})
+	}
+	s.triggerMu.Unlock()
+	select {
+	case s.triggerWake <- struct{}{}:
+	default:
+	}
+}
+
+// fireQueuedTriggers places what evaluateTriggers queued. Orders on a symbol
+// that is not accepting orders stay pending until a trade after it reopens.
+// An order queued twice fires once: fireConditionalOrder rechecks its status.
+func (s *Server) fireQueuedTriggers(ctx context.Context) {
+	s.triggerMu.Lock()
+	queue := s.triggerQueue
+	s.triggerQueue = nil
+	s.triggerMu.Unlock()
+	for _, fire := range queue {
+		s.state.mu.Lock()
+		symbol := s.state.orders[fire.orderID].Symbol
+		s.state.mu.Unlock()
+		if code := s.orderEntryBlock(symbol); code == "" {
+			s.fireConditionalOrder(ctx, fire.orderID, fire.price)
+		}
+		s.triggerPending.Done()
+	}
+}
+
+func (s *Server) startTriggerWorker() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.triggerCancel = cancel
+	s.triggerWG.Add(1)
+	go func() {
+		defer s.triggerWG.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.triggerWake:
+				// A placement already under way finishes on shutdown rather
+				// than being rejected halfway.
+				s.fireQueuedTriggers(context.WithoutCancel(ctx))
+			}
+		}
+	}()
+}
+
+// fireConditionalOrder flips a pending order to TRIGGERED and places its
+// child under order ID {orderId}:trigger, handing the reserve over. The
+// status check under the lock keeps a racing cancel from double-releasing.
+func (s *Server) fireConditionalOrder(ctx context.Context, orderID string, price int64) {
+	s.state.mu.Lock()
+	record, ok := s.state.orders[orderID]
+	if !ok || record.Status != orderStatusPendingTrigger || record.Trigger == nil {
+		s.state.mu.Unlock()
+		return
+	}
+	childIdemKey := strings.TrimPrefix(orderID, "ord_") + ":trigger"
+	trigger := *record.Trigger
+	trigger.TriggeredAt = time.Now().UnixMilli()
+	trigger.TriggeredPrice = price
+	trigger.ChildOrderID = "ord_" + childIdemKey
+	carried := unconsumedReserve(record)
+	record.ReserveAmount = record.ReserveAmount.Sub(carried)
+	record.Status = orderStatusTriggered
+	record.Trigger = &trigger
+	s.state.orders[orderID] = record
+	s.state.mu.Unlock()
+
+	qty := record.Qty
+	currency := record.ReserveCurrency
+	if record.GroupID != "" {
+		linkedCurrency, linkedAmount, openQty, ok := s.takeLinkedReserve(ctx, record)
+		if !ok {
+			s.abortTriggeredOrder(ctx, orderID, "CANCELED", currency, carried)
+			return
+		}
+		if linkedCurrency != "" {
+			currency = linkedCurrency
+		}
+		carried = carried.Add(linkedAmount)
+		qty = minDecimal(qty, openQty)
+		if qty.Sign() <= 0 {
+			s.abortTriggeredOrder(ctx, orderID, "CANCELED", currency, carried)
+			return
+		}
+	}
+
+	child := conditionalChildRequest(OrderRequest{
+		Symbol:              record.Symbol,
+		Side:                record.Side,
+		Type:                record.Type,
+		Price:               record.Price,
+		Qty:                 formatQty(qty),
+		TimeInForce:         record.TimeInForce,
+		ExpireAt:            record.ExpireAt,
+		SelfTradePrevention: record.SelfTradePrevention,
+	})
+	// The band is checked against the market at fire time, not at placement.
+	if err := s.checkPriceBand(child); err != nil {
+		log.Printf("service=edge-gateway msg=conditional_order_trigger_failed order_id=%s err=%v", orderID, err)
+		s.abortTriggeredOrder(ctx, orderID, "REJECTED", currency, carried)
+		return
+	}
+	if child.Type == "MARKET" && strings.ToUpper(child.Side) == "BUY" {
+		// Capped like any MARKET buy; the reserve carried over stays as is.
+		planned, err := s.planMarketOrder(child)
+		if err != nil {
+			log.Printf("service=edge-gateway msg=conditional_order_trigger_failed order_id=%s err=%v", orderID, err)
+			s.abortTriggeredOrder(ctx, orderID, "REJECTED", currency, carried)
+			return
+		}
+		child.protectPrice = planned.protectPrice
+	}
+	resp, err := s.placeOrderWithCore(ctx, record.OwnerUserID, childIdemKey, child, currency, carried)
+
+	s.state.mu.Lock()
+	record = s.state.orders[orderID]
+	if err != nil {
+		// placeOrderWithCore already returned the reserve to the owner.
+		log.Printf("service=edge-gateway msg=conditional_order_trigger_failed order_id=%s err=%v", orderID, err)
+		failed := *record.Trigger
+		failed.ChildOrderID = ""
+		record.Trigger = &failed
+		record.Status = "REJECTED"
+	} else if resp.Seq > record.Seq {
+		record.Seq = resp.Seq
+	}
+	s.state.orders[orderID] = record
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, record)
+}
+
+// abortTriggeredOrder closes a fired order without placing the child: as
+// CANCELED when its linked leg could not be pulled (it most likely filled
+// first), as REJECTED when the child would break the price band. The
+// reserve it carried goes back on the record, which releases it.
+func (s *Server) abortTriggeredOrder(ctx context.Context, orderID, status, currency string, carried Decimal) {
+	s.state.mu.Lock()
+	record := s.state.orders[orderID]
+	aborted := *record.Trigger
+	aborted.ChildOrderID = ""
+	record.Trigger = &aborted
+	record.Status = status
+	if status == "CANCELED" {
+		record.CanceledAt = time.Now().UnixMilli()
+	}
+	if carried.Sign() > 0 && currency != "" {
+		record.ReserveCurrency = currency
+		record.ReserveAmount = record.ReserveAmount.Add(carried)
+	}
+	s.state.orders[orderID] = record
+	s.state.mu.Unlock()
+	s.persistOrder(ctx, record)
+	s.releaseOrderReserve(ctx, orderID)
+}
+
+func isHeldOrderStatus(status string) bool {
+	return status == orderStatusPendingTrigger || status == orderStatusPendingEntry
+}
+
+// cancelHeldOrder cancels an order the gateway is still holding: a
+// conditional order that has not fired or a bracket exit waiting on its
+// entry. The core never saw it, so this only releases the reserve. An order
+// that moved on in the meantime is reported with its current status.
+func (s *Server) cancelHeldOrder(record OrderRecord) OrderResponse {
+	return s.closeHeldOrder(record, "CANCELED")
+}
+
+// closeHeldOrder is cancelHeldOrder for any closing status.
+func (s *Server) closeHeldOrder(record OrderRecord, status string) OrderResponse {
+	s.state.mu.Lock()
+	current, ok := s.state.orders[record.OrderID]
+	if !ok || !isHeldOrderStatus(current.Status) {
+		s.state.mu.Unlock()
+		resp := OrderResponse{
+			OrderID:       record.OrderID,
+			ClientOrderID: record.ClientOrderID,
+			Status:        record.Status,
+			Symbol:        record.Symbol,
+		}
+		if ok {
+			resp.Status = current.Status
+			resp.Seq = current.Seq
+			resp.CanceledAt = current.CanceledAt
+		}
+		if resp.Status != status {
+			resp.RejectCode = "ORDER_NOT_PENDING"
+		}
+		return resp
+	}
+	current.Status = status
+	current.CanceledAt = time.Now().UnixMilli()
+	s.state.orders[record.OrderID] = current
+	s.state.mu.Unlock()
+	s.persistOrder(context.Background(), current)
+	s.releaseOrderReserve(context.Background(), current.OrderID)
+	return OrderResponse{
+		OrderID:       current.OrderID,
+		ClientOrderID: current.ClientOrderID,
+		Status:        current.Status,
+		Symbol:        current.Symbol,
+		Seq:           current.Seq,
+		CanceledAt:    current.CanceledAt,
+	}
+}
@@ -0,0 +1,190 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func postSmokeTrade(t *testing.T, s *Server, tradeID, symbol string, price int64) {
+	t.Helper()
+	body := []byte(fmt.Sprintf(`{"tradeId":%q,"symbol":%q,"price":"%d","qty":"1"}`, tradeID, symbol, price))
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/smoke/trades", body, ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("smoke trade %s failed: %d body=%s", tradeID, w.Code, w.Body.String())
+	}
+	// Triggers fire on the trigger worker; wait for what this trade queued.
+	s.triggerPending.Wait()
+}
+
+func getOrderRecord(t *testing.T, s *Server, orderID string) OrderRecord {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/orders/"+orderID, nil, ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get %s failed: %d body=%s", orderID, w.Code, w.Body.String())
+	}
+	var record OrderRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &record); err != nil {
+		t.Fatalf("decode order: %v", err)
+	}
+	return record
+}
+
+func TestStopOrderTriggersOnLastTradePrice(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	postSmokeTrade(t, s, "stop-t1", "BTC-KRW", 100)
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"SELL","type":"STOP","qty":"1","stopPrice":"120"}`), "stop-reached"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected stop above last price to be rejected, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"SELL","type":"STOP","qty":"1","stopPrice":"90"}`), "stop-1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create stop failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var resp OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode create: %v", err)
+	}
+	if resp.Status != orderStatusPendingTrigger {
+		t.Fatalf("expected PENDING_TRIGGER, got %+v", resp)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "1.00000000" {
+		t.Fatalf("expected stop to reserve 1 BTC, got %+v", btc)
+	}
+
+	postSmokeTrade(t, s, "stop-t2", "BTC-KRW", 95)
+	record := getOrderRecord(t, s, "ord_stop-1")
+	if record.Status != orderStatusPendingTrigger || record.Trigger == nil || record.Trigger.StopPrice != 90 {
+		t.Fatalf("expected pending stop at 90, got %+v", record)
+	}
+
+	postSmokeTrade(t, s, "stop-t3", "BTC-KRW", 90)
+	record = getOrderRecord(t, s, "ord_stop-1")
+	if record.Status != orderStatusTriggered || record.Trigger.ChildOrderID != "ord_stop-1:trigger" || record.Trigger.TriggeredPrice != 90 {
+		t.Fatalf("expected triggered stop with child, got %+v trigger=%+v", record, record.Trigger)
+	}
+	child := getOrderRecord(t, s, "ord_stop-1:trigger")
+	if child.Status != "ACCEPTED" || child.Type != "MARKET" || child.Side != "SELL" {
+		t.Fatalf("unexpected child order: %+v", child)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "1.00000000" || btc.Available.String() != "1.00000000" {
+		t.Fatalf("expected reserve carried to child, got %+v", btc)
+	}
+}
+
+func TestTrailingStopFollowsTriggerSymbolAndCancelReleasesReserve(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	postSmokeTrade(t, s, "trail-t1", "ETH-KRW", 1000)
+	postSmokeTrade(t, s, "trail-t2", "BTC-KRW", 1000)
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"SELL","type":"TRAILING_STOP","qty":"1","triggerSymbol":"ETH-KRW","trailingPercent":"10"}`), "trail-pct"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create trailing stop failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if record := getOrderRecord(t, s, "ord_trail-pct"); record.Trigger.StopPrice != 900 {
+		t.Fatalf("expected initial stop 900, got %+v", record.Trigger)
+	}
+
+	postSmokeTrade(t, s, "trail-t3", "ETH-KRW", 1200)
+	postSmokeTrade(t, s, "trail-t4", "ETH-KRW", 1100)
+	record := getOrderRecord(t, s, "ord_trail-pct")
+	if record.Status != orderStatusPendingTrigger || record.Trigger.Watermark != 1200 || record.Trigger.StopPrice != 1080 {
+		t.Fatalf("expected stop to trail to 1080, got %+v trigger=%+v", record, record.Trigger)
+	}
+	postSmokeTrade(t, s, "trail-t5", "BTC-KRW", 500)
+	if record := getOrderRecord(t, s, "ord_trail-pct"); record.Status != orderStatusPendingTrigger {
+		t.Fatalf("trades on the order symbol must not fire an ETH trigger, got %s", record.Status)
+	}
+	postSmokeTrade(t, s, "trail-t6", "ETH-KRW", 1080)
+	if record := getOrderRecord(t, s, "ord_trail-pct"); record.Status != orderStatusTriggered {
+		t.Fatalf("expected trailing stop to trigger, got %s", record.Status)
+	}
+
+	// A buy trailing on its own symbol reserves at the current trigger level.
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"TRAILING_STOP","qty":"2","trailingOffset":"50"}`), "trail-abs"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create buy trailing stop failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "1100" {
+		t.Fatalf("expected 2 x 550 KRW held, got %+v", krw)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_trail-abs", nil, "trail-abs-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var resp OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode cancel: %v", err)
+	}
+	if resp.Status != "CANCELED" {
+		t.Fatalf("expected CANCELED, got %+v", resp)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" {
+		t.Fatalf("expected reserve released on cancel, got %+v", krw)
+	}
+	postSmokeTrade(t, s, "trail-t7", "BTC-KRW", 600)
+	if record := getOrderRecord(t, s, "ord_trail-abs"); record.Status != "CANCELED" {
+		t.Fatalf("canceled stop must not fire, got %s", record.Status)
+	}
+}
+
+func TestTradeConsumerQueuesTriggersForTheWorker(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	// Stop the worker so the test sees what the consumer left behind.
+	s.triggerCancel()
+	s.triggerWG.Wait()
+	ctx := context.Background()
+
+	for _, order := range []struct{ symbol, idemKey string }{{"BTC-KRW", "idx-btc"}, {"ETH-KRW", "idx-eth"}} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+			[]byte(`{"symbol":"`+order.symbol+`","side":"SELL","type":"STOP","qty":"0.1","stopPrice":"90"}`), order.idemKey))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create stop on %s failed: %d body=%s", order.symbol, w.Code, w.Body.String())
+		}
+	}
+	s.state.mu.Lock()
+	indexed := len(s.state.triggerIndex["BTC-KRW"])
+	s.state.mu.Unlock()
+	if indexed != 1 {
+		t.Fatalf("expected one stop indexed under BTC-KRW, got %d", indexed)
+	}
+
+	if err := s.consumeTradeMessage(ctx, []byte(
+		`{"tradeId":"idx-t1","symbol":"BTC-KRW","seq":7,"makerOrderId":"ord_a","takerOrderId":"ord_b","price":90,"quantity":1}`,
+	)); err != nil {
+		t.Fatalf("consume trade: %v", err)
+	}
+	if got := getOrderRecord(t, s, "ord_idx-btc"); got.Status != orderStatusPendingTrigger {
+		t.Fatalf("expected the consumer to leave the placement to the worker, got %s", got.Status)
+	}
+	s.fireQueuedTriggers(ctx)
+	if got := getOrderRecord(t, s, "ord_idx-btc"); got.Status != orderStatusTriggered {
+		t.Fatalf("expected the queued stop fired, got %s", got.Status)
+	}
+	if got := getOrderRecord(t, s, "ord_idx-eth"); got.Status != orderStatusPendingTrigger {
+		t.Fatalf("expected the ETH-KRW stop untouched, got %s", got.Status)
+	}
+}
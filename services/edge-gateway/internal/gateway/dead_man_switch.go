@@ -0,0 +1,427 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	minDeadManTimeout  = 5 * time.Second
+	maxDeadManTimeout  = time.Hour
+	deadManSweepPeriod = time.Second
+
+	deadManReasonTimeout    = "timeout"
+	deadManReasonDisconnect = "disconnect"
+)
+
+type DeadManSwitchRequest struct {
+	TimeoutSeconds int64 `json:"timeoutSeconds"`
+}
+
+// DeadManSwitchView is a principal's cancel-on-disconnect switch. Once it
+// fires it disarms itself and keeps what it did for the client to inspect.
+type DeadManSwitchView struct {
+	Armed           bool   `json:"armed"`
+	TimeoutSeconds  int64  `json:"timeoutSeconds,omitempty"`
+	ExpiresAt       int64  `json:"expiresAt,omitempty"`
+	LastHeartbeatAt int64  `json:"lastHeartbeatAt,omitempty"`
+	TriggeredAt     int64  `json:"triggeredAt,omitempty"`
+	TriggerReason   string `json:"triggerReason,omitempty"`
+	CanceledOrders  int    `json:"canceledOrders,omitempty"`
+	PendingCancels  int    `json:"pendingCancels,omitempty"`
+}
+
+// deadManSwitch is kept in Postgres so it survives a gateway restart.
+// pendingOrders are the orders it failed to cancel when it fired; the
+// sweeper retries them until they are canceled or close on their own.
+type deadManSwitch struct {
+	armed           bool
+	timeoutMs       int64
+	expiresAt       int64
+	lastHeartbeatAt int64
+	triggeredAt     int64
+	triggerReason   string
+	canceledOrders  int
+	pendingOrders   []string
+}
+
+func (d deadManSwitch) view() DeadManSwitchView {
+	view := DeadManSwitchView{
+		Armed:           d.armed,
+		LastHeartbeatAt: d.lastHeartbeatAt,
+		TriggeredAt:     d.triggeredAt,
+		TriggerReason:   d.triggerReason,
+		CanceledOrders:  d.canceledOrders,
+		PendingCancels:  len(d.pendingOrders),
+	}
+	if d.armed {
+		view.TimeoutSeconds = d.timeoutMs / 1000
+		view.ExpiresAt = d.expiresAt
+	}
+	return view
+}
+
+func (s *Server) handleGetDeadManSwitch(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	s.state.mu.Lock()
+	view := s.state.deadManSwitches[apiKey].view()
+	s.state.mu.Unlock()
+	writeJSON(w, http.StatusOK, view)
+}
+
+// handleArmDeadManSwitch arms (or re-arms with a new timeout) the caller's
+// switch. From then on the caller must heartbeat within the timeout or keep
+// an authenticated WebSocket open, or all of its open orders are canceled.
+func (s *Server) handleArmDeadManSwitch(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	var req DeadManSwitchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if timeout < minDeadManTimeout || timeout > maxDeadManTimeout {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf(
+			"timeoutSeconds must be between %d and %d", int64(minDeadManTimeout.Seconds()), int64(maxDeadManTimeout.Seconds()),
+		)})
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	s.state.mu.Lock()
+	sw := s.state.deadManSwitches[apiKey]
+	sw.armed = true
+	sw.timeoutMs = timeout.Milliseconds()
+	sw.lastHeartbeatAt = now
+	sw.expiresAt = now + sw.timeoutMs
+	s.state.deadManSwitches[apiKey] = sw
+	s.state.mu.Unlock()
+	s.persistDeadManSwitch(r.Context(), apiKey, sw)
+	writeJSON(w, http.StatusOK, sw.view())
+}
+
+func (s *Server) handleDisarmDeadManSwitch(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	s.state.mu.Lock()
+	sw := s.state.deadManSwitches[apiKey]
+	sw.armed = false
+	s.state.deadManSwitches[apiKey] = sw
+	s.state.mu.Unlock()
+	s.persistDeadManSwitch(r.Context(), apiKey, sw)
+	writeJSON(w, http.StatusOK, sw.view())
+}
+
+func (s *Server) handleDeadManHeartbeat(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	view, ok := s.refreshDeadManSwitch(apiKey, time.Now().UnixMilli())
+	if !ok {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "DEAD_MAN_SWITCH_NOT_ARMED"})
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// refreshDeadManSwitch pushes an armed switch's deadline out by its
+// timeout. It reports false when the principal has no armed switch.
+func (s *Server) refreshDeadManSwitch(userID string, nowMs int64) (DeadManSwitchView, bool) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	sw, ok := s.state.deadManSwitches[userID]
+	if !ok || !sw.armed {
+		return DeadManSwitchView{}, false
+	}
+	sw.lastHeartbeatAt = nowMs
+	sw.expiresAt = nowMs + sw.timeoutMs
+	s.state.deadManSwitches[userID] = sw
+	return sw.view(), true
+}
+
+// connectedPrincipals lists the principals with an authenticated WebSocket
+// open, leaving out the except connection.
+func (s *Server) connectedPrincipals(except *client) map[string]bool {
+	s.state.mu.Lock()
+	clients := make([]*client, 0, len(s.state.clients))
+	for c := range s.state.clients {
+		if c != except {
+			clients = append(clients, c)
+		}
+	}
+	s.state.mu.Unlock()
+
+	out := map[string]bool{}
+	for _, c := range clients {
+		if userID := c.principal(); userID != "" {
+			out[userID] = true
+		}
+	}
+	return out
+}
+
+// sweepDeadManSwitches keeps switches alive for principals with an open
+// authenticated WebSocket, fires the ones whose deadline passed and retries
+// the cancels earlier firings could not get through.
+func (s *Server) sweepDeadManSwitches(ctx context.Context, nowMs int64) {
+	connected := s.connectedPrincipals(nil)
+	var expired, retry []string
+	s.state.mu.Lock()
+	for userID, sw := range s.state.deadManSwitches {
+		if len(sw.pendingOrders) > 0 {
+			retry = append(retry, userID)
+		}
+		if !sw.armed {
+			continue
+		}
+		if connected[userID] {
+			sw.expiresAt = nowMs + sw.timeoutMs
+			s.state.deadManSwitches[userID] = sw
+			continue
+		}
+		if sw.expiresAt <= nowMs {
+			expired = append(expired, userID)
+		}
+	}
+	s.state.mu.Unlock()
+
+	for _, userID := range retry {
+		s.retryDeadManCancels(ctx, userID, nowMs)
+	}
+	for _, userID := range expired {
+		s.triggerDeadManSwitch(ctx, userID, deadManReasonTimeout, nowMs)
+	}
+}
+
+// handleWSDisconnect fires the principal's switch when its last
+// authenticated WebSocket goes away.
+func (s *Server) handleWSDisconnect(c *client) {
+	userID := c.principal()
+	if userID == "" || s.connectedPrincipals(c)[userID] {
+		return
+	}
+	s.triggerDeadManSwitch(context.Background(), userID, deadManReasonDisconnect, time.Now().UnixMilli())
+}
+
+// triggerDeadManSwitch disarms an armed switch and cancels every open order
+// of its principal. The disarm happens first so a concurrent sweep or
+// disconnect cannot fire it twice; orders whose cancel failed are left to
+// the sweeper to retry.
+func (s *Server) triggerDeadManSwitch(ctx context.Context, userID, reason string, nowMs int64) {
+	s.state.mu.Lock()
+	sw, ok := s.state.deadManSwitches[userID]
+	if !ok || !sw.armed {
+		s.state.mu.Unlock()
+		return
+	}
+	sw.armed = false
+	sw.triggeredAt = nowMs
+	sw.triggerReason = reason
+	sw.canceledOrders = 0
+	s.state.deadManSwitches[userID] = sw
+	s.state.deadManTriggers++
+	s.state.mu.Unlock()
+
+	s.persistDeadManSwitch(ctx, userID, sw)
+
+	canceled, failed := s.cancelOpenOrders(ctx, userID, fmt.Sprintf("dms-%d", nowMs), "", "")
+	log.Printf(
+		"service=edge-gateway msg=dead_man_switch_triggered user_id=%s reason=%s canceled=%d failed=%d",
+		userID, reason, len(canceled), len(failed),
+	)
+
+	s.state.mu.Lock()
+	sw = s.state.deadManSwitches[userID]
+	if sw.triggeredAt == nowMs {
+		sw.canceledOrders = len(canceled)
+	}
+	for _, failure := range failed {
+		sw.pendingOrders = appendMissing(sw.pendingOrders, failure.OrderID)
+	}
+	s.state.deadManSwitches[userID] = sw
+	s.state.mu.Unlock()
+	s.persistDeadManSwitch(ctx, userID, sw)
+}
+
+// retryDeadManCancels cancels again the orders a fired switch could not,
+// dropping the ones that are canceled now or were closed some other way.
+func (s *Server) retryDeadManCancels(ctx context.Context, userID string, nowMs int64) {
+	s.state.mu.Lock()
+	pending := append([]string(nil), s.state.deadManSwitches[userID].pendingOrders...)
+	s.state.mu.Unlock()
+
+	var done []string
+	canceled := 0
+	for _, orderID := range pending {
+		s.state.mu.Lock()
+		record, ok := s.state.orders[orderID]
+		s.state.mu.Unlock()
+		if !ok || !isOpenOrderStatus(record.Status) {
+			done = append(done, orderID)
+			continue
+		}
+		resp, err := s.cancelOrderWithCore(ctx, userID, fmt.Sprintf("dms-retry-%d:%s", nowMs, orderID), record)
+		if err != nil || resp.Status != "CANCELED" {
+			continue
+		}
+		done = append(done, orderID)
+		canceled++
+	}
+	if len(done) == 0 {
+		return
+	}
+
+	s.state.mu.Lock()
+	sw := s.state.deadManSwitches[userID]
+	for _, orderID := range done {
+		sw.pendingOrders = removeString(sw.pendingOrders, orderID)
+	}
+	sw.canceledOrders += canceled
+	s.state.deadManSwitches[userID] = sw
+	s.state.mu.Unlock()
+	s.persistDeadManSwitch(ctx, userID, sw)
+	log.Printf(
+		"service=edge-gateway msg=dead_man_switch_retried user_id=%s canceled=%d pending=%d",
+		userID, canceled, len(sw.pendingOrders),
+	)
+}
+
+func appendMissing(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func (s *Server) startDeadManSweeper() {
+	if s.coreClient == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.deadManCancel = cancel
+	s.deadManWG.Add(1)
+	go func() {
+		defer s.deadManWG.Done()
+		ticker := time.NewTicker(deadManSweepPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.sweepDeadManSwitches(ctx, now.UnixMilli())
+			}
+		}
+	}()
+}
+
+func (s *Server) initDeadManSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_dead_man_switches (
+			user_id TEXT PRIMARY KEY,
+			armed BOOLEAN NOT NULL,
+			timeout_ms BIGINT NOT NULL,
+			last_heartbeat_at_ms BIGINT NOT NULL DEFAULT 0,
+			triggered_at_ms BIGINT NOT NULL DEFAULT 0,
+			trigger_reason TEXT NOT NULL DEFAULT '',
+			canceled_orders INT NOT NULL DEFAULT 0,
+			pending_order_ids TEXT NOT NULL DEFAULT '[]'
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init dead man switch schema: %w", err)
+	}
+	return nil
+}
+
+// persistDeadManSwitch records the switch so a restart neither forgets an
+// armed switch nor the cancels it still owes.
+func (s *Server) persistDeadManSwitch(ctx context.Context, userID string, sw deadManSwitch) {
+	ctx, cancel := s.dbContext(context.WithoutCancel(ctx))
+	defer cancel()
+	if s.db == nil {
+		return
+	}
+	pending, err := json.Marshal(sw.pendingOrders)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=dead_man_switch_persist_failed user_id=%s err=%v", userID, err)
+		return
+	}
+	_, err = s.db.ExecContext(
+		ctx,
+		`INSERT INTO web_dead_man_switches(
+			user_id, armed, timeout_ms, last_heartbeat_at_ms, triggered_at_ms,
+			trigger_reason, canceled_orders, pending_order_ids
+		 ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (user_id) DO UPDATE SET
+		 armed = EXCLUDED.armed,
+		 timeout_ms = EXCLUDED.timeout_ms,
+		 last_heartbeat_at_ms = EXCLUDED.last_heartbeat_at_ms,
+		 triggered_at_ms = EXCLUDED.triggered_at_ms,
+		 trigger_reason = EXCLUDED.trigger_reason,
+		 canceled_orders = EXCLUDED.canceled_orders,
+		 pending_order_ids = EXCLUDED.pending_order_ids`,
+		userID,
+		sw.armed,
+		sw.timeoutMs,
+		sw.lastHeartbeatAt,
+		sw.triggeredAt,
+		sw.triggerReason,
+		sw.canceledOrders,
+		string(pending),
+	)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=dead_man_switch_persist_failed user_id=%s err=%v", userID, err)
+	}
+}
+
+// loadDeadManSwitches restores the stored switches. An armed switch gets a
+// fresh deadline: heartbeats could not reach a gateway that was down.
+func (s *Server) loadDeadManSwitches(ctx context.Context, nowMs int64) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, armed, timeout_ms, last_heartbeat_at_ms, triggered_at_ms,
+		       trigger_reason, canceled_orders, pending_order_ids
+		FROM web_dead_man_switches
+	`)
+	if err != nil {
+		return fmt.Errorf("load dead man switches: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID, pending string
+		var sw deadManSwitch
+		if err := rows.Scan(
+			&userID, &sw.armed, &sw.timeoutMs, &sw.lastHeartbeatAt, &sw.triggeredAt,
+			&sw.triggerReason, &sw.canceledOrders, &pending,
+		); err != nil {
+			return fmt.Errorf("scan dead man switch: %w", err)
+		}
+		if err := json.Unmarshal([]byte(pending), &sw.pendingOrders); err != nil {
+			return fmt.Errorf("decode dead man switch %s: %w", userID, err)
+		}
+		if sw.armed {
+			sw.expiresAt = nowMs + sw.timeoutMs
+		}
+		s.state.deadManSwitches[userID] = sw
+	}
+	return rows.Err()
+}
@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDeadManSwitchCancelsOrdersWhenHeartbeatsStop(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/account/dead-man-switch/heartbeat", nil, ""))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected heartbeat without a switch to be refused, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPut, "/v1/account/dead-man-switch", []byte(`{"timeoutSeconds":1}`), ""))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected too short timeout to be rejected, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPut, "/v1/account/dead-man-switch", []byte(`{"timeoutSeconds":30}`), ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("arm failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var armed DeadManSwitchView
+	if err := json.Unmarshal(w.Body.Bytes(), &armed); err != nil || !armed.Armed || armed.TimeoutSeconds != 30 {
+		t.Fatalf("unexpected arm response: %s", w.Body.String())
+	}
+
+	for _, key := range []string{"dms-1", "dms-2"} {
+		w = httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+			[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`), key))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create order failed: %d body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	s.sweepDeadManSwitches(context.Background(), armed.ExpiresAt-1)
+	if record := getOrderRecord(t, s, "ord_dms-1"); record.Status != "ACCEPTED" {
+		t.Fatalf("switch fired before its deadline: %s", record.Status)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/account/dead-man-switch/heartbeat", nil, ""))
+	var refreshed DeadManSwitchView
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil || refreshed.ExpiresAt < armed.ExpiresAt {
+		t.Fatalf("expected heartbeat to push the deadline out, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	s.sweepDeadManSwitches(context.Background(), refreshed.ExpiresAt)
+	for _, orderID := range []string{"ord_dms-1", "ord_dms-2"} {
+		if record := getOrderRecord(t, s, orderID); record.Status != "CANCELED" {
+			t.Fatalf("expected %s canceled by the switch, got %s", orderID, record.Status)
+		}
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" {
+		t.Fatalf("expected reserve released, got %+v", krw)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/dead-man-switch", nil, ""))
+	var fired DeadManSwitchView
+	if err := json.Unmarshal(w.Body.Bytes(), &fired); err != nil {
+		t.Fatalf("decode switch: %v", err)
+	}
+	if fired.Armed || fired.TriggerReason != deadManReasonTimeout || fired.CanceledOrders != 2 {
+		t.Fatalf("expected switch disarmed after firing, got %+v", fired)
+	}
+}
+
+func TestDeadManSwitchFiresWhenAuthenticatedSocketDrops(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	httpSrv := httptest.NewServer(s.Router())
+	defer httpSrv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpSrv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("websocket dial failed: %v", err)
+	}
+	ts := time.Now().UnixMilli()
+	if err := conn.WriteJSON(map[string]interface{}{
+		"op":        "AUTH",
+		"apiKey":    "test-key",
+		"ts":        ts,
+		"signature": sign("secret", "GET\n/ws\n"+strconv.FormatInt(ts, 10)+"\n"),
+	}); err != nil {
+		t.Fatalf("write auth: %v", err)
+	}
+	readWSMessage(t, conn, "Authenticated")
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPut, "/v1/account/dead-man-switch", []byte(`{"timeoutSeconds":5}`), ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("arm failed: %d body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`), "dms-ws"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create order failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	// The open socket keeps the switch alive past its deadline.
+	s.sweepDeadManSwitches(context.Background(), time.Now().Add(time.Minute).UnixMilli())
+	if record := getOrderRecord(t, s, "ord_dms-ws"); record.Status != "ACCEPTED" {
+		t.Fatalf("switch fired while the socket was open: %s", record.Status)
+	}
+
+	_ = conn.Close()
+	deadline := time.Now().Add(3 * time.Second)
+	for getOrderRecord(t, s, "ord_dms-ws").Status != "CANCELED" {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected order canceled after the socket dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.state.mu.Lock()
+	fired := s.state.deadManSwitches["test-key"]
+	s.state.mu.Unlock()
+	if fired.armed || fired.triggerReason != deadManReasonDisconnect {
+		t.Fatalf("expected disconnect trigger, got %+v", fired)
+	}
+}
+
+func TestDeadManSwitchRetriesCancelsThatFailed(t *testing.T) {
+	core := &stubCore{unknown: map[string]bool{"ord_dms-r1": true}}
+	s, cleanup := newTestServerWithCore(t, core)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPut, "/v1/account/dead-man-switch", []byte(`{"timeoutSeconds":5}`), ""))
+	var armed DeadManSwitchView
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &armed) != nil {
+		t.Fatalf("arm failed: %d body=%s", w.Code, w.Body.String())
+	}
+	for _, key := range []string{"dms-r1", "dms-r2"} {
+		w = httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+			[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`), key))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create order failed: %d body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	s.sweepDeadManSwitches(context.Background(), armed.ExpiresAt)
+	if record := getOrderRecord(t, s, "ord_dms-r1"); record.Status != "ACCEPTED" {
+		t.Fatalf("expected the refused cancel to leave the order open, got %s", record.Status)
+	}
+	s.state.mu.Lock()
+	fired := s.state.deadManSwitches["test-key"]
+	s.state.mu.Unlock()
+	if fired.armed || fired.canceledOrders != 1 || len(fired.pendingOrders) != 1 || fired.pendingOrders[0] != "ord_dms-r1" {
+		t.Fatalf("expected the failed cancel kept for retry, got %+v", fired)
+	}
+
+	// Orders placed after the switch fired are not the switch's to cancel.
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`), "dms-r3"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create order failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	core.mu.Lock()
+	delete(core.unknown, "ord_dms-r1")
+	core.mu.Unlock()
+	s.sweepDeadManSwitches(context.Background(), armed.ExpiresAt+1000)
+	if record := getOrderRecord(t, s, "ord_dms-r1"); record.Status != "CANCELED" {
+		t.Fatalf("expected the sweep to retry the cancel, got %s", record.Status)
+	}
+	if record := getOrderRecord(t, s, "ord_dms-r3"); record.Status != "ACCEPTED" {
+		t.Fatalf("expected the later order left alone, got %s", record.Status)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/dead-man-switch", nil, ""))
+	var view DeadManSwitchView
+	if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil || view.CanceledOrders != 2 || view.PendingCancels != 0 {
+		t.Fatalf("expected both cancels done, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,291 @@
+package gateway
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// defaultAssetDecimals is the precision of a currency the registry does not
+// list.
+const defaultAssetDecimals = 8
+
+const maxDecimalScale = 18
+
+// maxDecimalDigits bounds the digits parseDecimal accepts, well past any
+// real amount.
+const maxDecimalDigits = 40
+
+// Decimal is an exact fixed-point amount of units × 10^-scale. Balances,
+// reserves and fees carry their asset's precision as the scale (0 for KRW,
+// 8 for BTC), matching the ledger's integer minor units, so they add up
+// without float64 drift. Operands of different scales are aligned to the
+// finer one. Units are a big.Int so arithmetic cannot wrap; the ledger,
+// which posts int64 minor units, rejects amounts beyond that range (see
+// minorUnits). JSON and SQL see the amount as a decimal string.
+type Decimal struct {
+	units *big.Int
+	scale int32
+}
+
+func decimalFromInt(v int64) Decimal {
+	return Decimal{units: big.NewInt(v)}
+}
+
+// decimalFromUnits is units minor units at scale.
+func decimalFromUnits(units int64, scale int32) Decimal {
+	return Decimal{units: big.NewInt(units), scale: scale}
+}
+
+// parseDecimal reads a plain decimal string such as "-12.5" exactly.
+func parseDecimal(raw string) (Decimal, bool) {
+	raw = strings.TrimSpace(raw)
+	neg := strings.HasPrefix(raw, "-")
+	raw = strings.TrimPrefix(strings.TrimPrefix(raw, "-"), "+")
+	whole, frac, _ := strings.Cut(raw, ".")
+	digits := whole + frac
+	if digits == "" || len(frac) > maxDecimalScale || len(digits) > maxDecimalDigits || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, false
+	}
+	units, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, false
+	}
+	if neg {
+		units.Neg(units)
+	}
+	return Decimal{units: units, scale: int32(len(frac))}, true
+}
+
+// decimalFromRat rounds r to scale decimals, up (toward +inf) or down.
+func decimalFromRat(r *big.Rat, scale int32, up bool) (Decimal, bool) {
+	if r == nil {
+		return Decimal{}, false
+	}
+	n := new(big.Int).Mul(r.Num(), pow10Big(scale))
+	q, m := new(big.Int).DivMod(n, r.Denom(), new(big.Int))
+	if up && m.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return Decimal{units: q, scale: scale}, true
+}
+
+// decimalFromFloat reads v as the shortest decimal that prints as v,
+// rounded to maxDecimalScale, so 0.1 is 0.1 rather than its binary
+// expansion. It is for floats from outside (config, legacy DOUBLE
+// PRECISION rows), never for arithmetic.
+func decimalFromFloat(v float64) (Decimal, bool) {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, 64))
+	if !ok {
+		return Decimal{}, false
+	}
+	return roundRat(r, maxDecimalScale).trimmed(), true
+}
+
+func pow10Big(scale int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+}
+
+// int is d's units; the zero Decimal has none yet.
+func (d Decimal) int() *big.Int {
+	if d.units == nil {
+		return new(big.Int)
+	}
+	return d.units
+}
+
+func (d Decimal) rescaled(scale int32) *big.Int {
+	return new(big.Int).Mul(d.int(), pow10Big(scale-d.scale))
+}
+
+func alignDecimals(a, b Decimal) (*big.Int, *big.Int, int32) {
+	if a.scale >= b.scale {
+		return a.int(), b.rescaled(a.scale), a.scale
+	}
+	return a.rescaled(b.scale), b.int(), b.scale
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+	a, b, scale := alignDecimals(d, o)
+	return Decimal{units: new(big.Int).Add(a, b), scale: scale}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+	a, b, scale := alignDecimals(d, o)
+	return Decimal{units: new(big.Int).Sub(a, b), scale: scale}
+}
+
+// MulInt multiplies by an integer such as a whole-unit trade price.
+func (d Decimal) MulInt(n int64) Decimal {
+	return Decimal{units: new(big.Int).Mul(d.int(), big.NewInt(n)), scale: d.scale}
+}
+
+// Mul is the exact product, at the sum of the scales.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{units: new(big.Int).Mul(d.int(), o.int()), scale: d.scale + o.scale}
+}
+
+func (d Decimal) Cmp(o Decimal) int {
+	a, b, _ := alignDecimals(d, o)
+	return a.Cmp(b)
+}
+
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+func minDecimal(a, b Decimal) Decimal {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// Floor and Ceil set the scale, rounding toward -inf or +inf when it
+// shrinks.
+func (d Decimal) Floor(scale int32) Decimal {
+	out, _ := decimalFromRat(d.Rat(), scale, false)
+	return out
+}
+
+func (d Decimal) Ceil(scale int32) Decimal {
+	out, _ := decimalFromRat(d.Rat(), scale, true)
+	return out
+}
+
+// Round sets the scale, rounding half away from zero when it shrinks.
+func (d Decimal) Round(scale int32) Decimal {
+	return roundRat(d.Rat(), scale)
+}
+
+func roundRat(r *big.Rat, scale int32) Decimal {
+	n := new(big.Int).Mul(r.Num(), pow10Big(scale))
+	n.Mul(n, big.NewInt(2))
+	if n.Sign() < 0 {
+		n.Sub(n, r.Denom())
+	} else {
+		n.Add(n, r.Denom())
+	}
+	q := n.Quo(n, new(big.Int).Mul(r.Denom(), big.NewInt(2)))
+	return Decimal{units: q, scale: scale}
+}
+
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.int(), pow10Big(d.scale))
+}
+
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// trimmed drops trailing fractional zeros, so "1.50" prints as "1.5".
+func (d Decimal) trimmed() Decimal {
+	units, scale := new(big.Int).Set(d.int()), d.scale
+	ten, m := big.NewInt(10), new(big.Int)
+	for scale > 0 {
+		q, r := new(big.Int).QuoRem(units, ten, m)
+		if r.Sign() != 0 {
+			break
+		}
+		units, scale = q, scale-1
+	}
+	return Decimal{units: units, scale: scale}
+}
+
+// String prints every decimal of the scale, e.g. "2.00000000" for BTC.
+func (d Decimal) String() string {
+	digits := d.int().String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if d.scale <= 0 {
+		return sign + digits
+	}
+	if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	split := len(digits) - int(d.scale)
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts the string form and, for older clients, a bare
+// number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(strings.TrimSpace(string(data)), `"`)
+	parsed, ok := parseDecimal(raw)
+	if !ok {
+		return fmt.Errorf("invalid decimal %s", data)
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads NUMERIC columns, which the driver hands over as text, and
+// rows not yet migrated off DOUBLE PRECISION. A float is rounded to
+// maxDecimalScale here; loaders that know the currency round it on to the
+// asset's scale.
+func (d *Decimal) Scan(src interface{}) error {
+	var raw string
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	case int64:
+		*d = decimalFromInt(v)
+		return nil
+	case float64:
+		parsed, ok := decimalFromFloat(v)
+		if !ok {
+			return fmt.Errorf("invalid decimal %v", v)
+		}
+		*d = parsed
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Decimal", src)
+	}
+	parsed, ok := parseDecimal(raw)
+	if !ok {
+		return fmt.Errorf("invalid decimal %q", raw)
+	}
+	*d = parsed
+	return nil
+}
+
+// assetScale is the number of decimals balances of currency are kept at.
+func (s *Server) assetScale(currency string) int32 {
+	if asset, ok := s.registry.assets[strings.ToUpper(strings.TrimSpace(currency))]; ok {
+		return int32(asset.Decimals)
+	}
+	return defaultAssetDecimals
+}
+
+// assetAmount is whole units of currency at the asset's scale.
+func (s *Server) assetAmount(currency string, whole int64) Decimal {
+	return decimalFromInt(whole).Floor(s.assetScale(currency))
+}
+
+// zero is 0 at d's scale.
+func (d Decimal) zero() Decimal {
+	return Decimal{scale: d.scale}
+}
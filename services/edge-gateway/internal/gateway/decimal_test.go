@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecimalReservesRoundTripWithoutDust(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	if d, ok := parseDecimal("0.00000001"); !ok || d.Add(d).String() != "0.00000002" || d.Ceil(0).String() != "1" || d.Floor(0).String() != "0" {
+		t.Fatalf("unexpected decimal arithmetic for %+v", d)
+	}
+	if _, ok := parseDecimal("1e-8"); ok {
+		t.Fatalf("expected exponent notation rejected")
+	}
+
+	place := func(key, body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(body), key))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create %s failed: %d body=%s", key, w.Code, w.Body.String())
+		}
+	}
+	cancel := func(key string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_"+key, nil, key+"-cancel"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("cancel %s failed: %d body=%s", key, w.Code, w.Body.String())
+		}
+	}
+
+	// Three satoshis of sells, one at a time, and a buy whose 0.000003 KRW
+	// notional rounds up to one won.
+	for _, key := range []string{"dust-1", "dust-2", "dust-3"} {
+		place(key, `{"symbol":"BTC-KRW","side":"SELL","type":"LIMIT","price":"100","qty":"0.00000001"}`)
+	}
+	place("dust-buy", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"0.00000003"}`)
+	wallet := s.snapshotWallet("test-key")
+	if wallet["BTC"].Hold.String() != "0.00000003" || wallet["BTC"].Available.String() != "1.99999997" || wallet["KRW"].Hold.String() != "1" {
+		t.Fatalf("unexpected reserves: %+v", wallet)
+	}
+
+	for _, key := range []string{"dust-1", "dust-2", "dust-3", "dust-buy"} {
+		cancel(key)
+	}
+	wallet = s.snapshotWallet("test-key")
+	raw, err := json.Marshal(wallet["BTC"])
+	if err != nil || string(raw) != `{"available":"2.00000000","hold":"0.00000000"}` {
+		t.Fatalf("expected BTC restored exactly, got %s", raw)
+	}
+	if wallet["KRW"].Available.String() != "50000000" || !wallet["KRW"].Hold.IsZero() {
+		t.Fatalf("expected KRW restored exactly, got %+v", wallet["KRW"])
+	}
+
+	var decoded walletBalance
+	if err := json.Unmarshal([]byte(`{"available":"1.5","hold":0}`), &decoded); err != nil || decoded.Available.String() != "1.5" {
+		t.Fatalf("decode balance: %v %+v", err, decoded)
+	}
+}
+
+func TestDecimalsDoNotWrapAndOversizedAmountsAreRejected(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	big, _ := parseDecimal("9223372036854775807")
+	if got := big.Add(decimalFromInt(1)).String(); got != "9223372036854775808" {
+		t.Fatalf("expected Add to carry past int64, got %s", got)
+	}
+	if got := big.MulInt(10).Sub(big).String(); got != "83010348331692982263" {
+		t.Fatalf("expected MulInt to carry past int64, got %s", got)
+	}
+	if got := big.Floor(8).String(); got != "9223372036854775807.00000000" {
+		t.Fatalf("expected Floor to keep a value past int64 minor units, got %s", got)
+	}
+	if _, err := s.minorUnits("BTC", big.Floor(8)); err == nil {
+		t.Fatalf("expected minor units past int64 rejected")
+	}
+
+	if _, err := s.fundingAmount("BTC", "100000000000"); err == nil {
+		t.Fatalf("expected an amount past the ledger range rejected")
+	}
+	if amount, err := s.fundingAmount("BTC", "92233720368.54775807"); err != nil || amount.String() != "92233720368.54775807" {
+		t.Fatalf("expected the largest bookable amount accepted, got %v %v", amount, err)
+	}
+
+	if _, err := loadRegistry(Config{Assets: []AssetSpec{{Symbol: "DUST", Decimals: maxDecimalScale + 1}}}); err == nil {
+		t.Fatalf("expected asset decimals past %d rejected", maxDecimalScale)
+	}
+}
+
+func TestDecimalScansLegacyFloatsAtTheirDecimalValue(t *testing.T) {
+	for _, tc := range []struct {
+		src  float64
+		want string
+	}{
+		{0.1, "0.1"},
+		{49999.99999999999, "49999.99999999999"},
+		{1e-20, "0"},
+		{12345678.9, "12345678.9"},
+	} {
+		var d Decimal
+		if err := d.Scan(tc.src); err != nil || d.String() != tc.want {
+			t.Fatalf("Scan(%v) = %s %v, want %s", tc.src, d, err, tc.want)
+		}
+	}
+
+	// 0.1 + 0.2 as stored by the float schema, read back at BTC's scale.
+	var d Decimal
+	if err := d.Scan(0.1 + 0.2); err != nil {
+		t.Fatalf("scan float sum: %v", err)
+	}
+	if got := d.Round(8).String(); got != "0.30000000" {
+		t.Fatalf("expected the float sum rounded to 0.3 BTC, got %s", got)
+	}
+	below, _ := parseDecimal("0.299999999999")
+	if got := below.Round(8).String(); got != "0.30000000" {
+		t.Fatalf("expected Round to go to the nearest unit, got %s", got)
+	}
+	negative, _ := parseDecimal("-0.125")
+	if got := negative.Round(2).String(); got != "-0.13" {
+		t.Fatalf("expected halves rounded away from zero, got %s", got)
+	}
+}
@@ -0,0 +1,355 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	feeVolumeWindowDays = 30
+	maxFeeRateBps       = 1000
+	// maxFeeRateScale is the finest rate the core accepts: 0.0001 bps.
+	maxFeeRateScale = 4
+	msPerDay        = 24 * 60 * 60 * 1000
+)
+
+// FeeTier is one step of the fee schedule: users whose trailing 30-day
+// quote volume is at least MinVolume pay MakerBps/TakerBps basis points of
+// each fill's quote amount.
+type FeeTier struct {
+	Name      string  `json:"name"`
+	MinVolume Decimal `json:"minVolume"`
+	MakerBps  Decimal `json:"makerBps"`
+	TakerBps  Decimal `json:"takerBps"`
+}
+
+type FeeOverrideRequest struct {
+	MakerBps Decimal `json:"makerBps"`
+	TakerBps Decimal `json:"takerBps"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// feeOverride pins a user's rates regardless of volume, e.g. for VIP deals.
+type feeOverride struct {
+	UserID    string  `json:"userId"`
+	MakerBps  Decimal `json:"makerBps"`
+	TakerBps  Decimal `json:"takerBps"`
+	Note      string  `json:"note,omitempty"`
+	UpdatedAt int64   `json:"updatedAt"`
+}
+
+// FeeScheduleView is a user's current rates and how they were derived.
+type FeeScheduleView struct {
+	UserID       string       `json:"userId"`
+	Volume30d    Decimal      `json:"volume30d"`
+	Tier         string       `json:"tier,omitempty"`
+	MakerBps     Decimal      `json:"makerBps"`
+	TakerBps     Decimal      `json:"takerBps"`
+	Override     *feeOverride `json:"override,omitempty"`
+	NextTier     *FeeTier     `json:"nextTier,omitempty"`
+	VolumeToNext *Decimal     `json:"volumeToNext,omitempty"`
+	Tiers        []FeeTier    `json:"tiers"`
+}
+
+// validateFeeTiers sorts tiers by MinVolume and checks they form a
+// schedule: named, starting at zero volume, with rates in range.
+func validateFeeTiers(tiers []FeeTier) ([]FeeTier, error) {
+	if len(tiers) == 0 {
+		return nil, nil
+	}
+	out := append([]FeeTier(nil), tiers...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].MinVolume.Cmp(out[j].MinVolume) < 0 })
+	seen := map[string]bool{}
+	for i, tier := range out {
+		if strings.TrimSpace(tier.Name) == "" || seen[tier.Name] {
+			return nil, fmt.Errorf("fee tier %d: name missing or duplicated", i)
+		}
+		seen[tier.Name] = true
+		if i > 0 && tier.MinVolume.Cmp(out[i-1].MinVolume) == 0 {
+			return nil, fmt.Errorf("fee tier %s: minVolume repeats %s", tier.Name, out[i-1].Name)
+		}
+		if !validFeeRate(tier.MakerBps) || !validFeeRate(tier.TakerBps) {
+			return nil, fmt.Errorf("fee tier %s: rates must be between 0 and %d bps, to %d decimals", tier.Name, maxFeeRateBps, maxFeeRateScale)
+		}
+	}
+	if !out[0].MinVolume.IsZero() {
+		return nil, fmt.Errorf("fee tier %s: the lowest tier must start at minVolume 0", out[0].Name)
+	}
+	return out, nil
+}
+
+// ParseFeeTiers reads a schedule written as name:minVolume:makerBps:takerBps
+// per tier, comma separated, e.g. "VIP0:0:10:15,VIP1:100000000:8:12".
+func ParseFeeTiers(raw string) ([]FeeTier, error) {
+	out := []FeeTier{}
+	for _, entry := range strings.Split(raw, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 4 {
+			continue
+		}
+		minVolume, okVolume := parseDecimal(parts[1])
+		makerBps, okMaker := parseDecimal(parts[2])
+		takerBps, okTaker := parseDecimal(parts[3])
+		if !okVolume || !okMaker || !okTaker {
+			return nil, fmt.Errorf("invalid fee tier %q", entry)
+		}
+		out = append(out, FeeTier{
+			Name:      strings.TrimSpace(parts[0]),
+			MinVolume: minVolume,
+			MakerBps:  makerBps,
+			TakerBps:  takerBps,
+		})
+	}
+	return out, nil
+}
+
+func validFeeRate(bps Decimal) bool {
+	return bps.Sign() >= 0 && bps.Cmp(decimalFromInt(maxFeeRateBps)) <= 0 && bps.trimmed().scale <= maxFeeRateScale
+}
+
+// recordTradeVolumeLocked adds a settled trade's quote amount to the user's
+// daily volume buckets and drops buckets that left the window.
+func (s *Server) recordTradeVolumeLocked(userID string, quoteAmount Decimal, tsMs int64) {
+	if userID == "" || userID == s.cfg.FeeAccountID {
+		return
+	}
+	day := tsMs / msPerDay
+	buckets := s.state.tradeVolume[userID]
+	if buckets == nil {
+		buckets = map[int64]Decimal{}
+		s.state.tradeVolume[userID] = buckets
+	}
+	buckets[day] = buckets[day].Add(quoteAmount)
+	for d := range buckets {
+		if d <= day-feeVolumeWindowDays {
+			delete(buckets, d)
+		}
+	}
+}
+
+// volume30dLocked is the user's quote volume over the last 30 UTC days,
+// today included.
+func (s *Server) volume30dLocked(userID string, nowMs int64) Decimal {
+	today := nowMs / msPerDay
+	total := Decimal{}
+	for day, volume := range s.state.tradeVolume[userID] {
+		if day > today-feeVolumeWindowDays && day <= today {
+			total = total.Add(volume)
+		}
+	}
+	return total
+}
+
+func (s *Server) feeScheduleLocked(userID string, nowMs int64) FeeScheduleView {
+	view := FeeScheduleView{
+		UserID:    userID,
+		Volume30d: s.volume30dLocked(userID, nowMs),
+		Tiers:     append([]FeeTier{}, s.cfg.FeeTiers...),
+	}
+	for _, tier := range s.cfg.FeeTiers {
+		if view.Volume30d.Cmp(tier.MinVolume) < 0 {
+			next := tier
+			toNext := tier.MinVolume.Sub(view.Volume30d)
+			view.NextTier = &next
+			view.VolumeToNext = &toNext
+			break
+		}
+		view.Tier = tier.Name
+		view.MakerBps = tier.MakerBps
+		view.TakerBps = tier.TakerBps
+	}
+	if override, ok := s.state.feeOverrides[userID]; ok {
+		view.Override = &override
+		view.MakerBps = override.MakerBps
+		view.TakerBps = override.TakerBps
+	}
+	return view
+}
+
+// orderFeeRates are the user's current rates, as stamped on an order sent
+// to the core: the core charges them on the order's fills, so the trade
+// events carry the fees the ledger and the wallets book. Without a schedule
+// the rates are left empty and the core charges nothing.
+func (s *Server) orderFeeRates(userID string) (makerBps, takerBps string) {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if len(s.cfg.FeeTiers) == 0 && len(s.state.feeOverrides) == 0 {
+		return "", ""
+	}
+	view := s.feeScheduleLocked(userID, time.Now().UnixMilli())
+	return view.MakerBps.trimmed().String(), view.TakerBps.trimmed().String()
+}
+
+// loadTradeVolume rebuilds the 30-day volume buckets from stored fills.
+func (s *Server) loadTradeVolume(ctx context.Context) {
+	since := (time.Now().UnixMilli()/msPerDay - feeVolumeWindowDays + 1) * msPerDay
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT user_id, quote_amount, ts_ms FROM web_fills WHERE ts_ms >= $1`,
+		since,
+	)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=trade_volume_load_failed err=%v", err)
+		return
+	}
+	defer rows.Close()
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	for rows.Next() {
+		var userID, quoteAmount string
+		var tsMs int64
+		if err := rows.Scan(&userID, &quoteAmount, &tsMs); err != nil {
+			continue
+		}
+		if amount, ok := parseDecimal(quoteAmount); ok {
+			s.recordTradeVolumeLocked(userID, amount, tsMs)
+		}
+	}
+}
+
+func (s *Server) handleGetAccountFees(w http.ResponseWriter, r *http.Request) {
+	userID := s.apiKeyFromContext(r.Context())
+	if userID == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	s.state.mu.Lock()
+	view := s.feeScheduleLocked(userID, time.Now().UnixMilli())
+	s.state.mu.Unlock()
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (s *Server) handleGetFeeOverride(w http.ResponseWriter, r *http.Request) {
+	userID := strings.TrimSpace(chi.URLParam(r, "userId"))
+	s.state.mu.Lock()
+	view := s.feeScheduleLocked(userID, time.Now().UnixMilli())
+	s.state.mu.Unlock()
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (s *Server) handleSetFeeOverride(w http.ResponseWriter, r *http.Request) {
+	userID := strings.TrimSpace(chi.URLParam(r, "userId"))
+	var req FeeOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	if !validFeeRate(req.MakerBps) || !validFeeRate(req.TakerBps) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("rates must be between 0 and %d bps, to %d decimals", maxFeeRateBps, maxFeeRateScale)})
+		return
+	}
+	override := feeOverride{
+		UserID:    userID,
+		MakerBps:  req.MakerBps.trimmed(),
+		TakerBps:  req.TakerBps.trimmed(),
+		Note:      strings.TrimSpace(req.Note),
+		UpdatedAt: time.Now().UnixMilli(),
+	}
+	// The override is stored before it takes effect, so rates the core
+	// charges never come from one a restart would forget.
+	if err := s.persistFeeOverride(r.Context(), override); err != nil {
+		log.Printf("service=edge-gateway msg=fee_override_persist_failed user_id=%s err=%v", userID, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "fee_override_unavailable"})
+		return
+	}
+	s.state.mu.Lock()
+	s.state.feeOverrides[userID] = override
+	view := s.feeScheduleLocked(userID, override.UpdatedAt)
+	s.state.mu.Unlock()
+	log.Printf(
+		"service=edge-gateway msg=fee_override_set user_id=%s maker_bps=%s taker_bps=%s note=%q",
+		userID, override.MakerBps, override.TakerBps, override.Note,
+	)
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (s *Server) handleDeleteFeeOverride(w http.ResponseWriter, r *http.Request) {
+	userID := strings.TrimSpace(chi.URLParam(r, "userId"))
+	if err := s.deleteFeeOverride(r.Context(), userID); err != nil {
+		log.Printf("service=edge-gateway msg=fee_override_persist_failed user_id=%s err=%v", userID, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "fee_override_unavailable"})
+		return
+	}
+	s.state.mu.Lock()
+	delete(s.state.feeOverrides, userID)
+	view := s.feeScheduleLocked(userID, time.Now().UnixMilli())
+	s.state.mu.Unlock()
+	log.Printf("service=edge-gateway msg=fee_override_cleared user_id=%s", userID)
+	writeJSON(w, http.StatusOK, view)
+}
+
+func (s *Server) initFeeOverrideSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_fee_overrides (
+			user_id TEXT PRIMARY KEY,
+			maker_bps NUMERIC NOT NULL,
+			taker_bps NUMERIC NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			updated_at_ms BIGINT NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init fee override schema: %w", err)
+	}
+	return nil
+}
+
+// persistFeeOverride records a user's pinned rates so they outlive a
+// restart.
+func (s *Server) persistFeeOverride(ctx context.Context, override feeOverride) error {
+	ctx, cancel := s.dbContext(context.WithoutCancel(ctx))
+	defer cancel()
+	if s.db == nil {
+		return nil
+	}
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO web_fee_overrides(user_id, maker_bps, taker_bps, note, updated_at_ms)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (user_id) DO UPDATE SET
+		 maker_bps = EXCLUDED.maker_bps,
+		 taker_bps = EXCLUDED.taker_bps,
+		 note = EXCLUDED.note,
+		 updated_at_ms = EXCLUDED.updated_at_ms`,
+		override.UserID,
+		override.MakerBps,
+		override.TakerBps,
+		override.Note,
+		override.UpdatedAt,
+	)
+	return err
+}
+
+func (s *Server) deleteFeeOverride(ctx context.Context, userID string) error {
+	ctx, cancel := s.dbContext(context.WithoutCancel(ctx))
+	defer cancel()
+	if s.db == nil {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM web_fee_overrides WHERE user_id = $1`, userID)
+	return err
+}
+
+func (s *Server) loadFeeOverrides(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id, maker_bps, taker_bps, note, updated_at_ms FROM web_fee_overrides`)
+	if err != nil {
+		return fmt.Errorf("load fee overrides: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var override feeOverride
+		if err := rows.Scan(&override.UserID, &override.MakerBps, &override.TakerBps, &override.Note, &override.UpdatedAt); err != nil {
+			return fmt.Errorf("scan fee override: %w", err)
+		}
+		s.state.feeOverrides[override.UserID] = override
+	}
+	return rows.Err()
+}
@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFeeScheduleTiersByVolumeWithOverrides(t *testing.T) {
+	core := &stubCore{}
+	s, cleanup := newTestServerWithCore(t, core)
+	defer cleanup()
+
+	tiers, err := ParseFeeTiers("T1:10000:5:10,T0:0:10:20.5")
+	if err != nil {
+		t.Fatalf("parse tiers: %v", err)
+	}
+	if tiers, err = validateFeeTiers(tiers); err != nil {
+		t.Fatalf("validate tiers: %v", err)
+	}
+	s.cfg.FeeTiers = tiers
+	if _, err := validateFeeTiers([]FeeTier{{Name: "T1", MinVolume: decimalFromInt(10000)}}); err == nil {
+		t.Fatalf("expected a schedule without a zero-volume tier rejected")
+	}
+	if fine, err := ParseFeeTiers("T0:0:0.00001:1"); err != nil {
+		t.Fatalf("parse tiers: %v", err)
+	} else if _, err := validateFeeTiers(fine); err == nil {
+		t.Fatalf("expected a rate finer than the core charges rejected")
+	}
+
+	place := func(key, qty string) [2]string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+			[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"`+qty+`"}`), key))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create %s failed: %d body=%s", key, w.Code, w.Body.String())
+		}
+		core.mu.Lock()
+		defer core.mu.Unlock()
+		return core.feeBps["ord_"+key]
+	}
+	trade := func(body string) {
+		t.Helper()
+		if err := s.consumeTradeMessage(context.Background(), []byte(body)); err != nil {
+			t.Fatalf("consume trade: %v", err)
+		}
+	}
+	accountFees := func() FeeScheduleView {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/fees", nil, ""))
+		var view FeeScheduleView
+		if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("get fees failed: %d body=%s", w.Code, w.Body.String())
+		}
+		return view
+	}
+
+	fundSeller(t, s, "fee-counter", "BTC", "110")
+
+	// The core charges the rates the order carried: test-key's resting buy
+	// is the maker at 10 bps of 1000, the seller takes at its own rate.
+	if rates := place("fee-maker", "10"); rates != [2]string{"10", "20.5"} {
+		t.Fatalf("expected T0 rates sent to the core, got %v", rates)
+	}
+	trade(`{"tradeId":"sched-t1","symbol":"BTC-KRW","seq":100,"makerOrderId":"ord_fee-maker","takerOrderId":"ord_x","buyerUserId":"test-key","sellerUserId":"fee-counter","price":100,"quantity":10,"feeBuyer":1,"feeSeller":2}`)
+	s.state.mu.Lock()
+	fill := s.state.fills["test-key"][0]
+	s.state.mu.Unlock()
+	if fill.Fee != "1" || fill.Role != fillRoleMaker {
+		t.Fatalf("expected maker fee of 1, got %+v", fill)
+	}
+	view := accountFees()
+	if view.Tier != "T0" || view.Volume30d.Cmp(decimalFromInt(1000)) != 0 || view.MakerBps.Cmp(decimalFromInt(10)) != 0 || view.NextTier == nil || view.NextTier.Name != "T1" || view.VolumeToNext == nil || view.VolumeToNext.Cmp(decimalFromInt(9000)) != 0 {
+		t.Fatalf("unexpected fee view: %+v", view)
+	}
+
+	for _, body := range []string{`{"makerBps":-1,"takerBps":1}`, `{"makerBps":"0.00001","takerBps":1}`, `{"makerBps":0,"takerBps":1001}`} {
+		if w := adminRequest(t, s, http.MethodPut, "/v1/admin/users/test-key/fees", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected %s rejected, got %d", body, w.Code)
+		}
+	}
+	if w := adminRequest(t, s, http.MethodPut, "/v1/admin/users/test-key/fees", `{"makerBps":0,"takerBps":"1.5","note":"vip deal"}`); w.Code != http.StatusOK {
+		t.Fatalf("set override failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	// test-key now takes at its override; the seller's resting order is the maker.
+	if rates := place("fee-taker", "100"); rates != [2]string{"0", "1.5"} {
+		t.Fatalf("expected override rates sent to the core, got %v", rates)
+	}
+	trade(`{"tradeId":"sched-t2","symbol":"BTC-KRW","seq":101,"makerOrderId":"ord_y","takerOrderId":"ord_fee-taker","buyerUserId":"test-key","sellerUserId":"fee-counter","price":100,"quantity":100,"feeBuyer":1,"feeSeller":10}`)
+	view = accountFees()
+	if view.Tier != "T1" || view.Volume30d.Cmp(decimalFromInt(11000)) != 0 || view.Override == nil || view.TakerBps.String() != "1.5" || view.NextTier != nil {
+		t.Fatalf("expected T1 volume with override rates, got %+v", view)
+	}
+
+	w := adminRequest(t, s, http.MethodGet, "/v1/admin/fee-account", "")
+	var account struct {
+		Balances map[string]walletBalance `json:"balances"`
+	}
+	// The fee account collects exactly what the trade events charged.
+	if err := json.Unmarshal(w.Body.Bytes(), &account); err != nil || account.Balances["KRW"].Available.String() != "14" {
+		t.Fatalf("unexpected fees collected: %s", w.Body.String())
+	}
+
+	if w := adminRequest(t, s, http.MethodDelete, "/v1/admin/users/test-key/fees", ""); w.Code != http.StatusOK {
+		t.Fatalf("delete override failed: %d", w.Code)
+	}
+	if view := accountFees(); view.Override != nil || view.MakerBps.Cmp(decimalFromInt(5)) != 0 || view.TakerBps.Cmp(decimalFromInt(10)) != 0 {
+		t.Fatalf("expected tier rates after clearing the override, got %+v", view)
+	}
+}
+
+func TestOrdersCarryNoFeeRatesWithoutASchedule(t *testing.T) {
+	core := &stubCore{}
+	s, cleanup := newTestServerWithCore(t, core)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`), "fee-none"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create failed: %d body=%s", w.Code, w.Body.String())
+	}
+	core.mu.Lock()
+	rates := core.feeBps["ord_fee-none"]
+	core.mu.Unlock()
+	if rates != [2]string{"", ""} {
+		t.Fatalf("expected no rates sent without a schedule, got %v", rates)
+	}
+}
+
+func TestFeeOverrideUnchangedWhenItCannotBeStored(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	if w := adminRequest(t, s, http.MethodPut, "/v1/admin/users/test-key/fees", `{"makerBps":1,"takerBps":2}`); w.Code != http.StatusOK {
+		t.Fatalf("set override failed: %d body=%s", w.Code, w.Body.String())
+	}
+	down, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=none dbname=none sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer down.Close()
+	s.db = down
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		w := adminRequest(t, s, method, "/v1/admin/users/test-key/fees", `{"makerBps":0,"takerBps":0}`)
+		if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "fee_override_unavailable") {
+			t.Fatalf("%s: expected fee_override_unavailable, got %d body=%s", method, w.Code, w.Body.String())
+		}
+	}
+	s.state.mu.Lock()
+	override, ok := s.state.feeOverrides["test-key"]
+	s.state.mu.Unlock()
+	if !ok || override.MakerBps.String() != "1" || override.TakerBps.String() != "2" {
+		t.Fatalf("expected the stored override kept, got %+v ok=%v", override, ok)
+	}
+}
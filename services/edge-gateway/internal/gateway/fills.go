@@ -0,0 +1,403 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	fillRoleMaker = "MAKER"
+	fillRoleTaker = "TAKER"
+)
+
+// fillCacheSize is how many of a user's latest fills stay in memory. The
+// cache only answers when Postgres is disabled; it is trimmed back to this
+// size once it holds twice as many, so trimming stays amortized.
+const fillCacheSize = 1_000
+
+// FillRecord is one execution against one of the user's orders. A trade
+// between two orders produces a fill for each side.
+type FillRecord struct {
+	TradeID       string `json:"tradeId"`
+	OrderID       string `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId,omitempty"`
+	Symbol        string `json:"symbol"`
+	Side          string `json:"side"`
+	Role          string `json:"role"`
+	Price         string `json:"price"`
+	Qty           string `json:"qty"`
+	QuoteAmount   string `json:"quoteAmount"`
+	Fee           string `json:"fee"`
+	FeeCurrency   string `json:"feeCurrency,omitempty"`
+	Seq           uint64 `json:"seq"`
+	Ts            int64  `json:"ts"`
+
+	OwnerUserID string `json:"-"`
+}
+
+type tradeFill struct {
+	tradeID     string
+	symbol      string
+	price       int64
+	qty         int64
+	quoteAmount int64
+	feeBuyer    int64
+	feeSeller   int64
+	seq         uint64
+	tsMs        int64
+}
+
+func (s *Server) initFillSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_fills (
+			trade_id TEXT NOT NULL,
+			order_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			client_order_id TEXT NOT NULL DEFAULT '',
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,
+			role TEXT NOT NULL,
+			price TEXT NOT NULL,
+			qty TEXT NOT NULL,
+			quote_amount TEXT NOT NULL,
+			fee TEXT NOT NULL DEFAULT '0',
+			fee_currency TEXT NOT NULL DEFAULT '',
+			seq BIGINT NOT NULL DEFAULT 0,
+			ts_ms BIGINT NOT NULL,
+			PRIMARY KEY (trade_id, order_id)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init fills schema: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS web_fills_user_idx ON web_fills (user_id, ts_ms)`)
+	if err != nil {
+		return fmt.Errorf("init fills index: %w", err)
+	}
+	return nil
+}
+
+// recordTradeFills stores a fill for each side of the trade that belongs to
+// an order placed through this gateway. Buyer/seller user IDs alone cannot
+// say which order was maker, so orders we never saw are skipped.
+func (s *Server) recordTradeFills(trade tradeFill, makerOrderID, takerOrderID string) {
+	_, quote, _ := parseSymbol(trade.symbol)
+	fills := make([]FillRecord, 0, 2)
+
+	s.state.mu.Lock()
+	for _, leg := range []struct {
+		orderID string
+		role    string
+	}{
+		{orderID: strings.TrimSpace(makerOrderID), role: fillRoleMaker},
+		{orderID: strings.TrimSpace(takerOrderID), role: fillRoleTaker},
+	} {
+		record, ok := s.state.orders[leg.orderID]
+		if leg.orderID == "" || !ok {
+			continue
+		}
+		fee := trade.feeSeller
+		if record.Side == "BUY" {
+			fee = trade.feeBuyer
+		}
+		fill := FillRecord{
+			TradeID:       trade.tradeID,
+			OrderID:       record.OrderID,
+			ClientOrderID: record.ClientOrderID,
+			Symbol:        trade.symbol,
+			Side:          record.Side,
+			Role:          leg.role,
+			Price:         strconv.FormatInt(trade.price, 10),
+			Qty:           strconv.FormatInt(trade.qty, 10),
+			QuoteAmount:   strconv.FormatInt(trade.quoteAmount, 10),
+			Fee:           strconv.FormatInt(fee, 10),
+			FeeCurrency:   quote,
+			Seq:           trade.seq,
+			Ts:            trade.tsMs,
+			OwnerUserID:   record.OwnerUserID,
+		}
+		cached := append(s.state.fills[record.OwnerUserID], fill)
+		if len(cached) >= 2*fillCacheSize {
+			cached = append([]FillRecord(nil), cached[len(cached)-fillCacheSize:]...)
+		}
+		s.state.fills[record.OwnerUserID] = cached
+		fills = append(fills, fill)
+	}
+	s.state.mu.Unlock()
+
+	for _, fill := range fills {
+		s.persistFill(context.Background(), fill)
+	}
+}
+
+func (s *Server) persistFill(ctx context.Context, fill FillRecord) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	if s.db == nil {
+		return
+	}
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO web_fills(trade_id, order_id, user_id, client_order_id, symbol, side, role,
+		 price, qty, quote_amount, fee, fee_currency, seq, ts_ms)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		 ON CONFLICT (trade_id, order_id) DO NOTHING`,
+		fill.TradeID,
+		fill.OrderID,
+		fill.OwnerUserID,
+		fill.ClientOrderID,
+		fill.Symbol,
+		fill.Side,
+		fill.Role,
+		fill.Price,
+		fill.Qty,
+		fill.QuoteAmount,
+		fill.Fee,
+		fill.FeeCurrency,
+		int64(fill.Seq),
+		fill.Ts,
+	)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=fill_persist_failed trade_id=%s order_id=%s err=%v", fill.TradeID, fill.OrderID, err)
+	}
+}
+
+// queryFills returns the user's fills matching filter, sorted by seq and
+// order ID, starting after filter.cursor. orderID narrows to one order. A
+// limit of 0 returns every match; otherwise more reports whether another
+// page follows. Fills come from web_fills, or from the in-memory cache when
+// Postgres is disabled.
+func (s *Server) queryFills(ctx context.Context, userID, orderID string, filter orderListFilter, limit int) (fills []FillRecord, more bool, err error) {
+	if s.db == nil {
+		s.state.mu.Lock()
+		for _, fill := range s.state.fills[userID] {
+			if orderID != "" && fill.OrderID != orderID || !filter.matchesFill(fill) {
+				continue
+			}
+			if filter.cursor.set && !filter.cursor.lessFill(fill) {
+				continue
+			}
+			fills = append(fills, fill)
+		}
+		s.state.mu.Unlock()
+		sort.Slice(fills, func(i, j int) bool { return fillLess(fills[i], fills[j]) })
+	} else {
+		fills, err = s.loadFillsFromDB(ctx, userID, orderID, filter, limit)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if limit > 0 && len(fills) > limit {
+		return fills[:limit], true, nil
+	}
+	return fills, false, nil
+}
+
+// loadFillsFromDB reads up to limit+1 matching fills so the caller can tell
+// whether another page follows.
+func (s *Server) loadFillsFromDB(ctx context.Context, userID, orderID string, filter orderListFilter, limit int) ([]FillRecord, error) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	query := `SELECT trade_id, order_id, user_id, client_order_id, symbol, side, role,
+		price, qty, quote_amount, fee, fee_currency, seq, ts_ms
+		FROM web_fills WHERE user_id = $1`
+	args := []interface{}{userID}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if orderID != "" {
+		query += " AND order_id = " + arg(orderID)
+	}
+	if filter.symbol != "" {
+		query += " AND symbol = " + arg(filter.symbol)
+	}
+	if filter.side != "" {
+		query += " AND side = " + arg(filter.side)
+	}
+	if filter.fromMs > 0 {
+		query += " AND ts_ms >= " + arg(filter.fromMs)
+	}
+	if filter.toMs > 0 {
+		query += " AND ts_ms <= " + arg(filter.toMs)
+	}
+	if filter.cursor.set {
+		query += " AND (seq, order_id) > (" + arg(int64(filter.cursor.seq)) + ", " + arg(filter.cursor.orderID) + ")"
+	}
+	query += " ORDER BY seq, order_id"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit+1)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("load fills %s: %w", userID, err)
+	}
+	defer rows.Close()
+	var fills []FillRecord
+	for rows.Next() {
+		var fill FillRecord
+		var seq int64
+		if err := rows.Scan(&fill.TradeID, &fill.OrderID, &fill.OwnerUserID, &fill.ClientOrderID, &fill.Symbol,
+			&fill.Side, &fill.Role, &fill.Price, &fill.Qty, &fill.QuoteAmount, &fill.Fee, &fill.FeeCurrency,
+			&seq, &fill.Ts); err != nil {
+			return nil, fmt.Errorf("scan fill: %w", err)
+		}
+		fill.Seq = uint64(seq)
+		fills = append(fills, fill)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load fills %s: %w", userID, err)
+	}
+	return fills, nil
+}
+
+// handleGetOrderFills summarizes every fill of the order and pages through
+// them with limit and cursor.
+func (s *Server) handleGetOrderFills(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	orderID := chi.URLParam(r, "orderId")
+	filter, err := parseOrderListFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.state.mu.Lock()
+	record, ok := s.state.orders[orderID]
+	s.state.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "UNKNOWN_ORDER"})
+		return
+	}
+	if record.OwnerUserID != apiKey {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "FORBIDDEN"})
+		return
+	}
+	fills, _, err := s.queryFills(r.Context(), apiKey, orderID, orderListFilter{}, 0)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=fills_load_failed order_id=%s err=%v", orderID, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "fills_unavailable"})
+		return
+	}
+
+	var filledQty, notional, fee Decimal
+	feeCurrency := ""
+	for _, fill := range fills {
+		price, _ := parseDecimal(fill.Price)
+		qty, _ := parseDecimal(fill.Qty)
+		paid, _ := parseDecimal(fill.Fee)
+		filledQty = filledQty.Add(qty)
+		notional = notional.Add(price.Mul(qty))
+		fee = fee.Add(paid)
+		feeCurrency = fill.FeeCurrency
+	}
+	avgPrice := ""
+	if filledQty.Sign() > 0 {
+		avg, _ := decimalFromRat(new(big.Rat).Quo(notional.Rat(), filledQty.Rat()), maxDecimalScale, false)
+		avgPrice = formatQty(avg)
+	}
+
+	start := 0
+	if filter.cursor.set {
+		start = sort.Search(len(fills), func(i int) bool { return filter.cursor.lessFill(fills[i]) })
+	}
+	end := start + filter.limit
+	if end > len(fills) {
+		end = len(fills)
+	}
+	page := append([]FillRecord{}, fills[start:end]...)
+	nextCursor := ""
+	if end < len(fills) && len(page) > 0 {
+		last := page[len(page)-1]
+		nextCursor = encodeOrderCursor(last.Seq, last.OrderID)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"orderId":     orderID,
+		"filledQty":   formatQty(filledQty),
+		"avgPrice":    avgPrice,
+		"fee":         fee.String(),
+		"feeCurrency": feeCurrency,
+		"fills":       page,
+		"nextCursor":  nextCursor,
+	})
+}
+
+// handleListAccountTrades lists the caller's fills across orders, sorted by
+// seq like GET /v1/orders and paged with the same cursor format.
+func (s *Server) handleListAccountTrades(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	filter, err := parseOrderListFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	page, more, err := s.queryFills(r.Context(), apiKey, "", filter, filter.limit)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=fills_load_failed user_id=%s err=%v", apiKey, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "fills_unavailable"})
+		return
+	}
+	if page == nil {
+		page = []FillRecord{}
+	}
+	nextCursor := ""
+	if more {
+		last := page[len(page)-1]
+		nextCursor = encodeOrderCursor(last.Seq, last.OrderID)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"trades":     page,
+		"nextCursor": nextCursor,
+	})
+}
+
+// lessFill reports whether fill comes after the cursor.
+func (c orderCursor) lessFill(fill FillRecord) bool {
+	if fill.Seq != c.seq {
+		return fill.Seq > c.seq
+	}
+	return fill.OrderID > c.orderID
+}
+
+func (f orderListFilter) matchesFill(fill FillRecord) bool {
+	if f.symbol != "" && fill.Symbol != f.symbol {
+		return false
+	}
+	if f.side != "" && fill.Side != f.side {
+		return false
+	}
+	if f.fromMs > 0 && fill.Ts < f.fromMs {
+		return false
+	}
+	if f.toMs > 0 && fill.Ts > f.toMs {
+		return false
+	}
+	return true
+}
+
+// fillLess orders by seq, then order ID: both legs of a self-trade share a
+// trade ID and seq but never an order ID.
+func fillLess(a, b FillRecord) bool {
+	if a.Seq != b.Seq {
+		return a.Seq < b.Seq
+	}
+	return a.OrderID < b.OrderID
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFillsRecordedPerOrderAndAccount(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		key  string
+		body string
+	}{
+		{key: "fill-buy", body: `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"110","qty":"3","clientOrderId":"bid-1"}`},
+		{key: "fill-sell", body: `{"symbol":"BTC-KRW","side":"SELL","type":"LIMIT","price":"100","qty":"1"}`},
+	} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(tc.body), tc.key))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create %s failed: %d body=%s", tc.key, w.Code, w.Body.String())
+		}
+	}
+
+	trades := []string{
+		`{"tradeId":"t-1","symbol":"BTC-KRW","seq":11,"ts":1000,"makerOrderId":"ord_fill-buy","takerOrderId":"ord_fill-sell","buyerUserId":"test-key","sellerUserId":"test-key","price":100,"quantity":1,"quoteAmount":100,"feeBuyer":0,"feeSeller":1}`,
+		`{"tradeId":"t-2","symbol":"BTC-KRW","seq":12,"ts":2000,"makerOrderId":"ord_fill-buy","takerOrderId":"ord_other","buyerUserId":"test-key","sellerUserId":"someone","price":106,"quantity":2,"quoteAmount":212}`,
+	}
+	for _, raw := range trades {
+		if err := s.consumeTradeMessage(context.Background(), []byte(raw)); err != nil {
+			t.Fatalf("consume trade: %v", err)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/orders/ord_fill-buy/fills", nil, ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("order fills failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var orderFills struct {
+		FilledQty string       `json:"filledQty"`
+		AvgPrice  string       `json:"avgPrice"`
+		Fills     []FillRecord `json:"fills"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &orderFills); err != nil {
+		t.Fatalf("decode fills: %v", err)
+	}
+	if len(orderFills.Fills) != 2 || orderFills.FilledQty != "3" || orderFills.AvgPrice != "104" {
+		t.Fatalf("unexpected order fills: %s", w.Body.String())
+	}
+	if first := orderFills.Fills[0]; first.Role != fillRoleMaker || first.ClientOrderID != "bid-1" || first.Price != "100" {
+		t.Fatalf("unexpected first fill: %+v", first)
+	}
+	type fillsPage struct {
+		FilledQty  string       `json:"filledQty"`
+		Fills      []FillRecord `json:"fills"`
+		NextCursor string       `json:"nextCursor"`
+	}
+	fetchPage := func(query string) fillsPage {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/orders/ord_fill-buy/fills"+query, nil, ""))
+		var page fillsPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || len(page.Fills) != 1 || page.FilledQty != "3" {
+			t.Fatalf("unexpected fills page %q: %s", query, w.Body.String())
+		}
+		return page
+	}
+	first := fetchPage("?limit=1")
+	second := fetchPage("?limit=1&cursor=" + first.NextCursor)
+	if first.Fills[0].TradeID != "t-1" || first.NextCursor == "" || second.Fills[0].TradeID != "t-2" || second.NextCursor != "" {
+		t.Fatalf("unexpected fills pages: %+v then %+v", first, second)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/trades?symbol=BTC-KRW&to=1500", nil, ""))
+	var account struct {
+		Trades []FillRecord `json:"trades"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &account); err != nil {
+		t.Fatalf("decode account trades: %v", err)
+	}
+	if len(account.Trades) != 2 {
+		t.Fatalf("expected both legs of t-1, got %s", w.Body.String())
+	}
+	for _, fill := range account.Trades {
+		if fill.TradeID != "t-1" {
+			t.Fatalf("time filter leaked %s", fill.TradeID)
+		}
+		if fill.OrderID == "ord_fill-sell" && (fill.Role != fillRoleTaker || fill.Fee != "1" || fill.FeeCurrency != "KRW") {
+			t.Fatalf("unexpected taker fill: %+v", fill)
+		}
+	}
+}
+
+func TestFillCacheKeepsTheLatestFills(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	s.state.mu.Lock()
+	s.state.orders["ord_busy"] = OrderRecord{OrderID: "ord_busy", OwnerUserID: "test-key", Side: "BUY", Symbol: "BTC-KRW"}
+	s.state.mu.Unlock()
+	for i := 1; i <= 2*fillCacheSize; i++ {
+		s.recordTradeFills(tradeFill{tradeID: "t", symbol: "BTC-KRW", price: 100, qty: 1, quoteAmount: 100, seq: uint64(i)}, "ord_busy", "")
+	}
+	s.state.mu.Lock()
+	cached := s.state.fills["test-key"]
+	s.state.mu.Unlock()
+	if len(cached) != fillCacheSize || cached[0].Seq != fillCacheSize+1 {
+		t.Fatalf("expected the latest %d fills cached, got %d starting at seq %d", fillCacheSize, len(cached), cached[0].Seq)
+	}
+}
@@ -0,0 +1,1078 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Wallet operations of the funding flows. A withdrawal holds its amount
+// when requested, and either takes it out of hold once confirmed on chain
+// or releases it when it fails.
+const (
+	walletOpDeposit         = "DEPOSIT"
+	walletOpWithdrawHold    = "WITHDRAW_HOLD"
+	walletOpWithdrawRelease = "WITHDRAW_RELEASE"
+	walletOpWithdraw        = "WITHDRAW"
+)
+
+const (
+	depositStatusPending  = "PENDING"
+	depositStatusCredited = "CREDITED"
+
+	withdrawalStatusRequested = "REQUESTED"
+	withdrawalStatusApproved  = "APPROVED"
+	withdrawalStatusBroadcast = "BROADCAST"
+	withdrawalStatusConfirmed = "CONFIRMED"
+	withdrawalStatusFailed    = "FAILED"
+)
+
+const (
+	fundingPollInterval           = time.Second
+	defaultFundingConfirmations   = 3
+	defaultWithdrawalAddressDelay = 24 * time.Hour
+	maxAddressLength              = 128
+	maxAddressLabelLength         = 64
+)
+
+var (
+	errUnknownWithdrawal = errors.New("UNKNOWN_WITHDRAWAL")
+	errWithdrawalState   = errors.New("invalid withdrawal state")
+	// errWithdrawalHoldShort is a withdrawal whose asset holds less than
+	// its amount; it is left as it was rather than settled for less.
+	errWithdrawalHoldShort = errors.New("WITHDRAWAL_HOLD_SHORT")
+)
+
+type DepositAddress struct {
+	Asset     string `json:"asset"`
+	Address   string `json:"address"`
+	CreatedAt int64  `json:"createdAt"`
+
+	UserID string `json:"-"`
+}
+
+// DepositRecord is an inbound transfer to one of a user's deposit
+// addresses. It is credited once it has FundingConfirmations.
+type DepositRecord struct {
+	DepositID     string  `json:"depositId"`
+	Asset         string  `json:"asset"`
+	Address       string  `json:"address"`
+	TxHash        string  `json:"txHash"`
+	Amount        Decimal `json:"amount"`
+	Confirmations int     `json:"confirmations"`
+	Status        string  `json:"status"`
+	CreatedAt     int64   `json:"createdAt"`
+	CreditedAt    int64   `json:"creditedAt,omitempty"`
+
+	UserID string `json:"-"`
+}
+
+// WithdrawalRecord goes REQUESTED, APPROVED (by an admin), BROADCAST and
+// CONFIRMED; it is FAILED when rejected or dropped by the chain.
+type WithdrawalRecord struct {
+	WithdrawalID  string  `json:"withdrawalId"`
+	UserID        string  `json:"userId"`
+	Asset         string  `json:"asset"`
+	Address       string  `json:"address"`
+	Amount        Decimal `json:"amount"`
+	Status        string  `json:"status"`
+	TxHash        string  `json:"txHash,omitempty"`
+	Confirmations int     `json:"confirmations"`
+	Reason        string  `json:"reason,omitempty"`
+	CreatedAt     int64   `json:"createdAt"`
+	UpdatedAt     int64   `json:"updatedAt"`
+}
+
+// WithdrawalAddress is a whitelisted destination. Withdrawals can use it
+// from ActiveAt, WithdrawalAddressDelay after it was added.
+type WithdrawalAddress struct {
+	Asset     string `json:"asset"`
+	Address   string `json:"address"`
+	Label     string `json:"label,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+	ActiveAt  int64  `json:"activeAt"`
+
+	UserID string `json:"-"`
+}
+
+type DepositAddressRequest struct {
+	Asset string `json:"asset"`
+}
+
+type WithdrawalAddressRequest struct {
+	Asset   string `json:"asset"`
+	Address string `json:"address"`
+	Label   string `json:"label"`
+}
+
+type WithdrawalRequest struct {
+	Asset   string `json:"asset"`
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+func fundingKey(asset, address string) string {
+	return asset + "|" + address
+}
+
+// parseDailyLimits reads a per-asset daily limit map such as
+// Config.WithdrawalDailyLimits.
+func parseDailyLimits(raw map[string]string, reg *registry) (map[string]Decimal, error) {
+	out := make(map[string]Decimal, len(raw))
+	for asset, value := range raw {
+		asset = strings.ToUpper(strings.TrimSpace(asset))
+		spec, ok := reg.assets[asset]
+		if !ok {
+			return nil, fmt.Errorf("unknown asset %q", asset)
+		}
+		limit, ok := parseDecimal(value)
+		if !ok || limit.Sign() < 0 {
+			return nil, fmt.Errorf("invalid limit %q for %s", value, asset)
+		}
+		out[asset] = limit.Floor(int32(spec.Decimals))
+	}
+	return out, nil
+}
+
+func (s *Server) fundingAsset(raw string) (string, error) {
+	asset := strings.ToUpper(strings.TrimSpace(raw))
+	if _, ok := s.registry.assets[asset]; !ok {
+		return "", fmt.Errorf("unknown asset")
+	}
+	return asset, nil
+}
+
+// fundingAmount parses a positive amount with no more decimals than the
+// asset has and few enough minor units for a ledger posting.
+func (s *Server) fundingAmount(asset, raw string) (Decimal, error) {
+	amount, ok := parseDecimal(raw)
+	if !ok || amount.Sign() <= 0 {
+		return Decimal{}, fmt.Errorf("amount must be a positive decimal")
+	}
+	scale := s.assetScale(asset)
+	if amount.scale > scale {
+		return Decimal{}, fmt.Errorf("amount has more than %d decimals", scale)
+	}
+	if _, err := s.minorUnits(asset, amount); err != nil {
+		return Decimal{}, fmt.Errorf("amount is too large")
+	}
+	return amount.Floor(scale), nil
+}
+
+func validFundingAddress(address string) bool {
+	if address == "" || len(address) > maxAddressLength {
+		return false
+	}
+	for _, r := range address {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) handleCreateDepositAddress(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	var req DepositAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	asset, err := s.fundingAsset(req.Asset)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	key := apiKey + "|" + asset
+	s.state.mu.Lock()
+	existing, ok := s.state.depositAddresses[key]
+	s.state.mu.Unlock()
+	if ok {
+		writeJSON(w, http.StatusOK, existing)
+		return
+	}
+
+	address, err := s.chain.NewAddress(r.Context(), asset)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=deposit_address_failed user_id=%s asset=%s err=%v", apiKey, asset, err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "chain_unavailable"})
+		return
+	}
+	record := DepositAddress{Asset: asset, Address: address, CreatedAt: time.Now().UnixMilli(), UserID: apiKey}
+	record, err = s.persistDepositAddress(r.Context(), record)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=deposit_address_persist_failed user_id=%s asset=%s err=%v", apiKey, asset, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "funding_unavailable"})
+		return
+	}
+
+	// A concurrent request may have issued one first; every caller gets
+	// the address that was stored.
+	s.state.mu.Lock()
+	if existing, ok := s.state.depositAddresses[key]; ok {
+		record = existing
+	} else {
+		s.state.depositAddresses[key] = record
+	}
+	s.state.mu.Unlock()
+	writeJSON(w, http.StatusOK, record)
+}
+
+func (s *Server) handleListDepositAddresses(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	s.state.mu.Lock()
+	out := make([]DepositAddress, 0, 4)
+	for _, record := range s.state.depositAddresses {
+		if record.UserID == apiKey {
+			out = append(out, record)
+		}
+	}
+	s.state.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Asset < out[j].Asset })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"addresses": out})
+}
+
+func (s *Server) handleListDeposits(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	s.state.mu.Lock()
+	out := make([]DepositRecord, 0, 8)
+	for _, record := range s.state.deposits {
+		if record.UserID == apiKey {
+			out = append(out, record)
+		}
+	}
+	s.state.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].DepositID < out[j].DepositID
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deposits": out})
+}
+
+func (s *Server) handleAddWithdrawalAddress(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	var req WithdrawalAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	asset, err := s.fundingAsset(req.Asset)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	address := strings.TrimSpace(req.Address)
+	if !validFundingAddress(address) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid address"})
+		return
+	}
+	label := strings.TrimSpace(req.Label)
+	if len(label) > maxAddressLabelLength {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("label longer than %d characters", maxAddressLabelLength)})
+		return
+	}
+
+	s.state.mu.Lock()
+	existing, ok := s.state.withdrawalAddresses[apiKey][fundingKey(asset, address)]
+	s.state.mu.Unlock()
+	if ok {
+		// Re-adding an address must not restart, or skip, its time lock.
+		writeJSON(w, http.StatusOK, existing)
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	entry := WithdrawalAddress{
+		Asset:     asset,
+		Address:   address,
+		Label:     label,
+		CreatedAt: now,
+		ActiveAt:  now + s.cfg.WithdrawalAddressDelay.Milliseconds(),
+		UserID:    apiKey,
+	}
+	if err := s.persistWithdrawalAddress(r.Context(), entry); err != nil {
+		log.Printf("service=edge-gateway msg=withdrawal_address_persist_failed user_id=%s asset=%s err=%v", apiKey, asset, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "funding_unavailable"})
+		return
+	}
+	s.state.mu.Lock()
+	if existing, ok := s.state.withdrawalAddresses[apiKey][fundingKey(asset, address)]; ok {
+		entry = existing
+	} else {
+		if s.state.withdrawalAddresses[apiKey] == nil {
+			s.state.withdrawalAddresses[apiKey] = map[string]WithdrawalAddress{}
+		}
+		s.state.withdrawalAddresses[apiKey][fundingKey(asset, address)] = entry
+	}
+	s.state.mu.Unlock()
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (s *Server) handleListWithdrawalAddresses(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	s.state.mu.Lock()
+	out := make([]WithdrawalAddress, 0, len(s.state.withdrawalAddresses[apiKey]))
+	for _, entry := range s.state.withdrawalAddresses[apiKey] {
+		out = append(out, entry)
+	}
+	s.state.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		return fundingKey(out[i].Asset, out[i].Address) < fundingKey(out[j].Asset, out[j].Address)
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"addresses": out})
+}
+
+func (s *Server) handleDeleteWithdrawalAddress(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	asset := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "asset")))
+	address := strings.TrimSpace(chi.URLParam(r, "address"))
+	key := fundingKey(asset, address)
+
+	s.state.mu.Lock()
+	_, ok := s.state.withdrawalAddresses[apiKey][key]
+	s.state.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "UNKNOWN_ADDRESS"})
+		return
+	}
+	if s.db != nil {
+		ctx, cancel := s.dbContext(r.Context())
+		defer cancel()
+		_, err := s.db.ExecContext(
+			ctx,
+			`DELETE FROM web_withdrawal_addresses WHERE user_id = $1 AND asset = $2 AND address = $3`,
+			apiKey, asset, address,
+		)
+		if err != nil {
+			log.Printf("service=edge-gateway msg=withdrawal_address_delete_failed user_id=%s asset=%s err=%v", apiKey, asset, err)
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "funding_unavailable"})
+			return
+		}
+	}
+	s.state.mu.Lock()
+	delete(s.state.withdrawalAddresses[apiKey], key)
+	s.state.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": true})
+}
+
+func (s *Server) handleCreateWithdrawal(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key required"})
+		return
+	}
+	if status, body, ok := s.idempotencyGet(apiKey, idemKey, r.Method, r.URL.Path); ok {
+		writeRaw(w, status, body)
+		return
+	}
+
+	var req WithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	asset, err := s.fundingAsset(req.Asset)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	amount, err := s.fundingAmount(asset, req.Amount)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.snapshotWallet(apiKey)
+	record, err := s.requestWithdrawal(r.Context(), apiKey, asset, strings.TrimSpace(req.Address), amount)
+	if err != nil {
+		writeReserveError(w, err)
+		return
+	}
+	status, body := marshalResponse(http.StatusOK, record)
+	s.idempotencySet(apiKey, idemKey, r.Method, r.URL.Path, status, body)
+	writeRaw(w, status, body)
+}
+
+// requestWithdrawal checks the whitelist and the daily limit and holds the
+// amount, all under the user's wallet lock so concurrent requests cannot
+// both fit under the limit.
+func (s *Server) requestWithdrawal(ctx context.Context, userID, asset, address string, amount Decimal) (WithdrawalRecord, error) {
+	now := time.Now().UnixMilli()
+	record := WithdrawalRecord{
+		WithdrawalID: "wd_" + uuid.NewString(),
+		UserID:       userID,
+		Asset:        asset,
+		Address:      address,
+		Amount:       amount,
+		Status:       withdrawalStatusRequested,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	tx := s.beginWalletTx(walletOpWithdrawHold, record.WithdrawalID, userID)
+	defer tx.rollback()
+	s.state.mu.Lock()
+	entry, whitelisted := s.state.withdrawalAddresses[userID][fundingKey(asset, address)]
+	withdrawnToday := s.withdrawnTodayLocked(userID, asset, now)
+	s.state.mu.Unlock()
+	if !whitelisted {
+		return WithdrawalRecord{}, fmt.Errorf("address_not_whitelisted")
+	}
+	if entry.ActiveAt > now {
+		return WithdrawalRecord{}, fmt.Errorf("address_time_locked")
+	}
+	if limit, ok := s.withdrawalLimits[asset]; ok && withdrawnToday.Add(amount).Cmp(limit) > 0 {
+		return WithdrawalRecord{}, fmt.Errorf("daily_limit_exceeded")
+	}
+	bal := tx.balance(userID, asset)
+	if bal.Available.Cmp(amount) < 0 {
+		return WithdrawalRecord{}, fmt.Errorf("insufficient_balance")
+	}
+	bal.Available = bal.Available.Sub(amount)
+	bal.Hold = bal.Hold.Add(amount)
+	tx.set(userID, asset, bal)
+	s.stageWithdrawal(tx, record)
+	if err := tx.commit(ctx); err != nil {
+		return WithdrawalRecord{}, err
+	}
+	log.Printf("service=edge-gateway msg=withdrawal_requested withdrawal_id=%s user_id=%s asset=%s amount=%s", record.WithdrawalID, userID, asset, amount)
+	return record, nil
+}
+
+// withdrawnTodayLocked sums the user's withdrawals of asset requested
+// since UTC midnight that have not failed. Callers hold s.state.mu.
+func (s *Server) withdrawnTodayLocked(userID, asset string, nowMs int64) Decimal {
+	dayStart := time.UnixMilli(nowMs).UTC().Truncate(24 * time.Hour).UnixMilli()
+	total := s.assetAmount(asset, 0)
+	for _, record := range s.state.withdrawals {
+		if record.UserID == userID && record.Asset == asset && record.CreatedAt >= dayStart && record.Status != withdrawalStatusFailed {
+			total = total.Add(record.Amount)
+		}
+	}
+	return total
+}
+
+func (s *Server) stageWithdrawal(tx *walletTx, record WithdrawalRecord) {
+	tx.also(
+		func(ctx context.Context, dbTx *sql.Tx) error { return writeWithdrawal(ctx, dbTx, record) },
+		func() { s.state.withdrawals[record.WithdrawalID] = record },
+	)
+}
+
+// advanceWithdrawal moves a withdrawal from one of the from statuses to to,
+// applying mutate, and settles its hold when to is final: taken out on
+// CONFIRMED, released on FAILED. A hold short of the amount fails with
+// errWithdrawalHoldShort and leaves the withdrawal where it was.
+func (s *Server) advanceWithdrawal(ctx context.Context, withdrawalID string, from []string, to string, mutate func(*WithdrawalRecord)) (WithdrawalRecord, error) {
+	s.state.mu.Lock()
+	record, ok := s.state.withdrawals[withdrawalID]
+	s.state.mu.Unlock()
+	if !ok {
+		return WithdrawalRecord{}, errUnknownWithdrawal
+	}
+	s.snapshotWallet(record.UserID)
+
+	op := walletOpWithdraw
+	if to == withdrawalStatusFailed {
+		op = walletOpWithdrawRelease
+	}
+	tx := s.beginWalletTx(op, withdrawalID, record.UserID)
+	defer tx.rollback()
+	s.state.mu.Lock()
+	record = s.state.withdrawals[withdrawalID]
+	s.state.mu.Unlock()
+	allowed := false
+	for _, status := range from {
+		allowed = allowed || record.Status == status
+	}
+	if !allowed {
+		return record, fmt.Errorf("%w: withdrawal is %s", errWithdrawalState, record.Status)
+	}
+
+	record.Status = to
+	record.UpdatedAt = time.Now().UnixMilli()
+	if mutate != nil {
+		mutate(&record)
+	}
+	if to == withdrawalStatusConfirmed || to == withdrawalStatusFailed {
+		bal := tx.balance(record.UserID, record.Asset)
+		if bal.Hold.Cmp(record.Amount) < 0 {
+			log.Printf("service=edge-gateway msg=withdrawal_hold_short withdrawal_id=%s user_id=%s asset=%s amount=%s hold=%s",
+				withdrawalID, record.UserID, record.Asset, record.Amount, bal.Hold)
+			return WithdrawalRecord{}, fmt.Errorf("%w: %s hold %s is below %s", errWithdrawalHoldShort, record.Asset, bal.Hold, record.Amount)
+		}
+		bal.Hold = bal.Hold.Sub(record.Amount)
+		if to == withdrawalStatusFailed {
+			bal.Available = bal.Available.Add(record.Amount)
+		}
+		tx.set(record.UserID, record.Asset, bal)
+	}
+	s.stageWithdrawal(tx, record)
+	if err := tx.commit(ctx); err != nil {
+		return WithdrawalRecord{}, err
+	}
+	log.Printf("service=edge-gateway msg=withdrawal_%s withdrawal_id=%s user_id=%s reason=%s",
+		strings.ToLower(to), withdrawalID, record.UserID, record.Reason)
+	return record, nil
+}
+
+func (s *Server) handleListWithdrawals(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"withdrawals": s.listWithdrawals(apiKey, "")})
+}
+
+func (s *Server) handleAdminListWithdrawals(w http.ResponseWriter, r *http.Request) {
+	status := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("status")))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"withdrawals": s.listWithdrawals("", status)})
+}
+
+// listWithdrawals filters by user and status when they are set, oldest
+// first.
+func (s *Server) listWithdrawals(userID, status string) []WithdrawalRecord {
+	s.state.mu.Lock()
+	out := make([]WithdrawalRecord, 0, 8)
+	for _, record := range s.state.withdrawals {
+		if (userID == "" || record.UserID == userID) && (status == "" || record.Status == status) {
+			out = append(out, record)
+		}
+	}
+	s.state.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].WithdrawalID < out[j].WithdrawalID
+	})
+	return out
+}
+
+func (s *Server) handleApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
+	record, err := s.advanceWithdrawal(r.Context(), chi.URLParam(r, "withdrawalId"),
+		[]string{withdrawalStatusRequested}, withdrawalStatusApproved, nil)
+	writeWithdrawalResult(w, record, err)
+}
+
+func (s *Server) handleRejectWithdrawal(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "rejected"
+	}
+	record, err := s.advanceWithdrawal(r.Context(), chi.URLParam(r, "withdrawalId"),
+		[]string{withdrawalStatusRequested, withdrawalStatusApproved}, withdrawalStatusFailed,
+		func(record *WithdrawalRecord) { record.Reason = reason })
+	writeWithdrawalResult(w, record, err)
+}
+
+func writeWithdrawalResult(w http.ResponseWriter, record WithdrawalRecord, err error) {
+	switch {
+	case errors.Is(err, errUnknownWithdrawal):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, errWithdrawalState), errors.Is(err, errWithdrawalHoldShort):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeReserveError(w, err)
+	default:
+		writeJSON(w, http.StatusOK, record)
+	}
+}
+
+func (s *Server) startFundingWatcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.fundingCancel = cancel
+	s.fundingWG.Add(1)
+	go func() {
+		defer s.fundingWG.Done()
+		ticker := time.NewTicker(fundingPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.pollFunding(ctx)
+			}
+		}
+	}()
+}
+
+// pollFunding advances deposits and withdrawals from what the chain
+// reports.
+func (s *Server) pollFunding(ctx context.Context) {
+	s.pollDeposits(ctx)
+	s.pollWithdrawals(ctx)
+}
+
+func (s *Server) pollDeposits(ctx context.Context) {
+	s.state.mu.Lock()
+	owners := make(map[string]DepositAddress, len(s.state.depositAddresses))
+	assets := map[string]bool{}
+	for _, record := range s.state.depositAddresses {
+		owners[record.Address] = record
+		assets[record.Asset] = true
+	}
+	s.state.mu.Unlock()
+
+	for asset := range assets {
+		transfers, err := s.chain.Deposits(ctx, asset)
+		if err != nil {
+			log.Printf("service=edge-gateway msg=deposit_poll_failed asset=%s err=%v", asset, err)
+			continue
+		}
+		for _, transfer := range transfers {
+			owner, ok := owners[transfer.Address]
+			if !ok || owner.Asset != asset || transfer.Failed || transfer.Amount.Sign() <= 0 {
+				continue
+			}
+			s.observeDeposit(ctx, owner, transfer)
+		}
+	}
+}
+
+// observeDeposit records a deposit's confirmations and credits it once
+// there are enough, in the same wallet tx as its CREDITED status so it is
+// credited exactly once.
+func (s *Server) observeDeposit(ctx context.Context, owner DepositAddress, transfer ChainTransfer) {
+	depositID := "dep_" + strings.ToLower(owner.Asset) + "_" + transfer.TxHash
+	s.state.mu.Lock()
+	record, seen := s.state.deposits[depositID]
+	s.state.mu.Unlock()
+	if seen && (record.Status == depositStatusCredited || record.Confirmations == transfer.Confirmations) {
+		return
+	}
+	if !seen {
+		record = DepositRecord{
+			DepositID: depositID,
+			Asset:     owner.Asset,
+			Address:   owner.Address,
+			TxHash:    transfer.TxHash,
+			Amount:    transfer.Amount.Floor(s.assetScale(owner.Asset)),
+			Status:    depositStatusPending,
+			CreatedAt: time.Now().UnixMilli(),
+			UserID:    owner.UserID,
+		}
+	}
+	record.Confirmations = transfer.Confirmations
+	credit := record.Confirmations >= s.cfg.FundingConfirmations
+	s.snapshotWallet(owner.UserID)
+
+	tx := s.beginWalletTx(walletOpDeposit, depositID, owner.UserID)
+	defer tx.rollback()
+	s.state.mu.Lock()
+	current, ok := s.state.deposits[depositID]
+	s.state.mu.Unlock()
+	if ok && current.Status == depositStatusCredited {
+		return
+	}
+	if credit {
+		record.Status = depositStatusCredited
+		record.CreditedAt = time.Now().UnixMilli()
+		bal := tx.balance(owner.UserID, owner.Asset)
+		bal.Available = bal.Available.Add(record.Amount)
+		tx.set(owner.UserID, owner.Asset, bal)
+	}
+	tx.also(
+		func(ctx context.Context, dbTx *sql.Tx) error { return writeDeposit(ctx, dbTx, record) },
+		func() { s.state.deposits[depositID] = record },
+	)
+	if err := tx.commit(ctx); err != nil {
+		log.Printf("service=edge-gateway msg=deposit_update_failed deposit_id=%s err=%v", depositID, err)
+		return
+	}
+	if credit {
+		log.Printf("service=edge-gateway msg=deposit_credited deposit_id=%s user_id=%s asset=%s amount=%s",
+			depositID, owner.UserID, owner.Asset, record.Amount)
+	}
+}
+
+func (s *Server) pollWithdrawals(ctx context.Context) {
+	for _, record := range s.listWithdrawals("", withdrawalStatusApproved) {
+		txHash, err := s.chain.Send(ctx, record.Asset, record.Address, record.Amount, record.WithdrawalID)
+		if err != nil {
+			// Left APPROVED: the next poll sends it again, with the same
+			// reference.
+			log.Printf("service=edge-gateway msg=withdrawal_send_failed withdrawal_id=%s err=%v", record.WithdrawalID, err)
+			continue
+		}
+		_, err = s.advanceWithdrawal(ctx, record.WithdrawalID, []string{withdrawalStatusApproved}, withdrawalStatusBroadcast,
+			func(record *WithdrawalRecord) { record.TxHash = txHash })
+		if err != nil {
+			log.Printf("service=edge-gateway msg=withdrawal_update_failed withdrawal_id=%s err=%v", record.WithdrawalID, err)
+		}
+	}
+
+	for _, record := range s.listWithdrawals("", withdrawalStatusBroadcast) {
+		transfer, err := s.chain.Transfer(ctx, record.Asset, record.TxHash)
+		if err != nil {
+			log.Printf("service=edge-gateway msg=withdrawal_poll_failed withdrawal_id=%s err=%v", record.WithdrawalID, err)
+			continue
+		}
+		to := withdrawalStatusBroadcast
+		reason := ""
+		switch {
+		case transfer.Failed:
+			to, reason = withdrawalStatusFailed, "chain_failed"
+		case transfer.Confirmations >= s.cfg.FundingConfirmations:
+			to = withdrawalStatusConfirmed
+		case transfer.Confirmations == record.Confirmations:
+			continue
+		}
+		_, err = s.advanceWithdrawal(ctx, record.WithdrawalID, []string{withdrawalStatusBroadcast}, to,
+			func(record *WithdrawalRecord) {
+				record.Confirmations = transfer.Confirmations
+				record.Reason = reason
+			})
+		if err != nil {
+			log.Printf("service=edge-gateway msg=withdrawal_update_failed withdrawal_id=%s err=%v", record.WithdrawalID, err)
+		}
+	}
+}
+
+// simulatedChain is the chain when it is the in-process simulation; the
+// admin chain endpoints only drive that one.
+func (s *Server) simulatedChain(w http.ResponseWriter) (*SimulatedChain, bool) {
+	chain, ok := s.chain.(*SimulatedChain)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "chain is not simulated"})
+	}
+	return chain, ok
+}
+
+func (s *Server) handleSimulateDeposit(w http.ResponseWriter, r *http.Request) {
+	chain, ok := s.simulatedChain(w)
+	if !ok {
+		return
+	}
+	var req WithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	asset, err := s.fundingAsset(req.Asset)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	amount, err := s.fundingAmount(asset, req.Amount)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	txHash, err := chain.SimulateDeposit(asset, strings.TrimSpace(req.Address), amount)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"txHash": txHash})
+}
+
+func (s *Server) handleMineBlocks(w http.ResponseWriter, r *http.Request) {
+	chain, ok := s.simulatedChain(w)
+	if !ok {
+		return
+	}
+	var req struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Count <= 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "count must be > 0"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"height": chain.Mine(req.Count)})
+}
+
+func (s *Server) handleFailTransfer(w http.ResponseWriter, r *http.Request) {
+	chain, ok := s.simulatedChain(w)
+	if !ok {
+		return
+	}
+	if err := chain.FailTransfer(chi.URLParam(r, "txHash")); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"failed": true})
+}
+
+func (s *Server) initFundingSchema(ctx context.Context) error {
+	for _, stmt := range []struct {
+		name string
+		sql  string
+	}{
+		{name: "deposit addresses", sql: `
+			CREATE TABLE IF NOT EXISTS web_deposit_addresses (
+				address TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				asset TEXT NOT NULL,
+				created_at_ms BIGINT NOT NULL,
+				UNIQUE (user_id, asset)
+			)`},
+		{name: "deposits", sql: `
+			CREATE TABLE IF NOT EXISTS web_deposits (
+				deposit_id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				asset TEXT NOT NULL,
+				address TEXT NOT NULL,
+				tx_hash TEXT NOT NULL,
+				amount NUMERIC NOT NULL,
+				confirmations INT NOT NULL DEFAULT 0,
+				status TEXT NOT NULL,
+				created_at_ms BIGINT NOT NULL,
+				credited_at_ms BIGINT NOT NULL DEFAULT 0
+			)`},
+		{name: "withdrawals", sql: `
+			CREATE TABLE IF NOT EXISTS web_withdrawals (
+				withdrawal_id TEXT PRIMARY KEY,
+				user_id TEXT NOT NULL,
+				asset TEXT NOT NULL,
+				address TEXT NOT NULL,
+				amount NUMERIC NOT NULL,
+				status TEXT NOT NULL,
+				tx_hash TEXT NOT NULL DEFAULT '',
+				confirmations INT NOT NULL DEFAULT 0,
+				reason TEXT NOT NULL DEFAULT '',
+				created_at_ms BIGINT NOT NULL,
+				updated_at_ms BIGINT NOT NULL
+			)`},
+		{name: "withdrawal addresses", sql: `
+			CREATE TABLE IF NOT EXISTS web_withdrawal_addresses (
+				user_id TEXT NOT NULL,
+				asset TEXT NOT NULL,
+				address TEXT NOT NULL,
+				label TEXT NOT NULL DEFAULT '',
+				created_at_ms BIGINT NOT NULL,
+				active_at_ms BIGINT NOT NULL,
+				PRIMARY KEY (user_id, asset, address)
+			)`},
+	} {
+		if _, err := s.db.ExecContext(ctx, stmt.sql); err != nil {
+			return fmt.Errorf("init %s schema: %w", stmt.name, err)
+		}
+	}
+	return nil
+}
+
+// persistDepositAddress stores an issued address and returns the user's
+// address for the asset, which is an earlier one if another request won.
+func (s *Server) persistDepositAddress(ctx context.Context, record DepositAddress) (DepositAddress, error) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	if s.db == nil {
+		return record, nil
+	}
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO web_deposit_addresses(address, user_id, asset, created_at_ms) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (user_id, asset) DO UPDATE SET user_id = EXCLUDED.user_id
+		 RETURNING address, created_at_ms`,
+		record.Address,
+		record.UserID,
+		record.Asset,
+		record.CreatedAt,
+	).Scan(&record.Address, &record.CreatedAt)
+	return record, err
+}
+
+func (s *Server) persistWithdrawalAddress(ctx context.Context, entry WithdrawalAddress) error {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	if s.db == nil {
+		return nil
+	}
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO web_withdrawal_addresses(user_id, asset, address, label, created_at_ms, active_at_ms)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (user_id, asset, address) DO NOTHING`,
+		entry.UserID,
+		entry.Asset,
+		entry.Address,
+		entry.Label,
+		entry.CreatedAt,
+		entry.ActiveAt,
+	)
+	return err
+}
+
+func writeDeposit(ctx context.Context, dbTx *sql.Tx, record DepositRecord) error {
+	_, err := dbTx.ExecContext(
+		ctx,
+		`INSERT INTO web_deposits(deposit_id, user_id, asset, address, tx_hash, amount, confirmations, status, created_at_ms, credited_at_ms)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 ON CONFLICT (deposit_id) DO UPDATE SET
+		 confirmations = EXCLUDED.confirmations,
+		 status = EXCLUDED.status,
+		 credited_at_ms = EXCLUDED.credited_at_ms`,
+		record.DepositID,
+		record.UserID,
+		record.Asset,
+		record.Address,
+		record.TxHash,
+		record.Amount,
+		record.Confirmations,
+		record.Status,
+		record.CreatedAt,
+		record.CreditedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("write deposit %s: %w", record.DepositID, err)
+	}
+	return nil
+}
+
+func writeWithdrawal(ctx context.Context, dbTx *sql.Tx, record WithdrawalRecord) error {
+	_, err := dbTx.ExecContext(
+		ctx,
+		`INSERT INTO web_withdrawals(withdrawal_id, user_id, asset, address, amount, status, tx_hash, confirmations, reason, created_at_ms, updated_at_ms)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		 ON CONFLICT (withdrawal_id) DO UPDATE SET
+		 status = EXCLUDED.status,
+		 tx_hash = EXCLUDED.tx_hash,
+		 confirmations = EXCLUDED.confirmations,
+		 reason = EXCLUDED.reason,
+		 updated_at_ms = EXCLUDED.updated_at_ms`,
+		record.WithdrawalID,
+		record.UserID,
+		record.Asset,
+		record.Address,
+		record.Amount,
+		record.Status,
+		record.TxHash,
+		record.Confirmations,
+		record.Reason,
+		record.CreatedAt,
+		record.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("write withdrawal %s: %w", record.WithdrawalID, err)
+	}
+	return nil
+}
+
+// loadFunding restores deposit addresses, deposits, withdrawals and the
+// whitelist, so the watcher picks up where it stopped.
+func (s *Server) loadFunding(ctx context.Context) error {
+	addresses, err := s.db.QueryContext(ctx, `SELECT address, user_id, asset, created_at_ms FROM web_deposit_addresses`)
+	if err != nil {
+		return fmt.Errorf("load deposit addresses: %w", err)
+	}
+	defer addresses.Close()
+	for addresses.Next() {
+		var record DepositAddress
+		if err := addresses.Scan(&record.Address, &record.UserID, &record.Asset, &record.CreatedAt); err != nil {
+			return fmt.Errorf("scan deposit address: %w", err)
+		}
+		s.state.depositAddresses[record.UserID+"|"+record.Asset] = record
+	}
+
+	deposits, err := s.db.QueryContext(ctx, `SELECT deposit_id, user_id, asset, address, tx_hash, amount, confirmations, status, created_at_ms, credited_at_ms FROM web_deposits`)
+	if err != nil {
+		return fmt.Errorf("load deposits: %w", err)
+	}
+	defer deposits.Close()
+	for deposits.Next() {
+		var record DepositRecord
+		if err := deposits.Scan(&record.DepositID, &record.UserID, &record.Asset, &record.Address, &record.TxHash,
+			&record.Amount, &record.Confirmations, &record.Status, &record.CreatedAt, &record.CreditedAt); err != nil {
+			return fmt.Errorf("scan deposit: %w", err)
+		}
+		s.state.deposits[record.DepositID] = record
+	}
+
+	withdrawals, err := s.db.QueryContext(ctx, `SELECT withdrawal_id, user_id, asset, address, amount, status, tx_hash, confirmations, reason, created_at_ms, updated_at_ms FROM web_withdrawals`)
+	if err != nil {
+		return fmt.Errorf("load withdrawals: %w", err)
+	}
+	defer withdrawals.Close()
+	for withdrawals.Next() {
+		var record WithdrawalRecord
+		if err := withdrawals.Scan(&record.WithdrawalID, &record.UserID, &record.Asset, &record.Address, &record.Amount,
+			&record.Status, &record.TxHash, &record.Confirmations, &record.Reason, &record.CreatedAt, &record.UpdatedAt); err != nil {
+			return fmt.Errorf("scan withdrawal: %w", err)
+		}
+		s.state.withdrawals[record.WithdrawalID] = record
+	}
+
+	whitelist, err := s.db.QueryContext(ctx, `SELECT user_id, asset, address, label, created_at_ms, active_at_ms FROM web_withdrawal_addresses`)
+	if err != nil {
+		return fmt.Errorf("load withdrawal addresses: %w", err)
+	}
+	defer whitelist.Close()
+	for whitelist.Next() {
+		var entry WithdrawalAddress
+		if err := whitelist.Scan(&entry.UserID, &entry.Asset, &entry.Address, &entry.Label, &entry.CreatedAt, &entry.ActiveAt); err != nil {
+			return fmt.Errorf("scan withdrawal address: %w", err)
+		}
+		if s.state.withdrawalAddresses[entry.UserID] == nil {
+			s.state.withdrawalAddresses[entry.UserID] = map[string]WithdrawalAddress{}
+		}
+		s.state.withdrawalAddresses[entry.UserID][fundingKey(entry.Asset, entry.Address)] = entry
+	}
+
+	if chain, ok := s.chain.(*SimulatedChain); ok {
+		issued := make([]DepositAddress, 0, len(s.state.depositAddresses))
+		for _, address := range s.state.depositAddresses {
+			issued = append(issued, address)
+		}
+		pending := make([]WithdrawalRecord, 0, len(s.state.withdrawals))
+		for _, record := range s.state.withdrawals {
+			pending = append(pending, record)
+		}
+		chain.restore(issued, pending)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newFundingTestServer stops the funding watcher so tests advance the
+// chain and poll it themselves.
+func newFundingTestServer(t *testing.T) (*Server, *SimulatedChain, func()) {
+	t.Helper()
+	s, cleanup := newTestServer(t)
+	s.fundingCancel()
+	s.fundingWG.Wait()
+	return s, s.chain.(*SimulatedChain), cleanup
+}
+
+func fundingCall(t *testing.T, s *Server, method, path, body, idemKey string, out interface{}) int {
+	t.Helper()
+	var raw []byte
+	if body != "" {
+		raw = []byte(body)
+	}
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, method, path, raw, idemKey))
+	if out != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decode %s %s: %v body=%s", method, path, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+func TestDepositIsCreditedOnceConfirmed(t *testing.T) {
+	s, chain, cleanup := newFundingTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	var address DepositAddress
+	if code := fundingCall(t, s, http.MethodPost, "/v1/account/deposit-addresses", `{"asset":"btc"}`, "", &address); code != http.StatusOK || address.Address == "" {
+		t.Fatalf("issue address failed: %d %+v", code, address)
+	}
+	var again DepositAddress
+	fundingCall(t, s, http.MethodPost, "/v1/account/deposit-addresses", `{"asset":"BTC"}`, "", &again)
+	if again.Address != address.Address {
+		t.Fatalf("expected the same address, got %s and %s", address.Address, again.Address)
+	}
+
+	txHash, err := chain.SimulateDeposit("BTC", address.Address, decimalFromInt(1))
+	if err != nil {
+		t.Fatalf("simulate deposit: %v", err)
+	}
+	deposits := func() []DepositRecord {
+		t.Helper()
+		var out struct {
+			Deposits []DepositRecord `json:"deposits"`
+		}
+		fundingCall(t, s, http.MethodGet, "/v1/account/deposits", "", "", &out)
+		return out.Deposits
+	}
+
+	chain.Mine(2)
+	s.pollFunding(ctx)
+	if got := deposits(); len(got) != 1 || got[0].Status != depositStatusPending || got[0].Confirmations != 2 || got[0].TxHash != txHash {
+		t.Fatalf("expected a pending deposit with 2 confirmations, got %+v", got)
+	}
+	if got := s.snapshotWallet("test-key")["BTC"].Available.String(); got != "2.00000000" {
+		t.Fatalf("expected nothing credited yet, got %s", got)
+	}
+
+	chain.Mine(1)
+	s.pollFunding(ctx)
+	chain.Mine(5)
+	s.pollFunding(ctx)
+	if got := deposits(); len(got) != 1 || got[0].Status != depositStatusCredited || got[0].CreditedAt == 0 {
+		t.Fatalf("expected the deposit credited, got %+v", got)
+	}
+	if got := s.snapshotWallet("test-key")["BTC"].Available.String(); got != "3.00000000" {
+		t.Fatalf("expected the deposit credited once, got %s", got)
+	}
+}
+
+func TestWithdrawalRequiresAnUnlockedWhitelistedAddressWithinTheLimit(t *testing.T) {
+	s, _, cleanup := newFundingTestServer(t)
+	defer cleanup()
+
+	withdraw := func(key, amount string) int {
+		return fundingCall(t, s, http.MethodPost, "/v1/account/withdrawals",
+			`{"asset":"BTC","address":"bc1dest","amount":"`+amount+`"}`, key, nil)
+	}
+	if code := withdraw("wd-unlisted", "0.1"); code != http.StatusBadRequest {
+		t.Fatalf("expected unlisted address rejected, got %d", code)
+	}
+	var entry WithdrawalAddress
+	fundingCall(t, s, http.MethodPost, "/v1/account/withdrawal-addresses", `{"asset":"BTC","address":"bc1dest","label":"cold"}`, "", &entry)
+	if entry.ActiveAt-entry.CreatedAt != (24 * time.Hour).Milliseconds() {
+		t.Fatalf("expected a 24h time lock, got %+v", entry)
+	}
+	if code := withdraw("wd-locked", "0.1"); code != http.StatusBadRequest {
+		t.Fatalf("expected time-locked address rejected, got %d", code)
+	}
+
+	s.state.mu.Lock()
+	entry.ActiveAt = time.Now().Add(-time.Minute).UnixMilli()
+	s.state.withdrawalAddresses["test-key"][fundingKey("BTC", "bc1dest")] = entry
+	s.state.mu.Unlock()
+	s.withdrawalLimits = map[string]Decimal{"BTC": decimalFromInt(1)}
+
+	if code := withdraw("wd-1", "0.6"); code != http.StatusOK {
+		t.Fatalf("expected withdrawal accepted, got %d", code)
+	}
+	if code := withdraw("wd-1", "0.6"); code != http.StatusOK {
+		t.Fatalf("expected replay answered, got %d", code)
+	}
+	if code := withdraw("wd-2", "0.5"); code != http.StatusBadRequest {
+		t.Fatalf("expected daily limit enforced, got %d", code)
+	}
+	if code := withdraw("wd-3", "0.123456789"); code != http.StatusBadRequest {
+		t.Fatalf("expected excess decimals rejected, got %d", code)
+	}
+	bal := s.snapshotWallet("test-key")["BTC"]
+	if bal.Available.String() != "1.40000000" || bal.Hold.String() != "0.60000000" {
+		t.Fatalf("expected one withdrawal held, got %+v", bal)
+	}
+}
+
+func TestWithdrawalLifecycleSettlesTheHold(t *testing.T) {
+	s, chain, cleanup := newFundingTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now().UnixMilli()
+	s.state.withdrawalAddresses["test-key"] = map[string]WithdrawalAddress{
+		fundingKey("BTC", "bc1dest"): {Asset: "BTC", Address: "bc1dest", CreatedAt: now, ActiveAt: now, UserID: "test-key"},
+	}
+	request := func(key string) WithdrawalRecord {
+		t.Helper()
+		var record WithdrawalRecord
+		if code := fundingCall(t, s, http.MethodPost, "/v1/account/withdrawals",
+			`{"asset":"BTC","address":"bc1dest","amount":"0.5"}`, key, &record); code != http.StatusOK {
+			t.Fatalf("request withdrawal failed: %d", code)
+		}
+		return record
+	}
+	status := func(id string) WithdrawalRecord {
+		s.state.mu.Lock()
+		defer s.state.mu.Unlock()
+		return s.state.withdrawals[id]
+	}
+
+	confirmed := request("wd-ok")
+	if w := adminRequest(t, s, http.MethodPost, "/v1/admin/withdrawals/"+confirmed.WithdrawalID+"/approve", ""); w.Code != http.StatusOK {
+		t.Fatalf("approve failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if w := adminRequest(t, s, http.MethodPost, "/v1/admin/withdrawals/"+confirmed.WithdrawalID+"/approve", ""); w.Code != http.StatusConflict {
+		t.Fatalf("expected second approve rejected, got %d", w.Code)
+	}
+	s.pollFunding(ctx)
+	if got := status(confirmed.WithdrawalID); got.Status != withdrawalStatusBroadcast || got.TxHash == "" {
+		t.Fatalf("expected the withdrawal broadcast, got %+v", got)
+	}
+	chain.Mine(3)
+	s.pollFunding(ctx)
+	if got := status(confirmed.WithdrawalID); got.Status != withdrawalStatusConfirmed || got.Confirmations != 3 {
+		t.Fatalf("expected the withdrawal confirmed, got %+v", got)
+	}
+
+	rejected := request("wd-reject")
+	if w := adminRequest(t, s, http.MethodPost, "/v1/admin/withdrawals/"+rejected.WithdrawalID+"/reject", `{"reason":"aml"}`); w.Code != http.StatusOK {
+		t.Fatalf("reject failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if got := status(rejected.WithdrawalID); got.Status != withdrawalStatusFailed || got.Reason != "aml" {
+		t.Fatalf("expected the withdrawal rejected, got %+v", got)
+	}
+
+	dropped := request("wd-drop")
+	adminRequest(t, s, http.MethodPost, "/v1/admin/withdrawals/"+dropped.WithdrawalID+"/approve", "")
+	s.pollFunding(ctx)
+	if w := adminRequest(t, s, http.MethodPost, "/v1/admin/chain/transfers/"+status(dropped.WithdrawalID).TxHash+"/fail", ""); w.Code != http.StatusOK {
+		t.Fatalf("fail transfer failed: %d body=%s", w.Code, w.Body.String())
+	}
+	s.pollFunding(ctx)
+	if got := status(dropped.WithdrawalID); got.Status != withdrawalStatusFailed || got.Reason != "chain_failed" {
+		t.Fatalf("expected the withdrawal failed by the chain, got %+v", got)
+	}
+
+	bal := s.snapshotWallet("test-key")["BTC"]
+	if bal.Available.String() != "1.50000000" || !bal.Hold.IsZero() {
+		t.Fatalf("expected only the confirmed withdrawal taken out, got %+v", bal)
+	}
+	w := adminRequest(t, s, http.MethodGet, "/v1/admin/ledger/check", "")
+	var check struct {
+		OK bool `json:"ok"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &check) != nil || !check.OK {
+		t.Fatalf("expected the ledger consistent, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestWithdrawalWithAShortHoldIsLeftUnsettled(t *testing.T) {
+	s, chain, cleanup := newFundingTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now().UnixMilli()
+	s.state.withdrawalAddresses["test-key"] = map[string]WithdrawalAddress{
+		fundingKey("BTC", "bc1dest"): {Asset: "BTC", Address: "bc1dest", CreatedAt: now, ActiveAt: now, UserID: "test-key"},
+	}
+	var record WithdrawalRecord
+	if code := fundingCall(t, s, http.MethodPost, "/v1/account/withdrawals",
+		`{"asset":"BTC","address":"bc1dest","amount":"0.5"}`, "wd-short", &record); code != http.StatusOK {
+		t.Fatalf("request withdrawal failed: %d", code)
+	}
+	setHold := func(hold string) {
+		t.Helper()
+		amount, _ := parseDecimal(hold)
+		s.state.mu.Lock()
+		bal := s.state.wallets["test-key"]["BTC"]
+		bal.Hold = amount
+		s.state.wallets["test-key"]["BTC"] = bal
+		s.state.mu.Unlock()
+	}
+	status := func() string {
+		s.state.mu.Lock()
+		defer s.state.mu.Unlock()
+		return s.state.withdrawals[record.WithdrawalID].Status
+	}
+
+	setHold("0.2")
+	if w := adminRequest(t, s, http.MethodPost, "/v1/admin/withdrawals/"+record.WithdrawalID+"/reject", ""); w.Code != http.StatusConflict {
+		t.Fatalf("expected a reject short of the hold refused, got %d body=%s", w.Code, w.Body.String())
+	}
+	if got := status(); got != withdrawalStatusRequested {
+		t.Fatalf("expected the withdrawal still requested, got %s", got)
+	}
+
+	setHold("0.5")
+	adminRequest(t, s, http.MethodPost, "/v1/admin/withdrawals/"+record.WithdrawalID+"/approve", "")
+	s.pollFunding(ctx)
+	setHold("0.2")
+	chain.Mine(3)
+	s.pollFunding(ctx)
+	if got := status(); got != withdrawalStatusBroadcast {
+		t.Fatalf("expected the withdrawal left broadcast, got %s", got)
+	}
+	if bal := s.snapshotWallet("test-key")["BTC"]; bal.Hold.String() != "0.2" {
+		t.Fatalf("expected the hold untouched, got %+v", bal)
+	}
+
+	// Once the hold covers it again, the next poll settles it in full.
+	setHold("0.5")
+	s.pollFunding(ctx)
+	if got := status(); got != withdrawalStatusConfirmed {
+		t.Fatalf("expected the withdrawal confirmed, got %s", got)
+	}
+	if bal := s.snapshotWallet("test-key")["BTC"]; !bal.Hold.IsZero() {
+		t.Fatalf("expected the whole amount taken out of the hold, got %+v", bal)
+	}
+}
@@ -0,0 +1,788 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	exchangev1 "github.com/quanta-exchange/exchange-platform/contracts/gen/go/exchange/v1"
+)
+
+// Account kinds, as in the ledger service: a wallet balance of currency C
+// is split over user:<id>:C:AVAILABLE and user:<id>:C:HOLD.
+const (
+	ledgerKindAvailable = "AVAILABLE"
+	ledgerKindHold      = "HOLD"
+)
+
+// ledgerEntryType says how an entry of a wallet operation is labelled and,
+// for operations that bring money in or take it out, which system account
+// balances the postings no user wallet takes: the treasury funds opening
+// balances and custody holds what was deposited on chain or paid out by a
+// withdrawal. Every other operation only moves money between wallets, and
+// an entry of one that does not balance is refused. A trade with a side
+// not settled through this gateway books exactly that side's legs to the
+// counterparty account (see walletTx.addExternal), and must balance with
+// them.
+type ledgerEntryType struct {
+	referenceType string
+	entryKind     string
+	contra        string
+}
+
+var ledgerEntryTypes = map[string]ledgerEntryType{
+	walletOpOpen:    {referenceType: "ADJUSTMENT", entryKind: "OPENING_BALANCE", contra: "treasury"},
+	walletOpReserve: {referenceType: "ORDER", entryKind: "RESERVE"},
+	walletOpRelease: {referenceType: "ORDER", entryKind: "RELEASE"},
+	walletOpSettle:  {referenceType: "TRADE", entryKind: "FILL"},
+
+	walletOpDeposit:         {referenceType: "DEPOSIT", entryKind: "DEPOSIT", contra: "custody"},
+	walletOpWithdrawHold:    {referenceType: "WITHDRAWAL", entryKind: "RESERVE", contra: "custody"},
+	walletOpWithdrawRelease: {referenceType: "WITHDRAWAL", entryKind: "RELEASE", contra: "custody"},
+	walletOpWithdraw:        {referenceType: "WITHDRAWAL", entryKind: "WITHDRAWAL", contra: "custody"},
+	walletOpTransfer:        {referenceType: "TRANSFER", entryKind: "TRANSFER"},
+}
+
+// ledgerContraCounterparty books the trade side a one-sided settlement
+// does not settle through a wallet.
+const ledgerContraCounterparty = "counterparty"
+
+func validLedgerReferenceType(referenceType string) bool {
+	for _, entryType := range ledgerEntryTypes {
+		if entryType.referenceType == referenceType {
+			return true
+		}
+	}
+	return false
+}
+
+// LedgerPostingView is one posting of a ledger entry. Amounts are in the
+// currency's units; a debit adds to the account's balance.
+type LedgerPostingView struct {
+	AccountID string  `json:"accountId"`
+	Currency  string  `json:"currency"`
+	Amount    Decimal `json:"amount"`
+	IsDebit   bool    `json:"isDebit"`
+}
+
+type LedgerEntryView struct {
+	EntryID       string              `json:"entryId"`
+	Seq           uint64              `json:"seq"`
+	ReferenceType string              `json:"referenceType"`
+	ReferenceID   string              `json:"referenceId"`
+	EntryKind     string              `json:"entryKind"`
+	Postings      []LedgerPostingView `json:"postings"`
+	Ts            int64               `json:"ts"`
+}
+
+// LedgerMismatch is an account whose balance differs from the sum of its
+// postings: a wallet bucket's, or for the custody account what the
+// deposits and withdrawals amount to.
+type LedgerMismatch struct {
+	AccountID string  `json:"accountId"`
+	Balance   Decimal `json:"balance"`
+	Ledger    Decimal `json:"ledger"`
+}
+
+// ledgerAccountID names the account of a wallet bucket. System wallets such
+// as the fee account (system:fees) keep their name.
+func ledgerAccountID(walletID, currency, kind string) string {
+	if strings.HasPrefix(walletID, "system:") {
+		return walletID + ":" + currency + ":" + kind
+	}
+	return "user:" + walletID + ":" + currency + ":" + kind
+}
+
+// ledgerAccountWallet is the wallet an account belongs to: the user ID for
+// user accounts, the system name otherwise.
+func ledgerAccountWallet(accountID string) string {
+	parts := strings.Split(accountID, ":")
+	if len(parts) < 4 {
+		return ""
+	}
+	owner := strings.Join(parts[:len(parts)-2], ":")
+	return strings.TrimPrefix(owner, "user:")
+}
+
+// minorUnits is amount in the currency's smallest unit. Postings are
+// int64, so an amount beyond that range cannot be booked.
+func (s *Server) minorUnits(currency string, amount Decimal) (int64, error) {
+	units := amount.Floor(s.assetScale(currency)).int()
+	if !units.IsInt64() {
+		return 0, fmt.Errorf("%s amount %s exceeds the ledger range", currency, amount)
+	}
+	return units.Int64(), nil
+}
+
+// buildLedgerEntry turns the balance changes of a wallet operation into a
+// balanced entry: a posting per moved bucket, one to the counterparty
+// account per currency an external trade side moves, and per currency one
+// to the operation's system account for whatever no account took. It is
+// nil when nothing moved, and an error when an amount does not fit a
+// posting or the changes do not balance without a system account.
+func (s *Server) buildLedgerEntry(op, refID string, external map[string]Decimal, changes []walletChange) (*exchangev1.LedgerEntryAppended, error) {
+	entryType := ledgerEntryTypes[op]
+	entryID := "le_" + uuid.NewString()
+	entry := &exchangev1.LedgerEntryAppended{
+		Envelope: &exchangev1.EventEnvelope{
+			EventId:      entryID,
+			EventVersion: 1,
+			OccurredAt:   timestamppb.Now(),
+		},
+		EntryId:       entryID,
+		ReferenceType: entryType.referenceType,
+		ReferenceId:   refID,
+		EntryKind:     entryType.entryKind,
+	}
+	imbalance := map[string]int64{}
+	var currencies []string
+	for _, change := range changes {
+		currency := strings.ToUpper(change.currency)
+		for _, leg := range []struct {
+			kind          string
+			before, after Decimal
+		}{
+			{kind: ledgerKindAvailable, before: change.before.Available, after: change.after.Available},
+			{kind: ledgerKindHold, before: change.before.Hold, after: change.after.Hold},
+		} {
+			after, err := s.minorUnits(currency, leg.after)
+			if err != nil {
+				return nil, err
+			}
+			before, err := s.minorUnits(currency, leg.before)
+			if err != nil {
+				return nil, err
+			}
+			delta := after - before
+			if delta == 0 {
+				continue
+			}
+			if _, seen := imbalance[currency]; !seen {
+				currencies = append(currencies, currency)
+			}
+			imbalance[currency] += delta
+			entry.Postings = append(entry.Postings, ledgerPosting(ledgerAccountID(change.userID, currency, leg.kind), currency, delta))
+		}
+	}
+	externalCurrencies := make([]string, 0, len(external))
+	for currency := range external {
+		externalCurrencies = append(externalCurrencies, currency)
+	}
+	sort.Strings(externalCurrencies)
+	for _, currency := range externalCurrencies {
+		delta, err := s.minorUnits(currency, external[currency])
+		if err != nil {
+			return nil, err
+		}
+		if delta == 0 {
+			continue
+		}
+		currency = strings.ToUpper(currency)
+		if _, seen := imbalance[currency]; !seen {
+			currencies = append(currencies, currency)
+		}
+		imbalance[currency] += delta
+		account := ledgerAccountID("system:"+ledgerContraCounterparty, currency, ledgerKindAvailable)
+		entry.Postings = append(entry.Postings, ledgerPosting(account, currency, delta))
+	}
+	if len(entry.Postings) == 0 {
+		return nil, nil
+	}
+	contra := entryType.contra
+	for _, currency := range currencies {
+		if imbalance[currency] == 0 {
+			continue
+		}
+		if contra == "" {
+			return nil, fmt.Errorf("%s %s does not balance: %s off by %d", op, refID, currency, imbalance[currency])
+		}
+		account := ledgerAccountID("system:"+contra, currency, ledgerKindAvailable)
+		entry.Postings = append(entry.Postings, ledgerPosting(account, currency, -imbalance[currency]))
+	}
+	return entry, nil
+}
+
+func ledgerPosting(accountID, currency string, delta int64) *exchangev1.LedgerPosting {
+	if delta < 0 {
+		return &exchangev1.LedgerPosting{AccountId: accountID, Currency: currency, Amount: -delta}
+	}
+	return &exchangev1.LedgerPosting{AccountId: accountID, Currency: currency, Amount: delta, IsDebit: true}
+}
+
+// appendLedgerEntryLocked indexes entry under every wallet it posts to.
+// Callers hold s.state.mu.
+func (s *Server) appendLedgerEntryLocked(entry *exchangev1.LedgerEntryAppended) {
+	seen := map[string]bool{}
+	for _, posting := range entry.Postings {
+		walletID := ledgerAccountWallet(posting.AccountId)
+		if walletID == "" || seen[walletID] {
+			continue
+		}
+		seen[walletID] = true
+		s.state.ledger[walletID] = append(s.state.ledger[walletID], entry)
+	}
+}
+
+func (s *Server) openLedger(ctx context.Context, walletID string) error {
+	wallets := []string{walletID}
+	s.walletMu.RLock()
+	defer s.walletMu.RUnlock()
+	s.walletLocks.lock(wallets)
+	defer s.walletLocks.unlock(wallets)
+	return s.openLedgerLocked(ctx, walletID)
+}
+
+// openLedgerLocked loads the wallet's entries from Postgres the first time
+// it is needed. A wallet without any gets an opening entry for what it
+// holds: signup and demo balances, and balances from before the ledger.
+// Callers hold the wallet's lock, or walletMu exclusively, so the wallet
+// cannot move meanwhile.
+func (s *Server) openLedgerLocked(ctx context.Context, walletID string) error {
+	s.state.mu.Lock()
+	loaded := s.state.ledgerLoaded[walletID]
+	s.state.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	entries, err := s.loadLedgerFromDB(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		s.state.mu.Lock()
+		wallet := cloneWallet(s.state.wallets[walletID])
+		s.state.mu.Unlock()
+		currencies := make([]string, 0, len(wallet))
+		for currency := range wallet {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+		changes := make([]walletChange, 0, len(currencies))
+		for _, currency := range currencies {
+			zero := s.assetAmount(currency, 0)
+			changes = append(changes, walletChange{
+				userID:   walletID,
+				currency: currency,
+				before:   walletBalance{Available: zero, Hold: zero},
+				after:    wallet[currency],
+			})
+		}
+		entry, err := s.buildLedgerEntry(walletOpOpen, walletID, nil, changes)
+		if err != nil {
+			return err
+		}
+		if entry != nil {
+			if err := s.persistLedgerEntry(ctx, entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	s.state.mu.Lock()
+	if s.db == nil {
+		// Without a database the entries in memory are all there are, such
+		// as a system account's, which is never opened before it is posted to.
+		entries = append(s.state.ledger[walletID], entries...)
+	}
+	s.state.ledger[walletID] = entries
+	s.state.ledgerLoaded[walletID] = true
+	s.state.mu.Unlock()
+	return nil
+}
+
+func (s *Server) initLedgerSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_ledger_entries (
+			entry_seq BIGSERIAL PRIMARY KEY,
+			entry_id TEXT NOT NULL UNIQUE,
+			reference_type TEXT NOT NULL,
+			reference_id TEXT NOT NULL,
+			entry_kind TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init ledger entries schema: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_ledger_postings (
+			entry_seq BIGINT NOT NULL REFERENCES web_ledger_entries(entry_seq),
+			posting_idx INT NOT NULL,
+			account_id TEXT NOT NULL,
+			wallet_id TEXT NOT NULL,
+			currency TEXT NOT NULL,
+			amount BIGINT NOT NULL CHECK (amount > 0),
+			is_debit BOOLEAN NOT NULL,
+			PRIMARY KEY (entry_seq, posting_idx)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init ledger postings schema: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS web_ledger_postings_wallet_idx ON web_ledger_postings (wallet_id, entry_seq)
+	`)
+	if err != nil {
+		return fmt.Errorf("init ledger postings index: %w", err)
+	}
+	return nil
+}
+
+// persistLedgerEntry writes an entry on its own. Entries of balance changes
+// go through persistWalletTx instead, with the balances.
+func (s *Server) persistLedgerEntry(ctx context.Context, entry *exchangev1.LedgerEntryAppended) error {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	if s.db == nil {
+		s.assignLedgerSeq(entry)
+		return nil
+	}
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin ledger tx: %w", err)
+	}
+	defer func() { _ = dbTx.Rollback() }()
+	if err := writeLedgerEntry(ctx, dbTx, entry); err != nil {
+		return err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("commit ledger tx: %w", err)
+	}
+	return nil
+}
+
+// assignLedgerSeq numbers an entry when there is no database to do it.
+func (s *Server) assignLedgerSeq(entry *exchangev1.LedgerEntryAppended) {
+	s.state.mu.Lock()
+	s.state.ledgerSeq++
+	entry.Envelope.Seq = s.state.ledgerSeq
+	s.state.mu.Unlock()
+}
+
+func writeLedgerEntry(ctx context.Context, dbTx *sql.Tx, entry *exchangev1.LedgerEntryAppended) error {
+	var seq int64
+	err := dbTx.QueryRowContext(
+		ctx,
+		`INSERT INTO web_ledger_entries(entry_id, reference_type, reference_id, entry_kind, created_at)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING entry_seq`,
+		entry.EntryId,
+		entry.ReferenceType,
+		entry.ReferenceId,
+		entry.EntryKind,
+		entry.Envelope.OccurredAt.AsTime(),
+	).Scan(&seq)
+	if err != nil {
+		return fmt.Errorf("write ledger entry %s: %w", entry.EntryId, err)
+	}
+	for i, posting := range entry.Postings {
+		_, err := dbTx.ExecContext(
+			ctx,
+			`INSERT INTO web_ledger_postings(entry_seq, posting_idx, account_id, wallet_id, currency, amount, is_debit)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			seq,
+			i,
+			posting.AccountId,
+			ledgerAccountWallet(posting.AccountId),
+			posting.Currency,
+			posting.Amount,
+			posting.IsDebit,
+		)
+		if err != nil {
+			return fmt.Errorf("write ledger posting %s/%d: %w", entry.EntryId, i, err)
+		}
+	}
+	entry.Envelope.Seq = uint64(seq)
+	return nil
+}
+
+// loadLedgerFromDB reads every entry that posts to the wallet, with all of
+// their postings, oldest first.
+func (s *Server) loadLedgerFromDB(ctx context.Context, walletID string) ([]*exchangev1.LedgerEntryAppended, error) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	if s.db == nil {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT e.entry_seq, e.entry_id, e.reference_type, e.reference_id, e.entry_kind, e.created_at,
+		 p.account_id, p.currency, p.amount, p.is_debit
+		 FROM web_ledger_entries e JOIN web_ledger_postings p ON p.entry_seq = e.entry_seq
+		 WHERE e.entry_seq IN (SELECT entry_seq FROM web_ledger_postings WHERE wallet_id = $1)
+		 ORDER BY e.entry_seq, p.posting_idx`,
+		walletID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load ledger %s: %w", walletID, err)
+	}
+	defer rows.Close()
+
+	var entries []*exchangev1.LedgerEntryAppended
+	for rows.Next() {
+		var seq int64
+		var createdAt time.Time
+		var entryID, referenceType, referenceID, entryKind string
+		posting := &exchangev1.LedgerPosting{}
+		if err := rows.Scan(&seq, &entryID, &referenceType, &referenceID, &entryKind, &createdAt,
+			&posting.AccountId, &posting.Currency, &posting.Amount, &posting.IsDebit); err != nil {
+			return nil, fmt.Errorf("scan ledger %s: %w", walletID, err)
+		}
+		if n := len(entries); n == 0 || entries[n-1].EntryId != entryID {
+			entries = append(entries, &exchangev1.LedgerEntryAppended{
+				Envelope: &exchangev1.EventEnvelope{
+					EventId:      entryID,
+					EventVersion: 1,
+					Seq:          uint64(seq),
+					OccurredAt:   timestamppb.New(createdAt),
+				},
+				EntryId:       entryID,
+				ReferenceType: referenceType,
+				ReferenceId:   referenceID,
+				EntryKind:     entryKind,
+			})
+		}
+		last := entries[len(entries)-1]
+		last.Postings = append(last.Postings, posting)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load ledger %s: %w", walletID, err)
+	}
+	return entries, nil
+}
+
+// handleListLedger lists the entries that moved the caller's balances,
+// oldest first, showing only the caller's own postings: the main account's,
+// or with subAccount that sub-account's.
+func (s *Server) handleListLedger(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	q := r.URL.Query()
+	currency := strings.ToUpper(strings.TrimSpace(q.Get("currency")))
+	referenceType := strings.ToUpper(strings.TrimSpace(q.Get("referenceType")))
+	if referenceType != "" && !validLedgerReferenceType(referenceType) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid referenceType"})
+		return
+	}
+	limit := parseLimit(q.Get("limit"), 100)
+	var after uint64
+	if raw := strings.TrimSpace(q.Get("cursor")); raw != "" {
+		v, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid cursor"})
+			return
+		}
+		after = v
+	}
+
+	subAccount, err := normalizeSubAccount(q.Get("subAccount"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	walletID := subAccountWalletID(apiKey, subAccount)
+	if subAccount == "" {
+		s.snapshotWallet(apiKey)
+	}
+	if err := s.openLedger(r.Context(), walletID); err != nil {
+		log.Printf("service=edge-gateway msg=ledger_load_failed user_id=%s wallet_id=%s err=%v", apiKey, walletID, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "ledger_unavailable"})
+		return
+	}
+
+	s.state.mu.Lock()
+	matched := make([]LedgerEntryView, 0, 16)
+	for _, entry := range s.state.ledger[walletID] {
+		if entry.Envelope.GetSeq() <= after || referenceType != "" && entry.ReferenceType != referenceType {
+			continue
+		}
+		view := LedgerEntryView{
+			EntryID:       entry.EntryId,
+			Seq:           entry.Envelope.GetSeq(),
+			ReferenceType: entry.ReferenceType,
+			ReferenceID:   entry.ReferenceId,
+			EntryKind:     entry.EntryKind,
+			Ts:            entry.Envelope.GetOccurredAt().AsTime().UnixMilli(),
+		}
+		for _, posting := range entry.Postings {
+			if ledgerAccountWallet(posting.AccountId) != walletID || currency != "" && posting.Currency != currency {
+				continue
+			}
+			view.Postings = append(view.Postings, LedgerPostingView{
+				AccountID: posting.AccountId,
+				Currency:  posting.Currency,
+				Amount:    decimalFromUnits(posting.Amount, s.assetScale(posting.Currency)),
+				IsDebit:   posting.IsDebit,
+			})
+		}
+		if len(view.Postings) > 0 {
+			matched = append(matched, view)
+		}
+	}
+	s.state.mu.Unlock()
+
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Seq < matched[j].Seq })
+	page := matched
+	nextCursor := ""
+	if len(page) > limit {
+		page = page[:limit]
+		nextCursor = strconv.FormatUint(page[len(page)-1].Seq, 10)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"entries":    page,
+		"nextCursor": nextCursor,
+	})
+}
+
+// ledgerSystemWallets are the system accounts that balance entries
+// instead of holding a wallet; the fee account is a wallet.
+func ledgerSystemWallets() []string {
+	seen := map[string]bool{"system:" + ledgerContraCounterparty: true}
+	for _, entryType := range ledgerEntryTypes {
+		if entryType.contra != "" {
+			seen["system:"+entryType.contra] = true
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for walletID := range seen {
+		out = append(out, walletID)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ledgerWalletIDs is every wallet with a balance: those in memory and, with
+// a database, the stored ones, which are loaded so the check covers them.
+func (s *Server) ledgerWalletIDs(ctx context.Context) ([]string, error) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	if s.db != nil {
+		rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT user_id FROM web_wallet_balances`)
+		if err != nil {
+			return nil, fmt.Errorf("list wallets: %w", err)
+		}
+		var stored []string
+		for rows.Next() {
+			var userID string
+			if err := rows.Scan(&userID); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan wallet: %w", err)
+			}
+			stored = append(stored, userID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("list wallets: %w", err)
+		}
+		for _, userID := range stored {
+			if _, err := s.storedWallet(userID); err != nil {
+				return nil, fmt.Errorf("load wallet %s: %w", userID, err)
+			}
+		}
+	}
+	s.state.mu.Lock()
+	walletIDs := make([]string, 0, len(s.state.wallets))
+	for walletID := range s.state.wallets {
+		walletIDs = append(walletIDs, walletID)
+	}
+	s.state.mu.Unlock()
+	sort.Strings(walletIDs)
+	return walletIDs, nil
+}
+
+// LedgerCheck is the outcome of checkLedgerInvariant.
+type LedgerCheck struct {
+	OK                   bool                  `json:"ok"`
+	Wallets              int                   `json:"wallets"`
+	UnbalancedEntries    []string              `json:"unbalancedEntries"`
+	Mismatches           []LedgerMismatch      `json:"mismatches"`
+	SystemAccounts       []LedgerSystemAccount `json:"systemAccounts"`
+	UnbalancedCurrencies []LedgerCurrencyTotal `json:"unbalancedCurrencies"`
+}
+
+// LedgerSystemAccount is the net of a system account's postings; a debit
+// adds to it.
+type LedgerSystemAccount struct {
+	AccountID string  `json:"accountId"`
+	Net       Decimal `json:"net"`
+}
+
+// LedgerCurrencyTotal is a currency whose wallet balances and system
+// accounts do not net to zero.
+type LedgerCurrencyTotal struct {
+	Currency string  `json:"currency"`
+	Wallets  Decimal `json:"wallets"`
+	System   Decimal `json:"system"`
+}
+
+// checkLedgerInvariant sums the postings of every entry that touches a
+// wallet or a system account. Each entry must balance per currency, each
+// wallet bucket must match the sum of its account, the custody account
+// must match the deposits credited less the withdrawals confirmed, and the
+// treasury, custody and counterparty accounts must net the wallets, fee
+// account included, to zero per currency.
+func (s *Server) checkLedgerInvariant(ctx context.Context) (LedgerCheck, error) {
+	s.walletMu.Lock()
+	defer s.walletMu.Unlock()
+	walletIDs, err := s.ledgerWalletIDs(ctx)
+	if err != nil {
+		return LedgerCheck{}, err
+	}
+	systemIDs := ledgerSystemWallets()
+	for _, walletID := range append(append([]string{}, walletIDs...), systemIDs...) {
+		if err := s.openLedgerLocked(ctx, walletID); err != nil {
+			return LedgerCheck{}, err
+		}
+	}
+
+	check := LedgerCheck{
+		Wallets:              len(walletIDs),
+		UnbalancedEntries:    []string{},
+		Mismatches:           []LedgerMismatch{},
+		SystemAccounts:       []LedgerSystemAccount{},
+		UnbalancedCurrencies: []LedgerCurrencyTotal{},
+	}
+	sums := map[string]int64{}
+	checkedEntries := map[string]bool{}
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	for _, walletID := range append(append([]string{}, walletIDs...), systemIDs...) {
+		for _, entry := range s.state.ledger[walletID] {
+			if checkedEntries[entry.EntryId] {
+				continue
+			}
+			checkedEntries[entry.EntryId] = true
+			net := map[string]int64{}
+			for _, posting := range entry.Postings {
+				signed := posting.Amount
+				if !posting.IsDebit {
+					signed = -signed
+				}
+				net[posting.Currency] += signed
+				sums[posting.AccountId] += signed
+			}
+			for _, total := range net {
+				if total != 0 {
+					check.UnbalancedEntries = append(check.UnbalancedEntries, entry.EntryId)
+					break
+				}
+			}
+		}
+	}
+	ledgerAmount := func(accountID string) Decimal {
+		parts := strings.Split(accountID, ":")
+		return decimalFromUnits(sums[accountID], s.assetScale(parts[len(parts)-2]))
+	}
+
+	// Wallet buckets, and what they hold per currency.
+	isWallet := map[string]bool{}
+	balances := map[string]Decimal{}
+	walletTotals := map[string]Decimal{}
+	for _, walletID := range walletIDs {
+		isWallet[walletID] = true
+		for currency, bal := range s.state.wallets[walletID] {
+			scale := s.assetScale(currency)
+			available, hold := bal.Available.Floor(scale), bal.Hold.Floor(scale)
+			balances[ledgerAccountID(walletID, currency, ledgerKindAvailable)] = available
+			balances[ledgerAccountID(walletID, currency, ledgerKindHold)] = hold
+			walletTotals[currency] = walletTotals[currency].Add(available).Add(hold)
+		}
+	}
+
+	// The accounts outside the wallets, and what they hold per currency.
+	systemTotals := map[string]Decimal{}
+	for accountID := range sums {
+		walletID := ledgerAccountWallet(accountID)
+		if isWallet[walletID] {
+			if _, ok := balances[accountID]; !ok {
+				balances[accountID] = Decimal{}
+			}
+		} else {
+			parts := strings.Split(accountID, ":")
+			currency := parts[len(parts)-2]
+			systemTotals[currency] = systemTotals[currency].Add(ledgerAmount(accountID))
+		}
+		if strings.HasPrefix(accountID, "system:") {
+			check.SystemAccounts = append(check.SystemAccounts, LedgerSystemAccount{AccountID: accountID, Net: ledgerAmount(accountID)})
+		}
+	}
+	for accountID, balance := range balances {
+		if ledger := ledgerAmount(accountID); ledger.Cmp(balance) != 0 {
+			check.Mismatches = append(check.Mismatches, LedgerMismatch{AccountID: accountID, Balance: balance, Ledger: ledger})
+		}
+	}
+
+	// Custody took in what was deposited and paid out what was withdrawn.
+	custody := map[string]Decimal{}
+	for accountID := range sums {
+		if ledgerAccountWallet(accountID) == "system:custody" {
+			custody[accountID] = Decimal{}
+		}
+	}
+	for _, record := range s.state.deposits {
+		if record.Status == depositStatusCredited {
+			accountID := ledgerAccountID("system:custody", strings.ToUpper(record.Asset), ledgerKindAvailable)
+			custody[accountID] = custody[accountID].Sub(record.Amount.Floor(s.assetScale(record.Asset)))
+		}
+	}
+	for _, record := range s.state.withdrawals {
+		if record.Status == withdrawalStatusConfirmed {
+			accountID := ledgerAccountID("system:custody", strings.ToUpper(record.Asset), ledgerKindAvailable)
+			custody[accountID] = custody[accountID].Add(record.Amount.Floor(s.assetScale(record.Asset)))
+		}
+	}
+	for accountID, expected := range custody {
+		if ledger := ledgerAmount(accountID); ledger.Cmp(expected) != 0 {
+			check.Mismatches = append(check.Mismatches, LedgerMismatch{AccountID: accountID, Balance: expected, Ledger: ledger})
+		}
+	}
+
+	for currency := range systemTotals {
+		if _, ok := walletTotals[currency]; !ok {
+			walletTotals[currency] = decimalFromUnits(0, s.assetScale(currency))
+		}
+	}
+	for currency, wallets := range walletTotals {
+		system := systemTotals[currency].Add(decimalFromUnits(0, s.assetScale(currency)))
+		if wallets.Add(system).Sign() != 0 {
+			check.UnbalancedCurrencies = append(check.UnbalancedCurrencies, LedgerCurrencyTotal{Currency: currency, Wallets: wallets, System: system})
+		}
+	}
+
+	sort.Slice(check.Mismatches, func(i, j int) bool { return check.Mismatches[i].AccountID < check.Mismatches[j].AccountID })
+	sort.Slice(check.SystemAccounts, func(i, j int) bool { return check.SystemAccounts[i].AccountID < check.SystemAccounts[j].AccountID })
+	sort.Slice(check.UnbalancedCurrencies, func(i, j int) bool {
+		return check.UnbalancedCurrencies[i].Currency < check.UnbalancedCurrencies[j].Currency
+	})
+	check.OK = len(check.UnbalancedEntries) == 0 && len(check.Mismatches) == 0 && len(check.UnbalancedCurrencies) == 0
+	return check, nil
+}
+
+func (s *Server) handleCheckLedger(w http.ResponseWriter, r *http.Request) {
+	check, err := s.checkLedgerInvariant(r.Context())
+	if err != nil {
+		log.Printf("service=edge-gateway msg=ledger_check_failed err=%v", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "ledger_unavailable"})
+		return
+	}
+	if !check.OK {
+		log.Printf(
+			"service=edge-gateway msg=ledger_invariant_violated unbalanced_entries=%d mismatched_accounts=%d unbalanced_currencies=%d",
+			len(check.UnbalancedEntries), len(check.Mismatches), len(check.UnbalancedCurrencies),
+		)
+	}
+	writeJSON(w, http.StatusOK, check)
+}
@@ -0,0 +1,215 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	exchangev1 "github.com/quanta-exchange/exchange-platform/contracts/gen/go/exchange/v1"
+)
+
+func runLedgerCheck(t *testing.T, s *Server) LedgerCheck {
+	t.Helper()
+	w := adminRequest(t, s, http.MethodGet, "/v1/admin/ledger/check", "")
+	var out LedgerCheck
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &out) != nil {
+		t.Fatalf("ledger check failed: %d body=%s", w.Code, w.Body.String())
+	}
+	return out
+}
+
+func TestLedgerPostingsExplainEveryBalanceMovement(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"3"}`), "ledger-buy"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create order failed: %d body=%s", w.Code, w.Body.String())
+	}
+	trade := `{"tradeId":"ledger-t1","symbol":"BTC-KRW","seq":100,"makerOrderId":"ord_ledger-buy","takerOrderId":"ord_x","buyerUserId":"test-key","sellerUserId":"ledger-seller","price":100,"quantity":1,"feeBuyer":1,"feeSeller":2}`
+	if err := s.consumeTradeMessage(context.Background(), []byte(trade)); err != nil {
+		t.Fatalf("consume trade: %v", err)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_ledger-buy", nil, "ledger-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	list := func(query string) ([]LedgerEntryView, string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/ledger"+query, nil, ""))
+		var out struct {
+			Entries    []LedgerEntryView `json:"entries"`
+			NextCursor string            `json:"nextCursor"`
+		}
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &out) != nil {
+			t.Fatalf("list ledger %s failed: %d body=%s", query, w.Code, w.Body.String())
+		}
+		return out.Entries, out.NextCursor
+	}
+
+	entries, _ := list("")
+	var kinds []string
+	for _, entry := range entries {
+		kinds = append(kinds, entry.EntryKind)
+	}
+	if len(kinds) != 4 || kinds[0] != "OPENING_BALANCE" || kinds[1] != "RESERVE" || kinds[2] != "FILL" || kinds[3] != "RELEASE" {
+		t.Fatalf("unexpected entries: %v", kinds)
+	}
+	reserve := entries[1]
+	if reserve.ReferenceType != "ORDER" || reserve.ReferenceID != "ord_ledger-buy" || len(reserve.Postings) != 2 ||
+		reserve.Postings[0].AccountID != "user:test-key:KRW:AVAILABLE" || reserve.Postings[0].IsDebit || reserve.Postings[0].Amount.String() != "300" ||
+		reserve.Postings[1].AccountID != "user:test-key:KRW:HOLD" || !reserve.Postings[1].IsDebit {
+		t.Fatalf("unexpected reserve entry: %+v", reserve)
+	}
+
+	fills, _ := list("?currency=btc&referenceType=TRADE")
+	if len(fills) != 1 || len(fills[0].Postings) != 1 || fills[0].Postings[0].Amount.String() != "1.00000000" || !fills[0].Postings[0].IsDebit {
+		t.Fatalf("expected the BTC credit of the fill only, got %+v", fills)
+	}
+	page, cursor := list("?limit=2")
+	rest, last := list("?limit=2&cursor=" + cursor)
+	if len(page) != 2 || cursor == "" || len(rest) != 2 || rest[0].EntryKind != "FILL" || last != "" {
+		t.Fatalf("unexpected pages: %+v / %+v", page, rest)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/ledger?referenceType=BOGUS", nil, ""))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown reference type rejected, got %d", w.Code)
+	}
+
+	runCheck := func() LedgerCheck {
+		t.Helper()
+		return runLedgerCheck(t, s)
+	}
+	// test-key, the seller and the fee account.
+	if got := runCheck(); !got.OK || got.Wallets != 3 || got.SystemAccounts[0].AccountID != "system:fees:KRW:AVAILABLE" || got.SystemAccounts[0].Net.String() != "3" {
+		t.Fatalf("expected ledger consistent with balances, got %+v", got)
+	}
+
+	s.state.mu.Lock()
+	bal := s.state.wallets["test-key"]["KRW"]
+	bal.Available = bal.Available.Add(decimalFromInt(5))
+	s.state.wallets["test-key"]["KRW"] = bal
+	s.state.mu.Unlock()
+	got := runCheck()
+	if got.OK || len(got.Mismatches) != 1 || got.Mismatches[0].AccountID != "user:test-key:KRW:AVAILABLE" ||
+		got.Mismatches[0].Balance.Sub(got.Mismatches[0].Ledger).String() != "5" ||
+		len(got.UnbalancedCurrencies) != 1 || got.UnbalancedCurrencies[0].Currency != "KRW" {
+		t.Fatalf("expected the edited balance reported, got %+v", got)
+	}
+}
+
+func TestLedgerRefusesEntriesThatOnlyMoveMoneyButDoNotBalance(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	zero := s.assetAmount("KRW", 0)
+	credit := []walletChange{{
+		userID:   "test-key",
+		currency: "KRW",
+		before:   walletBalance{Available: zero, Hold: zero},
+		after:    walletBalance{Available: s.assetAmount("KRW", 100), Hold: zero},
+	}}
+	for _, op := range []string{walletOpReserve, walletOpRelease, walletOpSettle, walletOpTransfer} {
+		if _, err := s.buildLedgerEntry(op, "unbalanced", nil, credit); err == nil {
+			t.Fatalf("expected an unbalanced %s entry refused", op)
+		}
+	}
+	for _, op := range []string{walletOpOpen, walletOpDeposit, walletOpWithdraw} {
+		entry, err := s.buildLedgerEntry(op, "funded", nil, credit)
+		if err != nil || len(entry.Postings) != 2 {
+			t.Fatalf("expected %s balanced by its system account, got %v %v", op, entry, err)
+		}
+	}
+	external := map[string]Decimal{"KRW": s.assetAmount("KRW", -100)}
+	if entry, err := s.buildLedgerEntry(walletOpSettle, "one-sided", external, credit); err != nil || entry.Postings[1].AccountId != "system:counterparty:KRW:AVAILABLE" {
+		t.Fatalf("expected a one-sided trade balanced by the counterparty account, got %v %v", entry, err)
+	}
+	external["KRW"] = s.assetAmount("KRW", -90)
+	if _, err := s.buildLedgerEntry(walletOpSettle, "one-sided", external, credit); err == nil {
+		t.Fatalf("expected the counterparty to book only its own side")
+	}
+
+	// A seller short of the base it sold cannot be settled.
+	before := s.snapshotWallet("test-key")
+	trade := []byte(`{"tradeId":"short-t1","symbol":"BTC-KRW","seq":100,"makerOrderId":"ord_a","takerOrderId":"ord_b","buyerUserId":"test-key","sellerUserId":"short-seller","price":100,"quantity":5}`)
+	if err := s.consumeTradeMessage(context.Background(), trade); err == nil {
+		t.Fatalf("expected the short settlement refused")
+	}
+	if got := s.snapshotWallet("test-key"); !sameBalance(got["BTC"], before["BTC"]) || !sameBalance(got["KRW"], before["KRW"]) {
+		t.Fatalf("expected balances untouched, got %+v", got)
+	}
+}
+
+func TestLedgerCheckNetsTheSystemAccounts(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`), "sys-buy"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create order failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	// The seller is not settled here: the counterparty delivers the base and
+	// takes the quote, no more.
+	trade := `{"tradeId":"sys-t1","symbol":"BTC-KRW","seq":100,"makerOrderId":"ord_sys-buy","takerOrderId":"ord_x","buyerUserId":"test-key","price":100,"quantity":1,"feeBuyer":1}`
+	if err := s.consumeTradeMessage(context.Background(), []byte(trade)); err != nil {
+		t.Fatalf("consume trade: %v", err)
+	}
+	// A seller short of the base it sold is refused even against an
+	// external buyer.
+	short := `{"tradeId":"sys-t2","symbol":"BTC-KRW","seq":101,"makerOrderId":"ord_a","takerOrderId":"ord_b","sellerUserId":"sys-short","price":100,"quantity":5}`
+	if err := s.consumeTradeMessage(context.Background(), []byte(short)); err == nil {
+		t.Fatalf("expected the short one-sided settlement refused")
+	}
+
+	got := runLedgerCheck(t, s)
+	nets := map[string]string{}
+	for _, account := range got.SystemAccounts {
+		nets[account.AccountID] = account.Net.String()
+	}
+	// The treasury opened test-key and the short seller.
+	if !got.OK || nets["system:counterparty:BTC:AVAILABLE"] != "-1.00000000" ||
+		nets["system:counterparty:KRW:AVAILABLE"] != "100" || nets["system:fees:KRW:AVAILABLE"] != "1" ||
+		nets["system:treasury:BTC:AVAILABLE"] != "-4.00000000" {
+		t.Fatalf("expected the counterparty to net the external seller's side, got %+v", got)
+	}
+
+	// A credited deposit custody never booked.
+	s.state.mu.Lock()
+	s.state.deposits["dep_unbooked"] = DepositRecord{DepositID: "dep_unbooked", Asset: "BTC", Amount: decimalFromInt(1), Status: depositStatusCredited, UserID: "test-key"}
+	s.state.mu.Unlock()
+	got = runLedgerCheck(t, s)
+	if got.OK || len(got.Mismatches) != 1 || got.Mismatches[0].AccountID != "system:custody:BTC:AVAILABLE" ||
+		got.Mismatches[0].Balance.String() != "-1.00000000" || !got.Mismatches[0].Ledger.IsZero() {
+		t.Fatalf("expected custody checked against the deposits, got %+v", got)
+	}
+	s.state.mu.Lock()
+	delete(s.state.deposits, "dep_unbooked")
+	s.state.mu.Unlock()
+
+	// A system posting no wallet answers for leaves the books open.
+	s.state.mu.Lock()
+	s.state.ledger["system:treasury"] = append(s.state.ledger["system:treasury"], &exchangev1.LedgerEntryAppended{
+		EntryId: "le_stray",
+		Postings: []*exchangev1.LedgerPosting{
+			{AccountId: "system:treasury:KRW:AVAILABLE", Currency: "KRW", Amount: 7, IsDebit: true},
+			{AccountId: "system:counterparty:KRW:AVAILABLE", Currency: "KRW", Amount: 5},
+		},
+	})
+	s.state.mu.Unlock()
+	got = runLedgerCheck(t, s)
+	if got.OK || len(got.UnbalancedEntries) != 1 || len(got.UnbalancedCurrencies) != 1 ||
+		got.UnbalancedCurrencies[0].Wallets.Add(got.UnbalancedCurrencies[0].System).String() != "2" {
+		t.Fatalf("expected the stray posting to leave KRW open, got %+v", got)
+	}
+}
@@ -0,0 +1,560 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	exchangev1 "github.com/quanta-exchange/exchange-platform/contracts/gen/go/exchange/v1"
+)
+
+const (
+	orderStatusPendingEntry = "PENDING_ENTRY"
+	// orderStatusActivating marks a bracket exit whose placement with the
+	// core is in flight. It is neither held nor live: it cannot be canceled,
+	// and fills that beat the core's answer wait in activatingFills.
+	orderStatusActivating = "ACTIVATING"
+
+	groupRoleLimit      = "LIMIT"
+	groupRoleStop       = "STOP"
+	groupRoleEntry      = "ENTRY"
+	groupRoleTakeProfit = "TAKE_PROFIT"
+	groupRoleStopLoss   = "STOP_LOSS"
+)
+
+// OCOOrderRequest pairs a resting LIMIT leg with a stop leg on the same
+// symbol, side and quantity. Whichever leg fills or is canceled first
+// cancels the other.
+type OCOOrderRequest struct {
+	Symbol         string `json:"symbol"`
+	Side           string `json:"side"`
+	Qty            string `json:"qty"`
+	Price          string `json:"price"`
+	TimeInForce    string `json:"timeInForce"`
+	StopPrice      string `json:"stopPrice"`
+	StopLimitPrice string `json:"stopLimitPrice,omitempty"`
+}
+
+// BracketExit is one exit of a bracket: the take-profit uses Price, the
+// stop-loss uses StopPrice and becomes STOP_LIMIT when Price is set.
+type BracketExit struct {
+	Price     string `json:"price,omitempty"`
+	StopPrice string `json:"stopPrice,omitempty"`
+}
+
+type BracketOrderRequest struct {
+	Entry      OrderRequest `json:"entry"`
+	TakeProfit BracketExit  `json:"takeProfit"`
+	StopLoss   BracketExit  `json:"stopLoss"`
+}
+
+type OrderGroupResponse struct {
+	GroupID string          `json:"groupId"`
+	Orders  []OrderResponse `json:"orders"`
+}
+
+// handleCreateOCOOrder places a one-cancels-other pair as ord_{key}:limit
+// and ord_{key}:stop. Both legs sell or buy the same quantity, so the pair
+// reserves once, at the larger of the two requirements, on the LIMIT leg;
+// the stop takes that reserve over if it fires.
+func (s *Server) handleCreateOCOOrder(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key required"})
+		return
+	}
+	if status, body, ok := s.idempotencyGet(apiKey, idemKey, r.Method, r.URL.Path); ok {
+		writeRaw(w, status, body)
+		return
+	}
+
+	var req OCOOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	groupID := "grp_" + idemKey
+	limitLeg := OrderRequest{
+		Symbol:      req.Symbol,
+		Side:        req.Side,
+		Type:        "LIMIT",
+		Price:       req.Price,
+		Qty:         req.Qty,
+		TimeInForce: req.TimeInForce,
+		groupID:     groupID,
+		groupRole:   groupRoleLimit,
+	}
+	stopLeg := OrderRequest{
+		Symbol:    req.Symbol,
+		Side:      req.Side,
+		Type:      orderTypeStop,
+		Qty:       req.Qty,
+		StopPrice: req.StopPrice,
+		groupID:   groupID,
+		groupRole: groupRoleStop,
+	}
+	if strings.TrimSpace(req.StopLimitPrice) != "" {
+		stopLeg.Type = orderTypeStopLimit
+		stopLeg.Price = req.StopLimitPrice
+	}
+	for _, leg := range []OrderRequest{limitLeg, stopLeg} {
+		if err := s.validateOrderRequest(apiKey, leg); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if tif, _ := mapTimeInForce(req.TimeInForce); tif != exchangev1.TimeInForce_TIME_IN_FORCE_GTC {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "OCO limit leg must be GTC"})
+		return
+	}
+	// The LIMIT leg sits on the far side of the market from the stop: above
+	// it for sells (take profit over stop loss), below it for buys.
+	limitPrice, _ := strconv.ParseInt(strings.TrimSpace(req.Price), 10, 64)
+	stopPrice, _ := strconv.ParseInt(strings.TrimSpace(req.StopPrice), 10, 64)
+	if strings.ToUpper(strings.TrimSpace(req.Side)) == "SELL" && limitPrice <= stopPrice {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "SELL OCO price must be above stopPrice"})
+		return
+	}
+	if strings.ToUpper(strings.TrimSpace(req.Side)) == "BUY" && limitPrice >= stopPrice {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "BUY OCO price must be below stopPrice"})
+		return
+	}
+	if s.coreClient == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core_unavailable"})
+		return
+	}
+
+	currency, amount, err := s.linkedReserveRequirement(limitLeg, stopLeg)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	limitOrderID := "ord_" + idemKey + ":limit"
+	if _, err := s.applyReserve(apiKey, currency, amount, limitOrderID); err != nil {
+		writeReserveError(w, err)
+		return
+	}
+
+	// Hold the stop first so a fill on the LIMIT leg always finds it.
+	stopResp, err := s.holdConditionalOrder(r.Context(), apiKey, idemKey+":stop", stopLeg, "", Decimal{})
+	if err != nil {
+		s.releaseReserve(apiKey, currency, amount, limitOrderID)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	limitResp, err := s.placeOrderWithCore(r.Context(), apiKey, idemKey+":limit", limitLeg, currency, amount)
+	if err != nil {
+		s.cancelHeldOrder(OrderRecord{OrderID: stopResp.OrderID, Symbol: stopResp.Symbol})
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "core_unavailable"})
+		return
+	}
+	if limitResp.Status == "REJECTED" || limitResp.Status == "CANCELED" {
+		s.closeLinkedOrders(r.Context(), limitResp.OrderID)
+	}
+
+	status, body := marshalResponse(http.StatusOK, OrderGroupResponse{
+		GroupID: groupID,
+		Orders:  []OrderResponse{limitResp, s.orderResponseFor(stopResp.OrderID)},
+	})
+	s.idempotencySet(apiKey, idemKey, r.Method, r.URL.Path, status, body)
+	writeRaw(w, status, body)
+}
+
+// handleCreateBracketOrder places a LIMIT entry as ord_{key} with a
+// take-profit (ord_{key}:tp) and stop-loss (ord_{key}:sl) held as
+// PENDING_ENTRY. The exits go live as an OCO pair for the filled quantity
+// once the entry fills, or is canceled after a partial fill.
+func (s *Server) handleCreateBracketOrder(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key required"})
+		return
+	}
+	if status, body, ok := s.idempotencyGet(apiKey, idemKey, r.Method, r.URL.Path); ok {
+		writeRaw(w, status, body)
+		return
+	}
+
+	var req BracketOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	groupID := "grp_" + idemKey
+	entry := req.Entry
+	entry.groupID = groupID
+	entry.groupRole = groupRoleEntry
+	if err := s.validateOrderRequest(apiKey, entry); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errDuplicateClientOrderID) {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	if tif, _ := mapTimeInForce(entry.TimeInForce); strings.ToUpper(entry.Type) != "LIMIT" || tif != exchangev1.TimeInForce_TIME_IN_FORCE_GTC {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "bracket entry must be LIMIT GTC"})
+		return
+	}
+
+	exitSide := "SELL"
+	if strings.ToUpper(strings.TrimSpace(entry.Side)) == "SELL" {
+		exitSide = "BUY"
+	}
+	takeProfit := OrderRequest{
+		Symbol:    entry.Symbol,
+		Side:      exitSide,
+		Type:      "LIMIT",
+		Price:     req.TakeProfit.Price,
+		Qty:       entry.Qty,
+		groupID:   groupID,
+		groupRole: groupRoleTakeProfit,
+	}
+	stopLoss := OrderRequest{
+		Symbol:    entry.Symbol,
+		Side:      exitSide,
+		Type:      orderTypeStop,
+		Qty:       entry.Qty,
+		StopPrice: req.StopLoss.StopPrice,
+		groupID:   groupID,
+		groupRole: groupRoleStopLoss,
+	}
+	if strings.TrimSpace(req.StopLoss.Price) != "" {
+		stopLoss.Type = orderTypeStopLimit
+		stopLoss.Price = req.StopLoss.Price
+	}
+	for _, exit := range []OrderRequest{takeProfit, stopLoss} {
+		if err := s.validateOrderRequest(apiKey, exit); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	entryPrice, _ := strconv.ParseInt(strings.TrimSpace(entry.Price), 10, 64)
+	tpPrice, _ := strconv.ParseInt(strings.TrimSpace(takeProfit.Price), 10, 64)
+	slPrice, _ := strconv.ParseInt(strings.TrimSpace(stopLoss.StopPrice), 10, 64)
+	if exitSide == "SELL" && !(tpPrice > entryPrice && slPrice < entryPrice) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "BUY bracket needs takeProfit above and stopLoss below the entry price"})
+		return
+	}
+	if exitSide == "BUY" && !(tpPrice < entryPrice && slPrice > entryPrice) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "SELL bracket needs takeProfit below and stopLoss above the entry price"})
+		return
+	}
+	if s.coreClient == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core_unavailable"})
+		return
+	}
+
+	reserveCurrency, reserveAmount, err := s.tryReserveForOrder(apiKey, entry, "ord_"+idemKey)
+	if err != nil {
+		writeReserveError(w, err)
+		return
+	}
+	tpResp, _ := s.holdOrder(r.Context(), apiKey, idemKey+":tp", takeProfit, orderStatusPendingEntry, "", Decimal{})
+	slResp, _ := s.holdOrder(r.Context(), apiKey, idemKey+":sl", stopLoss, orderStatusPendingEntry, "", Decimal{})
+
+	entryResp, err := s.placeOrderWithCore(r.Context(), apiKey, idemKey, entry, reserveCurrency, reserveAmount)
+	if err != nil {
+		s.cancelHeldOrder(OrderRecord{OrderID: tpResp.OrderID, Symbol: tpResp.Symbol})
+		s.cancelHeldOrder(OrderRecord{OrderID: slResp.OrderID, Symbol: slResp.Symbol})
+		if errors.Is(err, errDuplicateClientOrderID) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "core_unavailable"})
+		return
+	}
+	if entryResp.Status == "REJECTED" || entryResp.Status == "CANCELED" {
+		s.closeLinkedOrders(r.Context(), entryResp.OrderID)
+	}
+
+	status, body := marshalResponse(http.StatusOK, OrderGroupResponse{
+		GroupID: groupID,
+		Orders: []OrderResponse{
+			entryResp,
+			s.orderResponseFor(tpResp.OrderID),
+			s.orderResponseFor(slResp.OrderID),
+		},
+	})
+	s.idempotencySet(apiKey, idemKey, r.Method, r.URL.Path, status, body)
+	writeRaw(w, status, body)
+}
+
+// linkedReserveRequirement is the single reserve shared by legs that can
+// never both execute: the largest of their individual requirements.
+func (s *Server) linkedReserveRequirement(legs ...OrderRequest) (string, Decimal, error) {
+	currency := ""
+	amount := Decimal{}
+	for _, leg := range legs {
+		legCurrency, legAmount, err := s.reserveRequirement(leg)
+		if err != nil {
+			return "", Decimal{}, err
+		}
+		if currency != "" && legCurrency != currency {
+			return "", Decimal{}, fmt.Errorf("linked legs must reserve the same currency")
+		}
+		currency = legCurrency
+		if legAmount.Cmp(amount) > 0 {
+			amount = legAmount
+		}
+	}
+	return currency, amount, nil
+}
+
+func (s *Server) orderResponseFor(orderID string) OrderResponse {
+	s.state.mu.Lock()
+	record := s.state.orders[orderID]
+	s.state.mu.Unlock()
+	return OrderResponse{
+		OrderID:       orderID,
+		ClientOrderID: record.ClientOrderID,
+		Status:        record.Status,
+		Symbol:        record.Symbol,
+		Seq:           record.Seq,
+		AcceptedAt:    record.AcceptedAt,
+		CanceledAt:    record.CanceledAt,
+	}
+}
+
+func (s *Server) linkedSiblings(record OrderRecord) []OrderRecord {
+	s.state.mu.Lock()
+	siblings := make([]OrderRecord, 0, 2)
+	for _, other := range s.state.orders {
+		if other.GroupID == record.GroupID && other.OrderID != record.OrderID && other.OwnerUserID == record.OwnerUserID {
+			siblings = append(siblings, other)
+		}
+	}
+	s.state.mu.Unlock()
+	sort.Slice(siblings, func(i, j int) bool { return siblings[i].OrderID < siblings[j].OrderID })
+	return siblings
+}
+
+// closeLinkedOrders applies group rules after orderID filled or closed. A
+// bracket entry that is done hands its filled quantity to the exits; any
+// other leg that filled or closed cancels the remaining legs.
+func (s *Server) closeLinkedOrders(ctx context.Context, orderID string) {
+	s.state.mu.Lock()
+	record, ok := s.state.orders[orderID]
+	s.state.mu.Unlock()
+	if !ok || record.GroupID == "" {
+		return
+	}
+
+	closed := record.Status == "CANCELED" || record.Status == "REJECTED"
+	if record.GroupRole == groupRoleEntry {
+		switch {
+		case record.Status == "FILLED" || (closed && record.FilledQty.Sign() > 0):
+			s.activateBracketExits(ctx, record)
+		case closed:
+			for _, sibling := range s.linkedSiblings(record) {
+				if sibling.Status == orderStatusPendingEntry {
+					s.cancelHeldOrder(sibling)
+				}
+			}
+		}
+		return
+	}
+	if !closed && record.FilledQty.Sign() <= 0 {
+		return
+	}
+	for _, sibling := range s.linkedSiblings(record) {
+		if sibling.GroupRole == groupRoleEntry || !isOpenOrderStatus(sibling.Status) {
+			continue
+		}
+		s.cancelLinkedLeg(ctx, sibling)
+	}
+}
+
+func (s *Server) cancelLinkedLeg(ctx context.Context, record OrderRecord) {
+	if isHeldOrderStatus(record.Status) {
+		s.cancelHeldOrder(record)
+		return
+	}
+	resp, err := s.cancelOnCore(ctx, record.OwnerUserID, record.OrderID+":linked-cancel", record, true)
+	if err != nil || resp.Status != "CANCELED" {
+		log.Printf(
+			"service=edge-gateway msg=linked_cancel_failed order_id=%s status=%s err=%v",
+			record.OrderID, resp.Status, err,
+		)
+	}
+}
+
+// takeLinkedReserve pulls the live legs of a firing stop's group off the
+// book and detaches their reserve for the stop's child. It also reports the
+// least open quantity left on them, so a partly filled take-profit shrinks
+// the stop. ok is false when a leg could not be canceled (it filled first).
+func (s *Server) takeLinkedReserve(ctx context.Context, stop OrderRecord) (string, Decimal, Decimal, bool) {
+	currency := ""
+	amount := Decimal{}
+	openQty := stop.Qty
+	for _, sibling := range s.linkedSiblings(stop) {
+		if sibling.GroupRole == groupRoleEntry || !isOpenOrderStatus(sibling.Status) {
+			continue
+		}
+		if isHeldOrderStatus(sibling.Status) {
+			s.cancelHeldOrder(sibling)
+			continue
+		}
+		resp, err := s.cancelOnCore(ctx, sibling.OwnerUserID, sibling.OrderID+":linked-cancel", sibling, false)
+		if err != nil || resp.Status != "CANCELED" {
+			return "", Decimal{}, Decimal{}, false
+		}
+		amount = amount.Add(s.detachOrderReserve(sibling.OrderID, resp.Seq, resp.CanceledAt))
+		currency = sibling.ReserveCurrency
+
+		s.state.mu.Lock()
+		current := s.state.orders[sibling.OrderID]
+		s.state.mu.Unlock()
+		openQty = minDecimal(openQty, current.Qty.Sub(current.FilledQty))
+	}
+	return currency, amount, openQty, true
+}
+
+// activateBracketExits turns the entry's PENDING_ENTRY exits into a live
+// OCO pair sized to the filled quantity. The take-profit is placed with the
+// shared reserve; the stop-loss then starts watching. The take-profit stays
+// in the order map as ACTIVATING while the core call is in flight.
+func (s *Server) activateBracketExits(ctx context.Context, entry OrderRecord) {
+	var takeProfit, stopLoss OrderRecord
+	var hasTakeProfit, hasStopLoss bool
+	s.state.mu.Lock()
+	for _, record := range s.state.orders {
+		if record.GroupID != entry.GroupID || record.Status != orderStatusPendingEntry {
+			continue
+		}
+		switch record.GroupRole {
+		case groupRoleTakeProfit:
+			takeProfit, hasTakeProfit = record, true
+		case groupRoleStopLoss:
+			stopLoss, hasStopLoss = record, true
+		}
+	}
+	if hasTakeProfit {
+		takeProfit.Status = orderStatusActivating
+		s.state.orders[takeProfit.OrderID] = takeProfit
+	}
+	s.state.mu.Unlock()
+	if !hasTakeProfit && !hasStopLoss {
+		return
+	}
+	if hasTakeProfit {
+		s.persistOrder(ctx, takeProfit)
+	}
+
+	qty := formatQty(entry.FilledQty)
+	legs := make([]OrderRequest, 0, 2)
+	var takeProfitReq OrderRequest
+	if hasTakeProfit {
+		takeProfitReq = OrderRequest{
+			Symbol:      takeProfit.Symbol,
+			Side:        takeProfit.Side,
+			Type:        "LIMIT",
+			Price:       takeProfit.Price,
+			Qty:         qty,
+			TimeInForce: takeProfit.TimeInForce,
+			groupID:     takeProfit.GroupID,
+			groupRole:   takeProfit.GroupRole,
+		}
+		legs = append(legs, takeProfitReq)
+	}
+	if hasStopLoss {
+		legs = append(legs, OrderRequest{
+			Symbol:    stopLoss.Symbol,
+			Side:      stopLoss.Side,
+			Type:      stopLoss.Type,
+			Price:     stopLoss.Price,
+			Qty:       qty,
+			StopPrice: strconv.FormatInt(stopLoss.Trigger.StopPrice, 10),
+		})
+	}
+
+	reserveRef := stopLoss.OrderID
+	if hasTakeProfit {
+		reserveRef = takeProfit.OrderID
+	}
+	currency, amount, err := s.linkedReserveRequirement(legs...)
+	if err == nil {
+		_, err = s.applyReserve(entry.OwnerUserID, currency, amount, reserveRef)
+	}
+	if err != nil {
+		log.Printf("service=edge-gateway msg=bracket_exit_reserve_failed group_id=%s err=%v", entry.GroupID, err)
+		if hasTakeProfit {
+			takeProfit.Status = "REJECTED"
+			s.state.mu.Lock()
+			s.state.orders[takeProfit.OrderID] = takeProfit
+			s.state.mu.Unlock()
+			s.persistOrder(ctx, takeProfit)
+		}
+		if hasStopLoss {
+			s.cancelHeldOrder(stopLoss)
+		}
+		return
+	}
+
+	if hasTakeProfit {
+		idemKey := strings.TrimPrefix(takeProfit.OrderID, "ord_")
+		resp, err := s.placeOrderWithCore(ctx, entry.OwnerUserID, idemKey, takeProfitReq, currency, amount)
+		if err != nil {
+			log.Printf("service=edge-gateway msg=bracket_exit_place_failed order_id=%s err=%v", takeProfit.OrderID, err)
+			takeProfit.Status = "REJECTED"
+			s.state.mu.Lock()
+			s.state.orders[takeProfit.OrderID] = takeProfit
+			delete(s.state.activatingFills, takeProfit.OrderID)
+			s.state.mu.Unlock()
+			s.persistOrder(ctx, takeProfit)
+		}
+		if err != nil || resp.Status == "REJECTED" || resp.Status == "CANCELED" {
+			if hasStopLoss {
+				s.cancelHeldOrder(stopLoss)
+			}
+			return
+		}
+	}
+	if !hasStopLoss {
+		return
+	}
+
+	s.state.mu.Lock()
+	current, ok := s.state.orders[stopLoss.OrderID]
+	activated := ok && current.Status == orderStatusPendingEntry
+	if activated {
+		current.Status = orderStatusPendingTrigger
+		current.Qty = entry.FilledQty
+		if !hasTakeProfit {
+			current.ReserveCurrency = currency
+			current.ReserveAmount = amount
+		}
+		s.state.orders[stopLoss.OrderID] = current
+	}
+	s.state.mu.Unlock()
+
+	if !activated {
+		// The stop-loss was canceled while the take-profit was being placed.
+		if hasTakeProfit {
+			s.closeLinkedOrders(ctx, stopLoss.OrderID)
+		} else {
+			s.releaseReserve(entry.OwnerUserID, currency, amount, reserveRef)
+		}
+		return
+	}
+	s.persistOrder(ctx, current)
+	if last, ok := s.lastTradePrice(current.Trigger.Symbol); ok && current.Trigger.reached(last) {
+		s.fireConditionalOrder(ctx, current.OrderID, last)
+	}
+}
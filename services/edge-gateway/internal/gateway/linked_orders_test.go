@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func createOrderGroup(t *testing.T, s *Server, path, body, idemKey string) OrderGroupResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, path, []byte(body), idemKey))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create %s failed: %d body=%s", idemKey, w.Code, w.Body.String())
+	}
+	var resp OrderGroupResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode group: %v", err)
+	}
+	return resp
+}
+
+func TestOCOLegsCancelEachOtherAndShareReserve(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	postSmokeTrade(t, s, "oco-t1", "BTC-KRW", 100)
+
+	group := createOrderGroup(t, s, "/v1/orders/oco",
+		`{"symbol":"BTC-KRW","side":"SELL","qty":"1","price":"120","stopPrice":"90"}`, "oco-1")
+	if group.GroupID != "grp_oco-1" || len(group.Orders) != 2 ||
+		group.Orders[0].Status != "ACCEPTED" || group.Orders[1].Status != orderStatusPendingTrigger {
+		t.Fatalf("unexpected OCO response: %+v", group)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "1.00000000" {
+		t.Fatalf("expected one shared 1 BTC reserve, got %+v", btc)
+	}
+
+	// The LIMIT leg fills, so the stop must go.
+	if err := s.consumeTradeMessage(context.Background(), []byte(
+		`{"tradeId":"oco-fill","symbol":"BTC-KRW","seq":50,"makerOrderId":"ord_oco-1:limit","takerOrderId":"ord_x","buyerUserId":"someone","sellerUserId":"test-key","price":120,"quantity":1}`,
+	)); err != nil {
+		t.Fatalf("consume trade: %v", err)
+	}
+	if stop := getOrderRecord(t, s, "ord_oco-1:stop"); stop.Status != "CANCELED" || stop.GroupRole != groupRoleStop {
+		t.Fatalf("expected stop leg canceled by the fill, got %+v", stop)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "0.00000000" || btc.Available.String() != "1.00000000" {
+		t.Fatalf("expected reserve settled, got %+v", btc)
+	}
+
+	// The stop fires first and takes the LIMIT leg's reserve.
+	createOrderGroup(t, s, "/v1/orders/oco",
+		`{"symbol":"BTC-KRW","side":"SELL","qty":"1","price":"130","stopPrice":"95"}`, "oco-2")
+	postSmokeTrade(t, s, "oco-t2", "BTC-KRW", 95)
+	if limit := getOrderRecord(t, s, "ord_oco-2:limit"); limit.Status != "CANCELED" {
+		t.Fatalf("expected LIMIT leg canceled by the trigger, got %s", limit.Status)
+	}
+	stop := getOrderRecord(t, s, "ord_oco-2:stop")
+	if stop.Status != orderStatusTriggered || stop.Trigger.ChildOrderID != "ord_oco-2:stop:trigger" {
+		t.Fatalf("expected stop leg triggered, got %+v", stop)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "1.00000000" || btc.Available.String() != "0.00000000" {
+		t.Fatalf("expected reserve carried to the stop's child, got %+v", btc)
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders/oco",
+		[]byte(`{"symbol":"BTC-KRW","side":"SELL","qty":"1","price":"80","stopPrice":"90"}`), "oco-bad"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected inverted OCO prices to be rejected, got %d", w.Code)
+	}
+}
+
+func TestBracketExitsActivateAfterEntryFills(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	postSmokeTrade(t, s, "brk-t1", "BTC-KRW", 100)
+
+	group := createOrderGroup(t, s, "/v1/orders/bracket", `{
+		"entry": {"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"2"},
+		"takeProfit": {"price":"120"},
+		"stopLoss": {"stopPrice":"90"}
+	}`, "brk-1")
+	if len(group.Orders) != 3 || group.Orders[0].OrderID != "ord_brk-1" ||
+		group.Orders[1].Status != orderStatusPendingEntry || group.Orders[2].Status != orderStatusPendingEntry {
+		t.Fatalf("unexpected bracket response: %+v", group)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "0.00000000" {
+		t.Fatalf("exits must not reserve before the entry fills, got %+v", btc)
+	}
+
+	// Trades before the entry fills never fire the waiting stop-loss.
+	postSmokeTrade(t, s, "brk-t2", "BTC-KRW", 85)
+	if sl := getOrderRecord(t, s, "ord_brk-1:sl"); sl.Status != orderStatusPendingEntry {
+		t.Fatalf("stop-loss fired before entry filled: %s", sl.Status)
+	}
+	postSmokeTrade(t, s, "brk-t3", "BTC-KRW", 100)
+
+	if err := s.consumeTradeMessage(context.Background(), []byte(
+		`{"tradeId":"brk-fill","symbol":"BTC-KRW","seq":60,"makerOrderId":"ord_brk-1","takerOrderId":"ord_x","buyerUserId":"test-key","sellerUserId":"someone","price":100,"quantity":2}`,
+	)); err != nil {
+		t.Fatalf("consume trade: %v", err)
+	}
+	tp := getOrderRecord(t, s, "ord_brk-1:tp")
+	sl := getOrderRecord(t, s, "ord_brk-1:sl")
+	if tp.Status != "ACCEPTED" || tp.Qty.Cmp(decimalFromInt(2)) != 0 || tp.GroupID != "grp_brk-1" || tp.GroupRole != groupRoleTakeProfit {
+		t.Fatalf("expected live take-profit, got %+v", tp)
+	}
+	if sl.Status != orderStatusPendingTrigger || sl.Qty.Cmp(decimalFromInt(2)) != 0 {
+		t.Fatalf("expected armed stop-loss, got %+v", sl)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "2.00000000" || btc.Available.String() != "2.00000000" {
+		t.Fatalf("expected exits to share one 2 BTC reserve, got %+v", btc)
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_brk-1:sl", nil, "brk-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel stop-loss failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if tp := getOrderRecord(t, s, "ord_brk-1:tp"); tp.Status != "CANCELED" {
+		t.Fatalf("expected take-profit canceled with its stop-loss, got %s", tp.Status)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "0.00000000" || btc.Available.String() != "4.00000000" {
+		t.Fatalf("expected exit reserve released, got %+v", btc)
+	}
+}
+
+func TestBracketCanceledEntryCancelsExits(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	createOrderGroup(t, s, "/v1/orders/bracket", `{
+		"entry": {"symbol":"BTC-KRW","side":"SELL","type":"LIMIT","price":"100","qty":"1"},
+		"takeProfit": {"price":"80"},
+		"stopLoss": {"stopPrice":"110","price":"115"}
+	}`, "brk-2")
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_brk-2", nil, "brk-2-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel entry failed: %d body=%s", w.Code, w.Body.String())
+	}
+	for _, orderID := range []string{"ord_brk-2:tp", "ord_brk-2:sl"} {
+		if exit := getOrderRecord(t, s, orderID); exit.Status != "CANCELED" {
+			t.Fatalf("expected %s canceled with its unfilled entry, got %s", orderID, exit.Status)
+		}
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "0.00000000" || btc.Available.String() != "2.00000000" {
+		t.Fatalf("expected entry reserve released, got %+v", btc)
+	}
+}
+
+func TestActivatingTakeProfitKeepsFillsThatBeatThePlacement(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// The take-profit as activateBracketExits leaves it while PlaceOrder is
+	// in flight.
+	s.state.mu.Lock()
+	s.state.orders["ord_act-1:tp"] = OrderRecord{
+		OrderID: "ord_act-1:tp", Status: orderStatusActivating, Symbol: "BTC-KRW", OwnerUserID: "test-key",
+		Side: "SELL", Type: "LIMIT", Price: "120", Qty: decimalFromInt(2), GroupID: "grp_act-1", GroupRole: groupRoleTakeProfit,
+	}
+	s.state.mu.Unlock()
+	if _, err := s.applyReserve("test-key", "BTC", decimalFromInt(2), "ord_act-1:tp"); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+
+	s.applyOrderFill("ord_act-1:tp", 1, 120, 70)
+	if tp := getOrderRecord(t, s, "ord_act-1:tp"); tp.Status != orderStatusActivating || !tp.FilledQty.IsZero() {
+		t.Fatalf("expected the fill held while activating, got %+v", tp)
+	}
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_act-1:tp", nil, "act-cancel"))
+	var canceled OrderResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &canceled) != nil || canceled.RejectCode != "ORDER_ACTIVATING" {
+		t.Fatalf("expected the cancel refused while activating, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	if _, err := s.placeOrderWithCore(ctx, "test-key", "act-1:tp", OrderRequest{
+		Symbol: "BTC-KRW", Side: "SELL", Type: "LIMIT", Price: "120", Qty: "2",
+		groupID: "grp_act-1", groupRole: groupRoleTakeProfit,
+	}, "BTC", decimalFromInt(2)); err != nil {
+		t.Fatalf("place take-profit: %v", err)
+	}
+	if tp := getOrderRecord(t, s, "ord_act-1:tp"); tp.Status != "PARTIALLY_FILLED" || tp.FilledQty.Cmp(decimalFromInt(1)) != 0 {
+		t.Fatalf("expected the held fill applied once live, got %+v", tp)
+	}
+}
@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// openLiveStore connects to the Postgres named by EDGE_TEST_DB_DSN, in a
+// schema of its own that is dropped when the test ends. Tests that need a
+// real store skip without it. They have not been run against a live
+// database yet.
+func openLiveStore(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("EDGE_TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("EDGE_TEST_DB_DSN not set")
+	}
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	schema := "edge_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		_ = admin.Close()
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		_ = admin.Close()
+	})
+
+	// lib/pq passes unknown DSN settings on as run-time parameters.
+	if parsed, err := url.Parse(dsn); err == nil && parsed.Scheme != "" {
+		query := parsed.Query()
+		query.Set("search_path", schema)
+		parsed.RawQuery = query.Encode()
+		dsn = parsed.String()
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open db in %s: %v", schema, err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestWalletTxThatFailsMidTransactionLeavesTheStoreAndMemoryUntouched(t *testing.T) {
+	db := openLiveStore(t)
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+	s.db = db
+	if err := s.initSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	// A check on web_transfers fails our insert after the balances, the
+	// journal and the ledger entry were written in the same transaction.
+	if _, err := db.Exec(`ALTER TABLE web_transfers ADD CONSTRAINT live_fail_memo CHECK (memo <> 'fail')`); err != nil {
+		t.Fatalf("add check: %v", err)
+	}
+	defer db.Exec(`ALTER TABLE web_transfers DROP CONSTRAINT IF EXISTS live_fail_memo`)
+	transfer := func(memo, idemKey string) (TransferRecord, error) {
+		return s.transferBalance(ctx, TransferRecord{FromUserID: "test-key", ToUserID: "live-recipient", Currency: "KRW",
+			Amount: s.assetAmount("KRW", 1000), Memo: memo, IdemKey: idemKey})
+	}
+	before := s.snapshotWallet("test-key")
+	beforeRecipient := s.snapshotWallet("live-recipient")
+	if _, err := transfer("fail", "live-1"); !errors.Is(err, errWalletUnavailable) {
+		t.Fatalf("expected the failed commit reported, got %v", err)
+	}
+	if got := s.snapshotWallet("test-key"); !sameBalance(got["KRW"], before["KRW"]) {
+		t.Fatalf("expected KRW untouched in memory, got %+v want %+v", got["KRW"], before["KRW"])
+	}
+	if got := s.snapshotWallet("live-recipient"); !sameBalance(got["KRW"], beforeRecipient["KRW"]) {
+		t.Fatalf("expected the recipient not credited in memory, got %+v", got["KRW"])
+	}
+	for table, query := range map[string]string{
+		"web_wallet_balances": `SELECT count(*) FROM web_wallet_balances WHERE user_id IN ('test-key', 'live-recipient')`,
+		"web_wallet_journal":  `SELECT count(*) FROM web_wallet_journal WHERE user_id IN ('test-key', 'live-recipient')`,
+		"web_ledger_entries":  `SELECT count(*) FROM web_ledger_entries WHERE reference_type = 'TRANSFER'`,
+		"web_transfers":       `SELECT count(*) FROM web_transfers`,
+	} {
+		var rows int
+		if err := db.QueryRow(query).Scan(&rows); err != nil {
+			t.Fatalf("count %s: %v", table, err)
+		}
+		if rows != 0 {
+			t.Fatalf("expected no rows in %s after the rollback, got %d", table, rows)
+		}
+	}
+
+	// Another gateway already made this transfer: its row answers the key.
+	if _, err := db.Exec(`
+		INSERT INTO web_transfers(transfer_id, from_user_id, to_user_id, currency, amount, memo, created_at_ms, idem_key)
+		VALUES ('tr_other', 'test-key', 'live-recipient', 'KRW', 1000, '', 1, 'live-2')
+	`); err != nil {
+		t.Fatalf("seed transfer: %v", err)
+	}
+	if again, err := transfer("", "live-2"); err != nil || again.TransferID != "tr_other" {
+		t.Fatalf("expected the other gateway's transfer returned, got %+v %v", again, err)
+	}
+}
+
+func TestTradeSettlesAgainstACounterpartyLoadedFromStoredRows(t *testing.T) {
+	db := openLiveStore(t)
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	ctx := context.Background()
+	s.db = db
+	if err := s.initSchema(ctx); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	// The seller's wallet exists only in the store, as after a restart.
+	if _, err := db.Exec(`
+		INSERT INTO web_wallet_balances(user_id, currency, available, hold)
+		VALUES ('live-seller', 'BTC', 1.5, 0)
+	`); err != nil {
+		t.Fatalf("seed seller: %v", err)
+	}
+	trade := []byte(`{"tradeId":"live-t1","symbol":"BTC-KRW","seq":1,"makerOrderId":"ord_a","takerOrderId":"ord_b","buyerUserId":"test-key","sellerUserId":"live-seller","price":100,"quantity":1}`)
+	if err := s.consumeTradeMessage(ctx, trade); err != nil {
+		t.Fatalf("settle trade: %v", err)
+	}
+
+	seller := s.snapshotWallet("live-seller")
+	half, _ := parseDecimal("0.5")
+	if seller["BTC"].Available.Cmp(half) != 0 || seller["KRW"].Available.Cmp(decimalFromInt(100)) != 0 {
+		t.Fatalf("expected the seller debited from its stored balance, got %+v", seller)
+	}
+	stored := map[string]Decimal{}
+	rows, err := db.Query(`SELECT currency, available FROM web_wallet_balances WHERE user_id = 'live-seller'`)
+	if err != nil {
+		t.Fatalf("read seller rows: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var currency string
+		var available Decimal
+		if err := rows.Scan(&currency, &available); err != nil {
+			t.Fatalf("scan seller row: %v", err)
+		}
+		stored[currency] = available
+	}
+	if stored["BTC"].Cmp(seller["BTC"].Available) != 0 || stored["KRW"].Cmp(seller["KRW"].Available) != 0 {
+		t.Fatalf("expected the seller's rows updated, got %+v", stored)
+	}
+
+	w := adminRequest(t, s, http.MethodGet, "/v1/admin/ledger/check", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok":true`) {
+		t.Fatalf("expected the ledger consistent, got %d body=%s", w.Code, w.Body.String())
+	}
+}
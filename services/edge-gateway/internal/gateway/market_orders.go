@@ -0,0 +1,258 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxSlippagePercent = 100
+
+// bookLevel is one price level of the cached order book snapshot. A nil
+// qty is a level of unlimited depth.
+type bookLevel struct {
+	price *big.Rat
+	qty   *big.Rat
+}
+
+// bookSide returns the cached levels of one side of symbol's book, best
+// first. Without a book the last trade stands in as a single level of
+// unlimited depth; with neither it returns nil.
+func (s *Server) bookSide(symbol, side string) []bookLevel {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if payload, ok := s.cacheGet(ctx, cacheKey("book", symbol)); ok {
+		var msg struct {
+			Data struct {
+				Bids [][]string `json:"bids"`
+				Asks [][]string `json:"asks"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(payload, &msg); err == nil {
+			raws := msg.Data.Bids
+			if side == "asks" {
+				raws = msg.Data.Asks
+			}
+			levels := make([]bookLevel, 0, len(raws))
+			for _, raw := range raws {
+				if len(raw) < 2 {
+					continue
+				}
+				price, pok := parsePositiveRat(raw[0])
+				qty, qok := parsePositiveRat(raw[1])
+				if !pok || !qok {
+					continue
+				}
+				levels = append(levels, bookLevel{price: price, qty: qty})
+			}
+			if len(levels) > 0 {
+				return levels
+			}
+		}
+	}
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	tape := s.state.tradeTape[symbol]
+	if len(tape) == 0 {
+		return nil
+	}
+	return []bookLevel{{price: new(big.Rat).SetInt64(tape[len(tape)-1].price)}}
+}
+
+// walkAsks prices a buy against ask levels no dearer than limit (nil for no
+// limit), stopping once qty is bought or, when qty is nil, once quote is
+// spent. It returns what was bought, what it cost and the deepest price
+// touched, nil when nothing was.
+func walkAsks(asks []bookLevel, qty, quote, limit *big.Rat) (bought, cost, deepest *big.Rat) {
+	bought, cost = new(big.Rat), new(big.Rat)
+	for _, level := range asks {
+		if limit != nil && level.price.Cmp(limit) > 0 {
+			break
+		}
+		var take *big.Rat
+		if qty != nil {
+			take = new(big.Rat).Sub(qty, bought)
+		} else {
+			take = new(big.Rat).Sub(quote, cost)
+			take.Quo(take, level.price)
+		}
+		if level.qty != nil && level.qty.Cmp(take) < 0 {
+			take = level.qty
+		}
+		if take.Sign() <= 0 {
+			break
+		}
+		bought.Add(bought, take)
+		cost.Add(cost, new(big.Rat).Mul(take, level.price))
+		deepest = level.price
+	}
+	return bought, cost, deepest
+}
+
+// validateMarketOrderOptions checks quoteQty and maxSlippagePercent, which
+// only plain MARKET orders take.
+func validateMarketOrderOptions(req OrderRequest) error {
+	isMarket := strings.ToUpper(strings.TrimSpace(req.Type)) == "MARKET"
+	if strings.TrimSpace(req.QuoteQty) != "" {
+		if !isMarket || strings.ToUpper(strings.TrimSpace(req.Side)) != "BUY" {
+			return fmt.Errorf("quoteQty requires MARKET BUY")
+		}
+		if strings.TrimSpace(req.Qty) != "" {
+			return fmt.Errorf("qty and quoteQty are mutually exclusive")
+		}
+		if _, ok := parsePositiveRat(req.QuoteQty); !ok {
+			return fmt.Errorf("invalid quoteQty")
+		}
+	}
+	if req.MaxSlippagePercent != 0 {
+		if !isMarket {
+			return fmt.Errorf("maxSlippagePercent requires MARKET")
+		}
+		if req.MaxSlippagePercent < 0 || req.MaxSlippagePercent > maxSlippagePercent || math.IsNaN(req.MaxSlippagePercent) {
+			return fmt.Errorf("maxSlippagePercent must be between 0 and %d", maxSlippagePercent)
+		}
+	}
+	return nil
+}
+
+// planMarketOrder sizes a MARKET order against the book before funds are
+// reserved. Every MARKET buy goes to the core as LIMIT IOC, so it can never
+// pay more than it reserved. A quoteQty buy becomes a base quantity, rounded
+// down to the lot, that the walked asks sell for at most quoteQty; it
+// reserves quoteQty and is capped at the deepest ask it needs. With
+// maxSlippagePercent the order is capped at the best price moved by that
+// much; other buys are capped at the deepest ask their qty walks to. A buy
+// reserves qty at its cap.
+func (s *Server) planMarketOrder(req OrderRequest) (OrderRequest, error) {
+	if strings.ToUpper(strings.TrimSpace(req.Type)) != "MARKET" {
+		return req, nil
+	}
+	m, ok := s.registry.market(req.Symbol)
+	if !ok {
+		return req, fmt.Errorf("UNKNOWN_SYMBOL")
+	}
+	buy := strings.ToUpper(strings.TrimSpace(req.Side)) == "BUY"
+	if !buy && req.MaxSlippagePercent == 0 {
+		return req, nil
+	}
+	side := "bids"
+	if buy {
+		side = "asks"
+	}
+	levels := s.bookSide(req.Symbol, side)
+	if len(levels) == 0 {
+		return req, fmt.Errorf("price_unavailable")
+	}
+
+	var limit *big.Rat
+	if req.MaxSlippagePercent > 0 {
+		slippage, _ := new(big.Rat).SetString(strconv.FormatFloat(req.MaxSlippagePercent, 'f', -1, 64))
+		slippage.Quo(slippage, big.NewRat(100, 1))
+		if buy {
+			factor := new(big.Rat).Add(big.NewRat(1, 1), slippage)
+			limit = roundToTick(m, factor.Mul(factor, levels[0].price), false)
+		} else {
+			factor := new(big.Rat).Sub(big.NewRat(1, 1), slippage)
+			limit = roundToTick(m, factor.Mul(factor, levels[0].price), true)
+		}
+		if limit == nil {
+			return req, fmt.Errorf("maxSlippagePercent too wide")
+		}
+		req.protectPrice = formatTickPrice(m, limit)
+	}
+	if !buy {
+		return req, nil
+	}
+	scale := s.assetScale(m.spec.Quote)
+
+	if quote := strings.TrimSpace(req.QuoteQty); quote != "" {
+		budget, _ := parsePositiveRat(quote)
+		bought, _, deepest := walkAsks(levels, nil, budget, limit)
+		// The cap must reach the deepest ask, and qty at the cap must fit
+		// the budget.
+		deepest = roundToTick(m, deepest, true)
+		qty := floorToLot(m, bought)
+		if deepest != nil && new(big.Rat).Mul(qty, deepest).Cmp(budget) > 0 {
+			qty = floorToLot(m, new(big.Rat).Quo(budget, deepest))
+		}
+		if deepest == nil || qty.Sign() <= 0 || qty.Cmp(m.minQty) < 0 {
+			return req, fmt.Errorf("INSUFFICIENT_LIQUIDITY")
+		}
+		req.Qty = formatStep(qty, m.lotSize)
+		req.protectPrice = formatTickPrice(m, deepest)
+		req.marketReserve, _ = decimalFromRat(budget, scale, false)
+		return req, nil
+	}
+
+	qty, ok := parsePositiveRat(req.Qty)
+	if !ok {
+		return req, fmt.Errorf("invalid qty")
+	}
+	if limit == nil {
+		// Whatever lies beyond the cached depth is left unfilled.
+		_, _, deepest := walkAsks(levels, qty, nil, nil)
+		if limit = roundToTick(m, deepest, true); limit == nil {
+			return req, fmt.Errorf("price_unavailable")
+		}
+		req.protectPrice = formatTickPrice(m, limit)
+	}
+	reserve, ok := decimalFromRat(new(big.Rat).Mul(qty, limit), scale, true)
+	if !ok {
+		return req, fmt.Errorf("invalid qty")
+	}
+	req.marketReserve = reserve
+	return req, nil
+}
+
+// roundToTick rounds price to the tick that applies to it, up or down. It
+// returns nil for a missing or non-positive result.
+func roundToTick(m market, price *big.Rat, up bool) *big.Rat {
+	if price == nil || price.Sign() <= 0 {
+		return nil
+	}
+	rounded := roundToStep(price, m.tickSize(price), up)
+	if rounded.Sign() <= 0 {
+		return nil
+	}
+	return rounded
+}
+
+func floorToLot(m market, qty *big.Rat) *big.Rat {
+	if qty == nil || qty.Sign() <= 0 {
+		return new(big.Rat)
+	}
+	return roundToStep(qty, m.lotSize, false)
+}
+
+func roundToStep(value, step *big.Rat, up bool) *big.Rat {
+	steps := new(big.Rat).Quo(value, step)
+	n := new(big.Int).Quo(steps.Num(), steps.Denom())
+	if up && !steps.IsInt() {
+		n.Add(n, big.NewInt(1))
+	}
+	return new(big.Rat).Mul(new(big.Rat).SetInt(n), step)
+}
+
+func formatTickPrice(m market, price *big.Rat) string {
+	return formatStep(roundToStep(price, m.tickSize(price), false), m.tickSize(price))
+}
+
+// formatStep prints value with as many decimals as step has, trimming
+// trailing zeros.
+func formatStep(value, step *big.Rat) string {
+	decimals := 0
+	for d := new(big.Int).Set(step.Denom()); d.Cmp(big.NewInt(1)) > 0; d.Quo(d, big.NewInt(10)) {
+		decimals++
+	}
+	out := value.FloatString(decimals)
+	if strings.Contains(out, ".") {
+		out = strings.TrimRight(strings.TrimRight(out, "0"), ".")
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQuoteQtyMarketBuyReservesBudgetAndRefundsRemainder(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	// The smoke trade leaves a book with asks of 20 @ 501, 39 @ 502, ...
+	postSmokeTrade(t, s, "mkt-t1", "BTC-KRW", 500)
+
+	for name, body := range map[string]string{
+		"quote-on-limit": `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"500","quoteQty":"10020"}`,
+		"quote-on-sell":  `{"symbol":"BTC-KRW","side":"SELL","type":"MARKET","quoteQty":"10020"}`,
+		"quote-and-qty":  `{"symbol":"BTC-KRW","side":"BUY","type":"MARKET","qty":"1","quoteQty":"10020"}`,
+		"slip-on-limit":  `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"500","qty":"1","maxSlippagePercent":1}`,
+	} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(body), "mkt-"+name))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body=%s", name, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"MARKET","quoteQty":"10020"}`), "mkt-quote"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("quoteQty buy failed: %d body=%s", w.Code, w.Body.String())
+	}
+	record := getOrderRecord(t, s, "ord_mkt-quote")
+	if record.Qty.Cmp(decimalFromInt(20)) != 0 || record.QuoteQty != "10020" || record.Price != "501" || record.TimeInForce != "IOC" {
+		t.Fatalf("expected 20 BTC at no worse than 501, got %+v", record)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "10020" {
+		t.Fatalf("expected the quote budget reserved, got %+v", krw)
+	}
+
+	if err := s.consumeTradeMessage(context.Background(), []byte(
+		`{"tradeId":"mkt-fill","symbol":"BTC-KRW","seq":100,"makerOrderId":"ord_maker","takerOrderId":"ord_mkt-quote","buyerUserId":"test-key","price":500,"quantity":20}`,
+	)); err != nil {
+		t.Fatalf("consume fill: %v", err)
+	}
+	if record := getOrderRecord(t, s, "ord_mkt-quote"); record.Status != "FILLED" {
+		t.Fatalf("expected order filled, got %s", record.Status)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "49990000" {
+		t.Fatalf("expected unspent budget refunded, got %+v", krw)
+	}
+}
+
+func TestMarketOrdersReserveFromBookDepthAndSlippageLimit(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	postSmokeTrade(t, s, "mkt-t2", "BTC-KRW", 500)
+	place := func(key, body string) OrderRecord {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(body), key))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s failed: %d body=%s", key, w.Code, w.Body.String())
+		}
+		return getOrderRecord(t, s, "ord_"+key)
+	}
+
+	// 30 BTC walks 20 @ 501 and 10 @ 502, so it is capped at 502.
+	before := s.snapshotWallet("test-key")["KRW"].Hold
+	record := place("mkt-walk", `{"symbol":"BTC-KRW","side":"BUY","type":"MARKET","qty":"30"}`)
+	if record.Price != "502" || record.TimeInForce != "IOC" {
+		t.Fatalf("expected IOC capped at the deepest walked ask, got %+v", record)
+	}
+	if hold := s.snapshotWallet("test-key")["KRW"].Hold.Sub(before); hold.String() != "15060" {
+		t.Fatalf("expected reserve of 30 at the cap, got %v", hold)
+	}
+
+	// 1% over the best ask of 501 is 506.01, down to the 1 KRW tick.
+	before = s.snapshotWallet("test-key")["KRW"].Hold
+	record = place("mkt-slip", `{"symbol":"BTC-KRW","side":"BUY","type":"MARKET","qty":"10","maxSlippagePercent":1}`)
+	if record.Price != "506" || record.TimeInForce != "IOC" || record.Type != "MARKET" {
+		t.Fatalf("expected IOC protected at 506, got %+v", record)
+	}
+	if hold := s.snapshotWallet("test-key")["KRW"].Hold.Sub(before); hold.String() != "5060" {
+		t.Fatalf("expected reserve at the protection price, got %v", hold)
+	}
+
+	// 1% under the best bid of 499 is 494.01, up to the 1 KRW tick.
+	record = place("mkt-slip-sell", `{"symbol":"BTC-KRW","side":"SELL","type":"MARKET","qty":"1","maxSlippagePercent":1}`)
+	if record.Price != "495" || record.TimeInForce != "IOC" {
+		t.Fatalf("expected sell protected at 495, got %+v", record)
+	}
+}
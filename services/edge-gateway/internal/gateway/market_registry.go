@@ -0,0 +1,376 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	marketStatusTrading  = "TRADING"
+	marketStatusDisabled = "DISABLED"
+)
+
+// MarketSpec describes a tradable market. Decimal fields are strings so a
+// registry file keeps exact values; TickBands pick the tick size from the
+// order price, the last band whose minPrice is at or below it winning.
+type MarketSpec struct {
+	Symbol      string     `json:"symbol"`
+	Base        string     `json:"base"`
+	Quote       string     `json:"quote"`
+	Status      string     `json:"status"`
+	TickBands   []TickBand `json:"tickBands"`
+	LotSize     string     `json:"lotSize"`
+	MinQty      string     `json:"minQty"`
+	MaxQty      string     `json:"maxQty"`
+	MinNotional string     `json:"minNotional"`
+}
+
+type TickBand struct {
+	MinPrice string `json:"minPrice"`
+	TickSize string `json:"tickSize"`
+}
+
+type AssetSpec struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Decimals int    `json:"decimals"`
+}
+
+// RegistryFile is the JSON document Config.RegistryFile points at.
+type RegistryFile struct {
+	Markets []MarketSpec `json:"markets"`
+	Assets  []AssetSpec  `json:"assets"`
+}
+
+type tickBand struct {
+	minPrice *big.Rat
+	tickSize *big.Rat
+}
+
+type market struct {
+	spec        MarketSpec
+	tickBands   []tickBand
+	lotSize     *big.Rat
+	minQty      *big.Rat
+	maxQty      *big.Rat
+	minNotional *big.Rat
+}
+
+// registry is built once in New and never mutated, so it is read without
+// holding the state lock.
+type registry struct {
+	markets     map[string]market
+	marketOrder []string
+	assets      map[string]AssetSpec
+	assetOrder  []string
+}
+
+// krwTickBands is the price-dependent tick table of KRW markets.
+func krwTickBands() []TickBand {
+	return []TickBand{
+		{MinPrice: "0", TickSize: "0.0001"},
+		{MinPrice: "0.1", TickSize: "0.001"},
+		{MinPrice: "1", TickSize: "0.01"},
+		{MinPrice: "10", TickSize: "0.1"},
+		{MinPrice: "100", TickSize: "1"},
+		{MinPrice: "1000", TickSize: "5"},
+		{MinPrice: "10000", TickSize: "10"},
+		{MinPrice: "100000", TickSize: "50"},
+		{MinPrice: "500000", TickSize: "100"},
+		{MinPrice: "1000000", TickSize: "500"},
+		{MinPrice: "2000000", TickSize: "1000"},
+	}
+}
+
+// defaultMarkets are the markets served when no registry file is configured.
+func defaultMarkets() []MarketSpec {
+	krw := func(base, lot, maxQty string) MarketSpec {
+		return MarketSpec{
+			Symbol:      base + "-KRW",
+			Base:        base,
+			Quote:       "KRW",
+			Status:      marketStatusTrading,
+			TickBands:   krwTickBands(),
+			LotSize:     lot,
+			MinQty:      lot,
+			MaxQty:      maxQty,
+			MinNotional: "5000",
+		}
+	}
+	return []MarketSpec{
+		krw("BTC", "0.00000001", "10000"),
+		krw("ETH", "0.00000001", "100000"),
+		krw("SOL", "0.00000001", "1000000"),
+		krw("XRP", "0.000001", "100000000"),
+		krw("BNB", "0.00000001", "1000000"),
+	}
+}
+
+func defaultAssets() []AssetSpec {
+	return []AssetSpec{
+		{Symbol: "KRW", Name: "Korean Won", Decimals: 0},
+		{Symbol: "BTC", Name: "Bitcoin", Decimals: 8},
+		{Symbol: "ETH", Name: "Ethereum", Decimals: 8},
+		{Symbol: "SOL", Name: "Solana", Decimals: 8},
+		{Symbol: "XRP", Name: "XRP", Decimals: 6},
+		{Symbol: "BNB", Name: "BNB", Decimals: 8},
+	}
+}
+
+// loadRegistry builds the registry from cfg: the registry file when one is
+// set, else cfg.Markets and cfg.Assets, else the defaults.
+func loadRegistry(cfg Config) (*registry, error) {
+	markets, assets := cfg.Markets, cfg.Assets
+	if cfg.RegistryFile != "" {
+		raw, err := os.ReadFile(cfg.RegistryFile)
+		if err != nil {
+			return nil, err
+		}
+		var file RegistryFile
+		if err := json.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", cfg.RegistryFile, err)
+		}
+		markets, assets = file.Markets, file.Assets
+	}
+	if len(markets) == 0 {
+		markets = defaultMarkets()
+	}
+	if len(assets) == 0 {
+		assets = defaultAssets()
+	}
+
+	reg := &registry{markets: map[string]market{}, assets: map[string]AssetSpec{}}
+	for _, asset := range assets {
+		asset.Symbol = strings.ToUpper(strings.TrimSpace(asset.Symbol))
+		if asset.Symbol == "" || asset.Decimals < 0 || asset.Decimals > maxDecimalScale {
+			return nil, fmt.Errorf("asset %q: symbol and 0 to %d decimals required", asset.Symbol, maxDecimalScale)
+		}
+		if _, dup := reg.assets[asset.Symbol]; dup {
+			return nil, fmt.Errorf("asset %s listed twice", asset.Symbol)
+		}
+		reg.assets[asset.Symbol] = asset
+		reg.assetOrder = append(reg.assetOrder, asset.Symbol)
+	}
+	for _, spec := range markets {
+		m, err := compileMarket(spec, reg.assets)
+		if err != nil {
+			return nil, fmt.Errorf("market %q: %w", spec.Symbol, err)
+		}
+		if _, dup := reg.markets[m.spec.Symbol]; dup {
+			return nil, fmt.Errorf("market %s listed twice", m.spec.Symbol)
+		}
+		reg.markets[m.spec.Symbol] = m
+		reg.marketOrder = append(reg.marketOrder, m.spec.Symbol)
+	}
+	return reg, nil
+}
+
+func compileMarket(spec MarketSpec, assets map[string]AssetSpec) (market, error) {
+	spec.Symbol = strings.ToUpper(strings.TrimSpace(spec.Symbol))
+	spec.Base = strings.ToUpper(strings.TrimSpace(spec.Base))
+	spec.Quote = strings.ToUpper(strings.TrimSpace(spec.Quote))
+	spec.Status = strings.ToUpper(strings.TrimSpace(spec.Status))
+	if spec.Status == "" {
+		spec.Status = marketStatusTrading
+	}
+	if spec.Status != marketStatusTrading && spec.Status != marketStatusDisabled {
+		return market{}, fmt.Errorf("invalid status %q", spec.Status)
+	}
+	if spec.Symbol != spec.Base+"-"+spec.Quote {
+		return market{}, fmt.Errorf("symbol must be BASE-QUOTE")
+	}
+	for _, asset := range []string{spec.Base, spec.Quote} {
+		if _, ok := assets[asset]; !ok {
+			return market{}, fmt.Errorf("unknown asset %s", asset)
+		}
+	}
+
+	m := market{spec: spec}
+	var ok bool
+	if m.lotSize, ok = parsePositiveRat(spec.LotSize); !ok {
+		return market{}, fmt.Errorf("invalid lotSize")
+	}
+	if m.minQty, ok = parsePositiveRat(spec.MinQty); !ok {
+		return market{}, fmt.Errorf("invalid minQty")
+	}
+	if spec.MaxQty != "" {
+		if m.maxQty, ok = parsePositiveRat(spec.MaxQty); !ok || m.maxQty.Cmp(m.minQty) < 0 {
+			return market{}, fmt.Errorf("invalid maxQty")
+		}
+	}
+	if spec.MinNotional != "" {
+		if m.minNotional, ok = parseRat(spec.MinNotional); !ok || m.minNotional.Sign() < 0 {
+			return market{}, fmt.Errorf("invalid minNotional")
+		}
+	}
+	if len(spec.TickBands) == 0 {
+		return market{}, fmt.Errorf("tickBands required")
+	}
+	for i, band := range spec.TickBands {
+		minPrice, ok := parseRat(band.MinPrice)
+		if !ok || minPrice.Sign() < 0 {
+			return market{}, fmt.Errorf("tick band %d: invalid minPrice", i)
+		}
+		tick, ok := parsePositiveRat(band.TickSize)
+		if !ok {
+			return market{}, fmt.Errorf("tick band %d: invalid tickSize", i)
+		}
+		if i == 0 && minPrice.Sign() != 0 {
+			return market{}, fmt.Errorf("first tick band must start at 0")
+		}
+		if i > 0 && minPrice.Cmp(m.tickBands[i-1].minPrice) <= 0 {
+			return market{}, fmt.Errorf("tick bands must be sorted by minPrice")
+		}
+		m.tickBands = append(m.tickBands, tickBand{minPrice: minPrice, tickSize: tick})
+	}
+	return m, nil
+}
+
+func parseRat(raw string) (*big.Rat, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.ContainsAny(raw, "/eE") {
+		return nil, false
+	}
+	r, ok := new(big.Rat).SetString(raw)
+	return r, ok
+}
+
+func parsePositiveRat(raw string) (*big.Rat, bool) {
+	r, ok := parseRat(raw)
+	if !ok || r.Sign() <= 0 {
+		return nil, false
+	}
+	return r, true
+}
+
+func isMultipleOf(value, step *big.Rat) bool {
+	return new(big.Rat).Quo(value, step).IsInt()
+}
+
+func (m market) tickSize(price *big.Rat) *big.Rat {
+	tick := m.tickBands[0].tickSize
+	for _, band := range m.tickBands {
+		if price.Cmp(band.minPrice) < 0 {
+			break
+		}
+		tick = band.tickSize
+	}
+	return tick
+}
+
+func (r *registry) market(symbol string) (market, bool) {
+	m, ok := r.markets[strings.ToUpper(strings.TrimSpace(symbol))]
+	return m, ok
+}
+
+// validatePrice checks a price against the market's tick table.
+func (m market) validatePrice(raw string) error {
+	price, ok := parsePositiveRat(raw)
+	if !ok {
+		return fmt.Errorf("invalid price")
+	}
+	if !isMultipleOf(price, m.tickSize(price)) {
+		return fmt.Errorf("INVALID_TICK_SIZE")
+	}
+	return nil
+}
+
+// validateQty checks a quantity against the market's lot size and bounds.
+func (m market) validateQty(raw string) error {
+	qty, ok := parsePositiveRat(raw)
+	if !ok {
+		return fmt.Errorf("invalid qty")
+	}
+	if !isMultipleOf(qty, m.lotSize) {
+		return fmt.Errorf("INVALID_LOT_SIZE")
+	}
+	if qty.Cmp(m.minQty) < 0 {
+		return fmt.Errorf("QTY_BELOW_MIN")
+	}
+	if m.maxQty != nil && qty.Cmp(m.maxQty) > 0 {
+		return fmt.Errorf("QTY_ABOVE_MAX")
+	}
+	return nil
+}
+
+// validateMarketRules checks an order against its market's registry entry.
+// Orders without a limit price (market and stop orders) skip the tick and
+// notional checks; the core prices them.
+func (s *Server) validateMarketRules(req OrderRequest) error {
+	m, ok := s.registry.market(req.Symbol)
+	if !ok {
+		return fmt.Errorf("UNKNOWN_SYMBOL")
+	}
+	if m.spec.Status != marketStatusTrading {
+		return fmt.Errorf("MARKET_NOT_TRADING")
+	}
+	if req.TriggerSymbol != "" {
+		if _, ok := s.registry.market(req.TriggerSymbol); !ok {
+			return fmt.Errorf("UNKNOWN_TRIGGER_SYMBOL")
+		}
+	}
+	if quote := strings.TrimSpace(req.QuoteQty); quote != "" {
+		// planMarketOrder sizes the quantity to the lot later.
+		if amount, ok := parsePositiveRat(quote); ok && m.minNotional != nil && amount.Cmp(m.minNotional) < 0 {
+			return fmt.Errorf("NOTIONAL_BELOW_MIN")
+		}
+	} else if err := m.validateQty(req.Qty); err != nil {
+		return err
+	}
+	if req.VisibleQty != "" {
+		if err := m.validateQty(req.VisibleQty); err != nil {
+			return err
+		}
+	}
+	if req.StopPrice != "" && (req.TriggerSymbol == "" || strings.EqualFold(req.TriggerSymbol, m.spec.Symbol)) {
+		if err := m.validatePrice(req.StopPrice); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(req.Price) == "" {
+		return nil
+	}
+	if err := m.validatePrice(req.Price); err != nil {
+		return err
+	}
+	if m.minNotional != nil {
+		price, _ := parseRat(req.Price)
+		qty, _ := parseRat(req.Qty)
+		if new(big.Rat).Mul(price, qty).Cmp(m.minNotional) < 0 {
+			return fmt.Errorf("NOTIONAL_BELOW_MIN")
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleListMarkets(w http.ResponseWriter, _ *http.Request) {
+	out := make([]map[string]interface{}, 0, len(s.registry.marketOrder))
+	for _, symbol := range s.registry.marketOrder {
+		spec := s.registry.markets[symbol].spec
+		out = append(out, map[string]interface{}{
+			"symbol":      spec.Symbol,
+			"base":        spec.Base,
+			"quote":       spec.Quote,
+			"status":      spec.Status,
+			"mode":        s.symbolMode(symbol).Mode,
+			"tickBands":   spec.TickBands,
+			"lotSize":     spec.LotSize,
+			"minQty":      spec.MinQty,
+			"maxQty":      spec.MaxQty,
+			"minNotional": spec.MinNotional,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"markets": out})
+}
+
+func (s *Server) handleListAssets(w http.ResponseWriter, _ *http.Request) {
+	out := make([]AssetSpec, 0, len(s.registry.assetOrder))
+	for _, symbol := range s.registry.assetOrder {
+		out = append(out, s.registry.assets[symbol])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"assets": out})
+}
@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testMarkets is the default registry without minimum notionals, so tests
+// can trade single units at toy prices.
+func testMarkets() []MarketSpec {
+	markets := defaultMarkets()
+	for i := range markets {
+		markets[i].MinNotional = ""
+	}
+	return markets
+}
+
+func TestCreateOrderValidatesAgainstMarketRegistry(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for name, tc := range map[string]struct {
+		body string
+		code string
+	}{
+		"unknown-symbol":  {`{"symbol":"BTC-KWR","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`, "UNKNOWN_SYMBOL"},
+		"unknown-trigger": {`{"symbol":"BTC-KRW","side":"SELL","type":"STOP","qty":"1","stopPrice":"90","triggerSymbol":"DOGE-KRW"}`, "UNKNOWN_TRIGGER_SYMBOL"},
+		"off-tick-band":   {`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"1000250","qty":"1"}`, "INVALID_TICK_SIZE"},
+		"off-tick-low":    {`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100.5","qty":"1"}`, "INVALID_TICK_SIZE"},
+		"off-tick-stop":   {`{"symbol":"BTC-KRW","side":"SELL","type":"STOP","qty":"1","stopPrice":"1000250"}`, "INVALID_TICK_SIZE"},
+		"sub-lot":         {`{"symbol":"XRP-KRW","side":"BUY","type":"LIMIT","price":"900","qty":"1.0000001"}`, "INVALID_LOT_SIZE"},
+		"above-max":       {`{"symbol":"BTC-KRW","side":"SELL","type":"LIMIT","price":"100","qty":"10001"}`, "QTY_ABOVE_MAX"},
+	} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(tc.body), "reg-"+name))
+		var resp map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusBadRequest || resp["error"] != tc.code {
+			t.Fatalf("%s: expected 400 %s, got %d body=%s", name, tc.code, w.Code, w.Body.String())
+		}
+	}
+
+	// Ticks widen with price: 1,000,500 sits on the 500 KRW band.
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"btc-krw","side":"BUY","type":"LIMIT","price":"1000500","qty":"0.5"}`), "reg-ok"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected on-tick order accepted, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegistryFileServesMarketsAndAssets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.json")
+	file := RegistryFile{
+		Markets: []MarketSpec{{
+			Symbol:      "ETH-BTC",
+			Base:        "ETH",
+			Quote:       "BTC",
+			TickBands:   []TickBand{{MinPrice: "0", TickSize: "0.00001"}},
+			LotSize:     "0.001",
+			MinQty:      "0.01",
+			MinNotional: "0.001",
+		}},
+		Assets: []AssetSpec{
+			{Symbol: "BTC", Name: "Bitcoin", Decimals: 8},
+			{Symbol: "ETH", Name: "Ethereum", Decimals: 8},
+		},
+	}
+	raw, _ := json.Marshal(file)
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("write registry: %v", err)
+	}
+	s, err := New(Config{DisableDB: true, DisableCore: true, RegistryFile: path})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	defer s.Close()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/markets", nil))
+	var markets struct {
+		Markets []struct {
+			Symbol string `json:"symbol"`
+			Status string `json:"status"`
+			MinQty string `json:"minQty"`
+		} `json:"markets"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &markets); err != nil || len(markets.Markets) != 1 {
+		t.Fatalf("unexpected markets: %d body=%s", w.Code, w.Body.String())
+	}
+	if m := markets.Markets[0]; m.Symbol != "ETH-BTC" || m.Status != marketStatusTrading || m.MinQty != "0.01" {
+		t.Fatalf("unexpected market: %+v", m)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/assets", nil))
+	var assets struct {
+		Assets []AssetSpec `json:"assets"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &assets); err != nil || len(assets.Assets) != 2 || assets.Assets[1].Decimals != 8 {
+		t.Fatalf("unexpected assets: %s", w.Body.String())
+	}
+
+	if err := s.validateMarketRules(OrderRequest{Symbol: "ETH-BTC", Qty: "0.01", Price: "0.05"}); err == nil || err.Error() != "NOTIONAL_BELOW_MIN" {
+		t.Fatalf("expected NOTIONAL_BELOW_MIN, got %v", err)
+	}
+
+	file.Assets = file.Assets[:1]
+	raw, _ = json.Marshal(file)
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatalf("write registry: %v", err)
+	}
+	if _, err := New(Config{DisableDB: true, DisableCore: true, RegistryFile: path}); err == nil {
+		t.Fatalf("expected a market on an unlisted asset to be refused")
+	}
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+type BatchOrderRequest struct {
+	Orders []OrderRequest `json:"orders"`
+}
+
+type BatchOrderResult struct {
+	Index  int            `json:"index"`
+	Result string         `json:"result"`
+	Order  *OrderResponse `json:"order,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+const (
+	batchResultAccepted = "accepted"
+	batchResultRejected = "rejected"
+	batchResultError    = "error"
+)
+
+// handleCreateOrderBatch places up to MaxBatchOrders orders under a single
+// signature and Idempotency-Key. Orders are validated, reserved and sent
+// in request order; each gets order ID ord_{key}:batch:{index}, kept in the
+// ':' namespace of derived orders so it cannot take the ID of a single order
+// placed under key {key}-{index}. One order failing does not stop the rest.
+func (s *Server) handleCreateOrderBatch(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key required"})
+		return
+	}
+	if status, body, ok := s.idempotencyGet(apiKey, idemKey, r.Method, r.URL.Path); ok {
+		writeRaw(w, status, body)
+		return
+	}
+
+	var req BatchOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	if len(req.Orders) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "orders required"})
+		return
+	}
+	if len(req.Orders) > s.cfg.MaxBatchOrders {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error": fmt.Sprintf("at most %d orders per batch", s.cfg.MaxBatchOrders),
+		})
+		return
+	}
+	if s.coreClient == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core_unavailable"})
+		return
+	}
+
+	results := make([]BatchOrderResult, 0, len(req.Orders))
+	for i, order := range req.Orders {
+		results = append(results, s.placeBatchOrder(r, apiKey, idemKey+":batch:"+strconv.Itoa(i), i, order))
+	}
+
+	status, body := marshalResponse(http.StatusOK, map[string]interface{}{"results": results})
+	s.idempotencySet(apiKey, idemKey, r.Method, r.URL.Path, status, body)
+	writeRaw(w, status, body)
+}
+
+func (s *Server) placeBatchOrder(r *http.Request, userID, idemKey string, index int, order OrderRequest) BatchOrderResult {
+	result := BatchOrderResult{Index: index}
+	if err := s.validateOrderRequest(userID, order); err != nil {
+		result.Result = batchResultRejected
+		result.Error = err.Error()
+		return result
+	}
+	order, err := s.planMarketOrder(order)
+	if err != nil {
+		result.Result = batchResultRejected
+		result.Error = err.Error()
+		return result
+	}
+	reserveCurrency, reserveAmount, err := s.tryReserveForOrder(userID, order, "ord_"+idemKey)
+	if err != nil {
+		result.Result = batchResultRejected
+		result.Error = err.Error()
+		return result
+	}
+	resp, err := s.placeOrderWithCore(r.Context(), userID, idemKey, order, reserveCurrency, reserveAmount)
+	if errors.Is(err, errDuplicateClientOrderID) {
+		result.Result = batchResultRejected
+		result.Error = err.Error()
+		return result
+	}
+	if err != nil {
+		result.Result = batchResultError
+		result.Error = "core_unavailable"
+		return result
+	}
+	result.Order = &resp
+	if resp.Status == "REJECTED" {
+		result.Result = batchResultRejected
+		result.Error = resp.RejectCode
+		return result
+	}
+	result.Result = batchResultAccepted
+	return result
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateOrderBatchReturnsResultPerOrder(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	body := []byte(`{"orders":[
+		{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"},
+		{"symbol":"BTC-KRW","side":"HOLD","type":"LIMIT","price":"100","qty":"1"},
+		{"symbol":"BTC-KRW","side":"SELL","type":"LIMIT","price":"100","qty":"1000"},
+		{"symbol":"BTC-KRW","side":"SELL","type":"LIMIT","price":"110","qty":"1"}
+	]}`)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders/batch", body, "batch-1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("batch failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Results []BatchOrderResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode batch: %v", err)
+	}
+	want := []string{batchResultAccepted, batchResultRejected, batchResultRejected, batchResultAccepted}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), resp.Results)
+	}
+	for i, r := range resp.Results {
+		if r.Index != i || r.Result != want[i] {
+			t.Fatalf("result %d: expected %s, got %+v", i, want[i], r)
+		}
+	}
+	if resp.Results[2].Error != "insufficient_balance" {
+		t.Fatalf("expected insufficient_balance, got %q", resp.Results[2].Error)
+	}
+	if resp.Results[3].Order == nil || resp.Results[3].Order.OrderID != "ord_batch-1:batch:3" {
+		t.Fatalf("unexpected order id for index 3: %+v", resp.Results[3].Order)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"90","qty":"1"}`), "batch-1-3"))
+	var single OrderResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &single) != nil || single.OrderID != "ord_batch-1-3" || single.Status == "REJECTED" {
+		t.Fatalf("expected a single order not to collide with a batch child, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	tooMany := `{"orders":[` + strings.TrimSuffix(strings.Repeat(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"1","qty":"1"},`, 21), ",") + `]}`
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders/batch", []byte(tooMany), "batch-2"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for oversized batch, got %d", w.Code)
+	}
+}
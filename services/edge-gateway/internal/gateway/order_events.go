@@ -0,0 +1,304 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	orderEventAccepted       = "OrderAccepted"
+	orderEventRejected       = "OrderRejected"
+	orderEventCanceled       = "OrderCanceled"
+	orderEventCancelRejected = "CancelRejected"
+
+	// earlyOrderEventTTL bounds how long an event for an order this gateway
+	// has not recorded yet waits for the PlaceOrder response to land.
+	earlyOrderEventTTL = time.Minute
+)
+
+// orderEventPayload is one core order lifecycle event from the order topic.
+// eventType may be omitted; the kind is then read off the envelope eventId
+// ("{symbol}-{seq}-{kind}").
+type orderEventPayload struct {
+	Envelope          tradeEventEnvelope `json:"envelope"`
+	EventType         string             `json:"eventType"`
+	OrderID           string             `json:"orderId"`
+	UserID            string             `json:"userId"`
+	RejectCode        string             `json:"rejectCode"`
+	Detail            string             `json:"detail"`
+	RemainingQuantity *Decimal           `json:"remainingQuantity"`
+	Symbol            string             `json:"symbol"`
+	Seq               uint64             `json:"seq"`
+	TsMs              int64              `json:"ts"`
+}
+
+type orderLifecycleEvent struct {
+	kind       string
+	orderID    string
+	symbol     string
+	seq        uint64
+	tsMs       int64
+	rejectCode string
+	remaining  Decimal
+	hasRemain  bool
+	receivedAt int64
+}
+
+// pendingCancel is a core cancel whose fills have not all arrived on the
+// trade topic yet; the order closes once filledQty is reached so the reserve
+// those fills consume is still held when they settle.
+type pendingCancel struct {
+	filledQty  Decimal
+	seq        uint64
+	canceledAt int64
+}
+
+func (s *Server) kafkaBrokerList() []string {
+	brokers := make([]string, 0, 3)
+	for _, raw := range strings.Split(s.cfg.KafkaBrokers, ",") {
+		v := strings.TrimSpace(raw)
+		if v != "" {
+			brokers = append(brokers, v)
+		}
+	}
+	return brokers
+}
+
+func (s *Server) startOrderEventConsumer() {
+	brokers := s.kafkaBrokerList()
+	if len(brokers) == 0 || strings.TrimSpace(s.cfg.KafkaOrderTopic) == "" {
+		return
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		GroupID:     s.cfg.KafkaGroupID + "-orders",
+		Topic:       s.cfg.KafkaOrderTopic,
+		StartOffset: kafka.LastOffset,
+		MinBytes:    1,
+		MaxBytes:    10e6,
+		MaxWait:     1 * time.Second,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.orderConsumer = reader
+	s.orderCancel = cancel
+	s.orderEvents = true
+	s.orderWG.Add(1)
+	go func() {
+		defer s.orderWG.Done()
+		for {
+			msg, err := reader.ReadMessage(ctx)
+			if err != nil {
+				if errors.Is(err, context.Canceled) || errors.Is(err, kafka.ErrGroupClosed) {
+					return
+				}
+				log.Printf("service=edge-gateway msg=order_event_consume_failed topic=%s reason=%v", s.cfg.KafkaOrderTopic, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(500 * time.Millisecond):
+				}
+				continue
+			}
+			if err := s.consumeOrderEventMessage(ctx, msg.Value); err != nil {
+				log.Printf("service=edge-gateway msg=order_event_apply_failed reason=%v payload=%s", err, string(msg.Value))
+			}
+		}
+	}()
+}
+
+// consumeOrderEventMessage applies one lifecycle event to state.orders. The
+// core numbers events per symbol, so anything at or below the last applied
+// seq of its symbol is a duplicate or arrived out of order and is dropped.
+func (s *Server) consumeOrderEventMessage(ctx context.Context, raw []byte) error {
+	var payload orderEventPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return fmt.Errorf("decode order event payload: %w", err)
+	}
+	ev := orderLifecycleEvent{
+		kind:       orderEventKind(payload),
+		orderID:    strings.TrimSpace(payload.OrderID),
+		symbol:     strings.ToUpper(strings.TrimSpace(payload.Envelope.Symbol)),
+		seq:        payload.Envelope.Seq,
+		tsMs:       payload.TsMs,
+		rejectCode: payload.RejectCode,
+		receivedAt: time.Now().UnixMilli(),
+	}
+	if payload.RemainingQuantity != nil {
+		ev.remaining, ev.hasRemain = *payload.RemainingQuantity, true
+	}
+	if ev.symbol == "" {
+		ev.symbol = strings.ToUpper(strings.TrimSpace(payload.Symbol))
+	}
+	if ev.seq == 0 {
+		ev.seq = payload.Seq
+	}
+	if ev.tsMs <= 0 {
+		if t, err := time.Parse(time.RFC3339Nano, payload.Envelope.OccurredAtRaw); err == nil {
+			ev.tsMs = t.UnixMilli()
+		}
+	}
+	if ev.tsMs <= 0 {
+		ev.tsMs = ev.receivedAt
+	}
+
+	switch {
+	case ev.orderID == "":
+		return fmt.Errorf("missing orderId")
+	case ev.symbol == "":
+		return fmt.Errorf("missing symbol")
+	case ev.seq == 0:
+		return fmt.Errorf("missing seq")
+	}
+	switch ev.kind {
+	case orderEventAccepted, orderEventRejected, orderEventCanceled, orderEventCancelRejected:
+	default:
+		return fmt.Errorf("unknown order event type %q", ev.kind)
+	}
+
+	s.state.mu.Lock()
+	if ev.seq <= s.state.orderEventSeq[ev.symbol] {
+		s.state.orderEventsStale++
+		s.state.mu.Unlock()
+		return nil
+	}
+	s.state.orderEventSeq[ev.symbol] = ev.seq
+	s.state.orderEventsApplied++
+	s.state.mu.Unlock()
+
+	s.applyOrderEvent(ctx, ev)
+	return nil
+}
+
+func orderEventKind(payload orderEventPayload) string {
+	if kind := strings.TrimSpace(payload.EventType); kind != "" {
+		return kind
+	}
+	id := payload.Envelope.EventID
+	if i := strings.LastIndex(id, "-"); i >= 0 {
+		return id[i+1:]
+	}
+	return ""
+}
+
+func (s *Server) applyOrderEvent(ctx context.Context, ev orderLifecycleEvent) {
+	s.state.mu.Lock()
+	record, ok := s.state.orders[ev.orderID]
+	if !ok || record.Status == orderStatusActivating {
+		// The PlaceOrder response may still be in flight; hold closing
+		// events until the record is live.
+		if ev.kind == orderEventRejected || ev.kind == orderEventCanceled {
+			s.stashEarlyOrderEventLocked(ev)
+		}
+		s.state.mu.Unlock()
+		return
+	}
+	if !isOpenOrderStatus(record.Status) || isHeldOrderStatus(record.Status) {
+		s.state.mu.Unlock()
+		return
+	}
+
+	switch ev.kind {
+	case orderEventAccepted:
+		if record.AcceptedAt == 0 {
+			record.AcceptedAt = ev.tsMs
+			s.state.orders[ev.orderID] = record
+			s.state.mu.Unlock()
+			s.persistOrder(ctx, record)
+			return
+		}
+		s.state.mu.Unlock()
+	case orderEventRejected:
+		s.state.mu.Unlock()
+		if record.FilledQty.Sign() > 0 {
+			return
+		}
+		s.markOrderClosed(ev.orderID, "REJECTED", ev.seq, ev.tsMs)
+		s.closeCoreOrderLinks(ctx, record)
+		log.Printf(
+			"service=edge-gateway msg=order_rejected_by_core order_id=%s reject_code=%s",
+			ev.orderID, ev.rejectCode,
+		)
+	case orderEventCanceled:
+		filledQty := record.FilledQty
+		if ev.hasRemain {
+			filledQty = record.Qty.Sub(ev.remaining)
+		}
+		if record.FilledQty.Cmp(filledQty) < 0 {
+			s.state.pendingCancels[ev.orderID] = pendingCancel{filledQty: filledQty, seq: ev.seq, canceledAt: ev.tsMs}
+			s.state.mu.Unlock()
+			return
+		}
+		s.state.mu.Unlock()
+		s.markOrderCanceled(ev.orderID, ev.seq, ev.tsMs)
+		s.closeCoreOrderLinks(ctx, record)
+	case orderEventCancelRejected:
+		s.state.mu.Unlock()
+		log.Printf(
+			"service=edge-gateway msg=cancel_rejected_by_core order_id=%s reject_code=%s",
+			ev.orderID, ev.rejectCode,
+		)
+	default:
+		s.state.mu.Unlock()
+	}
+}
+
+// closeCoreOrderLinks settles what hangs off an order the core closed: its
+// OCO/bracket siblings and its algo parent.
+func (s *Server) closeCoreOrderLinks(ctx context.Context, record OrderRecord) {
+	if record.GroupID != "" {
+		s.closeLinkedOrders(ctx, record.OrderID)
+	}
+	if record.ParentOrderID != "" {
+		s.releaseAlgoChild(ctx, record.OrderID)
+	}
+}
+
+func (s *Server) stashEarlyOrderEventLocked(ev orderLifecycleEvent) {
+	cutoff := ev.receivedAt - earlyOrderEventTTL.Milliseconds()
+	for id, early := range s.state.earlyOrderEvents {
+		if early.receivedAt < cutoff {
+			delete(s.state.earlyOrderEvents, id)
+		}
+	}
+	s.state.earlyOrderEvents[ev.orderID] = ev
+}
+
+// applyEarlyOrderEvent replays a closing event that arrived before the
+// order was recorded.
+func (s *Server) applyEarlyOrderEvent(ctx context.Context, orderID string) {
+	s.state.mu.Lock()
+	ev, ok := s.state.earlyOrderEvents[orderID]
+	delete(s.state.earlyOrderEvents, orderID)
+	s.state.mu.Unlock()
+	if ok && time.Now().UnixMilli()-ev.receivedAt <= earlyOrderEventTTL.Milliseconds() {
+		s.applyOrderEvent(ctx, ev)
+	}
+}
+
+// completePendingCancel closes an order the core canceled once the fills
+// that preceded the cancel have all been applied.
+func (s *Server) completePendingCancel(ctx context.Context, orderID string) {
+	s.state.mu.Lock()
+	pc, ok := s.state.pendingCancels[orderID]
+	record := s.state.orders[orderID]
+	if !ok || record.FilledQty.Cmp(pc.filledQty) < 0 {
+		s.state.mu.Unlock()
+		return
+	}
+	delete(s.state.pendingCancels, orderID)
+	s.state.mu.Unlock()
+	if !isOpenOrderStatus(record.Status) {
+		return
+	}
+	s.markOrderCanceled(orderID, pc.seq, pc.canceledAt)
+	s.closeCoreOrderLinks(ctx, record)
+}
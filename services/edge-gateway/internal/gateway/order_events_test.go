@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func consumeOrderEvent(t *testing.T, s *Server, kind, orderID string, seq uint64, remaining int64) {
+	t.Helper()
+	if err := s.consumeOrderEventMessage(context.Background(), []byte(fmt.Sprintf(
+		`{"envelope":{"eventId":"BTC-KRW-%d-%s","symbol":"BTC-KRW","seq":%d},"orderId":%q,"userId":"test-key","remainingQuantity":"%d"}`,
+		seq, kind, seq, orderID, remaining,
+	))); err != nil {
+		t.Fatalf("consume %s %s: %v", kind, orderID, err)
+	}
+}
+
+func TestOrderCanceledEventClosesOrderAndReleasesReserve(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	place := func(key string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+			[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"10","timeInForce":"IOC"}`), key))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create %s failed: %d body=%s", key, w.Code, w.Body.String())
+		}
+	}
+
+	// IOC remainder canceled by the core after the fill was applied.
+	place("evt-ioc")
+	fillOrder(t, s, "evt-fill-1", "ord_evt-ioc", 4)
+	consumeOrderEvent(t, s, orderEventCanceled, "ord_evt-ioc", 10, 6)
+	if record := getOrderRecord(t, s, "ord_evt-ioc"); record.Status != "CANCELED" || record.FilledQty.Cmp(decimalFromInt(4)) != 0 {
+		t.Fatalf("expected partially filled IOC canceled, got %+v", record)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "49999600" {
+		t.Fatalf("expected unfilled remainder released, got %+v", krw)
+	}
+
+	// The cancel overtakes the fill: the order waits for it before closing.
+	place("evt-race")
+	consumeOrderEvent(t, s, orderEventCanceled, "ord_evt-race", 20, 6)
+	if record := getOrderRecord(t, s, "ord_evt-race"); record.Status != "ACCEPTED" {
+		t.Fatalf("expected order open until its fill lands, got %s", record.Status)
+	}
+	fillOrder(t, s, "evt-fill-2", "ord_evt-race", 4)
+	if record := getOrderRecord(t, s, "ord_evt-race"); record.Status != "CANCELED" || record.FilledQty.Cmp(decimalFromInt(4)) != 0 {
+		t.Fatalf("expected order canceled after its fill, got %+v", record)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "49999200" {
+		t.Fatalf("expected reserve settled exactly, got %+v", krw)
+	}
+
+	// The cancel overtakes the PlaceOrder response.
+	consumeOrderEvent(t, s, orderEventCanceled, "ord_evt-early", 30, 10)
+	place("evt-early")
+	if record := getOrderRecord(t, s, "ord_evt-early"); record.Status != "CANCELED" {
+		t.Fatalf("expected early cancel applied once recorded, got %s", record.Status)
+	}
+
+	// Replays and stale events are dropped.
+	place("evt-stale")
+	consumeOrderEvent(t, s, orderEventCanceled, "ord_evt-stale", 30, 10)
+	consumeOrderEvent(t, s, orderEventRejected, "ord_evt-stale", 25, 0)
+	if record := getOrderRecord(t, s, "ord_evt-stale"); record.Status != "ACCEPTED" {
+		t.Fatalf("expected stale events ignored, got %s", record.Status)
+	}
+	if err := s.consumeOrderEventMessage(context.Background(), []byte(`{"envelope":{"symbol":"BTC-KRW","seq":40},"orderId":"ord_evt-stale"}`)); err == nil {
+		t.Fatalf("expected event without a type rejected")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	mw := httptest.NewRecorder()
+	s.Router().ServeHTTP(mw, req)
+	if !strings.Contains(mw.Body.String(), "edge_order_events_stale_total 2\n") {
+		t.Fatalf("expected stale events counted, got:\n%s", mw.Body.String())
+	}
+}
+
+func TestOrderCanceledEventWaitsForExactlyTheFilledQuantity(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"2.5"}`), "evt-frac"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	// The cancel leaves 0.5 of 2.5: it closes the order once exactly 2 filled.
+	if err := s.consumeOrderEventMessage(context.Background(), []byte(
+		`{"envelope":{"eventId":"BTC-KRW-10-`+orderEventCanceled+`","symbol":"BTC-KRW","seq":10},"orderId":"ord_evt-frac","remainingQuantity":"0.5"}`,
+	)); err != nil {
+		t.Fatalf("consume cancel: %v", err)
+	}
+	fillOrder(t, s, "evt-frac-1", "ord_evt-frac", 1)
+	if record := getOrderRecord(t, s, "ord_evt-frac"); record.Status != "PARTIALLY_FILLED" {
+		t.Fatalf("expected the order open until 2 filled, got %+v", record)
+	}
+	fillOrder(t, s, "evt-frac-2", "ord_evt-frac", 1)
+	record := getOrderRecord(t, s, "ord_evt-frac")
+	if record.Status != "CANCELED" || record.FilledQty.Cmp(decimalFromInt(2)) != 0 {
+		t.Fatalf("expected the order canceled at 2 filled, got %+v", record)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "49999800" {
+		t.Fatalf("expected the unfilled 0.5 released, got %+v", krw)
+	}
+}
@@ -0,0 +1,145 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	exchangev1 "github.com/quanta-exchange/exchange-platform/contracts/gen/go/exchange/v1"
+)
+
+const (
+	timeInForceGTD = "GTD"
+
+	orderStatusExpired = "EXPIRED"
+
+	expirySweepInterval = time.Second
+	// expiryUnknownGrace is how long past its expiry an order the core no
+	// longer knows waits for the fill or cancel events that explain why.
+	expiryUnknownGrace = time.Minute
+)
+
+// validateExpireAt checks the GTD half of an order: expireAt is required
+// with GTD, must be in the future, and means nothing without it. The core
+// rests GTD orders as GTC; the gateway's sweeper enforces the expiry.
+func validateExpireAt(req OrderRequest, nowMs int64) error {
+	if strings.ToUpper(strings.TrimSpace(req.TimeInForce)) != timeInForceGTD {
+		if req.ExpireAt != 0 {
+			return fmt.Errorf("expireAt requires GTD")
+		}
+		return nil
+	}
+	if strings.ToUpper(strings.TrimSpace(req.Type)) == "MARKET" {
+		return fmt.Errorf("GTD not allowed for MARKET")
+	}
+	if req.ExpireAt <= nowMs {
+		return fmt.Errorf("expireAt must be in the future")
+	}
+	return nil
+}
+
+// timeInForceLabel is the time in force an order record shows: GTD for
+// good-till-date orders, which the core only sees as GTC.
+func timeInForceLabel(raw string, tif exchangev1.TimeInForce) string {
+	if strings.ToUpper(strings.TrimSpace(raw)) == timeInForceGTD {
+		return timeInForceGTD
+	}
+	return strings.TrimPrefix(tif.String(), "TIME_IN_FORCE_")
+}
+
+// expireOrders closes every open order whose expireAt has passed by nowMs,
+// oldest deadline first. Orders on a symbol that rejects cancels, or whose
+// cancel failed or was refused, are retried on the next sweep, except ones
+// the core no longer knows, which expireOrder settles once their grace runs
+// out.
+func (s *Server) expireOrders(ctx context.Context, nowMs int64) {
+	var due []OrderRecord
+	s.state.mu.Lock()
+	for _, record := range s.state.orders {
+		if record.ExpireAt > 0 && record.ExpireAt <= nowMs && isOpenOrderStatus(record.Status) {
+			due = append(due, record)
+		}
+	}
+	s.state.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].ExpireAt != due[j].ExpireAt {
+			return due[i].ExpireAt < due[j].ExpireAt
+		}
+		return due[i].OrderID < due[j].OrderID
+	})
+	for _, record := range due {
+		s.expireOrder(ctx, record, nowMs)
+	}
+}
+
+// expireOrder closes one order as EXPIRED the way a cancel would close it:
+// gateway-held orders locally, algo parents with their children, and live
+// orders through CancelOrder.
+func (s *Server) expireOrder(ctx context.Context, record OrderRecord, nowMs int64) {
+	switch {
+	case isAlgoOrderType(record.Type):
+		s.closeAlgoOrder(ctx, record.OrderID, orderStatusExpired)
+	case isHeldOrderStatus(record.Status):
+		s.closeHeldOrder(record, orderStatusExpired)
+	default:
+		resp, err := s.cancelOnCore(ctx, record.OwnerUserID, record.OrderID+":expire", record, false)
+		switch {
+		case err == nil && resp.Status == "CANCELED":
+			s.markOrderClosed(record.OrderID, orderStatusExpired, resp.Seq, resp.CanceledAt)
+		case err == nil && resp.RejectCode == "UNKNOWN_ORDER":
+			if nowMs-record.ExpireAt >= expiryUnknownGrace.Milliseconds() {
+				s.closeUnknownOrder(record.OrderID, nowMs)
+			}
+		default:
+			log.Printf(
+				"service=edge-gateway msg=order_expire_failed order_id=%s status=%s reject_code=%s err=%v",
+				record.OrderID, resp.Status, resp.RejectCode, err,
+			)
+		}
+	}
+}
+
+// closeUnknownOrder settles an expired order the core has no record of: it
+// left the book by filling or closing and the events saying so never
+// arrived. It becomes FILLED if its fills cover it and EXPIRED otherwise,
+// releasing what it still reserves, so the sweeper stops retrying it.
+func (s *Server) closeUnknownOrder(orderID string, nowMs int64) {
+	s.state.mu.Lock()
+	record, ok := s.state.orders[orderID]
+	s.state.mu.Unlock()
+	if !ok || !isOpenOrderStatus(record.Status) {
+		return
+	}
+	status, closedAt := orderStatusExpired, nowMs
+	if record.Qty.Sign() > 0 && record.FilledQty.Cmp(record.Qty) >= 0 {
+		status, closedAt = "FILLED", 0
+	}
+	log.Printf("service=edge-gateway msg=order_unknown_to_core order_id=%s status=%s", orderID, status)
+	s.markOrderClosed(orderID, status, 0, closedAt)
+}
+
+func (s *Server) startExpirySweeper() {
+	if s.coreClient == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.expiryCancel = cancel
+	s.expiryWG.Add(1)
+	go func() {
+		defer s.expiryWG.Done()
+		ticker := time.NewTicker(expirySweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.expireOrders(ctx, now.UnixMilli())
+			}
+		}
+	}()
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGoodTillDateOrderExpiresAndReleasesReserve(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	for name, body := range map[string]string{
+		"missing-expiry": `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","timeInForce":"GTD"}`,
+		"past-expiry":    `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","timeInForce":"GTD","expireAt":1000}`,
+		"expiry-on-gtc":  fmt.Sprintf(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","expireAt":%d}`, expireAt),
+		"market-gtd":     fmt.Sprintf(`{"symbol":"BTC-KRW","side":"SELL","type":"MARKET","qty":"1","timeInForce":"GTD","expireAt":%d}`, expireAt),
+	} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(body), "gtd-"+name))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body=%s", name, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(fmt.Sprintf(
+		`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"3","timeInForce":"GTD","expireAt":%d}`, expireAt,
+	)), "gtd-1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create GTD failed: %d body=%s", w.Code, w.Body.String())
+	}
+	record := getOrderRecord(t, s, "ord_gtd-1")
+	if record.TimeInForce != "GTD" || record.ExpireAt != expireAt || record.Status != "ACCEPTED" {
+		t.Fatalf("unexpected GTD record: %+v", record)
+	}
+
+	s.expireOrders(context.Background(), expireAt-1)
+	if record := getOrderRecord(t, s, "ord_gtd-1"); record.Status != "ACCEPTED" {
+		t.Fatalf("order expired early: %s", record.Status)
+	}
+
+	fillOrder(t, s, "gtd-fill", "ord_gtd-1", 1)
+	s.expireOrders(context.Background(), expireAt)
+	record = getOrderRecord(t, s, "ord_gtd-1")
+	if record.Status != orderStatusExpired || record.FilledQty.Cmp(decimalFromInt(1)) != 0 || record.CanceledAt == 0 {
+		t.Fatalf("expected partially filled order EXPIRED, got %+v", record)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "49999900" {
+		t.Fatalf("expected unfilled reserve released, got %+v", krw)
+	}
+
+	if open := s.openOrdersFor("test-key", "", ""); len(open) != 0 {
+		t.Fatalf("expired order must not be open, got %+v", open)
+	}
+}
+
+func TestGoodTillDateStopExpiresWhileHeld(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	postSmokeTrade(t, s, "gtd-stop-t1", "BTC-KRW", 100)
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(fmt.Sprintf(
+		`{"symbol":"BTC-KRW","side":"SELL","type":"STOP","qty":"1","stopPrice":"90","timeInForce":"GTD","expireAt":%d}`, expireAt,
+	)), "gtd-stop"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create GTD stop failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	s.expireOrders(context.Background(), expireAt+1)
+	if record := getOrderRecord(t, s, "ord_gtd-stop"); record.Status != orderStatusExpired {
+		t.Fatalf("expected held stop EXPIRED, got %s", record.Status)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "0.00000000" || btc.Available.String() != "2.00000000" {
+		t.Fatalf("expected stop reserve released, got %+v", btc)
+	}
+	postSmokeTrade(t, s, "gtd-stop-t2", "BTC-KRW", 80)
+	if record := getOrderRecord(t, s, "ord_gtd-stop"); record.Status != orderStatusExpired {
+		t.Fatalf("expired stop must not fire, got %s", record.Status)
+	}
+}
+
+func TestExpirySettlesAnOrderTheCoreNoLongerKnows(t *testing.T) {
+	core := &stubCore{unknown: map[string]bool{"ord_gtd-lost": true}}
+	s, cleanup := newTestServerWithCore(t, core)
+	defer cleanup()
+	ctx := context.Background()
+
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(fmt.Sprintf(
+		`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"2","timeInForce":"GTD","expireAt":%d}`, expireAt,
+	)), "gtd-lost"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create GTD failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	// Its fill or cancel events may still be on the way.
+	s.expireOrders(ctx, expireAt)
+	if record := getOrderRecord(t, s, "ord_gtd-lost"); record.Status != "ACCEPTED" {
+		t.Fatalf("expected the order left open within the grace, got %s", record.Status)
+	}
+
+	s.expireOrders(ctx, expireAt+expiryUnknownGrace.Milliseconds())
+	if record := getOrderRecord(t, s, "ord_gtd-lost"); record.Status != orderStatusExpired {
+		t.Fatalf("expected the order EXPIRED once the grace ran out, got %s", record.Status)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" || krw.Available.String() != "50000000" {
+		t.Fatalf("expected the reserve released, got %+v", krw)
+	}
+}
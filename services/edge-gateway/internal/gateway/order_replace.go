@@ -0,0 +1,190 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ReplaceOrderRequest struct {
+	Price string `json:"price"`
+	Qty   string `json:"qty"`
+}
+
+type ReplaceOrderResponse struct {
+	Old OrderResponse `json:"old"`
+	New OrderResponse `json:"new"`
+}
+
+// handleReplaceOrder cancels a resting LIMIT order and places its
+// replacement under the request's Idempotency-Key. The old order's unused
+// reserve is carried over and only the difference is reserved or released,
+// so shrinking an order never needs fresh balance.
+func (s *Server) handleReplaceOrder(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key required"})
+		return
+	}
+	orderID := chi.URLParam(r, "orderId")
+	pathKey := "/v1/orders/" + orderID
+	if status, body, ok := s.idempotencyGet(apiKey, idemKey, r.Method, pathKey); ok {
+		writeRaw(w, status, body)
+		return
+	}
+
+	var req ReplaceOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	if strings.TrimSpace(req.Price) == "" && strings.TrimSpace(req.Qty) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "price or qty required"})
+		return
+	}
+	if s.coreClient == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core_unavailable"})
+		return
+	}
+
+	s.state.mu.Lock()
+	record, ok := s.state.orders[orderID]
+	s.state.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "UNKNOWN_ORDER"})
+		return
+	}
+	if record.OwnerUserID != apiKey {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "FORBIDDEN"})
+		return
+	}
+	if !isOpenOrderStatus(record.Status) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "ORDER_NOT_OPEN"})
+		return
+	}
+	if record.Type != "LIMIT" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "only LIMIT orders can be replaced"})
+		return
+	}
+	if record.GroupID != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "linked orders cannot be replaced"})
+		return
+	}
+	if record.ParentOrderID != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "TWAP and ICEBERG children cannot be replaced"})
+		return
+	}
+	if code := s.orderEntryBlock(record.Symbol); code != "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+		return
+	}
+
+	next := OrderRequest{
+		Symbol:              record.Symbol,
+		Side:                record.Side,
+		Type:                record.Type,
+		Price:               record.Price,
+		Qty:                 formatQty(record.Qty.Sub(record.FilledQty)),
+		TimeInForce:         record.TimeInForce,
+		ExpireAt:            record.ExpireAt,
+		ClientOrderID:       record.ClientOrderID,
+		PostOnly:            record.PostOnly,
+		SelfTradePrevention: record.SelfTradePrevention,
+	}
+	if v := strings.TrimSpace(req.Price); v != "" {
+		next.Price = v
+	}
+	if v := strings.TrimSpace(req.Qty); v != "" {
+		next.Qty = v
+	}
+	if err := s.validateMarketRules(next); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := s.checkPriceBand(next); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	currency, required, err := s.reserveRequirement(next)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Top up before touching the book so a larger replacement fails cleanly
+	// on insufficient balance while the old order keeps resting.
+	newOrderID := "ord_" + idemKey
+	carried := unconsumedReserve(record)
+	topUp := required.zero()
+	if required.Cmp(carried) > 0 {
+		topUp = required.Sub(carried)
+		if _, err := s.applyReserve(apiKey, currency, topUp, newOrderID); err != nil {
+			writeReserveError(w, err)
+			return
+		}
+	}
+
+	oldResp, err := s.cancelOnCore(r.Context(), apiKey, idemKey+":cancel", record, false)
+	if err != nil || oldResp.Status != "CANCELED" {
+		if topUp.Sign() > 0 {
+			s.releaseReserve(apiKey, currency, topUp, newOrderID)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "core_unavailable"})
+			return
+		}
+		status, body := marshalResponse(http.StatusConflict, map[string]interface{}{
+			"error": "REPLACE_CANCEL_REJECTED",
+			"old":   oldResp,
+		})
+		s.idempotencySet(apiKey, idemKey, r.Method, pathKey, status, body)
+		writeRaw(w, status, body)
+		return
+	}
+
+	// Fills may have landed between the snapshot and the cancel, so settle
+	// against what the cancel actually detached.
+	held := s.detachOrderReserve(orderID, oldResp.Seq, oldResp.CanceledAt).Add(topUp)
+	switch held.Cmp(required) {
+	case 1:
+		s.releaseReserve(apiKey, currency, held.Sub(required), newOrderID)
+	case -1:
+		if _, err := s.applyReserve(apiKey, currency, required.Sub(held), newOrderID); err != nil {
+			s.releaseReserve(apiKey, currency, held, newOrderID)
+			status, body := marshalResponse(http.StatusOK, ReplaceOrderResponse{
+				Old: oldResp,
+				New: OrderResponse{Status: "REJECTED", Symbol: record.Symbol, RejectCode: "insufficient_balance"},
+			})
+			s.idempotencySet(apiKey, idemKey, r.Method, pathKey, status, body)
+			writeRaw(w, status, body)
+			return
+		}
+	}
+
+	newResp, err := s.placeOrderWithCore(r.Context(), apiKey, idemKey, next, currency, required)
+	if err != nil {
+		newResp = OrderResponse{Status: "REJECTED", Symbol: record.Symbol, RejectCode: "core_unavailable"}
+	} else {
+		s.state.mu.Lock()
+		old, ok := s.state.orders[orderID]
+		if ok {
+			old.ReplacedBy = newResp.OrderID
+			s.state.orders[orderID] = old
+		}
+		s.state.mu.Unlock()
+		if ok {
+			s.persistOrder(r.Context(), old)
+		}
+	}
+
+	status, body := marshalResponse(http.StatusOK, ReplaceOrderResponse{Old: oldResp, New: newResp})
+	s.idempotencySet(apiKey, idemKey, r.Method, pathKey, status, body)
+	writeRaw(w, status, body)
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReplaceOrderAdjustsReserveByDifference(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	// Reserve the whole default KRW balance so a re-acquire would fail.
+	body := []byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"50000000","qty":"1"}`)
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", body, "replace-orig"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPatch, "/v1/orders/ord_replace-orig", []byte(`{"price":"49000000"}`), "replace-1"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("replace failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var resp ReplaceOrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode replace: %v", err)
+	}
+	if resp.Old.Status != "CANCELED" || resp.New.Status != "ACCEPTED" || resp.New.OrderID != "ord_replace-1" {
+		t.Fatalf("unexpected replace response: %+v", resp)
+	}
+	krw := s.snapshotWallet("test-key")["KRW"]
+	if krw.Hold.String() != "49000000" || krw.Available.String() != "1000000" {
+		t.Fatalf("expected hold 49M / available 1M, got %+v", krw)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPatch, "/v1/orders/ord_replace-1", []byte(`{"qty":"2"}`), "replace-2"))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected insufficient balance on upsize, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPatch, "/v1/orders/ord_replace-1", []byte(`{"price":"48999999"}`), "replace-3"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_TICK_SIZE") {
+		t.Fatalf("expected off-tick replacement rejected, got %d body=%s", w.Code, w.Body.String())
+	}
+	s.state.mu.Lock()
+	still := s.state.orders["ord_replace-1"]
+	old := s.state.orders["ord_replace-orig"]
+	s.state.mu.Unlock()
+	if still.Status != "ACCEPTED" {
+		t.Fatalf("expected order to keep resting after failed upsize, got %s", still.Status)
+	}
+	if old.ReplacedBy != "ord_replace-1" {
+		t.Fatalf("expected replacedBy link, got %q", old.ReplacedBy)
+	}
+}
@@ -0,0 +1,287 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+const orderColumns = `order_id, user_id, client_order_id, symbol, side, order_type, price, time_in_force,
+	qty, filled_qty, status, seq, created_at_ms, accepted_at_ms, canceled_at_ms,
+	reserve_currency, reserve_amount, reserve_consumed, replaced_by, post_only, self_trade_prevention, trigger_spec,
+	group_id, group_role, algo_spec, parent_order_id, expire_at_ms, quote_qty`
+
+func (s *Server) initOrderSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_orders (
+			order_id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			client_order_id TEXT NOT NULL DEFAULT '',
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,
+			order_type TEXT NOT NULL,
+			price TEXT NOT NULL DEFAULT '',
+			time_in_force TEXT NOT NULL DEFAULT '',
+			qty NUMERIC NOT NULL DEFAULT 0,
+			filled_qty NUMERIC NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			seq BIGINT NOT NULL DEFAULT 0,
+			created_at_ms BIGINT NOT NULL DEFAULT 0,
+			accepted_at_ms BIGINT NOT NULL DEFAULT 0,
+			canceled_at_ms BIGINT NOT NULL DEFAULT 0,
+			reserve_currency TEXT NOT NULL DEFAULT '',
+			reserve_amount NUMERIC NOT NULL DEFAULT 0,
+			reserve_consumed NUMERIC NOT NULL DEFAULT 0,
+			replaced_by TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init orders schema: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		ALTER TABLE web_orders
+		ADD COLUMN IF NOT EXISTS post_only BOOLEAN NOT NULL DEFAULT false,
+		ADD COLUMN IF NOT EXISTS self_trade_prevention TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS trigger_spec TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS group_id TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS group_role TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS algo_spec TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS parent_order_id TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS expire_at_ms BIGINT NOT NULL DEFAULT 0,
+		ADD COLUMN IF NOT EXISTS quote_qty TEXT NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		return fmt.Errorf("migrate orders schema: %w", err)
+	}
+	if err := s.migrateNumericColumns(ctx, "web_orders", "qty", "filled_qty", "reserve_amount", "reserve_consumed"); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS web_orders_open_idx
+		ON web_orders (status) WHERE status IN ('ACCEPTED', 'PARTIALLY_FILLED')
+	`)
+	if err != nil {
+		return fmt.Errorf("init orders index: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS web_orders_pending_trigger_idx
+		ON web_orders (status) WHERE status IN ('PENDING_TRIGGER', 'PENDING_ENTRY')
+	`)
+	if err != nil {
+		return fmt.Errorf("init orders trigger index: %w", err)
+	}
+	return nil
+}
+
+// persistOrder writes the record through to Postgres and pushes it to the
+// owner's private order stream. Updates never move an order backwards:
+// fills and cancels race on separate goroutines, and the core seq tells
+// which write is newer. The write outlives ctx's cancellation: by the time
+// an order is persisted the core already holds it, so a client hanging up
+// must not leave the row behind.
+func (s *Server) persistOrder(ctx context.Context, record OrderRecord) {
+	ctx, cancel := s.dbContext(context.WithoutCancel(ctx))
+	defer cancel()
+	s.publishOrderUpdate(record)
+	if s.db == nil {
+		return
+	}
+	if err := writeOrder(ctx, s.db, record); err != nil {
+		log.Printf("service=edge-gateway msg=order_persist_failed order_id=%s err=%v", record.OrderID, err)
+	}
+}
+
+// sqlExecer is a *sql.DB or a *sql.Tx.
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// writeOrder upserts the order row, on its own or inside a wallet
+// transaction that moves its reserve.
+func writeOrder(ctx context.Context, db sqlExecer, record OrderRecord) error {
+	triggerSpec := ""
+	if record.Trigger != nil {
+		raw, err := json.Marshal(record.Trigger)
+		if err != nil {
+			return err
+		}
+		triggerSpec = string(raw)
+	}
+	algoSpec := ""
+	if record.Algo != nil {
+		raw, err := json.Marshal(record.Algo)
+		if err != nil {
+			return err
+		}
+		algoSpec = string(raw)
+	}
+	_, err := db.ExecContext(
+		ctx,
+		`INSERT INTO web_orders(`+orderColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11,
+		 $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28)
+		 ON CONFLICT (order_id) DO UPDATE SET
+		 filled_qty = EXCLUDED.filled_qty,
+		 status = EXCLUDED.status,
+		 qty = EXCLUDED.qty,
+		 seq = EXCLUDED.seq,
+		 canceled_at_ms = EXCLUDED.canceled_at_ms,
+		 reserve_amount = EXCLUDED.reserve_amount,
+		 reserve_consumed = EXCLUDED.reserve_consumed,
+		 replaced_by = EXCLUDED.replaced_by,
+		 trigger_spec = EXCLUDED.trigger_spec,
+		 algo_spec = EXCLUDED.algo_spec,
+		 updated_at = now()
+		 WHERE web_orders.seq <= EXCLUDED.seq`,
+		record.OrderID,
+		record.OwnerUserID,
+		record.ClientOrderID,
+		record.Symbol,
+		record.Side,
+		record.Type,
+		record.Price,
+		record.TimeInForce,
+		record.Qty,
+		record.FilledQty,
+		record.Status,
+		int64(record.Seq),
+		record.CreatedAt,
+		record.AcceptedAt,
+		record.CanceledAt,
+		record.ReserveCurrency,
+		record.ReserveAmount,
+		record.ReserveConsumed,
+		record.ReplacedBy,
+		record.PostOnly,
+		record.SelfTradePrevention,
+		triggerSpec,
+		record.GroupID,
+		record.GroupRole,
+		algoSpec,
+		record.ParentOrderID,
+		record.ExpireAt,
+		record.QuoteQty,
+	)
+	if err != nil {
+		return fmt.Errorf("write order %s: %w", record.OrderID, err)
+	}
+	return nil
+}
+
+// loadOrders restores every persisted order after a restart. Resting
+// orders and the ones the gateway still holds (untriggered stops, bracket
+// exits awaiting their entry) keep their cancels, fills, triggers and
+// reserve releases working; TWAP and ICEBERG parents pick their schedule
+// back up from their state. Closed orders come back so order history and
+// fill lookups answer the same as before the restart.
+func (s *Server) loadOrders(ctx context.Context) error {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT `+orderColumns+` FROM web_orders ORDER BY created_at_ms, seq, order_id`,
+	)
+	if err != nil {
+		return fmt.Errorf("load orders: %w", err)
+	}
+	defer rows.Close()
+
+	var records []OrderRecord
+	for rows.Next() {
+		var record OrderRecord
+		var seq int64
+		var triggerSpec, algoSpec string
+		if err := rows.Scan(
+			&record.OrderID,
+			&record.OwnerUserID,
+			&record.ClientOrderID,
+			&record.Symbol,
+			&record.Side,
+			&record.Type,
+			&record.Price,
+			&record.TimeInForce,
+			&record.Qty,
+			&record.FilledQty,
+			&record.Status,
+			&seq,
+			&record.CreatedAt,
+			&record.AcceptedAt,
+			&record.CanceledAt,
+			&record.ReserveCurrency,
+			&record.ReserveAmount,
+			&record.ReserveConsumed,
+			&record.ReplacedBy,
+			&record.PostOnly,
+			&record.SelfTradePrevention,
+			&triggerSpec,
+			&record.GroupID,
+			&record.GroupRole,
+			&algoSpec,
+			&record.ParentOrderID,
+			&record.ExpireAt,
+			&record.QuoteQty,
+		); err != nil {
+			return fmt.Errorf("scan order: %w", err)
+		}
+		record.Seq = uint64(seq)
+		if record.ReserveCurrency != "" {
+			scale := s.assetScale(record.ReserveCurrency)
+			record.ReserveAmount = record.ReserveAmount.Round(scale)
+			record.ReserveConsumed = record.ReserveConsumed.Round(scale)
+		}
+		if triggerSpec != "" {
+			var trigger OrderTrigger
+			if err := json.Unmarshal([]byte(triggerSpec), &trigger); err != nil {
+				return fmt.Errorf("decode trigger for order %s: %w", record.OrderID, err)
+			}
+			record.Trigger = &trigger
+		}
+		if algoSpec != "" {
+			var algo AlgoState
+			if err := json.Unmarshal([]byte(algoSpec), &algo); err != nil {
+				return fmt.Errorf("decode algo state for order %s: %w", record.OrderID, err)
+			}
+			record.Algo = &algo
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load orders: %w", err)
+	}
+
+	s.restoreOrders(records)
+	log.Printf("service=edge-gateway msg=orders_restored count=%d", len(records))
+	return nil
+}
+
+// restoreOrders installs previously persisted orders and their client order
+// IDs, oldest first so a reused client order ID ends up on its latest order.
+// Owners of open orders have their wallets loaded first: releasing a
+// restored reserve against an uncached wallet would otherwise start from the
+// default balances.
+func (s *Server) restoreOrders(records []OrderRecord) {
+	owners := map[string]struct{}{}
+	for _, record := range records {
+		if isOpenOrderStatus(record.Status) {
+			owners[record.OwnerUserID] = struct{}{}
+		}
+	}
+	for userID := range owners {
+		s.snapshotWallet(userID)
+	}
+
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	for _, record := range records {
+		s.state.orders[record.OrderID] = record
+		s.indexTriggerLocked(record)
+		if record.ClientOrderID != "" {
+			s.state.clientOrderIDs[clientOrderIndexKey(record.OwnerUserID, record.ClientOrderID)] = record.OrderID
+		}
+		// A close whose release never committed before the restart.
+		if orderReleasePending(record) {
+			s.state.orderReleaseRetries[record.OrderID] = true
+		}
+	}
+}
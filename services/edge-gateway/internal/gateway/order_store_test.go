@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRestoredOrderCanBeCanceledAndReleasesReserve(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	wallet := s.snapshotWallet("test-key")
+	krw := wallet["KRW"]
+	krw.Available = krw.Available.Sub(decimalFromInt(300))
+	krw.Hold = krw.Hold.Add(decimalFromInt(300))
+	wallet["KRW"] = krw
+	s.state.mu.Lock()
+	s.state.wallets["test-key"] = wallet
+	s.state.mu.Unlock()
+
+	s.restoreOrders([]OrderRecord{{
+		OrderID:         "ord_before-restart",
+		ClientOrderID:   "grid-3",
+		Status:          "PARTIALLY_FILLED",
+		Symbol:          "BTC-KRW",
+		Seq:             7,
+		OwnerUserID:     "test-key",
+		ReserveCurrency: "KRW",
+		ReserveAmount:   decimalFromInt(400),
+		ReserveConsumed: decimalFromInt(100),
+		Side:            "BUY",
+		Type:            "LIMIT",
+		Price:           "100",
+		TimeInForce:     "GTC",
+		Qty:             decimalFromInt(4),
+		FilledQty:       decimalFromInt(1),
+	}, {
+		OrderID:       "ord_filled-before-restart",
+		ClientOrderID: "grid-2",
+		Status:        "FILLED",
+		Symbol:        "BTC-KRW",
+		Seq:           5,
+		OwnerUserID:   "test-key",
+		Side:          "SELL",
+		Type:          "LIMIT",
+		Price:         "110",
+		TimeInForce:   "GTC",
+		Qty:           decimalFromInt(1),
+		FilledQty:     decimalFromInt(1),
+	}})
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/by-client-id/grid-3", nil, "restart-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel of restored order failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var resp OrderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Status != "CANCELED" || resp.OrderID != "ord_before-restart" {
+		t.Fatalf("unexpected cancel response: %s", w.Body.String())
+	}
+	if got := s.snapshotWallet("test-key")["KRW"]; got.Hold.String() != "0" || got.Available.Cmp(krw.Available.Add(decimalFromInt(300))) != 0 {
+		t.Fatalf("expected unconsumed reserve released, got %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/orders?status=closed", nil, ""))
+	var list struct {
+		Orders []OrderRecord `json:"orders"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &list) != nil || len(list.Orders) != 2 {
+		t.Fatalf("expected restored closed orders listed, got %d body=%s", w.Code, w.Body.String())
+	}
+}
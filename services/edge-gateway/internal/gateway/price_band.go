@@ -0,0 +1,277 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	priceBandRejectCode = "PRICE_BAND"
+
+	priceBandRefLastTrade = "LAST_TRADE"
+	priceBandRefFixed     = "REFERENCE_PRICE"
+)
+
+type PriceBandRequest struct {
+	MaxDeviationPercent Decimal `json:"maxDeviationPercent"`
+	ReferencePrice      string  `json:"referencePrice,omitempty"`
+}
+
+// priceBandRecord is a per-symbol override of Config.PriceBandPercent. A
+// zero MaxDeviationPercent turns the band off for the symbol.
+type priceBandRecord struct {
+	Symbol              string  `json:"symbol"`
+	MaxDeviationPercent Decimal `json:"maxDeviationPercent"`
+	ReferencePrice      string  `json:"referencePrice,omitempty"`
+	UpdatedAt           int64   `json:"updatedAt,omitempty"`
+}
+
+// PriceBandView is a symbol's effective band and the price it is anchored
+// to right now. The prices are left out when there is no anchor or the band
+// is off.
+type PriceBandView struct {
+	priceBandRecord
+	Reference     string   `json:"reference"`
+	AnchorPrice   *Decimal `json:"anchorPrice,omitempty"`
+	LowerPrice    *Decimal `json:"lowerPrice,omitempty"`
+	UpperPrice    *Decimal `json:"upperPrice,omitempty"`
+	Overridden    bool     `json:"overridden"`
+	RejectedTotal uint64   `json:"rejectedTotal"`
+}
+
+func (s *Server) priceBand(symbol string) (priceBandRecord, bool) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if rec, ok := s.state.priceBands[symbol]; ok {
+		return rec, true
+	}
+	return priceBandRecord{Symbol: symbol, MaxDeviationPercent: s.defaultPriceBandPercent()}, false
+}
+
+// defaultPriceBandPercent is Config.PriceBandPercent as a Decimal; a value
+// that does not convert turns the band off.
+func (s *Server) defaultPriceBandPercent() Decimal {
+	percent, _ := decimalFromFloat(s.cfg.PriceBandPercent)
+	return percent
+}
+
+// priceBandAnchor is the price a band is measured from: the fixed reference
+// price when one is set, else the symbol's last trade.
+func (s *Server) priceBandAnchor(band priceBandRecord) (string, Decimal, bool) {
+	if band.ReferencePrice != "" {
+		price, ok := parseDecimal(band.ReferencePrice)
+		return priceBandRefFixed, price, ok && price.Sign() > 0
+	}
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	tape := s.state.tradeTape[band.Symbol]
+	if len(tape) == 0 {
+		return priceBandRefLastTrade, Decimal{}, false
+	}
+	return priceBandRefLastTrade, decimalFromInt(tape[len(tape)-1].price), true
+}
+
+// checkPriceBand rejects limit prices further than the symbol's band from
+// its anchor. Orders without a limit price, symbols with the band off and
+// symbols with nothing to anchor to pass.
+func (s *Server) checkPriceBand(req OrderRequest) error {
+	if isConditionalOrderType(req.Type) || strings.TrimSpace(req.Price) == "" {
+		return nil
+	}
+	band, _ := s.priceBand(req.Symbol)
+	if band.MaxDeviationPercent.Sign() <= 0 {
+		return nil
+	}
+	_, anchor, ok := s.priceBandAnchor(band)
+	if !ok {
+		return nil
+	}
+	price, ok := parseDecimal(req.Price)
+	if !ok {
+		return nil
+	}
+	deviation := price.Sub(anchor)
+	if deviation.Sign() < 0 {
+		deviation = anchor.Sub(price)
+	}
+	if deviation.MulInt(100).Cmp(band.MaxDeviationPercent.Mul(anchor)) <= 0 {
+		return nil
+	}
+	s.state.mu.Lock()
+	s.state.priceBandRejects[band.Symbol]++
+	s.state.mu.Unlock()
+	return errors.New(priceBandRejectCode)
+}
+
+func (s *Server) priceBandView(symbol string) PriceBandView {
+	band, overridden := s.priceBand(symbol)
+	reference, anchor, ok := s.priceBandAnchor(band)
+	view := PriceBandView{priceBandRecord: band, Reference: reference, Overridden: overridden}
+	if ok {
+		view.AnchorPrice = &anchor
+		if band.MaxDeviationPercent.Sign() > 0 {
+			// anchor × percent / 100: dividing by 100 is two more decimals.
+			width := anchor.Mul(band.MaxDeviationPercent)
+			width = Decimal{units: width.int(), scale: width.scale + 2}
+			lower, upper := anchor.Sub(width).trimmed(), anchor.Add(width).trimmed()
+			view.LowerPrice, view.UpperPrice = &lower, &upper
+		}
+	}
+	s.state.mu.Lock()
+	view.RejectedTotal = s.state.priceBandRejects[band.Symbol]
+	s.state.mu.Unlock()
+	return view
+}
+
+func (s *Server) handleGetPriceBand(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "symbol")))
+	if _, ok := s.registry.market(symbol); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "UNKNOWN_SYMBOL"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.priceBandView(symbol))
+}
+
+func (s *Server) handleSetPriceBand(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "symbol")))
+	m, ok := s.registry.market(symbol)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "UNKNOWN_SYMBOL"})
+		return
+	}
+	var req PriceBandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	if req.MaxDeviationPercent.Sign() < 0 || req.MaxDeviationPercent.Cmp(decimalFromInt(1000)) > 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "maxDeviationPercent must be between 0 and 1000"})
+		return
+	}
+	reference := strings.TrimSpace(req.ReferencePrice)
+	if reference != "" {
+		if err := m.validatePrice(reference); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid referencePrice"})
+			return
+		}
+	}
+
+	rec := priceBandRecord{
+		Symbol:              symbol,
+		MaxDeviationPercent: req.MaxDeviationPercent,
+		ReferencePrice:      reference,
+		UpdatedAt:           time.Now().UnixMilli(),
+	}
+	s.state.mu.Lock()
+	s.state.priceBands[symbol] = rec
+	s.state.mu.Unlock()
+	s.persistPriceBand(r.Context(), rec)
+	log.Printf(
+		"service=edge-gateway msg=price_band_changed symbol=%s max_deviation_pct=%s reference_price=%q",
+		symbol, rec.MaxDeviationPercent, rec.ReferencePrice,
+	)
+	writeJSON(w, http.StatusOK, s.priceBandView(symbol))
+}
+
+// handleResetPriceBand drops the symbol's override so it follows
+// Config.PriceBandPercent again.
+func (s *Server) handleResetPriceBand(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "symbol")))
+	if _, ok := s.registry.market(symbol); !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "UNKNOWN_SYMBOL"})
+		return
+	}
+	s.state.mu.Lock()
+	delete(s.state.priceBands, symbol)
+	s.state.mu.Unlock()
+	s.deletePriceBand(r.Context(), symbol)
+	log.Printf("service=edge-gateway msg=price_band_reset symbol=%s", symbol)
+	writeJSON(w, http.StatusOK, s.priceBandView(symbol))
+}
+
+func (s *Server) handleListPriceBands(w http.ResponseWriter, _ *http.Request) {
+	out := make([]PriceBandView, 0, len(s.registry.marketOrder))
+	for _, symbol := range s.registry.marketOrder {
+		out = append(out, s.priceBandView(symbol))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"defaultMaxDeviationPercent": s.defaultPriceBandPercent(),
+		"bands":                      out,
+	})
+}
+
+func (s *Server) initPriceBandSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_price_bands (
+			symbol TEXT PRIMARY KEY,
+			max_deviation_percent NUMERIC NOT NULL,
+			reference_price TEXT NOT NULL DEFAULT '',
+			updated_at_ms BIGINT NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init price band schema: %w", err)
+	}
+	return nil
+}
+
+// persistPriceBand records a symbol's override so it outlives a restart.
+func (s *Server) persistPriceBand(ctx context.Context, rec priceBandRecord) {
+	ctx, cancel := s.dbContext(context.WithoutCancel(ctx))
+	defer cancel()
+	if s.db == nil {
+		return
+	}
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO web_price_bands(symbol, max_deviation_percent, reference_price, updated_at_ms)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (symbol) DO UPDATE SET
+		 max_deviation_percent = EXCLUDED.max_deviation_percent,
+		 reference_price = EXCLUDED.reference_price,
+		 updated_at_ms = EXCLUDED.updated_at_ms`,
+		rec.Symbol,
+		rec.MaxDeviationPercent,
+		rec.ReferencePrice,
+		rec.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=price_band_persist_failed symbol=%s err=%v", rec.Symbol, err)
+	}
+}
+
+func (s *Server) deletePriceBand(ctx context.Context, symbol string) {
+	ctx, cancel := s.dbContext(context.WithoutCancel(ctx))
+	defer cancel()
+	if s.db == nil {
+		return
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM web_price_bands WHERE symbol = $1`, symbol); err != nil {
+		log.Printf("service=edge-gateway msg=price_band_persist_failed symbol=%s err=%v", symbol, err)
+	}
+}
+
+func (s *Server) loadPriceBands(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT symbol, max_deviation_percent, reference_price, updated_at_ms FROM web_price_bands`)
+	if err != nil {
+		return fmt.Errorf("load price bands: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec priceBandRecord
+		if err := rows.Scan(&rec.Symbol, &rec.MaxDeviationPercent, &rec.ReferencePrice, &rec.UpdatedAt); err != nil {
+			return fmt.Errorf("scan price band: %w", err)
+		}
+		s.state.priceBands[rec.Symbol] = rec
+	}
+	return rows.Err()
+}
@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func adminRequest(t *testing.T, s *Server, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	req.Header.Set("X-Admin-Token", "admin-secret")
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	return w
+}
+
+func TestPriceBandRejectsFatFingerLimitOrders(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	postSmokeTrade(t, s, "band-t1", "BTC-KRW", 100)
+	order := func(key, price string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+			[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"`+price+`","qty":"1"}`), key))
+		return w
+	}
+	if w := order("band-off", "1000"); w.Code != http.StatusOK {
+		t.Fatalf("expected no band by default, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	if w := adminRequest(t, s, http.MethodPut, "/v1/admin/symbols/BTC-KRW/price-band", `{"maxDeviationPercent":-1}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected negative band rejected, got %d", w.Code)
+	}
+	w := adminRequest(t, s, http.MethodPut, "/v1/admin/symbols/BTC-KRW/price-band", `{"maxDeviationPercent":10}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("set band failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var view PriceBandView
+	if err := json.Unmarshal(w.Body.Bytes(), &view); err != nil || view.AnchorPrice == nil || view.AnchorPrice.String() != "100" || view.UpperPrice == nil || view.UpperPrice.String() != "110" || !view.Overridden {
+		t.Fatalf("unexpected band view: %s", w.Body.String())
+	}
+
+	if w := order("band-in", "110"); w.Code != http.StatusOK {
+		t.Fatalf("expected price inside the band accepted, got %d body=%s", w.Code, w.Body.String())
+	}
+	w = order("band-out", "1000")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), priceBandRejectCode) {
+		t.Fatalf("expected PRICE_BAND, got %d body=%s", w.Code, w.Body.String())
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "1110" {
+		t.Fatalf("rejected order must not reserve, got %+v", krw)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	mw := httptest.NewRecorder()
+	s.Router().ServeHTTP(mw, req)
+	if !strings.Contains(mw.Body.String(), "edge_price_band_reject_total 1\n") {
+		t.Fatalf("expected reject counted in metrics, got:\n%s", mw.Body.String())
+	}
+
+	// A fixed reference price anchors the band instead of the last trade.
+	if w := adminRequest(t, s, http.MethodPut, "/v1/admin/symbols/BTC-KRW/price-band", `{"maxDeviationPercent":10,"referencePrice":"1000"}`); w.Code != http.StatusOK {
+		t.Fatalf("set reference failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if w := order("band-ref", "1000"); w.Code != http.StatusOK {
+		t.Fatalf("expected price at the reference accepted, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	if w := adminRequest(t, s, http.MethodDelete, "/v1/admin/symbols/BTC-KRW/price-band", ""); w.Code != http.StatusOK {
+		t.Fatalf("reset band failed: %d", w.Code)
+	}
+	w = adminRequest(t, s, http.MethodGet, "/v1/admin/price-bands", "")
+	var list struct {
+		Bands []PriceBandView `json:"bands"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Bands) == 0 {
+		t.Fatalf("unexpected band list: %d body=%s", w.Code, w.Body.String())
+	}
+	if btc := list.Bands[0]; btc.Symbol != "BTC-KRW" || btc.Overridden || !btc.MaxDeviationPercent.IsZero() || btc.RejectedTotal != 1 {
+		t.Fatalf("expected BTC-KRW back on the default band, got %+v", btc)
+	}
+}
+
+func TestPriceBandChecksTriggeredChildren(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	postSmokeTrade(t, s, "band-trg-t1", "BTC-KRW", 100)
+	if w := adminRequest(t, s, http.MethodPut, "/v1/admin/symbols/BTC-KRW/price-band", `{"maxDeviationPercent":10}`); w.Code != http.StatusOK {
+		t.Fatalf("set band failed: %d body=%s", w.Code, w.Body.String())
+	}
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"SELL","type":"STOP_LIMIT","stopPrice":"90","price":"60","qty":"1"}`), "band-trg"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create stop-limit failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	postSmokeTrade(t, s, "band-trg-t2", "BTC-KRW", 90)
+	record := getOrderRecord(t, s, "ord_band-trg")
+	if record.Status != "REJECTED" || record.Trigger == nil || record.Trigger.ChildOrderID != "" {
+		t.Fatalf("expected the trigger rejected by the band, got %+v", record)
+	}
+	if btc := s.snapshotWallet("test-key")["BTC"]; btc.Hold.String() != "0.00000000" || btc.Available.String() != "2.00000000" {
+		t.Fatalf("expected the reserve released, got %+v", btc)
+	}
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+)
+
+// releaseRetryInterval is how often releases whose wallet commit failed
+// are tried again.
+const releaseRetryInterval = 5 * time.Second
+
+// releaseOrderReserve hands back what a closed order still reserves beyond
+// its fills. The order row, with that reserve detached, is written in the
+// same wallet transaction as the owner's balance, so a record never drops a
+// reserve the wallet still holds. When the commit fails both stay as they
+// were and the order is queued for the release retrier; loadOrders queues
+// the ones a restart finds.
+func (s *Server) releaseOrderReserve(ctx context.Context, orderID string) {
+	s.state.mu.Lock()
+	record, ok := s.state.orders[orderID]
+	s.state.mu.Unlock()
+	if !ok {
+		s.state.mu.Lock()
+		delete(s.state.orderReleaseRetries, orderID)
+		s.state.mu.Unlock()
+		return
+	}
+	tx := s.beginWalletTx(walletOpRelease, orderID, record.OwnerUserID)
+	defer tx.rollback()
+	// Read again under the owner's lock: a release may have landed meanwhile.
+	s.state.mu.Lock()
+	record = s.state.orders[orderID]
+	s.state.mu.Unlock()
+	if !orderReleasePending(record) {
+		s.state.mu.Lock()
+		delete(s.state.orderReleaseRetries, orderID)
+		s.state.mu.Unlock()
+		return
+	}
+	amount := unconsumedReserve(record)
+	record.ReserveAmount = record.ReserveAmount.Sub(amount)
+	stageRelease(tx, record.OwnerUserID, record.ReserveCurrency, amount)
+	tx.also(func(ctx context.Context, dbTx *sql.Tx) error {
+		return writeOrder(ctx, dbTx, record)
+	}, func() {
+		current := s.state.orders[orderID]
+		current.ReserveAmount = current.ReserveAmount.Sub(amount)
+		s.state.orders[orderID] = current
+		delete(s.state.orderReleaseRetries, orderID)
+	})
+	if err := tx.commit(context.WithoutCancel(ctx)); err != nil {
+		s.state.mu.Lock()
+		s.state.orderReleaseRetries[orderID] = true
+		s.state.mu.Unlock()
+		log.Printf(
+			"service=edge-gateway msg=order_release_failed order_id=%s currency=%s amount=%s err=%v",
+			orderID, record.ReserveCurrency, amount, err,
+		)
+		return
+	}
+	s.state.mu.Lock()
+	record = s.state.orders[orderID]
+	s.state.mu.Unlock()
+	s.publishOrderUpdate(record)
+}
+
+// orderReleasePending reports whether a closed order still carries reserve
+// it did not use. A TRIGGERED order handed its reserve to its child.
+func orderReleasePending(record OrderRecord) bool {
+	switch {
+	case isOpenOrderStatus(record.Status), record.Status == orderStatusActivating, record.Status == orderStatusTriggered:
+		return false
+	case record.OwnerUserID == "" || record.ReserveCurrency == "":
+		return false
+	}
+	return unconsumedReserve(record).Sign() > 0
+}
+
+// retryReserveReleases tries every queued release again, oldest reference
+// first. Ones that fail again go back on the queue.
+func (s *Server) retryReserveReleases(ctx context.Context) {
+	s.state.mu.Lock()
+	releases := s.state.releaseRetries
+	s.state.releaseRetries = map[string]reserveRelease{}
+	orderIDs := make([]string, 0, len(s.state.orderReleaseRetries))
+	for orderID := range s.state.orderReleaseRetries {
+		orderIDs = append(orderIDs, orderID)
+	}
+	s.state.mu.Unlock()
+
+	refIDs := make([]string, 0, len(releases))
+	for refID := range releases {
+		refIDs = append(refIDs, refID)
+	}
+	sort.Strings(refIDs)
+	for _, refID := range refIDs {
+		release := releases[refID]
+		s.releaseReserve(release.userID, release.currency, release.amount, refID)
+	}
+	sort.Strings(orderIDs)
+	for _, orderID := range orderIDs {
+		s.releaseOrderReserve(ctx, orderID)
+	}
+}
+
+func (s *Server) startReleaseRetrier() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.releaseCancel = cancel
+	s.releaseWG.Add(1)
+	go func() {
+		defer s.releaseWG.Done()
+		ticker := time.NewTicker(releaseRetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.retryReserveReleases(ctx)
+			}
+		}
+	}()
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math/big"
 	"net/http"
 	"sort"
 	"strconv"
@@ -43,6 +44,7 @@ const (
 	slowConsumerCloseCode = 4001
 	defaultBookDepth      = 20
 	defaultCandleInterval = "1m"
+	defaultDBTimeout      = 5 * time.Second
 )
 
 type contextKey string
@@ -74,7 +76,56 @@ type Config struct {
 	CoreTimeout        time.Duration
 	KafkaBrokers       string
 	KafkaTradeTopic    string
+	KafkaOrderTopic    string
 	KafkaGroupID       string
+	AdminToken         string
+	MaxBatchOrders     int
+
+	// DBTimeout bounds each Postgres call made while serving, such as a
+	// wallet commit, so a stalled database fails the call rather than
+	// holding its wallets' locks.
+	DBTimeout time.Duration
+
+	// FeeAccountID is the wallet trade fees are credited to; it defaults to
+	// the ledger's fee account.
+	FeeAccountID string
+
+	// FeeTiers is the volume-tiered maker/taker schedule used to price
+	// trades the core sends without fees; empty charges nothing unless a
+	// user has an admin override.
+	FeeTiers []FeeTier
+
+	// PriceBandPercent is the default maximum deviation of a limit price
+	// from the symbol's last trade; 0 leaves symbols without an admin
+	// override unbanded.
+	PriceBandPercent float64
+
+	// RegistryFile names a JSON RegistryFile with the markets and assets to
+	// serve; Markets and Assets are used when it is empty, and the built-in
+	// registry when those are empty too.
+	RegistryFile string
+	Markets      []MarketSpec
+	Assets       []AssetSpec
+
+	// Chain is the adapter deposits and withdrawals go through; nil runs
+	// them on an in-process SimulatedChain.
+	Chain ChainAdapter
+
+	// FundingConfirmations is the number of blocks after which a deposit
+	// is credited and a withdrawal final.
+	FundingConfirmations int
+
+	// WithdrawalAddressDelay is how long a newly whitelisted withdrawal
+	// address stays locked.
+	WithdrawalAddressDelay time.Duration
+
+	// WithdrawalDailyLimits caps, per asset, what one user can withdraw in
+	// a UTC day; assets not listed are unlimited.
+	WithdrawalDailyLimits map[string]string
+
+	// TransferDailyLimits caps, per currency, what one user can send to
+	// other users in a UTC day; currencies not listed are unlimited.
+	TransferDailyLimits map[string]string
 }
 
 type OrderRequest struct {
@@ -84,17 +135,47 @@ type OrderRequest struct {
 	Price       string `json:"price"`
 	Qty         string `json:"qty"`
 	TimeInForce string `json:"timeInForce"`
+	ExpireAt    int64  `json:"expireAt,omitempty"`
+
+	ClientOrderID       string `json:"clientOrderId,omitempty"`
+	PostOnly            bool   `json:"postOnly,omitempty"`
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty"`
+
+	StopPrice        string `json:"stopPrice,omitempty"`
+	TriggerSymbol    string `json:"triggerSymbol,omitempty"`
+	TriggerDirection string `json:"triggerDirection,omitempty"`
+	TrailingOffset   string `json:"trailingOffset,omitempty"`
+	TrailingPercent  string `json:"trailingPercent,omitempty"`
+
+	VisibleQty      string `json:"visibleQty,omitempty"`
+	DurationSeconds int64  `json:"durationSeconds,omitempty"`
+	Slices          int    `json:"slices,omitempty"`
+
+	QuoteQty           string  `json:"quoteQty,omitempty"`
+	MaxSlippagePercent float64 `json:"maxSlippagePercent,omitempty"`
+
+	// Set by the gateway for legs of OCO and bracket groups and for the
+	// children of TWAP and ICEBERG orders; never decoded.
+	groupID       string
+	groupRole     string
+	parentOrderID string
+
+	// Set by planMarketOrder: the quote a MARKET buy reserves and the
+	// protection price it is sent to the core with as LIMIT IOC.
+	marketReserve Decimal
+	protectPrice  string
 }
 
 type OrderResponse struct {
-	OrderID     string `json:"orderId"`
-	Status      string `json:"status"`
-	Symbol      string `json:"symbol"`
-	Seq         uint64 `json:"seq"`
-	AcceptedAt  int64  `json:"acceptedAt,omitempty"`
-	CanceledAt  int64  `json:"canceledAt,omitempty"`
-	RejectCode  string `json:"rejectCode,omitempty"`
-	Correlation string `json:"correlationId,omitempty"`
+	OrderID       string `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId,omitempty"`
+	Status        string `json:"status"`
+	Symbol        string `json:"symbol"`
+	Seq           uint64 `json:"seq"`
+	AcceptedAt    int64  `json:"acceptedAt,omitempty"`
+	CanceledAt    int64  `json:"canceledAt,omitempty"`
+	RejectCode    string `json:"rejectCode,omitempty"`
+	Correlation   string `json:"correlationId,omitempty"`
 }
 
 type AuthCredentialsRequest struct {
@@ -114,29 +195,65 @@ type AuthSessionResponse struct {
 }
 
 type BalanceView struct {
-	Currency  string  `json:"currency"`
-	Available float64 `json:"available"`
-	Hold      float64 `json:"hold"`
-	Total     float64 `json:"total"`
-	PriceKRW  float64 `json:"priceKrw,omitempty"`
-	ValueKRW  float64 `json:"valueKrw,omitempty"`
+	Currency  string   `json:"currency"`
+	Available Decimal  `json:"available"`
+	Hold      Decimal  `json:"hold"`
+	Total     Decimal  `json:"total"`
+	PriceKRW  *Decimal `json:"priceKrw,omitempty"`
+	ValueKRW  *Decimal `json:"valueKrw,omitempty"`
 }
 
 type OrderRecord struct {
-	OrderID    string `json:"orderId"`
-	Status     string `json:"status"`
-	Symbol     string `json:"symbol"`
-	Seq        uint64 `json:"seq"`
-	AcceptedAt int64  `json:"acceptedAt"`
-	CanceledAt int64  `json:"canceledAt,omitempty"`
+	OrderID       string `json:"orderId"`
+	ClientOrderID string `json:"clientOrderId,omitempty"`
+	Status        string `json:"status"`
+	Symbol        string `json:"symbol"`
+	Seq           uint64 `json:"seq"`
+	CreatedAt     int64  `json:"createdAt,omitempty"`
+	AcceptedAt    int64  `json:"acceptedAt"`
+	CanceledAt    int64  `json:"canceledAt,omitempty"`
 
 	OwnerUserID     string  `json:"-"`
 	ReserveCurrency string  `json:"-"`
-	ReserveAmount   float64 `json:"-"`
-	ReserveConsumed float64 `json:"-"`
-	Side            string  `json:"-"`
-	Qty             float64 `json:"-"`
-	FilledQty       float64 `json:"filledQty,omitempty"`
+	ReserveAmount   Decimal `json:"-"`
+	ReserveConsumed Decimal `json:"-"`
+	Side            string  `json:"side,omitempty"`
+	Type            string  `json:"type,omitempty"`
+	Price           string  `json:"price,omitempty"`
+	TimeInForce     string  `json:"timeInForce,omitempty"`
+	ExpireAt        int64   `json:"expireAt,omitempty"`
+	Qty             Decimal `json:"qty"`
+	QuoteQty        string  `json:"quoteQty,omitempty"`
+	FilledQty       Decimal `json:"filledQty"`
+	ReplacedBy      string  `json:"replacedBy,omitempty"`
+
+	PostOnly            bool   `json:"postOnly,omitempty"`
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty"`
+
+	Trigger   *OrderTrigger `json:"trigger,omitempty"`
+	GroupID   string        `json:"groupId,omitempty"`
+	GroupRole string        `json:"groupRole,omitempty"`
+
+	Algo          *AlgoState `json:"algo,omitempty"`
+	ParentOrderID string     `json:"parentOrderId,omitempty"`
+}
+
+type orderListFilter struct {
+	symbol string
+	side   string
+	status string
+	fromMs int64
+	toMs   int64
+	limit  int
+	cursor orderCursor
+}
+
+// orderCursor points at the last order of a page. Seq is only unique per
+// symbol (one core per symbol), so the order ID breaks ties.
+type orderCursor struct {
+	seq     uint64
+	orderID string
+	set     bool
 }
 
 type tradeEventEnvelope struct {
@@ -159,6 +276,8 @@ type tradeEventPayload struct {
 	Price        interface{}        `json:"price"`
 	Quantity     interface{}        `json:"quantity"`
 	QuoteAmount  interface{}        `json:"quoteAmount"`
+	FeeBuyer     interface{}        `json:"feeBuyer"`
+	FeeSeller    interface{}        `json:"feeSeller"`
 	Symbol       string             `json:"symbol"`
 	Seq          uint64             `json:"seq"`
 	TsMs         int64              `json:"ts"`
@@ -193,6 +312,12 @@ type WSCommand struct {
 	LastSeq  uint64 `json:"lastSeq,omitempty"`
 	Depth    int    `json:"depth,omitempty"`
 	Interval string `json:"interval,omitempty"`
+
+	// AUTH: a session token, or an API key with a signed timestamp.
+	Token     string `json:"token,omitempty"`
+	APIKey    string `json:"apiKey,omitempty"`
+	Ts        int64  `json:"ts,omitempty"`
+	Signature string `json:"signature,omitempty"`
 }
 
 type idempotencyRecord struct {
@@ -216,8 +341,8 @@ type sessionRecord struct {
 }
 
 type walletBalance struct {
-	Available float64 `json:"available"`
-	Hold      float64 `json:"hold"`
+	Available Decimal `json:"available"`
+	Hold      Decimal `json:"hold"`
 }
 
 type state struct {
@@ -242,12 +367,45 @@ type state struct {
 	sessionsMemory  map[string]sessionRecord
 	wallets         map[string]map[string]walletBalance
 	appliedTrades   map[string]int64
+	symbolModes     map[string]symbolModeRecord
+	clientOrderIDs  map[string]string
+	fills           map[string][]FillRecord
+	deadManSwitches map[string]deadManSwitch
+	priceBands      map[string]priceBandRecord
+	triggerIndex    map[string]map[string]struct{}
+	activatingFills map[string][]orderFill
+
+	orderEventSeq    map[string]uint64
+	pendingCancels   map[string]pendingCancel
+	earlyOrderEvents map[string]orderLifecycleEvent
+	// Releases whose wallet commit failed, retried by the release retrier:
+	// by reference for reserves no record carries, and by order ID for
+	// closed orders that still carry theirs.
+	releaseRetries      map[string]reserveRelease
+	orderReleaseRetries map[string]bool
+	tradeVolume         map[string]map[int64]Decimal
+	feeOverrides        map[string]feeOverride
+	ledger              map[string][]*exchangev1.LedgerEntryAppended
+	ledgerLoaded        map[string]bool
+	ledgerSeq           uint64
+
+	depositAddresses    map[string]DepositAddress
+	deposits            map[string]DepositRecord
+	withdrawals         map[string]WithdrawalRecord
+	withdrawalAddresses map[string]map[string]WithdrawalAddress
+	transfers           []TransferRecord
+	transfersByUser     map[string][]int
+	transferKeys        map[transferKey]int
 
 	ordersTotal        uint64
 	tradesTotal        uint64
 	slowConsumerCloses uint64
 	wsDroppedMsgs      uint64
 	replayDetected     uint64
+	deadManTriggers    uint64
+	priceBandRejects   map[string]uint64
+	orderEventsApplied uint64
+	orderEventsStale   uint64
 }
 
 type wsSubscription struct {
@@ -276,6 +434,7 @@ type client struct {
 	closeOnce   sync.Once
 	conflated   map[string][]byte
 	subscribers map[string]wsSubscription
+	userID      string
 }
 
 func (c *client) closeSend() {
@@ -359,12 +518,44 @@ type Server struct {
 	coreConn      *grpc.ClientConn
 	coreClient    exchangev1.TradingCoreServiceClient
 	state         *state
+	walletMu      sync.RWMutex // shared by wallet txs, exclusive for the ledger check
+	walletLocks   walletLocks
+	registry      *registry
+	chain         ChainAdapter
 	upgrader      websocket.Upgrader
 	tracer        trace.Tracer
 	traceShutdown func(context.Context) error
 	tradeConsumer *kafka.Reader
 	tradeCancel   context.CancelFunc
 	tradeWG       sync.WaitGroup
+	orderConsumer *kafka.Reader
+	orderCancel   context.CancelFunc
+	orderWG       sync.WaitGroup
+	// orderEvents is set while core order events are consumed: orders the
+	// core cancels on its own then close through their OrderCanceled event,
+	// which knows how much of them filled.
+	orderEvents   bool
+	algoCancel    context.CancelFunc
+	algoWG        sync.WaitGroup
+	expiryCancel  context.CancelFunc
+	expiryWG      sync.WaitGroup
+	deadManCancel context.CancelFunc
+	deadManWG     sync.WaitGroup
+	releaseCancel context.CancelFunc
+	releaseWG     sync.WaitGroup
+	fundingCancel context.CancelFunc
+	fundingWG     sync.WaitGroup
+	triggerCancel context.CancelFunc
+	triggerWG     sync.WaitGroup
+	// Conditional orders whose trigger held wait in triggerQueue for the
+	// trigger worker; triggerPending counts those not yet handled.
+	triggerMu      sync.Mutex
+	triggerQueue   []triggerFire
+	triggerWake    chan struct{}
+	triggerPending sync.WaitGroup
+
+	withdrawalLimits map[string]Decimal
+	transferLimits   map[string]Decimal
 }
 
 func New(cfg Config) (*Server, error) {
@@ -407,12 +598,49 @@ func New(cfg Config) (*Server, error) {
 	if cfg.KafkaTradeTopic == "" {
 		cfg.KafkaTradeTopic = "core.trade-events.v1"
 	}
+	if cfg.KafkaOrderTopic == "" {
+		cfg.KafkaOrderTopic = "core.order-events.v1"
+	}
 	if cfg.KafkaGroupID == "" {
 		cfg.KafkaGroupID = "edge-trades-v1"
 	}
+	if cfg.MaxBatchOrders <= 0 {
+		cfg.MaxBatchOrders = 20
+	}
+	if cfg.FeeAccountID == "" {
+		cfg.FeeAccountID = defaultFeeAccountID
+	}
+	if cfg.DBTimeout <= 0 {
+		cfg.DBTimeout = defaultDBTimeout
+	}
+	if cfg.Chain == nil {
+		cfg.Chain = NewSimulatedChain()
+	}
+	if cfg.FundingConfirmations <= 0 {
+		cfg.FundingConfirmations = defaultFundingConfirmations
+	}
+	if cfg.WithdrawalAddressDelay <= 0 {
+		cfg.WithdrawalAddressDelay = defaultWithdrawalAddressDelay
+	}
+
+	reg, err := loadRegistry(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("load market registry: %w", err)
+	}
+	cfg.FeeTiers, err = validateFeeTiers(cfg.FeeTiers)
+	if err != nil {
+		return nil, fmt.Errorf("load fee tiers: %w", err)
+	}
+	withdrawalLimits, err := parseDailyLimits(cfg.WithdrawalDailyLimits, reg)
+	if err != nil {
+		return nil, fmt.Errorf("load withdrawal limits: %w", err)
+	}
+	transferLimits, err := parseDailyLimits(cfg.TransferDailyLimits, reg)
+	if err != nil {
+		return nil, fmt.Errorf("load transfer limits: %w", err)
+	}
 
 	var db *sql.DB
-	var err error
 	if !cfg.DisableDB {
 		db, err = sql.Open("postgres", cfg.DBDsn)
 		if err != nil {
@@ -457,33 +685,86 @@ func New(cfg Config) (*Server, error) {
 		redis:      rdb,
 		coreConn:   coreConn,
 		coreClient: coreClient,
+		registry:   reg,
+		chain:      cfg.Chain,
 		state: &state{
-			nextSeq:            1,
-			nextOrderID:        1,
-			orders:             map[string]OrderRecord{},
-			idempotencyResults: map[string]idempotencyRecord{},
-			replayCache:        map[string]int64{},
-			rateWindow:         map[string][]int64{},
-			authFailReason:     map[string]uint64{},
-			clients:            map[*client]struct{}{},
-			historyBySymbol:    map[string][]WSMessage{},
-			tradeTape:          map[string][]tradePoint{},
-			cacheMemory:        map[string][]byte{},
-			usersByEmail:       map[string]userRecord{},
-			usersByID:          map[string]userRecord{},
-			sessionsMemory:     map[string]sessionRecord{},
-			wallets:            map[string]map[string]walletBalance{},
-			appliedTrades:      map[string]int64{},
+			nextSeq:             1,
+			nextOrderID:         1,
+			orders:              map[string]OrderRecord{},
+			idempotencyResults:  map[string]idempotencyRecord{},
+			replayCache:         map[string]int64{},
+			rateWindow:          map[string][]int64{},
+			authFailReason:      map[string]uint64{},
+			clients:             map[*client]struct{}{},
+			historyBySymbol:     map[string][]WSMessage{},
+			tradeTape:           map[string][]tradePoint{},
+			cacheMemory:         map[string][]byte{},
+			usersByEmail:        map[string]userRecord{},
+			usersByID:           map[string]userRecord{},
+			sessionsMemory:      map[string]sessionRecord{},
+			wallets:             map[string]map[string]walletBalance{},
+			appliedTrades:       map[string]int64{},
+			symbolModes:         map[string]symbolModeRecord{},
+			clientOrderIDs:      map[string]string{},
+			fills:               map[string][]FillRecord{},
+			deadManSwitches:     map[string]deadManSwitch{},
+			priceBands:          map[string]priceBandRecord{},
+			triggerIndex:        map[string]map[string]struct{}{},
+			activatingFills:     map[string][]orderFill{},
+			priceBandRejects:    map[string]uint64{},
+			orderEventSeq:       map[string]uint64{},
+			pendingCancels:      map[string]pendingCancel{},
+			releaseRetries:      map[string]reserveRelease{},
+			orderReleaseRetries: map[string]bool{},
+			earlyOrderEvents:    map[string]orderLifecycleEvent{},
+			tradeVolume:         map[string]map[int64]Decimal{},
+			feeOverrides:        map[string]feeOverride{},
+			ledger:              map[string][]*exchangev1.LedgerEntryAppended{},
+			ledgerLoaded:        map[string]bool{},
+
+			depositAddresses:    map[string]DepositAddress{},
+			deposits:            map[string]DepositRecord{},
+			withdrawals:         map[string]WithdrawalRecord{},
+			withdrawalAddresses: map[string]map[string]WithdrawalAddress{},
+			transfersByUser:     map[string][]int{},
+			transferKeys:        map[transferKey]int{},
 		},
 		upgrader:      websocket.Upgrader{CheckOrigin: func(_ *http.Request) bool { return true }},
 		tracer:        otelTracer,
 		traceShutdown: otelShutdown,
+		triggerWake:   make(chan struct{}, 1),
+
+		withdrawalLimits: withdrawalLimits,
+		transferLimits:   transferLimits,
 	}
 
 	if s.db != nil {
 		if err := s.initSchema(context.Background()); err != nil {
 			return nil, err
 		}
+		if err := s.loadOrders(context.Background()); err != nil {
+			return nil, err
+		}
+		s.loadFeeWallet(context.Background())
+		if err := s.loadFunding(context.Background()); err != nil {
+			return nil, err
+		}
+		if err := s.loadTransfers(context.Background()); err != nil {
+			return nil, err
+		}
+		if err := s.loadSymbolModes(context.Background()); err != nil {
+			return nil, err
+		}
+		if err := s.loadDeadManSwitches(context.Background(), time.Now().UnixMilli()); err != nil {
+			return nil, err
+		}
+		if err := s.loadPriceBands(context.Background()); err != nil {
+			return nil, err
+		}
+		if err := s.loadFeeOverrides(context.Background()); err != nil {
+			return nil, err
+		}
+		s.loadTradeVolume(context.Background())
 	}
 
 	r := chi.NewRouter()
@@ -492,10 +773,13 @@ func New(cfg Config) (*Server, error) {
 	r.Get("/readyz", s.handleReady)
 	r.Get("/metrics", s.handleMetrics)
 
+	r.Get("/v1/markets", s.handleListMarkets)
+	r.Get("/v1/assets", s.handleListAssets)
 	r.Get("/v1/markets/{symbol}/trades", s.handleGetTrades)
 	r.Get("/v1/markets/{symbol}/orderbook", s.handleGetOrderbook)
 	r.Get("/v1/markets/{symbol}/candles", s.handleGetCandles)
 	r.Get("/v1/markets/{symbol}/ticker", s.handleGetTicker)
+	r.Get("/v1/markets/{symbol}/status", s.handleGetMarketStatus)
 	r.Post("/v1/auth/signup", s.handleSignUp)
 	r.Post("/v1/auth/login", s.handleLogin)
 
@@ -510,11 +794,58 @@ func New(cfg Config) (*Server, error) {
 	r.Group(func(protected chi.Router) {
 		protected.Use(s.authMiddleware)
 		protected.Post("/v1/orders", s.handleCreateOrder)
+		protected.Post("/v1/orders/batch", s.handleCreateOrderBatch)
+		protected.Post("/v1/orders/oco", s.handleCreateOCOOrder)
+		protected.Post("/v1/orders/bracket", s.handleCreateBracketOrder)
+		protected.Get("/v1/orders", s.handleListOrders)
+		protected.Delete("/v1/orders", s.handleCancelAllOrders)
 		protected.Delete("/v1/orders/{orderId}", s.handleCancelOrder)
 		protected.Get("/v1/orders/{orderId}", s.handleGetOrder)
+		protected.Get("/v1/orders/{orderId}/fills", s.handleGetOrderFills)
+		protected.Get("/v1/orders/by-client-id/{clientOrderId}", s.handleGetOrderByClientID)
+		protected.Delete("/v1/orders/by-client-id/{clientOrderId}", s.handleCancelOrderByClientID)
+		protected.Patch("/v1/orders/{orderId}", s.handleReplaceOrder)
+		protected.Get("/v1/account/trades", s.handleListAccountTrades)
+		protected.Get("/v1/account/fees", s.handleGetAccountFees)
+		protected.Get("/v1/account/ledger", s.handleListLedger)
+		protected.Post("/v1/account/deposit-addresses", s.handleCreateDepositAddress)
+		protected.Get("/v1/account/deposit-addresses", s.handleListDepositAddresses)
+		protected.Get("/v1/account/deposits", s.handleListDeposits)
+		protected.Post("/v1/account/withdrawal-addresses", s.handleAddWithdrawalAddress)
+		protected.Get("/v1/account/withdrawal-addresses", s.handleListWithdrawalAddresses)
+		protected.Delete("/v1/account/withdrawal-addresses/{asset}/{address}", s.handleDeleteWithdrawalAddress)
+		protected.Post("/v1/account/withdrawals", s.handleCreateWithdrawal)
+		protected.Get("/v1/account/withdrawals", s.handleListWithdrawals)
+		protected.Post("/v1/account/transfers", s.handleCreateTransfer)
+		protected.Get("/v1/account/transfers", s.handleListTransfers)
+		protected.Get("/v1/account/sub-accounts", s.handleListSubAccounts)
+		protected.Get("/v1/account/dead-man-switch", s.handleGetDeadManSwitch)
+		protected.Put("/v1/account/dead-man-switch", s.handleArmDeadManSwitch)
+		protected.Delete("/v1/account/dead-man-switch", s.handleDisarmDeadManSwitch)
+		protected.Post("/v1/account/dead-man-switch/heartbeat", s.handleDeadManHeartbeat)
 		protected.Post("/v1/smoke/trades", s.handleSmokeTrade)
 	})
 
+	r.Group(func(admin chi.Router) {
+		admin.Use(s.adminMiddleware)
+		admin.Post("/v1/admin/symbols/{symbol}/mode", s.handleSetSymbolMode)
+		admin.Get("/v1/admin/fee-account", s.handleGetFeeAccount)
+		admin.Get("/v1/admin/ledger/check", s.handleCheckLedger)
+		admin.Get("/v1/admin/withdrawals", s.handleAdminListWithdrawals)
+		admin.Post("/v1/admin/withdrawals/{withdrawalId}/approve", s.handleApproveWithdrawal)
+		admin.Post("/v1/admin/withdrawals/{withdrawalId}/reject", s.handleRejectWithdrawal)
+		admin.Post("/v1/admin/chain/deposits", s.handleSimulateDeposit)
+		admin.Post("/v1/admin/chain/blocks", s.handleMineBlocks)
+		admin.Post("/v1/admin/chain/transfers/{txHash}/fail", s.handleFailTransfer)
+		admin.Get("/v1/admin/users/{userId}/fees", s.handleGetFeeOverride)
+		admin.Put("/v1/admin/users/{userId}/fees", s.handleSetFeeOverride)
+		admin.Delete("/v1/admin/users/{userId}/fees", s.handleDeleteFeeOverride)
+		admin.Get("/v1/admin/price-bands", s.handleListPriceBands)
+		admin.Get("/v1/admin/symbols/{symbol}/price-band", s.handleGetPriceBand)
+		admin.Put("/v1/admin/symbols/{symbol}/price-band", s.handleSetPriceBand)
+		admin.Delete("/v1/admin/symbols/{symbol}/price-band", s.handleResetPriceBand)
+	})
+
 	r.Get("/ws", s.handleWS)
 	s.router = r
 
@@ -525,6 +856,13 @@ func New(cfg Config) (*Server, error) {
 	}
 
 	s.startTradeConsumer()
+	s.startOrderEventConsumer()
+	s.startAlgoScheduler()
+	s.startExpirySweeper()
+	s.startDeadManSweeper()
+	s.startReleaseRetrier()
+	s.startFundingWatcher()
+	s.startTriggerWorker()
 
 	return s, nil
 }
@@ -539,6 +877,37 @@ func (s *Server) Close() error {
 		_ = s.tradeConsumer.Close()
 	}
 	s.tradeWG.Wait()
+	if s.orderCancel != nil {
+		s.orderCancel()
+	}
+	if s.orderConsumer != nil {
+		_ = s.orderConsumer.Close()
+	}
+	s.orderWG.Wait()
+	if s.algoCancel != nil {
+		s.algoCancel()
+	}
+	s.algoWG.Wait()
+	if s.expiryCancel != nil {
+		s.expiryCancel()
+	}
+	s.expiryWG.Wait()
+	if s.deadManCancel != nil {
+		s.deadManCancel()
+	}
+	s.deadManWG.Wait()
+	if s.releaseCancel != nil {
+		s.releaseCancel()
+	}
+	s.releaseWG.Wait()
+	if s.fundingCancel != nil {
+		s.fundingCancel()
+	}
+	s.fundingWG.Wait()
+	if s.triggerCancel != nil {
+		s.triggerCancel()
+	}
+	s.triggerWG.Wait()
 	if s.db != nil {
 		_ = s.db.Close()
 	}
@@ -592,8 +961,8 @@ func (s *Server) initSchema(ctx context.Context) error {
 		CREATE TABLE IF NOT EXISTS web_wallet_balances (
 			user_id TEXT NOT NULL,
 			currency TEXT NOT NULL,
-			available DOUBLE PRECISION NOT NULL DEFAULT 0,
-			hold DOUBLE PRECISION NOT NULL DEFAULT 0,
+			available NUMERIC NOT NULL DEFAULT 0,
+			hold NUMERIC NOT NULL DEFAULT 0,
 			updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
 			PRIMARY KEY (user_id, currency)
 		)
@@ -601,6 +970,62 @@ func (s *Server) initSchema(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("init wallet schema: %w", err)
 	}
+	if err := s.migrateNumericColumns(ctx, "web_wallet_balances", "available", "hold"); err != nil {
+		return err
+	}
+	if err := s.initWalletJournalSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.initLedgerSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.initFundingSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.initTransferSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.initSymbolModeSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.initDeadManSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.initPriceBandSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.initFeeOverrideSchema(ctx); err != nil {
+		return err
+	}
+	if err := s.initOrderSchema(ctx); err != nil {
+		return err
+	}
+	return s.initFillSchema(ctx)
+}
+
+// migrateNumericColumns converts amount columns created as DOUBLE PRECISION
+// by earlier versions to exact NUMERIC. Columns already converted are left
+// alone so startup does not rewrite the table.
+func (s *Server) migrateNumericColumns(ctx context.Context, table string, columns ...string) error {
+	for _, column := range columns {
+		var dataType string
+		err := s.db.QueryRowContext(
+			ctx,
+			`SELECT data_type FROM information_schema.columns WHERE table_name = $1 AND column_name = $2`,
+			table,
+			column,
+		).Scan(&dataType)
+		if err != nil || dataType != "double precision" {
+			continue
+		}
+		_, err = s.db.ExecContext(ctx, fmt.Sprintf(
+			`ALTER TABLE %s ALTER COLUMN %s TYPE NUMERIC USING %s::numeric`,
+			table, column, column,
+		))
+		if err != nil {
+			return fmt.Errorf("migrate %s.%s to numeric: %w", table, column, err)
+		}
+	}
 	return nil
 }
 
@@ -636,6 +1061,13 @@ func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
 	slowClose := s.state.slowConsumerCloses
 	droppedMsgs := s.state.wsDroppedMsgs
 	replayDetected := s.state.replayDetected
+	deadManTriggers := s.state.deadManTriggers
+	orderEventsApplied := s.state.orderEventsApplied
+	orderEventsStale := s.state.orderEventsStale
+	priceBandRejects := uint64(0)
+	for _, c := range s.state.priceBandRejects {
+		priceBandRejects += c
+	}
 	queueLens := make([]int, 0, len(s.state.clients))
 	for c := range s.state.clients {
 		queueLens = append(queueLens, c.queueLen())
@@ -654,6 +1086,10 @@ func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
 	_, _ = w.Write([]byte("edge_ws_close_slow_consumer_total " + strconv.FormatUint(slowClose, 10) + "\n"))
 	_, _ = w.Write([]byte("edge_auth_fail_total " + strconv.FormatUint(authFail, 10) + "\n"))
 	_, _ = w.Write([]byte("edge_replay_detect_total " + strconv.FormatUint(replayDetected, 10) + "\n"))
+	_, _ = w.Write([]byte("edge_dead_man_switch_triggers_total " + strconv.FormatUint(deadManTriggers, 10) + "\n"))
+	_, _ = w.Write([]byte("edge_price_band_reject_total " + strconv.FormatUint(priceBandRejects, 10) + "\n"))
+	_, _ = w.Write([]byte("edge_order_events_applied_total " + strconv.FormatUint(orderEventsApplied, 10) + "\n"))
+	_, _ = w.Write([]byte("edge_order_events_stale_total " + strconv.FormatUint(orderEventsStale, 10) + "\n"))
 	_, _ = w.Write([]byte("ws_active_conns " + strconv.Itoa(clients) + "\n"))
 	_, _ = w.Write([]byte("ws_send_queue_p99 " + strconv.Itoa(queueP99) + "\n"))
 	_, _ = w.Write([]byte("ws_dropped_msgs " + strconv.FormatUint(droppedMsgs, 10) + "\n"))
@@ -878,22 +1314,25 @@ func (s *Server) handleGetBalances(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 		return
 	}
-	balances := s.snapshotWallet(userID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"userId":   userID,
+		"balances": balanceViews(s.snapshotWallet(userID)),
+	})
+}
+
+// balanceViews lists a wallet's balances by currency.
+func balanceViews(balances map[string]walletBalance) []BalanceView {
 	out := make([]BalanceView, 0, len(balances))
 	for currency, bal := range balances {
-		total := bal.Available + bal.Hold
 		out = append(out, BalanceView{
 			Currency:  currency,
 			Available: bal.Available,
 			Hold:      bal.Hold,
-			Total:     total,
+			Total:     bal.Available.Add(bal.Hold),
 		})
 	}
 	sort.Slice(out, func(i, j int) bool { return out[i].Currency < out[j].Currency })
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"userId":   userID,
-		"balances": out,
-	})
+	return out
 }
 
 func (s *Server) handleGetPortfolio(w http.ResponseWriter, r *http.Request) {
@@ -904,10 +1343,11 @@ func (s *Server) handleGetPortfolio(w http.ResponseWriter, r *http.Request) {
 	}
 	balances := s.snapshotWallet(userID)
 	assets := make([]BalanceView, 0, len(balances))
-	totalValue := 0.0
+	krwScale := s.assetScale("KRW")
+	totalValue := s.assetAmount("KRW", 0)
 	for currency, bal := range balances {
-		total := bal.Available + bal.Hold
-		price := 1.0
+		total := bal.Available.Add(bal.Hold)
+		price := int64(1)
 		if currency != "KRW" {
 			if latest, ok := s.latestPriceKRW(currency); ok && latest > 0 {
 				price = latest
@@ -915,18 +1355,19 @@ func (s *Server) handleGetPortfolio(w http.ResponseWriter, r *http.Request) {
 				price = 0
 			}
 		}
-		value := total * price
-		totalValue += value
+		priceKRW := s.assetAmount("KRW", price)
+		value := total.MulInt(price).Floor(krwScale)
+		totalValue = totalValue.Add(value)
 		assets = append(assets, BalanceView{
 			Currency:  currency,
 			Available: bal.Available,
 			Hold:      bal.Hold,
 			Total:     total,
-			PriceKRW:  price,
-			ValueKRW:  value,
+			PriceKRW:  &priceKRW,
+			ValueKRW:  &value,
 		})
 	}
-	sort.Slice(assets, func(i, j int) bool { return assets[i].ValueKRW > assets[j].ValueKRW })
+	sort.Slice(assets, func(i, j int) bool { return assets[i].ValueKRW.Cmp(*assets[j].ValueKRW) > 0 })
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"userId":          userID,
 		"assets":          assets,
@@ -936,6 +1377,8 @@ func (s *Server) handleGetPortfolio(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) createUser(ctx context.Context, email, passwordHash string) (userRecord, error) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
 	if existing, ok := s.getUserByEmail(ctx, email); ok {
 		return existing, fmt.Errorf("already_exists")
 	}
@@ -946,7 +1389,7 @@ func (s *Server) createUser(ctx context.Context, email, passwordHash string) (us
 		PasswordHash: passwordHash,
 		CreatedAtMs:  time.Now().UnixMilli(),
 	}
-	defaults := defaultWalletBalances()
+	defaults := s.defaultWalletBalances()
 
 	if s.db != nil {
 		tx, err := s.db.BeginTx(ctx, nil)
@@ -976,18 +1419,13 @@ func (s *Server) createUser(ctx context.Context, email, passwordHash string) (us
 		}
 
 		for currency, bal := range defaults {
-			_, err = tx.ExecContext(
-				ctx,
-				`INSERT INTO web_wallet_balances(user_id, currency, available, hold) VALUES ($1, $2, $3, $4)
-				 ON CONFLICT (user_id, currency) DO UPDATE SET
-				 available = EXCLUDED.available,
-				 hold = EXCLUDED.hold,
-				 updated_at = now()`,
-				user.UserID,
-				currency,
-				bal.Available,
-				bal.Hold,
-			)
+			zero := s.assetAmount(currency, 0)
+			err = writeWalletChange(ctx, tx, walletOpOpen, user.UserID, walletChange{
+				userID:   user.UserID,
+				currency: currency,
+				before:   walletBalance{Available: zero, Hold: zero},
+				after:    bal,
+			})
 			if err != nil {
 				return userRecord{}, fmt.Errorf("insert wallet: %w", err)
 			}
@@ -1009,6 +1447,8 @@ func (s *Server) createUser(ctx context.Context, email, passwordHash string) (us
 }
 
 func (s *Server) getUserByEmail(ctx context.Context, email string) (userRecord, bool) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
 	s.state.mu.Lock()
 	if user, ok := s.state.usersByEmail[email]; ok {
 		s.state.mu.Unlock()
@@ -1032,12 +1472,12 @@ func (s *Server) getUserByEmail(ctx context.Context, email string) (userRecord,
 	}
 	user.CreatedAtMs = createdAt.UnixMilli()
 
-	wallet := s.loadWalletFromDB(ctx, user.UserID)
+	wallet, walletErr := s.loadWalletFromDB(ctx, user.UserID)
 
 	s.state.mu.Lock()
 	s.state.usersByEmail[user.Email] = user
 	s.state.usersByID[user.UserID] = user
-	if _, ok := s.state.wallets[user.UserID]; !ok {
+	if _, ok := s.state.wallets[user.UserID]; !ok && walletErr == nil {
 		s.state.wallets[user.UserID] = wallet
 	}
 	s.state.mu.Unlock()
@@ -1045,6 +1485,8 @@ func (s *Server) getUserByEmail(ctx context.Context, email string) (userRecord,
 }
 
 func (s *Server) getUserByID(ctx context.Context, userID string) (userRecord, bool) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
 	s.state.mu.Lock()
 	if user, ok := s.state.usersByID[userID]; ok {
 		s.state.mu.Unlock()
@@ -1068,12 +1510,12 @@ func (s *Server) getUserByID(ctx context.Context, userID string) (userRecord, bo
 	}
 	user.CreatedAtMs = createdAt.UnixMilli()
 
-	wallet := s.loadWalletFromDB(ctx, user.UserID)
+	wallet, walletErr := s.loadWalletFromDB(ctx, user.UserID)
 
 	s.state.mu.Lock()
 	s.state.usersByEmail[user.Email] = user
 	s.state.usersByID[user.UserID] = user
-	if _, ok := s.state.wallets[user.UserID]; !ok {
+	if _, ok := s.state.wallets[user.UserID]; !ok && walletErr == nil {
 		s.state.wallets[user.UserID] = wallet
 	}
 	s.state.mu.Unlock()
@@ -1143,116 +1585,160 @@ func (s *Server) deleteSession(ctx context.Context, token string) {
 func (s *Server) snapshotWallet(userID string) map[string]walletBalance {
 	s.state.mu.Lock()
 	wallet, ok := s.state.wallets[userID]
+	if ok {
+		wallet = cloneWallet(wallet)
+	}
 	s.state.mu.Unlock()
 	if !ok {
 		if s.db != nil {
-			wallet = s.loadWalletFromDB(context.Background(), userID)
+			var err error
+			wallet, err = s.loadWalletFromDB(context.Background(), userID)
+			if err != nil {
+				// Left uncached; a wallet tx reads the rows again itself.
+				log.Printf("service=edge-gateway msg=wallet_load_failed user_id=%s err=%v", userID, err)
+				return map[string]walletBalance{}
+			}
 		}
 		if len(wallet) == 0 {
-			wallet = defaultWalletBalances()
+			wallet = s.defaultWalletBalances()
 		}
 		s.state.mu.Lock()
-		s.state.wallets[userID] = cloneWallet(wallet)
+		if current, loaded := s.state.wallets[userID]; loaded {
+			wallet = cloneWallet(current)
+		} else {
+			s.state.wallets[userID] = cloneWallet(wallet)
+		}
 		s.state.mu.Unlock()
 	}
-	return cloneWallet(wallet)
+	return wallet
 }
 
-func (s *Server) applyReserve(userID string, currency string, amount float64) (walletBalance, error) {
-	if amount <= 0 {
+// applyReserve moves amount from available to hold for the order refID.
+// It fails with insufficient_balance, or errWalletUnavailable when the
+// change could not be persisted.
+func (s *Server) applyReserve(userID, currency string, amount Decimal, refID string) (walletBalance, error) {
+	if amount.Sign() <= 0 {
 		return walletBalance{}, fmt.Errorf("amount must be > 0")
 	}
 	currency = strings.ToUpper(strings.TrimSpace(currency))
+	s.snapshotWallet(userID)
 
-	s.state.mu.Lock()
-	wallet, ok := s.state.wallets[userID]
-	if !ok {
-		wallet = defaultWalletBalances()
-	}
-	current := wallet[currency]
-	if current.Available+1e-9 < amount {
-		s.state.mu.Unlock()
+	tx := s.beginWalletTx(walletOpReserve, refID, userID)
+	defer tx.rollback()
+	current := tx.balance(userID, currency)
+	if current.Available.Cmp(amount) < 0 {
 		return walletBalance{}, fmt.Errorf("insufficient_balance")
 	}
-	current.Available -= amount
-	current.Hold += amount
-	wallet[currency] = current
-	s.state.wallets[userID] = wallet
-	s.state.mu.Unlock()
-
-	s.persistWalletBalance(context.Background(), userID, currency, current)
+	current.Available = current.Available.Sub(amount)
+	current.Hold = current.Hold.Add(amount)
+	tx.set(userID, currency, current)
+	if err := tx.commit(context.Background()); err != nil {
+		return walletBalance{}, err
+	}
 	return current, nil
 }
 
-func (s *Server) releaseReserve(userID, currency string, amount float64) walletBalance {
-	if amount <= 0 {
-		return walletBalance{}
+// releaseReserve returns up to amount of the order refID's hold to
+// available. It is for reserves no order record carries, such as one taken
+// for an order the core then refused; a release that cannot be committed
+// is queued for the release retrier, and the hold stays put meanwhile.
+func (s *Server) releaseReserve(userID, currency string, amount Decimal, refID string) {
+	if amount.Sign() <= 0 {
+		return
 	}
 	currency = strings.ToUpper(strings.TrimSpace(currency))
-	s.state.mu.Lock()
-	wallet, ok := s.state.wallets[userID]
-	if !ok {
-		wallet = defaultWalletBalances()
-	}
-	current := wallet[currency]
-	if current.Hold >= amount {
-		current.Hold -= amount
-		current.Available += amount
-	} else {
-		current.Available += current.Hold
-		current.Hold = 0
+
+	tx := s.beginWalletTx(walletOpRelease, refID, userID)
+	defer tx.rollback()
+	stageRelease(tx, userID, currency, amount)
+	if err := tx.commit(context.Background()); err != nil {
+		s.state.mu.Lock()
+		pending := s.state.releaseRetries[refID]
+		pending.userID, pending.currency = userID, currency
+		pending.amount = pending.amount.Add(amount)
+		s.state.releaseRetries[refID] = pending
+		s.state.mu.Unlock()
+		log.Printf(
+			"service=edge-gateway msg=reserve_release_failed ref_id=%s user_id=%s currency=%s amount=%s err=%v",
+			refID, userID, currency, amount, err,
+		)
 	}
-	wallet[currency] = current
-	s.state.wallets[userID] = wallet
-	s.state.mu.Unlock()
+}
+
+// stageRelease moves up to amount of the user's hold back to available.
+func stageRelease(tx *walletTx, userID, currency string, amount Decimal) {
+	current := tx.balance(userID, currency)
+	released := minDecimal(amount, current.Hold)
+	current.Hold = current.Hold.Sub(released)
+	current.Available = current.Available.Add(released)
+	tx.set(userID, currency, current)
+}
 
-	s.persistWalletBalance(context.Background(), userID, currency, current)
-	return current
+func (s *Server) tryReserveForOrder(userID string, req OrderRequest, orderID string) (string, Decimal, error) {
+	currency, amount, err := s.reserveRequirement(req)
+	if err != nil {
+		return "", Decimal{}, err
+	}
+	if _, err := s.applyReserve(userID, currency, amount, orderID); err != nil {
+		return "", Decimal{}, err
+	}
+	return currency, amount, nil
 }
 
-func (s *Server) tryReserveForOrder(userID string, req OrderRequest) (string, float64, error) {
+// reserveRequirement computes which currency and how much of it an order
+// must hold: quote notional for buys, base quantity for sells. Conditional
+// orders hold what their child order will need, and TWAP and ICEBERG
+// parents hold their whole quantity at their limit price. Amounts are
+// rounded up to the reserved asset's precision.
+func (s *Server) reserveRequirement(req OrderRequest) (string, Decimal, error) {
+	if isConditionalOrderType(req.Type) {
+		req = s.conditionalReserveRequest(req)
+	} else if isAlgoOrderType(req.Type) {
+		req.Type = "LIMIT"
+	}
 	base, quote, ok := parseSymbol(req.Symbol)
 	if !ok {
-		return "", 0, fmt.Errorf("invalid symbol")
+		return "", Decimal{}, fmt.Errorf("invalid symbol")
 	}
 
-	qty, err := strconv.ParseFloat(strings.TrimSpace(req.Qty), 64)
-	if err != nil || qty <= 0 {
-		return "", 0, fmt.Errorf("invalid qty")
+	qty, ok := parsePositiveRat(req.Qty)
+	if !ok {
+		return "", Decimal{}, fmt.Errorf("invalid qty")
 	}
 
+	var amount *big.Rat
+	currency := base
 	switch strings.ToUpper(req.Side) {
 	case "BUY":
-		price := 0.0
+		if req.marketReserve.Sign() > 0 {
+			return quote, req.marketReserve, nil
+		}
+		var price *big.Rat
 		if strings.ToUpper(req.Type) == "MARKET" {
-			if latest, found := s.latestPriceKRW(base); found {
-				price = latest
-			}
-		} else {
-			price, err = strconv.ParseFloat(strings.TrimSpace(req.Price), 64)
-			if err != nil || price <= 0 {
-				return "", 0, fmt.Errorf("invalid price")
+			if latest, found := s.latestPriceKRW(base); found && latest > 0 {
+				price = new(big.Rat).SetInt64(latest)
 			}
+		} else if price, ok = parsePositiveRat(req.Price); !ok {
+			return "", Decimal{}, fmt.Errorf("invalid price")
 		}
-		if price <= 0 {
-			return "", 0, fmt.Errorf("price_unavailable")
+		if price == nil {
+			return "", Decimal{}, fmt.Errorf("price_unavailable")
 		}
-		amount := qty * price
-		if _, err := s.applyReserve(userID, quote, amount); err != nil {
-			return "", 0, err
-		}
-		return quote, amount, nil
+		amount = new(big.Rat).Mul(qty, price)
+		currency = quote
 	case "SELL":
-		if _, err := s.applyReserve(userID, base, qty); err != nil {
-			return "", 0, err
-		}
-		return base, qty, nil
+		amount = qty
 	default:
-		return "", 0, fmt.Errorf("invalid side")
+		return "", Decimal{}, fmt.Errorf("invalid side")
+	}
+	reserve, ok := decimalFromRat(amount, s.assetScale(currency), true)
+	if !ok {
+		return "", Decimal{}, fmt.Errorf("invalid qty")
 	}
+	return currency, reserve, nil
 }
 
-func (s *Server) latestPriceKRW(base string) (float64, bool) {
+func (s *Server) latestPriceKRW(base string) (int64, bool) {
 	symbol := strings.ToUpper(strings.TrimSpace(base)) + "-KRW"
 	s.state.mu.Lock()
 	defer s.state.mu.Unlock()
@@ -1260,12 +1746,22 @@ func (s *Server) latestPriceKRW(base string) (float64, bool) {
 	if len(tape) == 0 {
 		return 0, false
 	}
-	return float64(tape[len(tape)-1].price), true
+	return tape[len(tape)-1].price, true
 }
 
-func (s *Server) loadWalletFromDB(ctx context.Context, userID string) map[string]walletBalance {
+// dbContext bounds a Postgres call, or transaction, by cfg.DBTimeout.
+func (s *Server) dbContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.cfg.DBTimeout)
+}
+
+// loadWalletFromDB is the user's stored wallet, or the demo balances for a
+// user without one. It fails when the rows cannot be read, so the demo
+// balances never stand in for stored ones.
+func (s *Server) loadWalletFromDB(ctx context.Context, userID string) (map[string]walletBalance, error) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
 	if s.db == nil {
-		return map[string]walletBalance{}
+		return map[string]walletBalance{}, nil
 	}
 	rows, err := s.db.QueryContext(
 		ctx,
@@ -1273,56 +1769,57 @@ func (s *Server) loadWalletFromDB(ctx context.Context, userID string) map[string
 		userID,
 	)
 	if err != nil {
-		return map[string]walletBalance{}
+		return nil, err
 	}
 	defer rows.Close()
 
+	out := s.scanWalletRows(rows)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return s.defaultWalletBalances(), nil
+	}
+	return out, nil
+}
+
+// scanWalletRows reads balances back at their asset's precision; dust a
+// float column left below it is dropped.
+func (s *Server) scanWalletRows(rows *sql.Rows) map[string]walletBalance {
 	out := map[string]walletBalance{}
 	for rows.Next() {
 		var currency string
-		var available float64
-		var hold float64
+		var available Decimal
+		var hold Decimal
 		if err := rows.Scan(&currency, &available, &hold); err != nil {
 			continue
 		}
-		out[strings.ToUpper(currency)] = walletBalance{
-			Available: available,
-			Hold:      hold,
+		currency = strings.ToUpper(currency)
+		scale := s.assetScale(currency)
+		out[currency] = walletBalance{
+			Available: available.Round(scale),
+			Hold:      hold.Round(scale),
 		}
 	}
-	if len(out) == 0 {
-		return defaultWalletBalances()
-	}
 	return out
 }
 
-func (s *Server) persistWalletBalance(ctx context.Context, userID, currency string, bal walletBalance) {
-	if s.db == nil {
-		return
-	}
-	_, _ = s.db.ExecContext(
-		ctx,
-		`INSERT INTO web_wallet_balances(user_id, currency, available, hold) VALUES ($1, $2, $3, $4)
-		 ON CONFLICT (user_id, currency) DO UPDATE SET
-		 available = EXCLUDED.available,
-		 hold = EXCLUDED.hold,
-		 updated_at = now()`,
-		userID,
-		strings.ToUpper(currency),
-		bal.Available,
-		bal.Hold,
-	)
-}
-
-func defaultWalletBalances() map[string]walletBalance {
-	return map[string]walletBalance{
-		"KRW": {Available: 50_000_000, Hold: 0},
-		"BTC": {Available: 2, Hold: 0},
-		"ETH": {Available: 8, Hold: 0},
-		"SOL": {Available: 240, Hold: 0},
-		"XRP": {Available: 15000, Hold: 0},
-		"BNB": {Available: 34, Hold: 0},
+func (s *Server) defaultWalletBalances() map[string]walletBalance {
+	out := map[string]walletBalance{}
+	for currency, whole := range map[string]int64{
+		"KRW": 50_000_000,
+		"BTC": 2,
+		"ETH": 8,
+		"SOL": 240,
+		"XRP": 15000,
+		"BNB": 34,
+	} {
+		out[currency] = walletBalance{
+			Available: s.assetAmount(currency, whole),
+			Hold:      s.assetAmount(currency, 0),
+		}
 	}
+	return out
 }
 
 func cloneWallet(in map[string]walletBalance) map[string]walletBalance {
@@ -1404,75 +1901,175 @@ func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
 		return
 	}
-	if req.Symbol == "" || req.Side == "" || req.Type == "" || req.Qty == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "symbol/side/type/qty required"})
+	if err := s.validateOrderRequest(apiKey, req); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errDuplicateClientOrderID) {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
 		return
 	}
-	side, ok := mapSide(req.Side)
-	if !ok {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid side"})
+
+	if s.coreClient == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core_unavailable"})
 		return
 	}
-	orderType, ok := mapOrderType(req.Type)
-	if !ok {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid type"})
+	req, err := s.planMarketOrder(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	tif, ok := mapTimeInForce(req.TimeInForce)
-	if !ok {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid timeInForce"})
+	reserveCurrency, reserveAmount, reserveErr := s.tryReserveForOrder(apiKey, req, "ord_"+idemKey)
+	if reserveErr != nil {
+		writeReserveError(w, reserveErr)
 		return
 	}
 
-	if s.coreClient == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core_unavailable"})
+	resp, err := s.placeOrderWithCore(r.Context(), apiKey, idemKey, req, reserveCurrency, reserveAmount)
+	if errors.Is(err, errDuplicateClientOrderID) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
 		return
 	}
-	reserveCurrency, reserveAmount, reserveErr := s.tryReserveForOrder(apiKey, req)
-	if reserveErr != nil {
-		if reserveErr.Error() == "insufficient_balance" {
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "insufficient_balance"})
-			return
-		}
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": reserveErr.Error()})
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "core_unavailable"})
 		return
 	}
+	status, body := marshalResponse(http.StatusOK, resp)
+	s.idempotencySet(apiKey, idemKey, r.Method, r.URL.Path, status, body)
+	writeRaw(w, status, body)
+}
+
+// validateOrderRequest runs the gateway-side checks an order must pass
+// before any funds are reserved for it.
+func (s *Server) validateOrderRequest(userID string, req OrderRequest) error {
+	if req.Symbol == "" || req.Side == "" || req.Type == "" || (req.Qty == "" && req.QuoteQty == "") {
+		return fmt.Errorf("symbol/side/type/qty required")
+	}
+	if req.ClientOrderID != "" {
+		if !isValidClientOrderID(req.ClientOrderID) {
+			return fmt.Errorf("invalid clientOrderId")
+		}
+		if s.clientOrderIDInUse(userID, req.ClientOrderID) {
+			return errDuplicateClientOrderID
+		}
+	}
+	if _, ok := mapSide(req.Side); !ok {
+		return fmt.Errorf("invalid side")
+	}
+	if err := validateMarketOrderOptions(req); err != nil {
+		return err
+	}
+	if hasAlgoFields(req) && !isAlgoOrderType(req.Type) {
+		return fmt.Errorf("visibleQty/durationSeconds/slices require TWAP or ICEBERG")
+	}
+	if isConditionalOrderType(req.Type) {
+		if err := s.validateConditionalOrder(req); err != nil {
+			return err
+		}
+	} else if isAlgoOrderType(req.Type) {
+		if err := validateAlgoOrder(req); err != nil {
+			return err
+		}
+	} else if hasTriggerFields(req) {
+		return fmt.Errorf("trigger fields require STOP, STOP_LIMIT or TRAILING_STOP")
+	} else if _, ok := mapOrderType(req.Type); !ok {
+		return fmt.Errorf("invalid type")
+	}
+	if err := s.validateMarketRules(req); err != nil {
+		return err
+	}
+	if err := s.checkPriceBand(req); err != nil {
+		return err
+	}
+	tif, ok := mapTimeInForce(req.TimeInForce)
+	if !ok {
+		return fmt.Errorf("invalid timeInForce")
+	}
+	if err := validateExpireAt(req, time.Now().UnixMilli()); err != nil {
+		return err
+	}
+	if _, ok := mapSelfTradePrevention(req.SelfTradePrevention); !ok {
+		return fmt.Errorf("invalid selfTradePrevention")
+	}
+	if req.PostOnly && (strings.ToUpper(req.Type) != "LIMIT" || tif != exchangev1.TimeInForce_TIME_IN_FORCE_GTC) {
+		return fmt.Errorf("postOnly requires LIMIT GTC")
+	}
+	if code := s.orderEntryBlock(req.Symbol); code != "" {
+		return errors.New(code)
+	}
+	return nil
+}
 
+// placeOrderWithCore forwards a validated order whose funds are already
+// reserved. The reserve is released when the core errors, rejects or
+// immediately cancels the order; otherwise it moves onto the order record.
+// Conditional orders are held by the gateway until they trigger, and TWAP
+// and ICEBERG parents are worked by the gateway through child orders.
+func (s *Server) placeOrderWithCore(
+	ctx context.Context,
+	userID string,
+	idemKey string,
+	req OrderRequest,
+	reserveCurrency string,
+	reserveAmount Decimal,
+) (OrderResponse, error) {
+	if isConditionalOrderType(req.Type) {
+		return s.holdConditionalOrder(ctx, userID, idemKey, req, reserveCurrency, reserveAmount)
+	}
+	if isAlgoOrderType(req.Type) {
+		return s.startAlgoOrder(ctx, userID, idemKey, req, reserveCurrency, reserveAmount)
+	}
+	side, _ := mapSide(req.Side)
+	orderType, _ := mapOrderType(req.Type)
+	tif, _ := mapTimeInForce(req.TimeInForce)
+	stp, _ := mapSelfTradePrevention(req.SelfTradePrevention)
 	orderID := fmt.Sprintf("ord_%s", idemKey)
-	commandID := uuid.NewString()
-	correlationID := uuid.NewString()
-	traceID := trace.SpanFromContext(r.Context()).SpanContext().TraceID().String()
-	if traceID == "" || traceID == "00000000000000000000000000000000" {
-		traceID = uuid.NewString()
+	price := req.Price
+	if req.protectPrice != "" {
+		orderType = exchangev1.OrderType_ORDER_TYPE_LIMIT
+		tif = exchangev1.TimeInForce_TIME_IN_FORCE_IOC
+		price = req.protectPrice
+	}
+
+	if req.ClientOrderID != "" && !s.claimClientOrderID(userID, req.ClientOrderID, orderID) {
+		if reserveCurrency != "" && reserveAmount.Sign() > 0 {
+			s.releaseReserve(userID, reserveCurrency, reserveAmount, orderID)
+		}
+		return OrderResponse{}, errDuplicateClientOrderID
 	}
 
+	makerFeeBps, takerFeeBps := s.orderFeeRates(userID)
 	coreReq := &exchangev1.PlaceOrderRequest{
 		Meta: &exchangev1.CommandMetadata{
-			CommandId:      commandID,
+			CommandId:      uuid.NewString(),
 			IdempotencyKey: idemKey,
-			UserId:         apiKey,
+			UserId:         userID,
 			Symbol:         req.Symbol,
 			TsServer:       timestamppb.Now(),
-			TraceId:        traceID,
-			CorrelationId:  correlationID,
+			TraceId:        traceIDFromContext(ctx),
+			CorrelationId:  uuid.NewString(),
 		},
-		OrderId:     orderID,
-		Side:        side,
-		OrderType:   orderType,
-		Price:       req.Price,
-		Quantity:    req.Qty,
-		TimeInForce: tif,
-	}
-
-	coreCtx, cancel := context.WithTimeout(r.Context(), s.cfg.CoreTimeout)
+		OrderId:             orderID,
+		Side:                side,
+		OrderType:           orderType,
+		Price:               price,
+		Quantity:            req.Qty,
+		TimeInForce:         tif,
+		PostOnly:            req.PostOnly,
+		SelfTradePrevention: stp,
+		MakerFeeBps:         makerFeeBps,
+		TakerFeeBps:         takerFeeBps,
+	}
+
+	coreCtx, cancel := context.WithTimeout(ctx, s.cfg.CoreTimeout)
 	defer cancel()
 	coreResp, err := s.coreClient.PlaceOrder(coreCtx, coreReq)
 	if err != nil {
-		if reserveCurrency != "" && reserveAmount > 0 {
-			s.releaseReserve(apiKey, reserveCurrency, reserveAmount)
+		if reserveCurrency != "" && reserveAmount.Sign() > 0 {
+			s.releaseReserve(userID, reserveCurrency, reserveAmount, orderID)
 		}
-		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "core_unavailable"})
-		return
+		s.releaseClientOrderID(userID, req.ClientOrderID, orderID)
+		return OrderResponse{}, err
 	}
 
 	acceptedAt := int64(0)
@@ -1483,45 +2080,88 @@ func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	if statusUpper == "PARTIAL" {
 		statusUpper = "PARTIALLY_FILLED"
 	}
-	if (!coreResp.Accepted || statusUpper == "REJECTED" || statusUpper == "CANCELED") && reserveCurrency != "" && reserveAmount > 0 {
-		s.releaseReserve(apiKey, reserveCurrency, reserveAmount)
-		reserveCurrency = ""
-		reserveAmount = 0
-	}
 
-	qty, _ := strconv.ParseFloat(strings.TrimSpace(req.Qty), 64)
+	qty, _ := parseDecimal(req.Qty)
 	record := OrderRecord{
 		OrderID:         coreResp.OrderId,
+		ClientOrderID:   req.ClientOrderID,
 		Status:          statusUpper,
 		Symbol:          coreResp.Symbol,
 		Seq:             coreResp.Seq,
+		CreatedAt:       time.Now().UnixMilli(),
 		AcceptedAt:      acceptedAt,
-		OwnerUserID:     apiKey,
+		OwnerUserID:     userID,
 		ReserveCurrency: reserveCurrency,
 		ReserveAmount:   reserveAmount,
 		Side:            strings.ToUpper(strings.TrimSpace(req.Side)),
+		Type:            strings.ToUpper(strings.TrimSpace(req.Type)),
+		Price:           strings.TrimSpace(price),
+		TimeInForce:     timeInForceLabel(req.TimeInForce, tif),
+		ExpireAt:        req.ExpireAt,
 		Qty:             qty,
+		QuoteQty:        strings.TrimSpace(req.QuoteQty),
+
+		PostOnly:            req.PostOnly,
+		SelfTradePrevention: selfTradePreventionLabel(stp),
+		GroupID:             req.groupID,
+		GroupRole:           req.groupRole,
+		ParentOrderID:       req.parentOrderID,
 	}
 	s.state.mu.Lock()
 	s.state.orders[coreResp.OrderId] = record
 	s.state.ordersTotal++
 	s.state.mu.Unlock()
-
-	resp := OrderResponse{
-		OrderID:     coreResp.OrderId,
-		Status:      statusUpper,
-		Symbol:      coreResp.Symbol,
-		Seq:         coreResp.Seq,
-		AcceptedAt:  acceptedAt,
-		RejectCode:  coreResp.RejectCode,
-		Correlation: coreResp.CorrelationId,
+	s.persistOrder(ctx, record)
+	// A refused or already canceled order hands its reserve straight back.
+	if !coreResp.Accepted || statusUpper == "REJECTED" || statusUpper == "CANCELED" {
+		s.releaseOrderReserve(ctx, record.OrderID)
+	}
+	s.applyActivatingFills(record.OrderID)
+	s.applyEarlyOrderEvent(ctx, record.OrderID)
+
+	// Self-trade prevention may have pulled the user's own resting orders.
+	// Fills of theirs may still be on the way, so with order events
+	// consumed each waits for its OrderCanceled event and releases only what
+	// those fills leave. Without Kafka no fill can arrive, and the whole
+	// reserve comes back right away.
+	if !s.orderEvents {
+		for _, canceledID := range coreResp.StpCanceledOrderIds {
+			s.markOrderCanceled(canceledID, coreResp.Seq, acceptedAt)
+			s.closeLinkedOrders(ctx, canceledID)
+			s.releaseAlgoChild(ctx, canceledID)
+		}
 	}
-	status, body := marshalResponse(http.StatusOK, resp)
-	s.idempotencySet(apiKey, idemKey, r.Method, r.URL.Path, status, body)
-	writeRaw(w, status, body)
+
+	return OrderResponse{
+		OrderID:       coreResp.OrderId,
+		ClientOrderID: req.ClientOrderID,
+		Status:        statusUpper,
+		Symbol:        coreResp.Symbol,
+		Seq:           coreResp.Seq,
+		AcceptedAt:    acceptedAt,
+		RejectCode:    coreResp.RejectCode,
+		Correlation:   coreResp.CorrelationId,
+	}, nil
 }
 
 func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "orderId")
+	s.serveCancelOrder(w, r, "/v1/orders/"+orderID, func(string) (OrderRecord, bool) {
+		s.state.mu.Lock()
+		defer s.state.mu.Unlock()
+		record, ok := s.state.orders[orderID]
+		return record, ok
+	})
+}
+
+// serveCancelOrder runs the single-order cancel flow for whichever order
+// lookup resolves for the caller; pathKey scopes the idempotency record.
+func (s *Server) serveCancelOrder(
+	w http.ResponseWriter,
+	r *http.Request,
+	pathKey string,
+	lookup func(userID string) (OrderRecord, bool),
+) {
 	apiKey := s.apiKeyFromContext(r.Context())
 	if apiKey == "" {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
@@ -1532,8 +2172,6 @@ func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key required"})
 		return
 	}
-	orderID := chi.URLParam(r, "orderId")
-	pathKey := "/v1/orders/" + orderID
 
 	if status, body, ok := s.idempotencyGet(apiKey, idemKey, r.Method, pathKey); ok {
 		writeRaw(w, status, body)
@@ -1544,51 +2182,93 @@ func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.state.mu.Lock()
-	record, ok := s.state.orders[orderID]
+	record, ok := lookup(apiKey)
 	if !ok {
-		s.state.mu.Unlock()
 		status, body := marshalResponse(http.StatusNotFound, map[string]string{"error": "UNKNOWN_ORDER"})
 		s.idempotencySet(apiKey, idemKey, r.Method, pathKey, status, body)
 		writeRaw(w, status, body)
 		return
 	}
 	if record.OwnerUserID != "" && record.OwnerUserID != apiKey {
-		s.state.mu.Unlock()
 		writeJSON(w, http.StatusForbidden, map[string]string{"error": "FORBIDDEN"})
 		return
 	}
-	s.state.mu.Unlock()
-
-	symbol := record.Symbol
-	if strings.TrimSpace(symbol) == "" {
-		symbol = "BTC-KRW"
+	resp, err := s.cancelOrderWithCore(r.Context(), apiKey, idemKey, record)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "core_unavailable"})
+		return
 	}
-	commandID := uuid.NewString()
-	correlationID := uuid.NewString()
-	traceID := trace.SpanFromContext(r.Context()).SpanContext().TraceID().String()
-	if traceID == "" || traceID == "00000000000000000000000000000000" {
-		traceID = uuid.NewString()
+	status, body := marshalResponse(http.StatusOK, resp)
+	s.idempotencySet(apiKey, idemKey, r.Method, pathKey, status, body)
+	writeRaw(w, status, body)
+}
+
+// cancelOrderWithCore sends CancelOrder for one order and, when the core
+// confirms, marks the record CANCELED and releases the unconsumed reserve.
+// Orders the gateway still holds are canceled locally, and canceling a
+// linked leg closes the rest of its group. TWAP and ICEBERG children are
+// only canceled through their parent.
+func (s *Server) cancelOrderWithCore(ctx context.Context, userID, idemKey string, record OrderRecord) (OrderResponse, error) {
+	var resp OrderResponse
+	if record.ParentOrderID != "" {
+		resp = s.orderResponseFor(record.OrderID)
+		resp.RejectCode = "ORDER_MANAGED_BY_PARENT"
+		return resp, nil
+	}
+	if record.Status == orderStatusActivating {
+		resp = s.orderResponseFor(record.OrderID)
+		resp.RejectCode = "ORDER_ACTIVATING"
+		return resp, nil
+	}
+	if isAlgoOrderType(record.Type) {
+		resp = s.cancelAlgoOrder(ctx, record)
+	} else if isHeldOrderStatus(record.Status) {
+		resp = s.cancelHeldOrder(record)
+	} else {
+		var err error
+		resp, err = s.cancelOnCore(ctx, userID, idemKey, record, true)
+		if err != nil {
+			return OrderResponse{}, err
+		}
+	}
+	if record.GroupID != "" && resp.Status == "CANCELED" {
+		s.closeLinkedOrders(ctx, record.OrderID)
+	}
+	return resp, nil
+}
+
+// cancelOnCore is cancelOrderWithCore with the state transition left to the
+// caller when releaseReserve is false (cancel-replace carries the reserve).
+func (s *Server) cancelOnCore(
+	ctx context.Context,
+	userID string,
+	idemKey string,
+	record OrderRecord,
+	releaseReserve bool,
+) (OrderResponse, error) {
+	orderID := record.OrderID
+	symbol := record.Symbol
+	if strings.TrimSpace(symbol) == "" {
+		symbol = "BTC-KRW"
 	}
 	coreReq := &exchangev1.CancelOrderRequest{
 		Meta: &exchangev1.CommandMetadata{
-			CommandId:      commandID,
+			CommandId:      uuid.NewString(),
 			IdempotencyKey: idemKey,
-			UserId:         apiKey,
+			UserId:         userID,
 			Symbol:         symbol,
 			TsServer:       timestamppb.Now(),
-			TraceId:        traceID,
-			CorrelationId:  correlationID,
+			TraceId:        traceIDFromContext(ctx),
+			CorrelationId:  uuid.NewString(),
 		},
 		OrderId: orderID,
 	}
 
-	coreCtx, cancel := context.WithTimeout(r.Context(), s.cfg.CoreTimeout)
+	coreCtx, cancel := context.WithTimeout(ctx, s.cfg.CoreTimeout)
 	defer cancel()
 	coreResp, err := s.coreClient.CancelOrder(coreCtx, coreReq)
 	if err != nil {
-		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "core_unavailable"})
-		return
+		return OrderResponse{}, err
 	}
 
 	canceledAt := int64(0)
@@ -1604,49 +2284,205 @@ func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
 		statusUpper = "PARTIALLY_FILLED"
 	}
 
-	if coreResp.Accepted && statusUpper == "CANCELED" {
-		s.state.mu.Lock()
-		record = s.state.orders[orderID]
-		record.Status = "CANCELED"
-		record.Seq = coreResp.Seq
-		record.CanceledAt = canceledAt
-		releaseAmount := record.ReserveAmount - record.ReserveConsumed
-		if releaseAmount < 0 {
-			releaseAmount = 0
-		}
-		record.ReserveAmount -= releaseAmount
-		s.state.orders[orderID] = record
+	if coreResp.Accepted && statusUpper == "CANCELED" && releaseReserve {
+		s.markOrderCanceled(orderID, coreResp.Seq, canceledAt)
+	}
+
+	return OrderResponse{
+		OrderID:       coreResp.OrderId,
+		ClientOrderID: record.ClientOrderID,
+		Status:        statusUpper,
+		Symbol:        coreResp.Symbol,
+		Seq:           coreResp.Seq,
+		CanceledAt:    canceledAt,
+		RejectCode:    coreResp.RejectCode,
+		Correlation:   coreResp.CorrelationId,
+	}, nil
+}
+
+// markOrderCanceled flips the record to CANCELED and returns its unconsumed
+// reserve to the owner.
+func (s *Server) markOrderCanceled(orderID string, seq uint64, canceledAt int64) {
+	s.markOrderClosed(orderID, "CANCELED", seq, canceledAt)
+}
+
+// markOrderClosed is markOrderCanceled for any closing status, such as
+// EXPIRED for a GTD order the sweeper pulled. The reserve stays on the
+// record until releaseOrderReserve has committed it back to the wallet.
+func (s *Server) markOrderClosed(orderID, status string, seq uint64, canceledAt int64) {
+	if _, ok := s.closeOrderRecord(orderID, status, seq, canceledAt, false); ok {
+		s.releaseOrderReserve(context.Background(), orderID)
+	}
+}
+
+// detachOrderReserve cancels the record and takes its unconsumed reserve
+// off it without releasing it, for callers that carry the reserve over to
+// another order.
+func (s *Server) detachOrderReserve(orderID string, seq uint64, canceledAt int64) Decimal {
+	detached, _ := s.closeOrderRecord(orderID, "CANCELED", seq, canceledAt, true)
+	return detached
+}
+
+// closeOrderRecord moves the record to status and persists it, with detach
+// taking its unconsumed reserve off it and returning that. ok is false when
+// the order is unknown.
+func (s *Server) closeOrderRecord(orderID, status string, seq uint64, canceledAt int64, detach bool) (Decimal, bool) {
+	s.state.mu.Lock()
+	record, ok := s.state.orders[orderID]
+	if !ok {
 		s.state.mu.Unlock()
+		return Decimal{}, false
+	}
+	record.Status = status
+	if seq > record.Seq {
+		record.Seq = seq
+	}
+	record.CanceledAt = canceledAt
+	detached := Decimal{}
+	if detach {
+		detached = unconsumedReserve(record)
+		record.ReserveAmount = record.ReserveAmount.Sub(detached)
+	}
+	s.state.orders[orderID] = record
+	s.state.mu.Unlock()
+	s.persistOrder(context.Background(), record)
+	return detached, true
+}
 
-		if releaseAmount > 0 && record.ReserveCurrency != "" && record.OwnerUserID != "" {
-			s.releaseReserve(record.OwnerUserID, record.ReserveCurrency, releaseAmount)
-		}
+// unconsumedReserve is what the order still holds beyond its fills, never
+// negative.
+func unconsumedReserve(record OrderRecord) Decimal {
+	remaining := record.ReserveAmount.Sub(record.ReserveConsumed)
+	if remaining.Sign() < 0 {
+		return remaining.zero()
 	}
+	return remaining
+}
+
+type massCancelRequest struct {
+	Symbol string `json:"symbol"`
+	Side   string `json:"side"`
+}
 
-	resp := OrderResponse{
-		OrderID:     coreResp.OrderId,
-		Status:      statusUpper,
-		Symbol:      coreResp.Symbol,
-		Seq:         coreResp.Seq,
-		CanceledAt:  canceledAt,
-		RejectCode:  coreResp.RejectCode,
-		Correlation: coreResp.CorrelationId,
+type massCancelFailure struct {
+	OrderID string `json:"orderId"`
+	Error   string `json:"error"`
+}
+
+// handleCancelAllOrders cancels every open order of the caller, optionally
+// narrowed by symbol and side. The core's CancelAll clears a whole symbol
+// book regardless of owner, so user-scoped mass cancel fans out CancelOrder.
+func (s *Server) handleCancelAllOrders(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
 	}
-	status, body := marshalResponse(http.StatusOK, resp)
-	s.idempotencySet(apiKey, idemKey, r.Method, pathKey, status, body)
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key required"})
+		return
+	}
+	if status, body, ok := s.idempotencyGet(apiKey, idemKey, r.Method, r.URL.Path); ok {
+		writeRaw(w, status, body)
+		return
+	}
+
+	var req massCancelRequest
+	if r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+			return
+		}
+	}
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	side := strings.ToUpper(strings.TrimSpace(req.Side))
+	if side != "" && side != "BUY" && side != "SELL" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid side"})
+		return
+	}
+	if s.coreClient == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core_unavailable"})
+		return
+	}
+
+	canceled, failed := s.cancelOpenOrders(r.Context(), apiKey, idemKey, symbol, side)
+	status, body := marshalResponse(http.StatusOK, map[string]interface{}{
+		"canceled": canceled,
+		"failed":   failed,
+	})
+	s.idempotencySet(apiKey, idemKey, r.Method, r.URL.Path, status, body)
 	writeRaw(w, status, body)
 }
 
+// cancelOpenOrders cancels the user's open orders matching symbol and side
+// (empty matches all). Each cancel is keyed idemKey+":"+orderID.
+func (s *Server) cancelOpenOrders(ctx context.Context, userID, idemKey, symbol, side string) ([]OrderResponse, []massCancelFailure) {
+	targets := s.openOrdersFor(userID, symbol, side)
+	canceled := make([]OrderResponse, 0, len(targets))
+	failed := make([]massCancelFailure, 0)
+	for _, record := range targets {
+		if record.ParentOrderID != "" {
+			// Canceled along with their TWAP or ICEBERG parent.
+			continue
+		}
+		resp, err := s.cancelOrderWithCore(ctx, userID, idemKey+":"+record.OrderID, record)
+		if err != nil {
+			failed = append(failed, massCancelFailure{OrderID: record.OrderID, Error: "core_unavailable"})
+			continue
+		}
+		if resp.Status != "CANCELED" {
+			code := resp.RejectCode
+			if code == "" {
+				code = resp.Status
+			}
+			failed = append(failed, massCancelFailure{OrderID: record.OrderID, Error: code})
+			continue
+		}
+		canceled = append(canceled, resp)
+	}
+	return canceled, failed
+}
+
+// openOrdersFor returns the user's open orders in seq order, optionally
+// narrowed by symbol and side (empty matches all).
+func (s *Server) openOrdersFor(userID, symbol, side string) []OrderRecord {
+	s.state.mu.Lock()
+	out := make([]OrderRecord, 0, 8)
+	for _, record := range s.state.orders {
+		if record.OwnerUserID != userID || !isOpenOrderStatus(record.Status) {
+			continue
+		}
+		if symbol != "" && strings.ToUpper(record.Symbol) != symbol {
+			continue
+		}
+		if side != "" && record.Side != side {
+			continue
+		}
+		out = append(out, record)
+	}
+	s.state.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return orderLess(out[i], out[j]) })
+	return out
+}
+
 func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "orderId")
+	s.serveGetOrder(w, r, func(string) (OrderRecord, bool) {
+		s.state.mu.Lock()
+		defer s.state.mu.Unlock()
+		record, ok := s.state.orders[orderID]
+		return record, ok
+	})
+}
+
+func (s *Server) serveGetOrder(w http.ResponseWriter, r *http.Request, lookup func(userID string) (OrderRecord, bool)) {
 	apiKey := s.apiKeyFromContext(r.Context())
 	if apiKey == "" {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
 		return
 	}
-	orderID := chi.URLParam(r, "orderId")
-	s.state.mu.Lock()
-	record, ok := s.state.orders[orderID]
-	s.state.mu.Unlock()
+	record, ok := lookup(apiKey)
 	if !ok {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "UNKNOWN_ORDER"})
 		return
@@ -1658,6 +2494,164 @@ func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, record)
 }
 
+func (s *Server) handleListOrders(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	filter, err := parseOrderListFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.state.mu.Lock()
+	matched := make([]OrderRecord, 0, 16)
+	for _, record := range s.state.orders {
+		if record.OwnerUserID != apiKey || !filter.matches(record) {
+			continue
+		}
+		matched = append(matched, record)
+	}
+	s.state.mu.Unlock()
+
+	sort.Slice(matched, func(i, j int) bool { return orderLess(matched[i], matched[j]) })
+
+	start := 0
+	if filter.cursor.set {
+		start = sort.Search(len(matched), func(i int) bool {
+			return filter.cursor.less(matched[i])
+		})
+	}
+	end := start + filter.limit
+	if end > len(matched) {
+		end = len(matched)
+	}
+	page := matched[start:end]
+
+	nextCursor := ""
+	if end < len(matched) && len(page) > 0 {
+		last := page[len(page)-1]
+		nextCursor = encodeOrderCursor(last.Seq, last.OrderID)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"orders":     page,
+		"nextCursor": nextCursor,
+	})
+}
+
+func parseOrderListFilter(r *http.Request) (orderListFilter, error) {
+	q := r.URL.Query()
+	filter := orderListFilter{
+		symbol: strings.ToUpper(strings.TrimSpace(q.Get("symbol"))),
+		side:   strings.ToUpper(strings.TrimSpace(q.Get("side"))),
+		status: strings.ToLower(strings.TrimSpace(q.Get("status"))),
+		limit:  parseLimit(q.Get("limit"), 100),
+	}
+	if filter.side != "" && filter.side != "BUY" && filter.side != "SELL" {
+		return orderListFilter{}, fmt.Errorf("invalid side")
+	}
+	switch filter.status {
+	case "":
+		filter.status = "all"
+	case "open", "closed", "all":
+	default:
+		return orderListFilter{}, fmt.Errorf("invalid status")
+	}
+	if raw := strings.TrimSpace(q.Get("from")); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			return orderListFilter{}, fmt.Errorf("invalid from")
+		}
+		filter.fromMs = v
+	}
+	if raw := strings.TrimSpace(q.Get("to")); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			return orderListFilter{}, fmt.Errorf("invalid to")
+		}
+		filter.toMs = v
+	}
+	if raw := strings.TrimSpace(q.Get("cursor")); raw != "" {
+		cursor, ok := decodeOrderCursor(raw)
+		if !ok {
+			return orderListFilter{}, fmt.Errorf("invalid cursor")
+		}
+		filter.cursor = cursor
+	}
+	return filter, nil
+}
+
+func (f orderListFilter) matches(record OrderRecord) bool {
+	if f.symbol != "" && strings.ToUpper(record.Symbol) != f.symbol {
+		return false
+	}
+	if f.side != "" && record.Side != f.side {
+		return false
+	}
+	switch f.status {
+	case "open":
+		if !isOpenOrderStatus(record.Status) {
+			return false
+		}
+	case "closed":
+		if isOpenOrderStatus(record.Status) {
+			return false
+		}
+	}
+	ts := record.CreatedAt
+	if ts == 0 {
+		ts = record.AcceptedAt
+	}
+	if f.fromMs > 0 && ts < f.fromMs {
+		return false
+	}
+	if f.toMs > 0 && ts > f.toMs {
+		return false
+	}
+	return true
+}
+
+func (c orderCursor) less(record OrderRecord) bool {
+	if record.Seq != c.seq {
+		return record.Seq > c.seq
+	}
+	return record.OrderID > c.orderID
+}
+
+func orderLess(a, b OrderRecord) bool {
+	if a.Seq != b.Seq {
+		return a.Seq < b.Seq
+	}
+	return a.OrderID < b.OrderID
+}
+
+func encodeOrderCursor(seq uint64, orderID string) string {
+	return strconv.FormatUint(seq, 10) + ":" + orderID
+}
+
+func decodeOrderCursor(raw string) (orderCursor, bool) {
+	parts := strings.SplitN(raw, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return orderCursor{}, false
+	}
+	seq, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return orderCursor{}, false
+	}
+	return orderCursor{seq: seq, orderID: parts[1], set: true}, true
+}
+
+func isOpenOrderStatus(status string) bool {
+	switch strings.ToUpper(status) {
+	case "ACCEPTED", "PARTIALLY_FILLED", orderStatusPendingTrigger, orderStatusPendingEntry:
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *Server) handleSmokeTrade(w http.ResponseWriter, r *http.Request) {
 	var req SmokeTradeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -1674,6 +2668,9 @@ func (s *Server) handleSmokeTrade(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
+	if price, err := strconv.ParseInt(req.Price, 10, 64); err == nil {
+		s.evaluateTriggers(r.Context(), req.Symbol, price)
+	}
 	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "settled", "seq": seq})
 }
 
@@ -1786,9 +2783,33 @@ func mapOrderType(value string) (exchangev1.OrderType, bool) {
 	}
 }
 
+func mapSelfTradePrevention(value string) (exchangev1.SelfTradePrevention, bool) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "", "NONE":
+		return exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_UNSPECIFIED, true
+	case "CANCEL_NEWEST":
+		return exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_CANCEL_NEWEST, true
+	case "CANCEL_OLDEST":
+		return exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_CANCEL_OLDEST, true
+	case "CANCEL_BOTH":
+		return exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_CANCEL_BOTH, true
+	default:
+		return exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_UNSPECIFIED, false
+	}
+}
+
+func selfTradePreventionLabel(stp exchangev1.SelfTradePrevention) string {
+	if stp == exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_UNSPECIFIED {
+		return ""
+	}
+	return strings.TrimPrefix(stp.String(), "SELF_TRADE_PREVENTION_")
+}
+
+// mapTimeInForce maps the API time in force onto the core's. GTD rests as
+// GTC on the core; the gateway expires it.
 func mapTimeInForce(value string) (exchangev1.TimeInForce, bool) {
 	switch strings.ToUpper(value) {
-	case "GTC", "":
+	case "GTC", "", timeInForceGTD:
 		return exchangev1.TimeInForce_TIME_IN_FORCE_GTC, true
 	case "IOC":
 		return exchangev1.TimeInForce_TIME_IN_FORCE_IOC, true
@@ -1800,6 +2821,8 @@ func mapTimeInForce(value string) (exchangev1.TimeInForce, bool) {
 }
 
 func (s *Server) appendSettlement(ctx context.Context, req SmokeTradeRequest) error {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
 	if s.db == nil {
 		return nil
 	}
@@ -1862,16 +2885,7 @@ func (s *Server) seedSampleMarketData(ctx context.Context) error {
 }
 
 func (s *Server) startTradeConsumer() {
-	if strings.TrimSpace(s.cfg.KafkaBrokers) == "" {
-		return
-	}
-	brokers := make([]string, 0, 3)
-	for _, raw := range strings.Split(s.cfg.KafkaBrokers, ",") {
-		v := strings.TrimSpace(raw)
-		if v != "" {
-			brokers = append(brokers, v)
-		}
-	}
+	brokers := s.kafkaBrokerList()
 	if len(brokers) == 0 {
 		return
 	}
@@ -1906,7 +2920,22 @@ func (s *Server) startTradeConsumer() {
 				}
 				continue
 			}
-			if err := s.consumeTradeMessage(ctx, msg.Value); err != nil {
+			// The offset is already committed, so a trade whose settlement
+			// could not be written is retried here until the wallet store
+			// is back.
+			for {
+				err = s.consumeTradeMessage(ctx, msg.Value)
+				if !errors.Is(err, errWalletUnavailable) {
+					break
+				}
+				log.Printf("service=edge-gateway msg=trade_settle_retry reason=%v payload=%s", err, string(msg.Value))
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(500 * time.Millisecond):
+				}
+			}
+			if err != nil {
 				log.Printf("service=edge-gateway msg=trade_apply_failed reason=%v payload=%s", err, string(msg.Value))
 			}
 		}
@@ -1943,6 +2972,15 @@ func (s *Server) consumeTradeMessage(ctx context.Context, raw []byte) error {
 		quoteAmount = parsed
 	}
 
+	feeBuyer, _ := parseInt64Any(payload.FeeBuyer)
+	feeSeller, _ := parseInt64Any(payload.FeeSeller)
+	if feeBuyer < 0 || feeSeller < 0 {
+		return fmt.Errorf("negative fee: buyer=%v seller=%v", payload.FeeBuyer, payload.FeeSeller)
+	}
+	if feeSeller > quoteAmount {
+		return fmt.Errorf("feeSeller %d exceeds quote amount %d", feeSeller, quoteAmount)
+	}
+
 	seq := payload.Envelope.Seq
 	if seq == 0 {
 		seq = payload.Seq
@@ -1965,9 +3003,30 @@ func (s *Server) consumeTradeMessage(ctx context.Context, raw []byte) error {
 		return nil
 	}
 
-	s.applyTradeSettlement(payload.BuyerUserID, payload.SellerUserID, symbol, qty, quoteAmount)
+	if err := s.applyTradeSettlement(ctx, payload.TradeID, payload.BuyerUserID, payload.SellerUserID, symbol, qty, quoteAmount, feeBuyer, feeSeller); err != nil {
+		// Nothing of the trade was applied; let a redelivery settle it.
+		s.state.mu.Lock()
+		delete(s.state.appliedTrades, payload.TradeID)
+		s.state.mu.Unlock()
+		return err
+	}
+	s.state.mu.Lock()
+	s.recordTradeVolumeLocked(payload.BuyerUserID, decimalFromInt(quoteAmount), tsMs)
+	s.recordTradeVolumeLocked(payload.SellerUserID, decimalFromInt(quoteAmount), tsMs)
+	s.state.mu.Unlock()
 	s.applyOrderFill(payload.MakerOrderID, qty, price, seq)
 	s.applyOrderFill(payload.TakerOrderID, qty, price, seq)
+	s.recordTradeFills(tradeFill{
+		tradeID:     payload.TradeID,
+		symbol:      symbol,
+		price:       price,
+		qty:         qty,
+		quoteAmount: quoteAmount,
+		feeBuyer:    feeBuyer,
+		feeSeller:   feeSeller,
+		seq:         seq,
+		tsMs:        tsMs,
+	}, payload.MakerOrderID, payload.TakerOrderID)
 
 	_, err := s.ingestSmokeTrade(ctx, SmokeTradeRequest{
 		TradeID: payload.TradeID,
@@ -1978,6 +3037,7 @@ func (s *Server) consumeTradeMessage(ctx context.Context, raw []byte) error {
 	if err != nil {
 		return fmt.Errorf("ingest trade message: %w", err)
 	}
+	s.evaluateTriggers(ctx, symbol, price)
 	return nil
 }
 
@@ -1999,107 +3059,119 @@ func (s *Server) markTradeApplied(tradeID string, tsMs int64) bool {
 	return true
 }
 
-type walletPersistUpdate struct {
-	userID   string
-	currency string
-	balance  walletBalance
-}
-
-func (s *Server) applyTradeSettlement(buyerUserID, sellerUserID, symbol string, qty, quoteAmount int64) {
+// applyTradeSettlement moves a trade between the two wallets in a single
+// wallet transaction. Both fees are in the quote currency, as the ledger
+// books them: the buyer pays quoteAmount plus feeBuyer, the seller receives
+// quoteAmount less feeSeller, and what was charged goes to the fee account.
+func (s *Server) applyTradeSettlement(ctx context.Context, tradeID, buyerUserID, sellerUserID, symbol string, qty, quoteAmount, feeBuyer, feeSeller int64) error {
 	base, quote, ok := parseSymbol(symbol)
 	if !ok {
-		return
+		return nil
 	}
-	qtyF := float64(qty)
-	quoteF := float64(quoteAmount)
+	baseQty := s.assetAmount(base, qty)
+	quoteTotal := s.assetAmount(quote, quoteAmount)
 
-	updates := make([]walletPersistUpdate, 0, 4)
-	s.state.mu.Lock()
+	// Either side may be a wallet this gateway has not loaded since it
+	// started; it settles against what that wallet holds.
+	for _, userID := range []string{buyerUserID, sellerUserID} {
+		if userID != "" {
+			s.snapshotWallet(userID)
+		}
+	}
+	tx := s.beginWalletTx(walletOpSettle, tradeID, buyerUserID, sellerUserID, s.cfg.FeeAccountID)
+	defer tx.rollback()
+	// A side the core did not name is settled elsewhere; the counterparty
+	// account books its legs, and only those.
+	if buyerUserID == "" {
+		tx.addExternal(base, baseQty)
+		tx.addExternal(quote, quoteTotal.MulInt(-1))
+	}
+	if sellerUserID == "" {
+		tx.addExternal(base, baseQty.MulInt(-1))
+		tx.addExternal(quote, quoteTotal)
+	}
+	collected := s.assetAmount(quote, 0)
 	if buyerUserID != "" {
-		updates = append(updates, s.settleBuyerLocked(buyerUserID, base, quote, qtyF, quoteF)...)
+		charged := settleBuyer(tx, buyerUserID, base, quote, baseQty, quoteTotal, s.assetAmount(quote, feeBuyer))
+		collected = collected.Add(charged)
 	}
 	if sellerUserID != "" {
-		updates = append(updates, s.settleSellerLocked(sellerUserID, base, quote, qtyF, quoteF)...)
+		sellerFee := s.assetAmount(quote, feeSeller)
+		settleSeller(tx, sellerUserID, base, quote, baseQty, quoteTotal, sellerFee)
+		collected = collected.Add(sellerFee)
 	}
-	s.state.mu.Unlock()
-
-	for _, update := range updates {
-		s.persistWalletBalance(context.Background(), update.userID, update.currency, update.balance)
+	if collected.Sign() > 0 {
+		s.creditFeeAccount(tx, quote, collected)
 	}
+	return tx.commit(ctx)
 }
 
-func (s *Server) settleBuyerLocked(userID, base, quote string, qty, quoteAmount float64) []walletPersistUpdate {
-	wallet := s.state.wallets[userID]
-	if wallet == nil {
-		wallet = map[string]walletBalance{}
+// settleBuyer takes the trade's quote out of the buyer's hold, where the
+// order reserved it, and the fee out of available, since reserves do not
+// cover fees; each falls back to the other bucket when short. It returns the
+// fee actually charged.
+func settleBuyer(tx *walletTx, userID, base, quote string, qty, quoteAmount, fee Decimal) Decimal {
+	quoteBal := takeFromHold(tx.balance(userID, quote), quoteAmount)
+	charged := fee.zero()
+	if fee.Sign() > 0 {
+		fromAvailable := minDecimal(fee, quoteBal.Available)
+		fromHold := minDecimal(fee.Sub(fromAvailable), quoteBal.Hold)
+		quoteBal.Available = quoteBal.Available.Sub(fromAvailable)
+		quoteBal.Hold = quoteBal.Hold.Sub(fromHold)
+		charged = fromAvailable.Add(fromHold)
 	}
+	tx.set(userID, quote, quoteBal)
 
-	quoteBal := wallet[quote]
-	remaining := quoteAmount
-	if quoteBal.Hold >= remaining {
-		quoteBal.Hold -= remaining
-		remaining = 0
-	} else {
-		remaining -= quoteBal.Hold
-		quoteBal.Hold = 0
-	}
-	if remaining > 0 {
-		quoteBal.Available -= remaining
-		if quoteBal.Available < 0 {
-			quoteBal.Available = 0
-		}
-	}
-	wallet[quote] = quoteBal
-
-	baseBal := wallet[base]
-	baseBal.Available += qty
-	wallet[base] = baseBal
-
-	s.state.wallets[userID] = wallet
-	return []walletPersistUpdate{
-		{userID: userID, currency: quote, balance: quoteBal},
-		{userID: userID, currency: base, balance: baseBal},
-	}
+	baseBal := tx.balance(userID, base)
+	baseBal.Available = baseBal.Available.Add(qty)
+	tx.set(userID, base, baseBal)
+	return charged
 }
 
-func (s *Server) settleSellerLocked(userID, base, quote string, qty, quoteAmount float64) []walletPersistUpdate {
-	wallet := s.state.wallets[userID]
-	if wallet == nil {
-		wallet = map[string]walletBalance{}
-	}
+func settleSeller(tx *walletTx, userID, base, quote string, qty, quoteAmount, fee Decimal) {
+	tx.set(userID, base, takeFromHold(tx.balance(userID, base), qty))
 
-	baseBal := wallet[base]
-	remaining := qty
-	if baseBal.Hold >= remaining {
-		baseBal.Hold -= remaining
-		remaining = 0
-	} else {
-		remaining -= baseBal.Hold
-		baseBal.Hold = 0
-	}
-	if remaining > 0 {
-		baseBal.Available -= remaining
-		if baseBal.Available < 0 {
-			baseBal.Available = 0
-		}
-	}
-	wallet[base] = baseBal
-
-	quoteBal := wallet[quote]
-	quoteBal.Available += quoteAmount
-	wallet[quote] = quoteBal
+	quoteBal := tx.balance(userID, quote)
+	quoteBal.Available = quoteBal.Available.Add(quoteAmount.Sub(fee))
+	tx.set(userID, quote, quoteBal)
+}
 
-	s.state.wallets[userID] = wallet
-	return []walletPersistUpdate{
-		{userID: userID, currency: base, balance: baseBal},
-		{userID: userID, currency: quote, balance: quoteBal},
+// takeFromHold debits amount from the hold the order reserved, falling
+// back to available for whatever the hold does not cover; available is
+// floored at zero.
+func takeFromHold(bal walletBalance, amount Decimal) walletBalance {
+	fromHold := minDecimal(amount, bal.Hold)
+	bal.Hold = bal.Hold.Sub(fromHold)
+	bal.Available = bal.Available.Sub(amount.Sub(fromHold))
+	if bal.Available.Sign() < 0 {
+		bal.Available = bal.Available.zero()
 	}
+	return bal
 }
 
 type reserveRelease struct {
 	userID   string
 	currency string
-	amount   float64
+	amount   Decimal
+}
+
+// orderFill is one fill of an order, as applyOrderFill takes it.
+type orderFill struct {
+	qty   int64
+	price int64
+	seq   uint64
+}
+
+// applyActivatingFills replays the fills an ACTIVATING order collected
+// before its placement answer came back, now that the record is live.
+func (s *Server) applyActivatingFills(orderID string) {
+	s.state.mu.Lock()
+	fills := s.state.activatingFills[orderID]
+	delete(s.state.activatingFills, orderID)
+	s.state.mu.Unlock()
+	for _, fill := range fills {
+		s.applyOrderFill(orderID, fill.qty, fill.price, fill.seq)
+	}
 }
 
 func (s *Server) applyOrderFill(orderID string, fillQty, fillPrice int64, seq uint64) {
@@ -2108,34 +3180,26 @@ func (s *Server) applyOrderFill(orderID string, fillQty, fillPrice int64, seq ui
 		return
 	}
 
-	var release *reserveRelease
-	fillQtyF := float64(fillQty)
-	fillQuoteF := float64(fillQty) * float64(fillPrice)
-
 	s.state.mu.Lock()
 	record, ok := s.state.orders[orderID]
+	if ok && record.Status == orderStatusActivating {
+		s.state.activatingFills[orderID] = append(s.state.activatingFills[orderID], orderFill{qty: fillQty, price: fillPrice, seq: seq})
+		s.state.mu.Unlock()
+		return
+	}
 	if ok {
-		record.FilledQty += fillQtyF
+		record.FilledQty = record.FilledQty.Add(decimalFromInt(fillQty))
 		switch strings.ToUpper(record.Side) {
 		case "BUY":
-			record.ReserveConsumed += fillQuoteF
+			record.ReserveConsumed = record.ReserveConsumed.Add(decimalFromInt(fillQty).MulInt(fillPrice))
 		case "SELL":
-			record.ReserveConsumed += fillQtyF
+			record.ReserveConsumed = record.ReserveConsumed.Add(decimalFromInt(fillQty))
 		}
 
-		if record.Qty > 0 && record.FilledQty >= record.Qty-1e-9 {
+		if record.Qty.Sign() > 0 && record.FilledQty.Cmp(record.Qty) >= 0 {
 			record.FilledQty = record.Qty
 			record.Status = "FILLED"
-			remainingReserve := record.ReserveAmount - record.ReserveConsumed
-			if remainingReserve > 1e-9 && record.OwnerUserID != "" && record.ReserveCurrency != "" {
-				release = &reserveRelease{
-					userID:   record.OwnerUserID,
-					currency: record.ReserveCurrency,
-					amount:   remainingReserve,
-				}
-				record.ReserveAmount -= remainingReserve
-			}
-		} else if record.FilledQty > 0 {
+		} else if record.FilledQty.Sign() > 0 {
 			record.Status = "PARTIALLY_FILLED"
 		}
 
@@ -2146,8 +3210,20 @@ func (s *Server) applyOrderFill(orderID string, fillQty, fillPrice int64, seq ui
 	}
 	s.state.mu.Unlock()
 
-	if release != nil {
-		s.releaseReserve(release.userID, release.currency, release.amount)
+	if ok {
+		s.persistOrder(context.Background(), record)
+	}
+	if ok && record.Status == "FILLED" {
+		s.releaseOrderReserve(context.Background(), orderID)
+	}
+	if ok && record.GroupID != "" {
+		s.closeLinkedOrders(context.Background(), orderID)
+	}
+	if ok && record.ParentOrderID != "" {
+		s.applyAlgoChildFill(context.Background(), record, fillQty, fillPrice, seq)
+	}
+	if ok {
+		s.completePendingCancel(context.Background(), orderID)
 	}
 }
 
@@ -2218,6 +3294,7 @@ func (s *Server) wsReader(c *client) {
 	defer func() {
 		c.closeSend()
 		_ = c.conn.Close()
+		s.handleWSDisconnect(c)
 	}()
 
 	for {
@@ -2225,6 +3302,9 @@ func (s *Server) wsReader(c *client) {
 		if err != nil {
 			return
 		}
+		if userID := c.principal(); userID != "" {
+			s.refreshDeadManSwitch(userID, time.Now().UnixMilli())
+		}
 		var cmd WSCommand
 		if err := json.Unmarshal(raw, &cmd); err != nil {
 			s.sendToClient(c, WSMessage{Type: "Error", Symbol: "", Seq: 0, Ts: time.Now().UnixMilli(), Data: map[string]string{"error": "invalid command"}}, false, "")
@@ -2232,8 +3312,23 @@ func (s *Server) wsReader(c *client) {
 		}
 
 		switch strings.ToUpper(cmd.Op) {
+		case "AUTH":
+			userID, err := s.authenticateWS(cmd)
+			if err != nil {
+				s.sendToClient(c, WSMessage{
+					Type: "Error", Symbol: "", Seq: 0, Ts: time.Now().UnixMilli(), Data: map[string]string{"error": err.Error()},
+				}, false, "")
+				continue
+			}
+			c.setPrincipal(userID)
+			s.sendToClient(c, WSMessage{
+				Type: "Authenticated", Symbol: "", Seq: 0, Ts: time.Now().UnixMilli(), Data: map[string]string{"userId": userID},
+			}, false, "")
 		case "SUB":
 			sub, err := parseWSSubscription(cmd)
+			if err == nil && sub.channel == privateOrdersChannel && c.principal() == "" {
+				err = fmt.Errorf("authentication required")
+			}
 			if err != nil {
 				s.sendToClient(c, WSMessage{
 					Type: "Error", Symbol: "", Seq: 0, Ts: time.Now().UnixMilli(), Data: map[string]string{"error": err.Error()},
@@ -2241,6 +3336,10 @@ func (s *Server) wsReader(c *client) {
 				continue
 			}
 			c.upsertSubscription(sub)
+			if sub.channel == privateOrdersChannel {
+				s.sendOrdersSnapshot(c, sub)
+				continue
+			}
 			s.sendSnapshot(c, sub)
 		case "UNSUB":
 			sub, err := parseWSSubscription(cmd)
@@ -2367,7 +3466,7 @@ func (s *Server) sendToClient(c *client, msg WSMessage, conflatableMessage bool,
 func parseWSSubscription(cmd WSCommand) (wsSubscription, error) {
 	channel := strings.ToLower(strings.TrimSpace(cmd.Channel))
 	symbol := strings.ToUpper(strings.TrimSpace(cmd.Symbol))
-	if channel == "" || symbol == "" {
+	if channel == "" || (symbol == "" && channel != privateOrdersChannel) {
 		return wsSubscription{}, fmt.Errorf("channel/symbol required")
 	}
 	if !supportedWSChannel(channel) {
@@ -2406,7 +3505,8 @@ func defaultSubscription(channel, symbol string) wsSubscription {
 }
 
 func supportedWSChannel(channel string) bool {
-	return channel == "trades" || channel == "book" || channel == "candles" || channel == "ticker"
+	return channel == "trades" || channel == "book" || channel == "candles" || channel == "ticker" ||
+		channel == privateOrdersChannel
 }
 
 func applySubscriptionView(msg WSMessage, sub wsSubscription) WSMessage {
@@ -2576,19 +3676,20 @@ func (s *Server) handleGetCandles(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleGetTicker(w http.ResponseWriter, r *http.Request) {
 	symbol := chi.URLParam(r, "symbol")
+	mode := s.symbolMode(symbol).Mode
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 	payload, ok := s.cacheGet(ctx, cacheKey("ticker", symbol))
 	if !ok {
-		writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "ticker": map[string]interface{}{}})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "mode": mode, "ticker": map[string]interface{}{}})
 		return
 	}
 	var msg WSMessage
 	if err := json.Unmarshal(payload, &msg); err != nil {
-		writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "ticker": map[string]interface{}{}})
+		writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "mode": mode, "ticker": map[string]interface{}{}})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "ticker": msg})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"symbol": symbol, "mode": mode, "ticker": msg})
 }
 
 func trimBookLevels(raw interface{}, depth int) []interface{} {
@@ -2753,6 +3854,14 @@ func (s *Server) authFail(reason string) {
 	s.state.mu.Unlock()
 }
 
+func traceIDFromContext(ctx context.Context) string {
+	traceID := trace.SpanFromContext(ctx).SpanContext().TraceID().String()
+	if traceID == "" || traceID == "00000000000000000000000000000000" {
+		return uuid.NewString()
+	}
+	return traceID
+}
+
 func (s *Server) apiKeyFromContext(ctx context.Context) string {
 	v, _ := ctx.Value(apiKeyContextKey).(string)
 	return v
@@ -2803,6 +3912,10 @@ func parseInt64Any(raw interface{}) (int64, bool) {
 	}
 }
 
+func formatQty(v Decimal) string {
+	return v.trimmed().String()
+}
+
 func parseLimit(raw string, fallback int) int {
 	if raw == "" {
 		return fallback
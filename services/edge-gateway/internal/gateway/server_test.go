@@ -8,9 +8,11 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,7 +27,14 @@ import (
 
 func newTestServer(t *testing.T) (*Server, func()) {
 	t.Helper()
-	coreAddr, shutdownCore := startTestCore(t)
+	return newTestServerWithCore(t, &stubCore{})
+}
+
+// newTestServerWithCore is newTestServer against a given stub core, for
+// tests that hook into its calls.
+func newTestServerWithCore(t *testing.T, core *stubCore) (*Server, func()) {
+	t.Helper()
+	coreAddr, shutdownCore := startTestCore(t, core)
 	s, err := New(Config{
 		DisableDB:          true,
 		WSQueueSize:        8,
@@ -35,6 +44,8 @@ func newTestServer(t *testing.T) (*Server, func()) {
 		RateLimitPerMinute: 100,
 		CoreAddr:           coreAddr,
 		CoreTimeout:        2 * time.Second,
+		AdminToken:         "admin-secret",
+		Markets:            testMarkets(),
 	})
 	if err != nil {
 		t.Fatalf("new server: %v", err)
@@ -55,10 +66,38 @@ func signHeaders(t *testing.T, method, path string, body []byte, tsMs int64) htt
 	return h
 }
 
+var signedRequestSeq int64
+
+// signedRequest builds an HMAC-signed request with a unique timestamp so that
+// consecutive calls in a test never trip replay detection.
+func signedRequest(t *testing.T, method, target string, body []byte, idemKey string) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	tsMs := time.Now().UnixMilli() + atomic.AddInt64(&signedRequestSeq, 1)
+	for k, vals := range signHeaders(t, method, req.URL.Path, body, tsMs) {
+		req.Header[k] = vals
+	}
+	if idemKey != "" {
+		req.Header.Set("Idempotency-Key", idemKey)
+	}
+	return req
+}
+
+// stubCore accepts every order without matching. It only remembers resting
+// limit orders so post-only and self-trade prevention answer like the
+// core's book: post-only is refused when the order would cross the best
+// opposite price, and STP only acts on crossing orders of the same user.
 type stubCore struct {
 	exchangev1.UnimplementedTradingCoreServiceServer
-	mu  sync.Mutex
-	seq uint64
+	mu      sync.Mutex
+	seq     uint64
+	resting map[string]stubRestingOrder
+	// beforeCancel, when set, runs before CancelOrder answers.
+	beforeCancel func(orderID string)
+	// unknown lists orders CancelOrder answers UNKNOWN_ORDER for.
+	unknown map[string]bool
+	// feeBps records the maker and taker rates each order was placed with.
+	feeBps map[string][2]string
 }
 
 func (s *stubCore) PlaceOrder(
@@ -66,28 +105,109 @@ func (s *stubCore) PlaceOrder(
 	req *exchangev1.PlaceOrderRequest,
 ) (*exchangev1.PlaceOrderResponse, error) {
 	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.seq++
-	seq := s.seq
-	s.mu.Unlock()
-	return &exchangev1.PlaceOrderResponse{
+	resp := &exchangev1.PlaceOrderResponse{
 		Accepted:      true,
 		OrderId:       req.OrderId,
 		Status:        "ACCEPTED",
 		Symbol:        req.GetMeta().GetSymbol(),
-		Seq:           seq,
+		Seq:           s.seq,
 		AcceptedAt:    timestamppb.Now(),
 		CorrelationId: req.GetMeta().GetCorrelationId(),
-	}, nil
+	}
+	if s.resting == nil {
+		s.resting = map[string]stubRestingOrder{}
+	}
+	if s.feeBps == nil {
+		s.feeBps = map[string][2]string{}
+	}
+	s.feeBps[req.OrderId] = [2]string{req.MakerFeeBps, req.TakerFeeBps}
+	var crossing, own []string
+	for orderID, resting := range s.resting {
+		if resting.side == req.Side || !stubCrosses(req, resting) {
+			continue
+		}
+		crossing = append(crossing, orderID)
+		if resting.userID == req.GetMeta().GetUserId() {
+			own = append(own, orderID)
+		}
+	}
+	sort.Strings(own)
+	stp := req.SelfTradePrevention
+	switch {
+	case req.PostOnly && len(crossing) > 0:
+		resp.Accepted = false
+		resp.Status = "REJECTED"
+		resp.RejectCode = "POST_ONLY_WOULD_TAKE"
+		return resp, nil
+	case len(own) == 0, stp == exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_UNSPECIFIED:
+	case stp == exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_CANCEL_NEWEST:
+		resp.Status = "CANCELED"
+		resp.RejectCode = "SELF_TRADE_PREVENTED"
+		return resp, nil
+	default:
+		for _, orderID := range own {
+			delete(s.resting, orderID)
+		}
+		resp.StpCanceledOrderIds = own
+		if stp == exchangev1.SelfTradePrevention_SELF_TRADE_PREVENTION_CANCEL_BOTH {
+			resp.Status = "CANCELED"
+			resp.RejectCode = "SELF_TRADE_PREVENTED"
+			return resp, nil
+		}
+	}
+	if req.OrderType == exchangev1.OrderType_ORDER_TYPE_LIMIT {
+		price, _ := parseDecimal(req.Price)
+		s.resting[req.OrderId] = stubRestingOrder{side: req.Side, price: price, userID: req.GetMeta().GetUserId()}
+	}
+	return resp, nil
+}
+
+type stubRestingOrder struct {
+	side   exchangev1.Side
+	price  Decimal
+	userID string
+}
+
+// stubCrosses reports whether req would trade against resting, which rests
+// on the other side.
+func stubCrosses(req *exchangev1.PlaceOrderRequest, resting stubRestingOrder) bool {
+	if req.OrderType == exchangev1.OrderType_ORDER_TYPE_MARKET {
+		return true
+	}
+	price, ok := parseDecimal(req.Price)
+	if !ok {
+		return false
+	}
+	if req.Side == exchangev1.Side_SIDE_BUY {
+		return price.Cmp(resting.price) >= 0
+	}
+	return price.Cmp(resting.price) <= 0
 }
 
 func (s *stubCore) CancelOrder(
 	_ context.Context,
 	req *exchangev1.CancelOrderRequest,
 ) (*exchangev1.CancelOrderResponse, error) {
+	if s.beforeCancel != nil {
+		s.beforeCancel(req.OrderId)
+	}
 	s.mu.Lock()
 	s.seq++
 	seq := s.seq
+	delete(s.resting, req.OrderId)
+	unknown := s.unknown[req.OrderId]
 	s.mu.Unlock()
+	if unknown {
+		return &exchangev1.CancelOrderResponse{
+			OrderId:    req.OrderId,
+			Status:     "REJECTED",
+			Symbol:     req.GetMeta().GetSymbol(),
+			Seq:        seq,
+			RejectCode: "UNKNOWN_ORDER",
+		}, nil
+	}
 	return &exchangev1.CancelOrderResponse{
 		Accepted:      true,
 		OrderId:       req.OrderId,
@@ -99,14 +219,31 @@ func (s *stubCore) CancelOrder(
 	}, nil
 }
 
-func startTestCore(t *testing.T) (string, func()) {
+func (s *stubCore) SetSymbolMode(
+	_ context.Context,
+	req *exchangev1.SetSymbolModeRequest,
+) (*exchangev1.SetSymbolModeResponse, error) {
+	s.mu.Lock()
+	s.seq++
+	seq := s.seq
+	s.mu.Unlock()
+	return &exchangev1.SetSymbolModeResponse{
+		Accepted: true,
+		Symbol:   req.GetMeta().GetSymbol(),
+		Seq:      seq,
+		ActedAt:  timestamppb.Now(),
+		Reason:   req.Reason,
+	}, nil
+}
+
+func startTestCore(t *testing.T, core *stubCore) (string, func()) {
 	t.Helper()
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
 		t.Fatalf("listen: %v", err)
 	}
 	server := grpc.NewServer()
-	exchangev1.RegisterTradingCoreServiceServer(server, &stubCore{})
+	exchangev1.RegisterTradingCoreServiceServer(server, core)
 	go func() {
 		_ = server.Serve(listener)
 	}()
@@ -283,6 +420,128 @@ func TestOrderLifecycleCreateGetCancel(t *testing.T) {
 	}
 }
 
+func TestListOrdersFiltersAndPaginates(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	create := func(idemKey, body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(body), idemKey))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create %s failed: %d body=%s", idemKey, w.Code, w.Body.String())
+		}
+	}
+	create("list-1", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`)
+	create("list-2", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"101","qty":"1"}`)
+	create("list-3", `{"symbol":"ETH-KRW","side":"SELL","type":"LIMIT","price":"50","qty":"1"}`)
+
+	cancelW := httptest.NewRecorder()
+	s.Router().ServeHTTP(cancelW, signedRequest(t, http.MethodDelete, "/v1/orders/ord_list-2", nil, "list-cancel"))
+	if cancelW.Code != http.StatusOK {
+		t.Fatalf("cancel failed: %d", cancelW.Code)
+	}
+
+	list := func(query string) ([]OrderRecord, string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/orders"+query, nil, ""))
+		if w.Code != http.StatusOK {
+			t.Fatalf("list %s failed: %d body=%s", query, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Orders     []OrderRecord `json:"orders"`
+			NextCursor string        `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		return resp.Orders, resp.NextCursor
+	}
+
+	open, _ := list("?status=open&symbol=BTC-KRW")
+	if len(open) != 1 || open[0].OrderID != "ord_list-1" {
+		t.Fatalf("expected only ord_list-1 open on BTC-KRW, got %+v", open)
+	}
+	closed, _ := list("?status=closed")
+	if len(closed) != 1 || closed[0].OrderID != "ord_list-2" {
+		t.Fatalf("expected only ord_list-2 closed, got %+v", closed)
+	}
+	sells, _ := list("?side=SELL")
+	if len(sells) != 1 || sells[0].Symbol != "ETH-KRW" {
+		t.Fatalf("expected one ETH-KRW sell, got %+v", sells)
+	}
+
+	seen := []string{}
+	cursor := ""
+	for i := 0; i < 4; i++ {
+		query := "?limit=2"
+		if cursor != "" {
+			query += "&cursor=" + cursor
+		}
+		page, next := list(query)
+		for _, o := range page {
+			seen = append(seen, o.OrderID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 orders across pages, got %v", seen)
+	}
+
+	badW := httptest.NewRecorder()
+	s.Router().ServeHTTP(badW, signedRequest(t, http.MethodGet, "/v1/orders?status=pending", nil, ""))
+	if badW.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid status, got %d", badW.Code)
+	}
+}
+
+func TestCancelAllOrdersReleasesReserves(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	for i, body := range []string{
+		`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"2"}`,
+		`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"200","qty":"1"}`,
+		`{"symbol":"ETH-KRW","side":"SELL","type":"LIMIT","price":"50","qty":"1"}`,
+	} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(body), "mass-"+strconv.Itoa(i)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("create %d failed: %d body=%s", i, w.Code, w.Body.String())
+		}
+	}
+	if hold := s.snapshotWallet("test-key")["KRW"].Hold; hold.String() != "400" {
+		t.Fatalf("expected 400 KRW on hold, got %v", hold)
+	}
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders", []byte(`{"symbol":"BTC-KRW"}`), "mass-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("mass cancel failed: %d body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Canceled []OrderResponse `json:"canceled"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode mass cancel: %v", err)
+	}
+	if len(resp.Canceled) != 2 {
+		t.Fatalf("expected 2 canceled orders, got %+v", resp.Canceled)
+	}
+
+	wallet := s.snapshotWallet("test-key")
+	if wallet["KRW"].Hold.String() != "0" {
+		t.Fatalf("expected KRW hold released, got %v", wallet["KRW"].Hold)
+	}
+	if wallet["ETH"].Hold.String() != "1.00000000" {
+		t.Fatalf("expected ETH sell reserve untouched, got %v", wallet["ETH"].Hold)
+	}
+}
+
 func TestTickerEndpointAfterSmokeTrade(t *testing.T) {
 	s, cleanup := newTestServer(t)
 	defer cleanup()
@@ -326,7 +585,7 @@ func TestTickerEndpointAfterSmokeTrade(t *testing.T) {
 }
 
 func TestSeededMarketEndpoints(t *testing.T) {
-	coreAddr, shutdownCore := startTestCore(t)
+	coreAddr, shutdownCore := startTestCore(t, &stubCore{})
 	defer shutdownCore()
 
 	s, err := New(Config{
@@ -416,7 +675,7 @@ func TestSeededMarketEndpoints(t *testing.T) {
 }
 
 func TestSessionAuthAndPortfolioFlow(t *testing.T) {
-	coreAddr, shutdownCore := startTestCore(t)
+	coreAddr, shutdownCore := startTestCore(t, &stubCore{})
 	defer shutdownCore()
 
 	s, err := New(Config{
@@ -499,7 +758,7 @@ func TestSessionAuthAndPortfolioFlow(t *testing.T) {
 }
 
 func TestSessionOrderRejectsInsufficientBalance(t *testing.T) {
-	coreAddr, shutdownCore := startTestCore(t)
+	coreAddr, shutdownCore := startTestCore(t, &stubCore{})
 	defer shutdownCore()
 
 	s, err := New(Config{
@@ -747,3 +1006,114 @@ func TestMetricsExposeWsBackpressureSeries(t *testing.T) {
 	assertMetric("ws_dropped_msgs", "7")
 	assertMetric("ws_slow_closes", "2")
 }
+
+func TestPostOnlyAndSelfTradePrevention(t *testing.T) {
+	// Another user's ask rests at 100: it makes crossing bids take, but
+	// self-trade prevention must leave it alone.
+	core := &stubCore{resting: map[string]stubRestingOrder{
+		"ord_other-ask": {side: exchangev1.Side_SIDE_SELL, price: decimalFromInt(100), userID: "other-user"},
+	}}
+	s, cleanup := newTestServerWithCore(t, core)
+	defer cleanup()
+
+	place := func(idemKey, body string) (int, OrderResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(body), idemKey))
+		var resp OrderResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	if code, _ := place("po-market", `{"symbol":"BTC-KRW","side":"BUY","type":"MARKET","qty":"1","postOnly":true}`); code != http.StatusBadRequest {
+		t.Fatalf("expected postOnly MARKET to be rejected, got %d", code)
+	}
+	if code, _ := place("stp-bad", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","selfTradePrevention":"DECREMENT"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected unknown STP mode to be rejected, got %d", code)
+	}
+
+	if _, passive := place("po-passive", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"99","qty":"1","postOnly":true}`); passive.Status != "ACCEPTED" {
+		t.Fatalf("expected a post-only bid below the ask to rest, got %+v", passive)
+	}
+	if _, foreign := place("stp-foreign", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","selfTradePrevention":"CANCEL_NEWEST"}`); foreign.Status != "ACCEPTED" || foreign.RejectCode != "" {
+		t.Fatalf("expected STP to ignore another user's ask, got %+v", foreign)
+	}
+	if code, _ := place("ask", `{"symbol":"BTC-KRW","side":"SELL","type":"LIMIT","price":"101","qty":"1"}`); code != http.StatusOK {
+		t.Fatalf("resting ask failed: %d", code)
+	}
+	before := s.snapshotWallet("test-key")["KRW"]
+
+	_, postOnly := place("po-cross", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"101","qty":"1","postOnly":true}`)
+	if postOnly.Status != "REJECTED" || postOnly.RejectCode != "POST_ONLY_WOULD_TAKE" {
+		t.Fatalf("unexpected post-only response: %+v", postOnly)
+	}
+	_, newest := place("stp-newest", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"101","qty":"1","selfTradePrevention":"CANCEL_NEWEST"}`)
+	if newest.Status != "CANCELED" || newest.RejectCode != "SELF_TRADE_PREVENTED" {
+		t.Fatalf("unexpected cancel-newest response: %+v", newest)
+	}
+	if got := s.snapshotWallet("test-key")["KRW"]; !sameBalance(got, before) {
+		t.Fatalf("rejected orders must not keep a reserve: before=%+v after=%+v", before, got)
+	}
+
+	_, oldest := place("stp-oldest", `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"101","qty":"1","selfTradePrevention":"CANCEL_OLDEST"}`)
+	if oldest.Status != "ACCEPTED" {
+		t.Fatalf("unexpected cancel-oldest response: %+v", oldest)
+	}
+	core.mu.Lock()
+	_, foreignResting := core.resting["ord_other-ask"]
+	core.mu.Unlock()
+	if !foreignResting {
+		t.Fatalf("expected STP to cancel only test-key's own ask")
+	}
+	s.state.mu.Lock()
+	ask := s.state.orders["ord_ask"]
+	bid := s.state.orders["ord_stp-oldest"]
+	s.state.mu.Unlock()
+	if ask.Status != "CANCELED" || !ask.ReserveAmount.IsZero() {
+		t.Fatalf("expected STP to cancel the resting ask and release it, got %+v", ask)
+	}
+	if bid.SelfTradePrevention != "CANCEL_OLDEST" {
+		t.Fatalf("expected STP mode on the record, got %+v", bid)
+	}
+	if hold := s.snapshotWallet("test-key")["BTC"].Hold; !hold.IsZero() {
+		t.Fatalf("expected ask reserve released, BTC hold=%v", hold)
+	}
+}
+
+func TestSelfTradeCancelKeepsTheReserveInFlightFillsUse(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	s.orderEvents = true
+
+	for _, tc := range []struct{ key, body string }{
+		{key: "ask", body: `{"symbol":"BTC-KRW","side":"SELL","type":"LIMIT","price":"100","qty":"2"}`},
+		{key: "bid", body: `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","selfTradePrevention":"CANCEL_OLDEST"}`},
+	} {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(tc.body), tc.key))
+		if w.Code != http.StatusOK {
+			t.Fatalf("place %s failed: %d body=%s", tc.key, w.Code, w.Body.String())
+		}
+	}
+	if hold := s.snapshotWallet("test-key")["BTC"].Hold; hold.String() != "2.00000000" {
+		t.Fatalf("expected the ask reserve held until its cancel event, BTC hold=%v", hold)
+	}
+
+	// One unit of the ask filled before the core canceled it; the cancel
+	// event lands before that fill does.
+	ctx := context.Background()
+	if err := s.consumeOrderEventMessage(ctx, []byte(`{"envelope":{"symbol":"BTC-KRW","seq":100},"eventType":"OrderCanceled","orderId":"ord_ask","remainingQuantity":"1"}`)); err != nil {
+		t.Fatalf("consume cancel: %v", err)
+	}
+	if err := s.consumeTradeMessage(ctx, []byte(`{"tradeId":"t-stp","symbol":"BTC-KRW","seq":99,"ts":1000,"makerOrderId":"ord_ask","takerOrderId":"ord_elsewhere","buyerUserId":"someone","sellerUserId":"test-key","price":100,"quantity":1,"quoteAmount":100}`)); err != nil {
+		t.Fatalf("consume trade: %v", err)
+	}
+
+	s.state.mu.Lock()
+	ask := s.state.orders["ord_ask"]
+	s.state.mu.Unlock()
+	btc := s.snapshotWallet("test-key")["BTC"]
+	if ask.Status != "CANCELED" || ask.FilledQty.Cmp(decimalFromInt(1)) != 0 || !btc.Hold.IsZero() || btc.Available.String() != "1.00000000" {
+		t.Fatalf("expected the filled unit settled and only the rest released, got order %+v BTC %+v", ask, btc)
+	}
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A sub-account is a wallet of its own under a user, such as an OTC desk's
+// book per client. It starts empty and only transfers move balances in or
+// out of it; orders, deposits and withdrawals use the main account.
+const subAccountSeparator = "/"
+
+var subAccountNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// SubAccountView is a sub-account and what it holds.
+type SubAccountView struct {
+	Name     string        `json:"name"`
+	Balances []BalanceView `json:"balances"`
+}
+
+// normalizeSubAccount lowercases a sub-account name and checks it: 1 to 32
+// letters, digits, '-' and '_'. An empty name is the main account.
+func normalizeSubAccount(raw string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(raw))
+	if name != "" && !subAccountNamePattern.MatchString(name) {
+		return "", fmt.Errorf("invalid sub-account name %q", raw)
+	}
+	return name, nil
+}
+
+// subAccountWalletID is the wallet of the user's sub-account name, or the
+// main account's when name is empty.
+func subAccountWalletID(userID, name string) string {
+	if name == "" {
+		return userID
+	}
+	return userID + subAccountSeparator + name
+}
+
+// subAccountWalletIDs lists the wallets of the user's sub-accounts, in
+// memory and, with a database, the stored ones.
+func (s *Server) subAccountWalletIDs(ctx context.Context, userID string) ([]string, error) {
+	prefix := userID + subAccountSeparator
+	seen := map[string]bool{}
+	if s.db != nil {
+		ctx, cancel := s.dbContext(ctx)
+		defer cancel()
+		rows, err := s.db.QueryContext(ctx,
+			`SELECT DISTINCT user_id FROM web_wallet_balances WHERE left(user_id, length($1)) = $1`, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("list sub-accounts: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var walletID string
+			if err := rows.Scan(&walletID); err != nil {
+				return nil, fmt.Errorf("scan sub-account: %w", err)
+			}
+			seen[walletID] = true
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("list sub-accounts: %w", err)
+		}
+	}
+	s.state.mu.Lock()
+	for walletID := range s.state.wallets {
+		if strings.HasPrefix(walletID, prefix) {
+			seen[walletID] = true
+		}
+	}
+	s.state.mu.Unlock()
+	walletIDs := make([]string, 0, len(seen))
+	for walletID := range seen {
+		walletIDs = append(walletIDs, walletID)
+	}
+	sort.Strings(walletIDs)
+	return walletIDs, nil
+}
+
+func (s *Server) handleListSubAccounts(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	walletIDs, err := s.subAccountWalletIDs(r.Context(), apiKey)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=sub_accounts_load_failed user_id=%s err=%v", apiKey, err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": errWalletUnavailable.Error()})
+		return
+	}
+	out := make([]SubAccountView, 0, len(walletIDs))
+	for _, walletID := range walletIDs {
+		wallet, err := s.storedWallet(walletID)
+		if err != nil {
+			log.Printf("service=edge-gateway msg=sub_accounts_load_failed user_id=%s wallet_id=%s err=%v", apiKey, walletID, err)
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": errWalletUnavailable.Error()})
+			return
+		}
+		out = append(out, SubAccountView{
+			Name:     strings.TrimPrefix(walletID, apiKey+subAccountSeparator),
+			Balances: balanceViews(wallet),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"subAccounts": out})
+}
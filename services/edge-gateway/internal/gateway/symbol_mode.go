@@ -0,0 +1,231 @@
+package gateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	exchangev1 "github.com/quanta-exchange/exchange-platform/contracts/gen/go/exchange/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	symbolModeNormal     = "NORMAL"
+	symbolModeCancelOnly = "CANCEL_ONLY"
+	symbolModeSoftHalt   = "SOFT_HALT"
+	symbolModeHardHalt   = "HARD_HALT"
+)
+
+type SymbolModeRequest struct {
+	Mode   string `json:"mode"`
+	Reason string `json:"reason"`
+}
+
+type symbolModeRecord struct {
+	Symbol    string `json:"symbol"`
+	Mode      string `json:"mode"`
+	Reason    string `json:"reason,omitempty"`
+	Seq       uint64 `json:"seq,omitempty"`
+	UpdatedAt int64  `json:"updatedAt,omitempty"`
+}
+
+func mapSymbolMode(value string) (exchangev1.SymbolMode, bool) {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case symbolModeNormal:
+		return exchangev1.SymbolMode_SYMBOL_MODE_NORMAL, true
+	case symbolModeCancelOnly:
+		return exchangev1.SymbolMode_SYMBOL_MODE_CANCEL_ONLY, true
+	case symbolModeSoftHalt:
+		return exchangev1.SymbolMode_SYMBOL_MODE_SOFT_HALT, true
+	case symbolModeHardHalt:
+		return exchangev1.SymbolMode_SYMBOL_MODE_HARD_HALT, true
+	default:
+		return exchangev1.SymbolMode_SYMBOL_MODE_UNSPECIFIED, false
+	}
+}
+
+// adminMiddleware guards operator endpoints with a shared admin token. Admin
+// routes stay closed when no token is configured.
+func (s *Server) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Admin-Token")
+		if s.cfg.AdminToken == "" || token == "" ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.AdminToken)) != 1 {
+			s.authFail("admin_token")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "admin token required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) symbolMode(symbol string) symbolModeRecord {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if rec, ok := s.state.symbolModes[symbol]; ok {
+		return rec
+	}
+	return symbolModeRecord{Symbol: symbol, Mode: symbolModeNormal}
+}
+
+// orderEntryBlock returns the reject code for new orders on symbol, or ""
+// when the mode allows them. Codes mirror the core's risk checks: the halts
+// reject as MARKET_HALTED and cancel-only as CANCEL_ONLY. Cancels are never
+// blocked here: the core takes them in every mode, so users, the dead-man
+// switch, GTD expiry and mass cancel can pull orders during a halt.
+func (s *Server) orderEntryBlock(symbol string) string {
+	switch s.symbolMode(symbol).Mode {
+	case symbolModeSoftHalt, symbolModeHardHalt:
+		return "MARKET_HALTED"
+	case symbolModeCancelOnly:
+		return "CANCEL_ONLY"
+	default:
+		return ""
+	}
+}
+
+func (s *Server) handleSetSymbolMode(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "symbol")))
+	if _, _, ok := parseSymbol(symbol); !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid symbol"})
+		return
+	}
+	var req SymbolModeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	mode, ok := mapSymbolMode(req.Mode)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid mode"})
+		return
+	}
+	if s.coreClient == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core_unavailable"})
+		return
+	}
+
+	commandID := uuid.NewString()
+	coreReq := &exchangev1.SetSymbolModeRequest{
+		Meta: &exchangev1.CommandMetadata{
+			CommandId:      commandID,
+			IdempotencyKey: commandID,
+			UserId:         "admin",
+			Symbol:         symbol,
+			TsServer:       timestamppb.Now(),
+			TraceId:        traceIDFromContext(r.Context()),
+			CorrelationId:  uuid.NewString(),
+		},
+		Mode:   mode,
+		Reason: strings.TrimSpace(req.Reason),
+	}
+	coreCtx, cancel := context.WithTimeout(r.Context(), s.cfg.CoreTimeout)
+	defer cancel()
+	coreResp, err := s.coreClient.SetSymbolMode(coreCtx, coreReq)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "core_unavailable"})
+		return
+	}
+	if !coreResp.Accepted {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "mode_change_rejected"})
+		return
+	}
+
+	updatedAt := time.Now().UnixMilli()
+	if coreResp.GetActedAt() != nil {
+		updatedAt = coreResp.ActedAt.AsTime().UnixMilli()
+	}
+	rec := symbolModeRecord{
+		Symbol:    symbol,
+		Mode:      strings.TrimPrefix(mode.String(), "SYMBOL_MODE_"),
+		Reason:    coreReq.Reason,
+		Seq:       coreResp.Seq,
+		UpdatedAt: updatedAt,
+	}
+	s.state.mu.Lock()
+	s.state.symbolModes[symbol] = rec
+	s.state.mu.Unlock()
+	// The core has already switched modes, so the write must not be
+	// abandoned when the admin client hangs up.
+	s.persistSymbolMode(context.WithoutCancel(r.Context()), rec)
+	log.Printf("service=edge-gateway msg=symbol_mode_changed symbol=%s mode=%s reason=%q", symbol, rec.Mode, rec.Reason)
+
+	writeJSON(w, http.StatusOK, rec)
+}
+
+func (s *Server) handleGetMarketStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.symbolMode(chi.URLParam(r, "symbol")))
+}
+
+func (s *Server) initSymbolModeSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_symbol_modes (
+			symbol TEXT PRIMARY KEY,
+			mode TEXT NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			seq BIGINT NOT NULL DEFAULT 0,
+			updated_at_ms BIGINT NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init symbol mode schema: %w", err)
+	}
+	return nil
+}
+
+// persistSymbolMode records the mode the core accepted so order entry stays
+// blocked across a gateway restart.
+func (s *Server) persistSymbolMode(ctx context.Context, rec symbolModeRecord) {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	if s.db == nil {
+		return
+	}
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO web_symbol_modes(symbol, mode, reason, seq, updated_at_ms)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (symbol) DO UPDATE SET
+		 mode = EXCLUDED.mode,
+		 reason = EXCLUDED.reason,
+		 seq = EXCLUDED.seq,
+		 updated_at_ms = EXCLUDED.updated_at_ms`,
+		rec.Symbol,
+		rec.Mode,
+		rec.Reason,
+		int64(rec.Seq),
+		rec.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=symbol_mode_persist_failed symbol=%s err=%v", rec.Symbol, err)
+	}
+}
+
+func (s *Server) loadSymbolModes(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT symbol, mode, reason, seq, updated_at_ms FROM web_symbol_modes`)
+	if err != nil {
+		return fmt.Errorf("load symbol modes: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rec symbolModeRecord
+		var seq int64
+		if err := rows.Scan(&rec.Symbol, &rec.Mode, &rec.Reason, &seq, &rec.UpdatedAt); err != nil {
+			return fmt.Errorf("scan symbol mode: %w", err)
+		}
+		rec.Seq = uint64(seq)
+		if rec.Mode == symbolModeNormal {
+			continue
+		}
+		s.state.symbolModes[rec.Symbol] = rec
+	}
+	return rows.Err()
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setSymbolModeForTest(t *testing.T, s *Server, symbol, mode string) *httptest.ResponseRecorder {
+	t.Helper()
+	body := []byte(`{"mode":"` + mode + `","reason":"test"}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/symbols/"+symbol+"/mode", bytes.NewReader(body))
+	req.Header.Set("X-Admin-Token", "admin-secret")
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	return w
+}
+
+func TestSetSymbolModeRequiresAdminToken(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/admin/symbols/BTC-KRW/mode", bytes.NewReader([]byte(`{"mode":"HARD_HALT"}`)))
+	req.Header.Set("X-Admin-Token", "wrong")
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", w.Code)
+	}
+}
+
+func TestSymbolModeBlocksOrderEntryButNotCancels(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	place := func(key, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(body), key))
+		return w
+	}
+	body := `{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	for _, key := range []string{"mode-before", "mode-mass-1", "mode-mass-2"} {
+		if w := place(key, body); w.Code != http.StatusOK {
+			t.Fatalf("create %s before halt failed: %d", key, w.Code)
+		}
+	}
+	if w := place("mode-gtd", fmt.Sprintf(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","timeInForce":"GTD","expireAt":%d}`, expireAt)); w.Code != http.StatusOK {
+		t.Fatalf("create GTD before halt failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	// New orders are refused with the code the core's risk check uses.
+	for _, tc := range []struct{ mode, code string }{
+		{"CANCEL_ONLY", "CANCEL_ONLY"},
+		{"SOFT_HALT", "MARKET_HALTED"},
+		{"HARD_HALT", "MARKET_HALTED"},
+	} {
+		if w := setSymbolModeForTest(t, s, "BTC-KRW", tc.mode); w.Code != http.StatusOK {
+			t.Fatalf("set %s failed: %d body=%s", tc.mode, w.Code, w.Body.String())
+		}
+		w := place("mode-"+tc.mode, body)
+		if w.Code != http.StatusBadRequest || !bytes.Contains(w.Body.Bytes(), []byte(tc.code)) {
+			t.Fatalf("expected %s rejection in %s, got %d body=%s", tc.code, tc.mode, w.Code, w.Body.String())
+		}
+	}
+
+	// A hard halt freezes matching, not cancels: the core takes them in
+	// every mode, so user cancels, GTD expiry and mass cancel keep working.
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_mode-before", nil, "mode-cancel"))
+	if w.Code != http.StatusOK || getOrderRecord(t, s, "ord_mode-before").Status != "CANCELED" {
+		t.Fatalf("expected cancel forwarded during HARD_HALT, got %d body=%s", w.Code, w.Body.String())
+	}
+	s.expireOrders(context.Background(), expireAt)
+	if record := getOrderRecord(t, s, "ord_mode-gtd"); record.Status != orderStatusExpired {
+		t.Fatalf("expected GTD order expired during HARD_HALT, got %s", record.Status)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders?symbol=BTC-KRW", nil, "mode-mass"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("mass cancel during HARD_HALT failed: %d body=%s", w.Code, w.Body.String())
+	}
+	for _, orderID := range []string{"ord_mode-mass-1", "ord_mode-mass-2"} {
+		if record := getOrderRecord(t, s, orderID); record.Status != "CANCELED" {
+			t.Fatalf("expected %s canceled by mass cancel, got %s", orderID, record.Status)
+		}
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "0" {
+		t.Fatalf("expected every reserve released, got %+v", krw)
+	}
+
+	statusW := httptest.NewRecorder()
+	s.Router().ServeHTTP(statusW, httptest.NewRequest(http.MethodGet, "/v1/markets/BTC-KRW/status", nil))
+	var status symbolModeRecord
+	if err := json.Unmarshal(statusW.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if status.Mode != "HARD_HALT" {
+		t.Fatalf("expected HARD_HALT on market status, got %+v", status)
+	}
+
+	if w := place("mode-other", `{"symbol":"ETH-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected other symbols unaffected, got %d", w.Code)
+	}
+
+	// The dead-man switch pulls orders on a halted symbol too.
+	if w := setSymbolModeForTest(t, s, "ETH-KRW", "HARD_HALT"); w.Code != http.StatusOK {
+		t.Fatalf("set HARD_HALT on ETH-KRW failed: %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPut, "/v1/account/dead-man-switch", []byte(`{"timeoutSeconds":30}`), ""))
+	var armed DeadManSwitchView
+	if err := json.Unmarshal(w.Body.Bytes(), &armed); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("arm failed: %d body=%s", w.Code, w.Body.String())
+	}
+	s.sweepDeadManSwitches(context.Background(), armed.ExpiresAt)
+	if record := getOrderRecord(t, s, "ord_mode-other"); record.Status != "CANCELED" {
+		t.Fatalf("expected the switch to cancel during HARD_HALT, got %s", record.Status)
+	}
+}
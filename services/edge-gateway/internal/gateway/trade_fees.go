@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"context"
+	"log"
+	"net/http"
+)
+
+// defaultFeeAccountID matches the ledger's system:fees account.
+const defaultFeeAccountID = "system:fees"
+
+// creditFeeAccount adds collected fees to the fee account. Unlike a user
+// wallet it starts empty rather than with the demo balances.
+func (s *Server) creditFeeAccount(tx *walletTx, currency string, amount Decimal) {
+	bal := tx.balance(s.cfg.FeeAccountID, currency)
+	bal.Available = bal.Available.Add(amount)
+	tx.set(s.cfg.FeeAccountID, currency, bal)
+}
+
+// loadFeeWallet restores the fee account's balances from the database so
+// fees collected before a restart are not overwritten.
+func (s *Server) loadFeeWallet(ctx context.Context) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT currency, available, hold FROM web_wallet_balances WHERE user_id = $1`,
+		s.cfg.FeeAccountID,
+	)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=fee_wallet_load_failed account=%s err=%v", s.cfg.FeeAccountID, err)
+		return
+	}
+	defer rows.Close()
+	wallet := s.scanWalletRows(rows)
+	s.state.mu.Lock()
+	s.state.wallets[s.cfg.FeeAccountID] = wallet
+	s.state.mu.Unlock()
+}
+
+func (s *Server) handleGetFeeAccount(w http.ResponseWriter, _ *http.Request) {
+	s.state.mu.Lock()
+	balances := cloneWallet(s.state.wallets[s.cfg.FeeAccountID])
+	s.state.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"accountId": s.cfg.FeeAccountID,
+		"balances":  balances,
+	})
+}
@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTradeFeesChargedInSettlementAndCollected(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"10"}`), "fee-buy"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	fundSeller(t, s, "fee-seller", "BTC", "11")
+	if err := s.consumeTradeMessage(context.Background(), []byte(
+		`{"tradeId":"fee-t1","symbol":"BTC-KRW","seq":100,"makerOrderId":"ord_fee-buy","takerOrderId":"ord_x","buyerUserId":"test-key","sellerUserId":"fee-seller","price":100,"quantity":10,"feeBuyer":5,"feeSeller":"7"}`,
+	)); err != nil {
+		t.Fatalf("consume trade: %v", err)
+	}
+
+	buyer := s.snapshotWallet("test-key")
+	if buyer["KRW"].Hold.String() != "0" || buyer["KRW"].Available.String() != "49998995" || buyer["BTC"].Available.String() != "12.00000000" {
+		t.Fatalf("expected buyer charged gross quote plus fee, got %+v", buyer)
+	}
+	s.state.mu.Lock()
+	sellerKRW := s.state.wallets["fee-seller"]["KRW"]
+	s.state.mu.Unlock()
+	if sellerKRW.Available.String() != "50000993" {
+		t.Fatalf("expected seller credited net of fee, got %+v", sellerKRW)
+	}
+
+	w = adminRequest(t, s, http.MethodGet, "/v1/admin/fee-account", "")
+	var account struct {
+		AccountID string                   `json:"accountId"`
+		Balances  map[string]walletBalance `json:"balances"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &account); err != nil || account.AccountID != defaultFeeAccountID || account.Balances["KRW"].Available.String() != "12" {
+		t.Fatalf("expected both fees collected, got %d body=%s", w.Code, w.Body.String())
+	}
+	if _, ok := account.Balances["BTC"]; ok {
+		t.Fatalf("fee account must not start with demo balances: %+v", account.Balances)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/orders/ord_fee-buy/fills", nil, ""))
+	var fills struct {
+		Fee         string       `json:"fee"`
+		FeeCurrency string       `json:"feeCurrency"`
+		Fills       []FillRecord `json:"fills"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &fills); err != nil || fills.Fee != "5" || fills.FeeCurrency != "KRW" || len(fills.Fills) != 1 || fills.Fills[0].Fee != "5" {
+		t.Fatalf("expected buyer fee on the fill, got %d body=%s", w.Code, w.Body.String())
+	}
+
+	if err := s.consumeTradeMessage(context.Background(), []byte(
+		`{"tradeId":"fee-t2","symbol":"BTC-KRW","seq":101,"buyerUserId":"test-key","sellerUserId":"fee-seller","price":100,"quantity":1,"feeSeller":101}`,
+	)); err == nil {
+		t.Fatalf("expected a seller fee above the quote amount rejected")
+	}
+}
+
+// fundSeller puts amount of currency on hold for userID, as the resting
+// sell orders of a counterparty would.
+func fundSeller(t *testing.T, s *Server, userID, currency, amount string) {
+	t.Helper()
+	d, ok := parseDecimal(amount)
+	if !ok {
+		t.Fatalf("bad amount %q", amount)
+	}
+	s.snapshotWallet(userID)
+	s.state.mu.Lock()
+	bal := s.state.wallets[userID][currency]
+	bal.Hold = bal.Hold.Add(d)
+	s.state.wallets[userID][currency] = bal
+	s.state.mu.Unlock()
+}
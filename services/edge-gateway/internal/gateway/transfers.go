@@ -0,0 +1,451 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// walletOpTransfer moves available balance from one wallet to another in a
+// single wallet tx: between users, or between a user's main account and
+// sub-accounts.
+const walletOpTransfer = "TRANSFER"
+
+const maxTransferMemoLength = 128
+
+var (
+	errUnknownRecipient  = errors.New("UNKNOWN_RECIPIENT")
+	errTransferKeyReused = errors.New("IDEMPOTENCY_KEY_REUSED")
+)
+
+// TransferRecord is an internal transfer as both parties see it; Direction
+// is OUT for the sender, IN for the recipient and INTERNAL for a move
+// between one user's own accounts.
+type TransferRecord struct {
+	TransferID     string  `json:"transferId"`
+	FromUserID     string  `json:"fromUserId"`
+	FromSubAccount string  `json:"fromSubAccount,omitempty"`
+	ToUserID       string  `json:"toUserId"`
+	ToSubAccount   string  `json:"toSubAccount,omitempty"`
+	Currency       string  `json:"currency"`
+	Amount         Decimal `json:"amount"`
+	Memo           string  `json:"memo,omitempty"`
+	Direction      string  `json:"direction,omitempty"`
+	CreatedAt      int64   `json:"createdAt"`
+	// IdemKey is the sender's Idempotency-Key; it is unique per sender.
+	IdemKey string `json:"-"`
+}
+
+// transferKey indexes transfers by sender and Idempotency-Key.
+type transferKey struct {
+	userID  string
+	idemKey string
+}
+
+// TransferRequest names the recipient by at most one of ToUserID and
+// ToEmail; without either it moves the balance between the caller's own
+// accounts. FromSubAccount and ToSubAccount pick a sub-account instead of
+// the main account on either side; ToSubAccount only names the caller's.
+type TransferRequest struct {
+	ToUserID       string `json:"toUserId"`
+	ToEmail        string `json:"toEmail"`
+	Currency       string `json:"currency"`
+	Amount         string `json:"amount"`
+	Memo           string `json:"memo"`
+	FromSubAccount string `json:"fromSubAccount"`
+	ToSubAccount   string `json:"toSubAccount"`
+}
+
+func (s *Server) handleCreateTransfer(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	idemKey := r.Header.Get("Idempotency-Key")
+	if idemKey == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Idempotency-Key required"})
+		return
+	}
+
+	var req TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		return
+	}
+	fromSub, err := normalizeSubAccount(req.FromSubAccount)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	toSub, err := normalizeSubAccount(req.ToSubAccount)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	currency, err := s.fundingAsset(req.Currency)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	amount, err := s.fundingAmount(currency, req.Amount)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	memo := strings.TrimSpace(req.Memo)
+	if len(memo) > maxTransferMemoLength {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("memo longer than %d characters", maxTransferMemoLength)})
+		return
+	}
+	recipient, err := s.resolveTransferRecipient(r.Context(), req, apiKey)
+	if errors.Is(err, errUnknownRecipient) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if toSub != "" && recipient != apiKey {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "toSubAccount must be one of your own sub-accounts"})
+		return
+	}
+	if subAccountWalletID(apiKey, fromSub) == subAccountWalletID(recipient, toSub) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cannot transfer to the same account"})
+		return
+	}
+
+	record, err := s.transferBalance(r.Context(), TransferRecord{
+		FromUserID:     apiKey,
+		FromSubAccount: fromSub,
+		ToUserID:       recipient,
+		ToSubAccount:   toSub,
+		Currency:       currency,
+		Amount:         amount,
+		Memo:           memo,
+		IdemKey:        idemKey,
+	})
+	if errors.Is(err, errTransferKeyReused) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeReserveError(w, err)
+		return
+	}
+	record.Direction = record.direction(apiKey)
+	writeJSON(w, http.StatusOK, record)
+}
+
+// resolveTransferRecipient returns the user ID a transfer is addressed to:
+// a signed-up user by ID or email, an API key principal by ID, or the
+// caller when neither is given.
+func (s *Server) resolveTransferRecipient(ctx context.Context, req TransferRequest, caller string) (string, error) {
+	userID := strings.TrimSpace(req.ToUserID)
+	email := normalizeEmail(req.ToEmail)
+	switch {
+	case userID != "" && email != "":
+		return "", fmt.Errorf("at most one of toUserId and toEmail allowed")
+	case email != "":
+		user, ok := s.getUserByEmail(ctx, email)
+		if !ok {
+			return "", errUnknownRecipient
+		}
+		return user.UserID, nil
+	case userID == "" || userID == caller:
+		return caller, nil
+	}
+	if _, ok := s.cfg.APISecrets[userID]; ok {
+		return userID, nil
+	}
+	if _, ok := s.getUserByID(ctx, userID); !ok {
+		return "", errUnknownRecipient
+	}
+	return userID, nil
+}
+
+// transferBalance debits the sender's account and credits the recipient's
+// in one wallet tx, under the sender's daily limit for the currency; moves
+// between one user's own accounts are not counted against it. A transfer
+// the sender already made with the same Idempotency-Key is returned as it
+// was, and a different one under that key is refused with
+// errTransferKeyReused. The sender's main wallet is always locked, so its
+// transfers check the key and the limit one at a time; the unique
+// (from_user_id, idem_key) index on web_transfers catches a gateway that
+// lost the race to another one.
+func (s *Server) transferBalance(ctx context.Context, record TransferRecord) (TransferRecord, error) {
+	record.TransferID = "tr_" + uuid.NewString()
+	record.CreatedAt = time.Now().UnixMilli()
+	from := subAccountWalletID(record.FromUserID, record.FromSubAccount)
+	to := subAccountWalletID(record.ToUserID, record.ToSubAccount)
+	// Main accounts start with the demo balances; sub-accounts start empty.
+	s.snapshotWallet(record.FromUserID)
+	if record.ToSubAccount == "" {
+		s.snapshotWallet(record.ToUserID)
+	}
+
+	tx := s.beginWalletTx(walletOpTransfer, record.TransferID, record.FromUserID, from, to)
+	defer tx.rollback()
+	prior, replayed, err := s.transferByIdemKey(ctx, record.FromUserID, record.IdemKey)
+	if err != nil {
+		log.Printf("service=edge-gateway msg=transfer_lookup_failed user_id=%s err=%v", record.FromUserID, err)
+		return TransferRecord{}, errWalletUnavailable
+	}
+	if replayed {
+		return replayTransfer(prior, record)
+	}
+	if limit, ok := s.transferLimits[record.Currency]; ok && record.ToUserID != record.FromUserID {
+		sentToday, err := s.transferredToday(ctx, record.FromUserID, record.Currency, record.CreatedAt)
+		if err != nil {
+			log.Printf("service=edge-gateway msg=transfer_lookup_failed user_id=%s err=%v", record.FromUserID, err)
+			return TransferRecord{}, errWalletUnavailable
+		}
+		if sentToday.Add(record.Amount).Cmp(limit) > 0 {
+			return TransferRecord{}, fmt.Errorf("daily_limit_exceeded")
+		}
+	}
+	sender := tx.balance(from, record.Currency)
+	if sender.Available.Cmp(record.Amount) < 0 {
+		return TransferRecord{}, fmt.Errorf("insufficient_balance")
+	}
+	sender.Available = sender.Available.Sub(record.Amount)
+	tx.set(from, record.Currency, sender)
+	recipient := tx.balance(to, record.Currency)
+	recipient.Available = recipient.Available.Add(record.Amount)
+	tx.set(to, record.Currency, recipient)
+	tx.also(
+		func(ctx context.Context, dbTx *sql.Tx) error { return writeTransfer(ctx, dbTx, record) },
+		func() { s.appendTransferLocked(record) },
+	)
+	if err := tx.commit(ctx); err != nil {
+		if prior, ok, _ := s.transferByIdemKey(context.WithoutCancel(ctx), record.FromUserID, record.IdemKey); ok {
+			return replayTransfer(prior, record)
+		}
+		return TransferRecord{}, err
+	}
+	log.Printf("service=edge-gateway msg=transfer_completed transfer_id=%s from_wallet_id=%s to_wallet_id=%s currency=%s amount=%s",
+		record.TransferID, from, to, record.Currency, record.Amount)
+	return record, nil
+}
+
+// replayTransfer answers a retry with the transfer its key made, as long
+// as it asks for the same one.
+func replayTransfer(prior, retry TransferRecord) (TransferRecord, error) {
+	if prior.ToUserID != retry.ToUserID || prior.FromSubAccount != retry.FromSubAccount ||
+		prior.ToSubAccount != retry.ToSubAccount || prior.Currency != retry.Currency ||
+		prior.Amount.Cmp(retry.Amount) != 0 {
+		return TransferRecord{}, errTransferKeyReused
+	}
+	return prior, nil
+}
+
+// direction is how userID sees the transfer, or "" when it is not theirs.
+func (record TransferRecord) direction(userID string) string {
+	switch {
+	case record.FromUserID == userID && record.ToUserID == userID:
+		return "INTERNAL"
+	case record.FromUserID == userID:
+		return "OUT"
+	case record.ToUserID == userID:
+		return "IN"
+	}
+	return ""
+}
+
+// transferByIdemKey finds the transfer userID made with idemKey: by its
+// unique key in web_transfers, which also finds one another gateway made,
+// or in memory without a database. Callers hold userID's wallet lock.
+func (s *Server) transferByIdemKey(ctx context.Context, userID, idemKey string) (TransferRecord, bool, error) {
+	key := transferKey{userID: userID, idemKey: idemKey}
+	if s.db == nil {
+		s.state.mu.Lock()
+		defer s.state.mu.Unlock()
+		idx, ok := s.state.transferKeys[key]
+		if !ok {
+			return TransferRecord{}, false, nil
+		}
+		return s.state.transfers[idx], true, nil
+	}
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	record := TransferRecord{IdemKey: idemKey}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT transfer_id, from_user_id, from_sub_account, to_user_id, to_sub_account, currency, amount, memo, created_at_ms
+		FROM web_transfers
+		WHERE from_user_id = $1 AND idem_key = $2
+	`, userID, idemKey).Scan(&record.TransferID, &record.FromUserID, &record.FromSubAccount, &record.ToUserID,
+		&record.ToSubAccount, &record.Currency, &record.Amount, &record.Memo, &record.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TransferRecord{}, false, nil
+	}
+	if err != nil {
+		return TransferRecord{}, false, fmt.Errorf("load transfer %s: %w", idemKey, err)
+	}
+	// Another gateway's transfer joins this gateway's history too.
+	s.state.mu.Lock()
+	if _, ok := s.state.transferKeys[key]; !ok {
+		s.appendTransferLocked(record)
+	}
+	s.state.mu.Unlock()
+	return record, true, nil
+}
+
+// transferredToday sums what userID sent other users of currency since UTC
+// midnight: from web_transfers, or from memory without a database.
+// Callers hold userID's wallet lock.
+func (s *Server) transferredToday(ctx context.Context, userID, currency string, nowMs int64) (Decimal, error) {
+	dayStart := time.UnixMilli(nowMs).UTC().Truncate(24 * time.Hour).UnixMilli()
+	total := s.assetAmount(currency, 0)
+	if s.db == nil {
+		s.state.mu.Lock()
+		defer s.state.mu.Unlock()
+		for _, idx := range s.state.transfersByUser[userID] {
+			record := s.state.transfers[idx]
+			if record.FromUserID == userID && record.ToUserID != userID && record.Currency == currency && record.CreatedAt >= dayStart {
+				total = total.Add(record.Amount)
+			}
+		}
+		return total, nil
+	}
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	var sent Decimal
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM web_transfers
+		WHERE from_user_id = $1 AND to_user_id <> $1 AND currency = $2 AND created_at_ms >= $3
+	`, userID, currency, dayStart).Scan(&sent)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("sum transfers of %s: %w", userID, err)
+	}
+	return total.Add(sent), nil
+}
+
+// appendTransferLocked adds a transfer to the history and its indexes.
+// Callers hold s.state.mu.
+func (s *Server) appendTransferLocked(record TransferRecord) {
+	idx := len(s.state.transfers)
+	s.state.transfers = append(s.state.transfers, record)
+	s.state.transfersByUser[record.FromUserID] = append(s.state.transfersByUser[record.FromUserID], idx)
+	if record.ToUserID != record.FromUserID {
+		s.state.transfersByUser[record.ToUserID] = append(s.state.transfersByUser[record.ToUserID], idx)
+	}
+	if record.IdemKey != "" {
+		s.state.transferKeys[transferKey{userID: record.FromUserID, idemKey: record.IdemKey}] = idx
+	}
+}
+
+func (s *Server) handleListTransfers(w http.ResponseWriter, r *http.Request) {
+	apiKey := s.apiKeyFromContext(r.Context())
+	if apiKey == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+		return
+	}
+	currency := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("currency")))
+	s.state.mu.Lock()
+	out := make([]TransferRecord, 0, 8)
+	for _, idx := range s.state.transfersByUser[apiKey] {
+		record := s.state.transfers[idx]
+		if currency != "" && record.Currency != currency {
+			continue
+		}
+		record.Direction = record.direction(apiKey)
+		out = append(out, record)
+	}
+	s.state.mu.Unlock()
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt < out[j].CreatedAt })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"transfers": out})
+}
+
+func (s *Server) initTransferSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_transfers (
+			transfer_id TEXT PRIMARY KEY,
+			from_user_id TEXT NOT NULL,
+			to_user_id TEXT NOT NULL,
+			currency TEXT NOT NULL,
+			amount NUMERIC NOT NULL,
+			memo TEXT NOT NULL DEFAULT '',
+			created_at_ms BIGINT NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init transfer schema: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `ALTER TABLE web_transfers ADD COLUMN IF NOT EXISTS idem_key TEXT`)
+	if err != nil {
+		return fmt.Errorf("migrate transfer schema: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		ALTER TABLE web_transfers
+			ADD COLUMN IF NOT EXISTS from_sub_account TEXT NOT NULL DEFAULT '',
+			ADD COLUMN IF NOT EXISTS to_sub_account TEXT NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		return fmt.Errorf("migrate transfer sub-accounts: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS web_transfers_idem_key_idx ON web_transfers (from_user_id, idem_key)`)
+	if err != nil {
+		return fmt.Errorf("init transfer idempotency index: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS web_transfers_daily_idx ON web_transfers (from_user_id, currency, created_at_ms)`)
+	if err != nil {
+		return fmt.Errorf("init transfer daily index: %w", err)
+	}
+	return nil
+}
+
+func writeTransfer(ctx context.Context, dbTx *sql.Tx, record TransferRecord) error {
+	_, err := dbTx.ExecContext(
+		ctx,
+		`INSERT INTO web_transfers(transfer_id, from_user_id, to_user_id, currency, amount, memo, created_at_ms, idem_key,
+		 from_sub_account, to_sub_account)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		record.TransferID,
+		record.FromUserID,
+		record.ToUserID,
+		record.Currency,
+		record.Amount,
+		record.Memo,
+		record.CreatedAt,
+		record.IdemKey,
+		record.FromSubAccount,
+		record.ToSubAccount,
+	)
+	if err != nil {
+		return fmt.Errorf("write transfer %s: %w", record.TransferID, err)
+	}
+	return nil
+}
+
+func (s *Server) loadTransfers(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT transfer_id, from_user_id, to_user_id, currency, amount, memo, created_at_ms, COALESCE(idem_key, ''),
+			from_sub_account, to_sub_account
+		FROM web_transfers
+		ORDER BY created_at_ms, transfer_id
+	`)
+	if err != nil {
+		return fmt.Errorf("load transfers: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var record TransferRecord
+		if err := rows.Scan(&record.TransferID, &record.FromUserID, &record.ToUserID, &record.Currency,
+			&record.Amount, &record.Memo, &record.CreatedAt, &record.IdemKey, &record.FromSubAccount, &record.ToSubAccount); err != nil {
+			return fmt.Errorf("scan transfer: %w", err)
+		}
+		s.appendTransferLocked(record)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,245 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestTransferMovesAvailableBalanceBetweenUsers(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/auth/signup",
+		bytes.NewReader([]byte(`{"email":"otc-client@example.com","password":"password1234"}`))))
+	var signup AuthSessionResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &signup) != nil {
+		t.Fatalf("signup failed: %d body=%s", w.Code, w.Body.String())
+	}
+	recipient := signup.User.UserID
+	s.transferLimits = map[string]Decimal{"KRW": decimalFromInt(1_500_000)}
+
+	transfer := func(body, idemKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/account/transfers", []byte(body), idemKey))
+		return w
+	}
+
+	w = transfer(`{"toEmail":"OTC-Client@example.com","currency":"krw","amount":"1000000","memo":"deal 42"}`, "tr-1")
+	var sent TransferRecord
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &sent) != nil || sent.ToUserID != recipient || sent.Direction != "OUT" {
+		t.Fatalf("transfer by email failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if replay := transfer(`{"toEmail":"otc-client@example.com","currency":"KRW","amount":"1000000"}`, "tr-1"); replay.Body.String() != w.Body.String() {
+		t.Fatalf("expected the replay answered from the first transfer, got %s", replay.Body.String())
+	}
+	for _, body := range []string{
+		`{"toEmail":"otc-client@example.com","currency":"KRW","amount":"999999"}`,
+		`{"toEmail":"otc-client@example.com","currency":"BTC","amount":"1000000"}`,
+		`{"toUserId":"test-key","toSubAccount":"otc","currency":"KRW","amount":"1000000"}`,
+	} {
+		if w := transfer(body, "tr-1"); w.Code != http.StatusConflict {
+			t.Fatalf("expected a reused key refused, got %d body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	for _, tc := range []struct {
+		name, body string
+		code       int
+	}{
+		{name: "over daily limit", body: `{"toUserId":"` + recipient + `","currency":"KRW","amount":"600000"}`, code: http.StatusBadRequest},
+		{name: "insufficient", body: `{"toUserId":"` + recipient + `","currency":"BTC","amount":"3"}`, code: http.StatusBadRequest},
+		{name: "self", body: `{"toUserId":"test-key","currency":"KRW","amount":"1"}`, code: http.StatusBadRequest},
+		{name: "unknown recipient", body: `{"toUserId":"nobody","currency":"KRW","amount":"1"}`, code: http.StatusNotFound},
+		{name: "two recipients", body: `{"toUserId":"` + recipient + `","toEmail":"otc-client@example.com","currency":"KRW","amount":"1"}`, code: http.StatusBadRequest},
+		{name: "excess decimals", body: `{"toUserId":"` + recipient + `","currency":"KRW","amount":"1.5"}`, code: http.StatusBadRequest},
+		{name: "another user's sub-account", body: `{"toUserId":"` + recipient + `","toSubAccount":"otc","currency":"KRW","amount":"1"}`, code: http.StatusBadRequest},
+	} {
+		if w := transfer(tc.body, "tr-"+tc.name); w.Code != tc.code {
+			t.Fatalf("%s: expected %d, got %d body=%s", tc.name, tc.code, w.Code, w.Body.String())
+		}
+	}
+	if w := transfer(`{"toUserId":"`+recipient+`","currency":"BTC","amount":"0.25"}`, "tr-btc"); w.Code != http.StatusOK {
+		t.Fatalf("transfer by user ID failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	sender := s.snapshotWallet("test-key")
+	received := s.snapshotWallet(recipient)
+	if sender["KRW"].Available.String() != "49000000" || sender["BTC"].Available.String() != "1.75000000" ||
+		received["KRW"].Available.String() != "51000000" || received["BTC"].Available.String() != "2.25000000" {
+		t.Fatalf("unexpected balances: sender %+v recipient %+v", sender, received)
+	}
+
+	s.state.mu.Lock()
+	history := append([]TransferRecord(nil), s.state.transfers...)
+	s.state.mu.Unlock()
+	if len(history) != 2 || history[0].Memo != "deal 42" {
+		t.Fatalf("expected two transfers recorded, got %+v", history)
+	}
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/transfers?currency=BTC", nil, ""))
+	var out struct {
+		Transfers []TransferRecord `json:"transfers"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &out) != nil || len(out.Transfers) != 1 || out.Transfers[0].Direction != "OUT" {
+		t.Fatalf("unexpected sender history: %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = adminRequest(t, s, http.MethodGet, "/v1/admin/ledger/check", "")
+	var check struct {
+		OK bool `json:"ok"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &check) != nil || !check.OK {
+		t.Fatalf("expected the ledger consistent, got %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestConcurrentTransfersWithOneIdempotencyKeyMoveTheBalanceOnce(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/auth/signup",
+		bytes.NewReader([]byte(`{"email":"otc-desk@example.com","password":"password1234"}`))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("signup failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	const attempts = 8
+	bodies := make([]string, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/account/transfers",
+				[]byte(`{"toEmail":"otc-desk@example.com","currency":"KRW","amount":"1000"}`), "tr-once"))
+			if w.Code != http.StatusOK {
+				t.Errorf("transfer %d failed: %d body=%s", i, w.Code, w.Body.String())
+			}
+			bodies[i] = w.Body.String()
+		}(i)
+	}
+	wg.Wait()
+	for _, body := range bodies[1:] {
+		if body != bodies[0] {
+			t.Fatalf("expected every attempt answered with the one transfer, got %s and %s", bodies[0], body)
+		}
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Available.String() != "49999000" {
+		t.Fatalf("expected 1000 KRW sent once, got %+v", krw)
+	}
+	s.state.mu.Lock()
+	recorded := len(s.state.transfers)
+	s.state.mu.Unlock()
+	if recorded != 1 {
+		t.Fatalf("expected one transfer recorded, got %d", recorded)
+	}
+}
+
+func TestTransfersMoveBalancesBetweenSubAccounts(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/auth/signup",
+		bytes.NewReader([]byte(`{"email":"sub-client@example.com","password":"password1234"}`))))
+	var signup AuthSessionResponse
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &signup) != nil {
+		t.Fatalf("signup failed: %d body=%s", w.Code, w.Body.String())
+	}
+	recipient := signup.User.UserID
+	s.transferLimits = map[string]Decimal{"KRW": decimalFromInt(500)}
+
+	transfer := func(body, idemKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/account/transfers", []byte(body), idemKey))
+		return w
+	}
+
+	// Moves between one's own accounts are not held to the daily limit.
+	w = transfer(`{"toSubAccount":"OTC","currency":"KRW","amount":"10000"}`, "sub-1")
+	var moved TransferRecord
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &moved) != nil ||
+		moved.ToUserID != "test-key" || moved.ToSubAccount != "otc" || moved.Direction != "INTERNAL" {
+		t.Fatalf("move into the sub-account failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if w := transfer(`{"fromSubAccount":"otc","currency":"KRW","amount":"2000"}`, "sub-2"); w.Code != http.StatusOK {
+		t.Fatalf("move back to the main account failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if w := transfer(`{"toEmail":"sub-client@example.com","fromSubAccount":"otc","currency":"KRW","amount":"300"}`, "sub-3"); w.Code != http.StatusOK {
+		t.Fatalf("transfer out of the sub-account failed: %d body=%s", w.Code, w.Body.String())
+	}
+
+	for _, tc := range []struct {
+		name, body string
+	}{
+		{name: "over daily limit", body: `{"toUserId":"` + recipient + `","fromSubAccount":"otc","currency":"KRW","amount":"201"}`},
+		{name: "insufficient", body: `{"fromSubAccount":"otc","toSubAccount":"desk","currency":"KRW","amount":"7701"}`},
+		{name: "same account", body: `{"fromSubAccount":"otc","toSubAccount":"OTC","currency":"KRW","amount":"1"}`},
+		{name: "invalid name", body: `{"toSubAccount":"otc desk","currency":"KRW","amount":"1"}`},
+		{name: "no demo balance", body: `{"fromSubAccount":"desk","currency":"BTC","amount":"1"}`},
+	} {
+		if w := transfer(tc.body, "sub-"+tc.name); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body=%s", tc.name, w.Code, w.Body.String())
+		}
+	}
+
+	main := s.snapshotWallet("test-key")
+	if main["KRW"].Available.String() != "49992000" {
+		t.Fatalf("unexpected main account KRW: %+v", main["KRW"])
+	}
+	if received := s.snapshotWallet(recipient)["KRW"]; received.Available.String() != "50000300" {
+		t.Fatalf("unexpected recipient KRW: %+v", received)
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/sub-accounts", nil, ""))
+	var listed struct {
+		SubAccounts []SubAccountView `json:"subAccounts"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &listed) != nil || len(listed.SubAccounts) != 1 ||
+		listed.SubAccounts[0].Name != "otc" || len(listed.SubAccounts[0].Balances) != 1 ||
+		listed.SubAccounts[0].Balances[0].Available.String() != "7700" {
+		t.Fatalf("unexpected sub-accounts: %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/transfers", nil, ""))
+	var history struct {
+		Transfers []TransferRecord `json:"transfers"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &history) != nil || len(history.Transfers) != 3 ||
+		history.Transfers[1].Direction != "INTERNAL" || history.Transfers[2].Direction != "OUT" {
+		t.Fatalf("unexpected history: %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodGet, "/v1/account/ledger?subAccount=otc", nil, ""))
+	var ledger struct {
+		Entries []LedgerEntryView `json:"entries"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &ledger) != nil || len(ledger.Entries) != 3 {
+		t.Fatalf("unexpected sub-account ledger: %d body=%s", w.Code, w.Body.String())
+	}
+	for _, entry := range ledger.Entries {
+		for _, posting := range entry.Postings {
+			if posting.AccountID != "user:test-key/otc:KRW:AVAILABLE" {
+				t.Fatalf("expected only the sub-account's postings, got %+v", posting)
+			}
+		}
+	}
+
+	w = adminRequest(t, s, http.MethodGet, "/v1/admin/ledger/check", "")
+	var check struct {
+		OK bool `json:"ok"`
+	}
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &check) != nil || !check.OK {
+		t.Fatalf("expected the ledger consistent, got %d body=%s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,435 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	exchangev1 "github.com/quanta-exchange/exchange-platform/contracts/gen/go/exchange/v1"
+)
+
+// Journal operations; the reference is the order ID for reserves and
+// releases, the trade ID for settlements and the user ID for opening
+// balances.
+const (
+	walletOpOpen    = "OPEN"
+	walletOpReserve = "RESERVE"
+	walletOpRelease = "RELEASE"
+	walletOpSettle  = "SETTLE"
+)
+
+// errWalletUnavailable is returned when a wallet change could not be
+// committed to Postgres; the in-memory balances are left untouched.
+var errWalletUnavailable = errors.New("wallet_unavailable")
+
+type walletKey struct {
+	userID   string
+	currency string
+}
+
+type walletChange struct {
+	userID   string
+	currency string
+	before   walletBalance
+	after    walletBalance
+}
+
+// walletTx stages the balance changes of one wallet operation. A tx locks
+// the wallets it touches from begin to commit, so the balances it reads
+// cannot move underneath it, while operations on other wallets go ahead.
+// Postgres is written first, in one transaction with a journal row per
+// change and the ledger entry of the operation, and memory follows only
+// once that commit succeeded.
+type walletTx struct {
+	s       *Server
+	op      string
+	refID   string
+	wallets []string
+	changes []walletChange
+	index   map[walletKey]int
+	records []walletRecord
+	// external is what a trade side not settled through this gateway
+	// moves, per currency; the counterparty account books it.
+	external map[string]Decimal
+	// err is a balance the tx could not read; commit refuses to write.
+	err  error
+	done bool
+}
+
+// walletRecord is a row that commits or fails with the balances, such as
+// a withdrawal's status, and the in-memory update that goes with it.
+type walletRecord struct {
+	write       func(context.Context, *sql.Tx) error
+	applyLocked func()
+}
+
+// walletLocks serializes wallet txs per wallet. A wallet's lock exists
+// while a tx holds or waits for it.
+type walletLocks struct {
+	mu    sync.Mutex
+	locks map[string]*walletLock
+}
+
+type walletLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock takes the locks of walletIDs, which are sorted, so txs over
+// overlapping wallets take them in the same order.
+func (l *walletLocks) lock(walletIDs []string) {
+	for _, walletID := range walletIDs {
+		l.mu.Lock()
+		if l.locks == nil {
+			l.locks = map[string]*walletLock{}
+		}
+		entry := l.locks[walletID]
+		if entry == nil {
+			entry = &walletLock{}
+			l.locks[walletID] = entry
+		}
+		entry.refs++
+		l.mu.Unlock()
+		entry.mu.Lock()
+	}
+}
+
+func (l *walletLocks) unlock(walletIDs []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, walletID := range walletIDs {
+		entry := l.locks[walletID]
+		entry.mu.Unlock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, walletID)
+		}
+	}
+}
+
+// beginWalletTx locks the wallets the operation can change, and holds
+// walletMu shared so the ledger check, which takes it exclusively, sees
+// no tx halfway.
+func (s *Server) beginWalletTx(op, refID string, walletIDs ...string) *walletTx {
+	seen := map[string]bool{}
+	wallets := make([]string, 0, len(walletIDs))
+	for _, walletID := range walletIDs {
+		if walletID != "" && !seen[walletID] {
+			seen[walletID] = true
+			wallets = append(wallets, walletID)
+		}
+	}
+	sort.Strings(wallets)
+	s.walletMu.RLock()
+	s.walletLocks.lock(wallets)
+	return &walletTx{s: s, op: op, refID: refID, wallets: wallets, index: map[walletKey]int{}}
+}
+
+// balance is the staged balance, or the committed one. A wallet not in
+// memory yet, such as a trade's counterparty or the fee account after a
+// restart, is read from its stored rows; when that read fails, or the
+// wallet is not one the tx locked, the tx can only roll back. A currency
+// the user has never held starts at zero.
+func (tx *walletTx) balance(userID, currency string) walletBalance {
+	if i, ok := tx.index[walletKey{userID, currency}]; ok {
+		return tx.changes[i].after
+	}
+	var wallet map[string]walletBalance
+	err := fmt.Errorf("not locked by %s %s", tx.op, tx.refID)
+	if i := sort.SearchStrings(tx.wallets, userID); i < len(tx.wallets) && tx.wallets[i] == userID {
+		wallet, err = tx.s.storedWallet(userID)
+	}
+	if err != nil && tx.err == nil {
+		tx.err = fmt.Errorf("load wallet %s: %w", userID, err)
+	}
+	bal, ok := wallet[currency]
+	if !ok {
+		zero := tx.s.assetAmount(currency, 0)
+		bal = walletBalance{Available: zero, Hold: zero}
+	}
+	return bal
+}
+
+// storedWallet is the user's wallet in memory, or else its rows in
+// Postgres, which are kept in memory from then on.
+func (s *Server) storedWallet(userID string) (map[string]walletBalance, error) {
+	s.state.mu.Lock()
+	wallet, ok := s.state.wallets[userID]
+	if ok || s.db == nil {
+		wallet = cloneWallet(wallet)
+		s.state.mu.Unlock()
+		return wallet, nil
+	}
+	s.state.mu.Unlock()
+	ctx, cancel := s.dbContext(context.Background())
+	defer cancel()
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT currency, available, hold FROM web_wallet_balances WHERE user_id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stored := s.scanWalletRows(rows)
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		return stored, nil
+	}
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if current, loaded := s.state.wallets[userID]; loaded {
+		return cloneWallet(current), nil
+	}
+	s.state.wallets[userID] = cloneWallet(stored)
+	return stored, nil
+}
+
+// addExternal stages delta of currency for the counterparty account, the
+// side of a trade this gateway does not settle. Only that much is booked
+// there: the entry must balance with it like any other.
+func (tx *walletTx) addExternal(currency string, delta Decimal) {
+	if tx.external == nil {
+		tx.external = map[string]Decimal{}
+	}
+	if current, ok := tx.external[currency]; ok {
+		delta = current.Add(delta)
+	}
+	tx.external[currency] = delta
+}
+
+func (tx *walletTx) set(userID, currency string, bal walletBalance) {
+	key := walletKey{userID, currency}
+	if i, ok := tx.index[key]; ok {
+		tx.changes[i].after = bal
+		return
+	}
+	before := tx.balance(userID, currency)
+	tx.index[key] = len(tx.changes)
+	tx.changes = append(tx.changes, walletChange{userID: userID, currency: currency, before: before, after: bal})
+}
+
+// also adds a record to the tx: write runs in its Postgres transaction and
+// applyLocked, under s.state.mu, once that committed.
+func (tx *walletTx) also(write func(context.Context, *sql.Tx) error, applyLocked func()) {
+	tx.records = append(tx.records, walletRecord{write: write, applyLocked: applyLocked})
+}
+
+// commit persists the staged changes and applies them to memory. It ends
+// the tx either way.
+func (tx *walletTx) commit(ctx context.Context) error {
+	if tx.done {
+		return nil
+	}
+	tx.done = true
+	defer tx.unlock()
+	if len(tx.changes) == 0 && len(tx.external) == 0 && len(tx.records) == 0 {
+		return nil
+	}
+	var entry *exchangev1.LedgerEntryAppended
+	err := tx.err
+	if err == nil {
+		err = tx.openLedgers(ctx)
+	}
+	if err == nil {
+		entry, err = tx.s.buildLedgerEntry(tx.op, tx.refID, tx.external, tx.changes)
+	}
+	if err == nil {
+		err = tx.s.persistWalletTx(ctx, tx, entry)
+	}
+	if err != nil {
+		log.Printf(
+			"service=edge-gateway msg=wallet_commit_failed op=%s ref_id=%s changes=%d err=%v",
+			tx.op, tx.refID, len(tx.changes), err,
+		)
+		return errWalletUnavailable
+	}
+	tx.s.state.mu.Lock()
+	for _, change := range tx.changes {
+		wallet := tx.s.state.wallets[change.userID]
+		if wallet == nil {
+			wallet = map[string]walletBalance{}
+			tx.s.state.wallets[change.userID] = wallet
+		}
+		wallet[change.currency] = change.after
+	}
+	if entry != nil {
+		tx.s.appendLedgerEntryLocked(entry)
+	}
+	for _, record := range tx.records {
+		record.applyLocked()
+	}
+	tx.s.state.mu.Unlock()
+	return nil
+}
+
+// openLedgers opens the ledger of every wallet the tx touches, so opening
+// entries record the balances from before the change.
+func (tx *walletTx) openLedgers(ctx context.Context) error {
+	for _, change := range tx.changes {
+		if err := tx.s.openLedgerLocked(ctx, change.userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback drops the staged changes. It is a no-op after commit, so it can
+// be deferred.
+func (tx *walletTx) rollback() {
+	if tx.done {
+		return
+	}
+	tx.done = true
+	tx.unlock()
+}
+
+func (tx *walletTx) unlock() {
+	tx.s.walletLocks.unlock(tx.wallets)
+	tx.s.walletMu.RUnlock()
+}
+
+// writeReserveError answers a failed reserve: 503 when the wallet could not
+// be written, 400 for everything the caller can fix.
+func writeReserveError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errWalletUnavailable) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) initWalletJournalSchema(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS web_wallet_journal (
+			journal_id BIGSERIAL PRIMARY KEY,
+			op TEXT NOT NULL,
+			ref_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			currency TEXT NOT NULL,
+			available_before NUMERIC NOT NULL,
+			hold_before NUMERIC NOT NULL,
+			available_after NUMERIC NOT NULL,
+			hold_after NUMERIC NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("init wallet journal schema: %w", err)
+	}
+	// The journal is append-only: updates and deletes fail.
+	_, err = s.db.ExecContext(ctx, `
+		CREATE OR REPLACE FUNCTION web_wallet_journal_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'web_wallet_journal is append-only';
+		END;
+		$$ LANGUAGE plpgsql
+	`)
+	if err != nil {
+		return fmt.Errorf("init wallet journal guard: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'web_wallet_journal_append_only') THEN
+				CREATE TRIGGER web_wallet_journal_append_only
+				BEFORE UPDATE OR DELETE ON web_wallet_journal
+				FOR EACH ROW EXECUTE FUNCTION web_wallet_journal_append_only();
+			END IF;
+		END
+		$$
+	`)
+	if err != nil {
+		return fmt.Errorf("init wallet journal trigger: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS web_wallet_journal_user_idx ON web_wallet_journal (user_id, journal_id)
+	`)
+	if err != nil {
+		return fmt.Errorf("init wallet journal index: %w", err)
+	}
+	return nil
+}
+
+// persistWalletTx writes the balances, their journal rows, the ledger entry
+// and the tx's records in one transaction. Without a database configured
+// wallets live in memory only.
+func (s *Server) persistWalletTx(ctx context.Context, tx *walletTx, entry *exchangev1.LedgerEntryAppended) error {
+	ctx, cancel := s.dbContext(ctx)
+	defer cancel()
+	if s.db == nil {
+		if entry != nil {
+			s.assignLedgerSeq(entry)
+		}
+		return nil
+	}
+	dbTx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin wallet tx: %w", err)
+	}
+	defer func() { _ = dbTx.Rollback() }()
+	for _, change := range tx.changes {
+		if err := writeWalletChange(ctx, dbTx, tx.op, tx.refID, change); err != nil {
+			return err
+		}
+	}
+	if entry != nil {
+		if err := writeLedgerEntry(ctx, dbTx, entry); err != nil {
+			return err
+		}
+	}
+	for _, record := range tx.records {
+		if err := record.write(ctx, dbTx); err != nil {
+			return err
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
+		return fmt.Errorf("commit wallet tx: %w", err)
+	}
+	return nil
+}
+
+func writeWalletChange(ctx context.Context, dbTx *sql.Tx, op, refID string, change walletChange) error {
+	currency := strings.ToUpper(change.currency)
+	_, err := dbTx.ExecContext(
+		ctx,
+		`INSERT INTO web_wallet_balances(user_id, currency, available, hold) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (user_id, currency) DO UPDATE SET
+		 available = EXCLUDED.available,
+		 hold = EXCLUDED.hold,
+		 updated_at = now()`,
+		change.userID,
+		currency,
+		change.after.Available,
+		change.after.Hold,
+	)
+	if err != nil {
+		return fmt.Errorf("write balance %s/%s: %w", change.userID, currency, err)
+	}
+	_, err = dbTx.ExecContext(
+		ctx,
+		`INSERT INTO web_wallet_journal(op, ref_id, user_id, currency, available_before, hold_before, available_after, hold_after)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		op,
+		refID,
+		change.userID,
+		currency,
+		change.before.Available,
+		change.before.Hold,
+		change.after.Available,
+		change.after.Hold,
+	)
+	if err != nil {
+		return fmt.Errorf("journal balance %s/%s: %w", change.userID, currency, err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWalletChangesAreNotAppliedWhenTheStoreIsDown(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	before := s.snapshotWallet("test-key")
+	down, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=none dbname=none sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer down.Close()
+	s.db = down
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`), "journal-down"))
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "wallet_unavailable") {
+		t.Fatalf("expected wallet_unavailable, got %d body=%s", w.Code, w.Body.String())
+	}
+	if got := s.snapshotWallet("test-key"); !sameBalance(got["KRW"], before["KRW"]) {
+		t.Fatalf("expected KRW untouched, got %+v want %+v", got["KRW"], before["KRW"])
+	}
+	s.state.mu.Lock()
+	_, recorded := s.state.orders["ord_journal-down"]
+	s.state.mu.Unlock()
+	if recorded {
+		t.Fatalf("expected no order recorded")
+	}
+
+	trade := []byte(`{"tradeId":"journal-t1","symbol":"BTC-KRW","seq":100,"makerOrderId":"ord_a","takerOrderId":"ord_b","buyerUserId":"test-key","sellerUserId":"journal-seller","price":100,"quantity":1}`)
+	if err := s.consumeTradeMessage(context.Background(), trade); !errors.Is(err, errWalletUnavailable) {
+		t.Fatalf("expected settlement to fail, got %v", err)
+	}
+	if got := s.snapshotWallet("test-key"); !sameBalance(got["BTC"], before["BTC"]) || !sameBalance(got["KRW"], before["KRW"]) {
+		t.Fatalf("expected balances untouched, got %+v", got)
+	}
+
+	// Once the store is back the same trade settles in full.
+	s.db = nil
+	if err := s.consumeTradeMessage(context.Background(), trade); err != nil {
+		t.Fatalf("retry trade: %v", err)
+	}
+	got := s.snapshotWallet("test-key")
+	if got["BTC"].Available.String() != "3.00000000" || got["KRW"].Available.String() != "49999900" {
+		t.Fatalf("expected the retried trade settled, got %+v", got)
+	}
+}
+
+func TestFailedReleasesKeepTheReserveOnTheOrderAndRetry(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders",
+		[]byte(`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1"}`), "release-down"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create order failed: %d body=%s", w.Code, w.Body.String())
+	}
+	down, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=none dbname=none sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer down.Close()
+	s.db = down
+
+	w = httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodDelete, "/v1/orders/ord_release-down", nil, "release-down-cancel"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("cancel failed: %d body=%s", w.Code, w.Body.String())
+	}
+	s.state.mu.Lock()
+	record := s.state.orders["ord_release-down"]
+	s.state.mu.Unlock()
+	if record.Status != "CANCELED" || record.ReserveAmount.String() != "100" {
+		t.Fatalf("expected the reserve kept on the canceled order, got %+v", record)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "100" {
+		t.Fatalf("expected the hold kept until the release commits, got %+v", krw)
+	}
+	s.state.mu.Lock()
+	queued := s.state.orderReleaseRetries["ord_release-down"]
+	s.state.mu.Unlock()
+	if !queued {
+		t.Fatalf("expected the failed release queued for retry")
+	}
+
+	// Still down: the retry fails the same way and stays queued.
+	s.retryReserveReleases(context.Background())
+	if krw := s.snapshotWallet("test-key")["KRW"]; krw.Hold.String() != "100" {
+		t.Fatalf("expected the hold kept while the store is down, got %+v", krw)
+	}
+
+	s.db = nil
+	s.retryReserveReleases(context.Background())
+	s.state.mu.Lock()
+	record = s.state.orders["ord_release-down"]
+	s.state.mu.Unlock()
+	if !record.ReserveAmount.IsZero() {
+		t.Fatalf("expected the reserve detached once released, got %+v", record)
+	}
+	if krw := s.snapshotWallet("test-key")["KRW"]; !krw.Hold.IsZero() || krw.Available.String() != "50000000" {
+		t.Fatalf("expected the hold released on retry, got %+v", krw)
+	}
+	s.state.mu.Lock()
+	left := len(s.state.orderReleaseRetries) + len(s.state.releaseRetries)
+	s.state.mu.Unlock()
+	if left != 0 {
+		t.Fatalf("expected the retry queue drained, %d left", left)
+	}
+}
+
+// sameBalance compares amounts; Decimals are not comparable with ==.
+func sameBalance(a, b walletBalance) bool {
+	return a.Available.Cmp(b.Available) == 0 && a.Hold.Cmp(b.Hold) == 0
+}
+
+func TestWalletTxsOnlyWaitForTheWalletsTheyShare(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	s.snapshotWallet("lock-a")
+
+	held := s.beginWalletTx(walletOpReserve, "lock-held", "lock-a")
+	done := func(run func()) chan struct{} {
+		ch := make(chan struct{})
+		go func() {
+			run()
+			close(ch)
+		}()
+		return ch
+	}
+
+	// Another wallet goes ahead while lock-a is held.
+	other := done(func() {
+		if _, err := s.applyReserve("test-key", "KRW", decimalFromInt(100), "lock-other"); err != nil {
+			t.Errorf("reserve on another wallet: %v", err)
+		}
+	})
+	select {
+	case <-other:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected a tx on another wallet not to wait")
+	}
+
+	// A tx on lock-a, and the ledger check, wait for it.
+	same := done(func() {
+		if _, err := s.applyReserve("lock-a", "KRW", decimalFromInt(100), "lock-same"); err != nil {
+			t.Errorf("reserve on the held wallet: %v", err)
+		}
+	})
+	check := done(func() {
+		if _, err := s.checkLedgerInvariant(context.Background()); err != nil {
+			t.Errorf("check ledger: %v", err)
+		}
+	})
+	select {
+	case <-same:
+		t.Fatalf("expected a tx on the held wallet to wait")
+	case <-check:
+		t.Fatalf("expected the ledger check to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	held.rollback()
+	for _, ch := range []chan struct{}{same, check} {
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected the waiting txs to go ahead once the wallet is free")
+		}
+	}
+
+	// A tx cannot change a wallet it did not lock.
+	tx := s.beginWalletTx(walletOpRelease, "lock-stray", "lock-a")
+	bal := tx.balance("test-key", "KRW")
+	bal.Available = bal.Available.Add(decimalFromInt(1))
+	tx.set("test-key", "KRW", bal)
+	if err := tx.commit(context.Background()); !errors.Is(err, errWalletUnavailable) {
+		t.Fatalf("expected a change to an unlocked wallet refused, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// privateOrdersChannel streams the authenticated principal's order updates.
+// Its subscriptions carry an optional symbol filter instead of a required
+// symbol.
+const privateOrdersChannel = "orders"
+
+// authenticateWS resolves the principal of a WebSocket AUTH command: either
+// a session token, or an API key signing "GET\n/ws\n{ts}\n" the same way
+// REST requests are signed.
+func (s *Server) authenticateWS(cmd WSCommand) (string, error) {
+	if token := strings.TrimSpace(cmd.Token); token != "" {
+		session, ok := s.getSession(context.Background(), token)
+		if !ok {
+			s.authFail("invalid_session")
+			return "", fmt.Errorf("invalid session")
+		}
+		return session.UserID, nil
+	}
+
+	apiKey := strings.TrimSpace(cmd.APIKey)
+	secret, ok := s.cfg.APISecrets[apiKey]
+	if apiKey == "" || !ok {
+		s.authFail("unknown_key")
+		return "", fmt.Errorf("invalid api key")
+	}
+	now := time.Now().UnixMilli()
+	if abs64(now-cmd.Ts) > s.cfg.TimestampSkew.Milliseconds() {
+		s.authFail("ts_skew")
+		return "", fmt.Errorf("timestamp skew")
+	}
+	canonical := strings.Join([]string{"GET", "/ws", strconv.FormatInt(cmd.Ts, 10), ""}, "\n")
+	if !hmac.Equal([]byte(sign(secret, canonical)), []byte(cmd.Signature)) {
+		s.authFail("bad_signature")
+		return "", fmt.Errorf("invalid signature")
+	}
+	if s.isReplay(apiKey, cmd.Signature, cmd.Ts, now) {
+		s.authFail("replay")
+		return "", fmt.Errorf("replay detected")
+	}
+	return apiKey, nil
+}
+
+// sendOrdersSnapshot answers an orders subscription with the principal's
+// open orders.
+func (s *Server) sendOrdersSnapshot(c *client, sub wsSubscription) {
+	s.sendToClient(c, WSMessage{
+		Type:    "Snapshot",
+		Channel: privateOrdersChannel,
+		Symbol:  sub.symbol,
+		Ts:      time.Now().UnixMilli(),
+		Data:    map[string]interface{}{"orders": s.openOrdersFor(c.principal(), sub.symbol, "")},
+	}, false, "")
+}
+
+// publishOrderUpdate pushes the record to its owner's sockets subscribed to
+// the orders channel. Order updates are never conflated.
+func (s *Server) publishOrderUpdate(record OrderRecord) {
+	if record.OwnerUserID == "" {
+		return
+	}
+	s.state.mu.Lock()
+	clients := make([]*client, 0, len(s.state.clients))
+	for c := range s.state.clients {
+		clients = append(clients, c)
+	}
+	s.state.mu.Unlock()
+
+	msg := WSMessage{
+		Type:    "OrderUpdated",
+		Channel: privateOrdersChannel,
+		Symbol:  record.Symbol,
+		Seq:     record.Seq,
+		Ts:      time.Now().UnixMilli(),
+		Data:    record,
+	}
+	for _, c := range clients {
+		if c.principal() != record.OwnerUserID {
+			continue
+		}
+		if c.wantsOrderUpdate(record.Symbol) {
+			s.sendToClient(c, msg, false, "")
+		}
+	}
+}
+
+func (c *client) principal() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.userID
+}
+
+func (c *client) setPrincipal(userID string) {
+	c.mu.Lock()
+	c.userID = userID
+	c.mu.Unlock()
+}
+
+func (c *client) wantsOrderUpdate(symbol string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, sub := range c.subscribers {
+		if sub.channel == privateOrdersChannel && (sub.symbol == "" || sub.symbol == symbol) {
+			return true
+		}
+	}
+	return false
+}
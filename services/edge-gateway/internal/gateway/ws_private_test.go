@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func readWSMessage(t *testing.T, conn *websocket.Conn, msgType string) WSMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		var msg WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read %s: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestPrivateOrdersChannelRequiresAuthAndStreamsExpiry(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	httpSrv := httptest.NewServer(s.Router())
+	defer httpSrv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpSrv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("websocket dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(map[string]interface{}{"op": "SUB", "channel": "orders"}); err != nil {
+		t.Fatalf("write subscribe: %v", err)
+	}
+	if msg := readWSMessage(t, conn, "Error"); !strings.Contains(msg.Data.(map[string]interface{})["error"].(string), "authentication") {
+		t.Fatalf("expected unauthenticated subscribe to fail, got %+v", msg)
+	}
+
+	ts := time.Now().UnixMilli()
+	if err := conn.WriteJSON(map[string]interface{}{
+		"op":        "AUTH",
+		"apiKey":    "test-key",
+		"ts":        ts,
+		"signature": sign("secret", "GET\n/ws\n"+strconv.FormatInt(ts, 10)+"\n"),
+	}); err != nil {
+		t.Fatalf("write auth: %v", err)
+	}
+	readWSMessage(t, conn, "Authenticated")
+	if err := conn.WriteJSON(map[string]interface{}{"op": "SUB", "channel": "orders"}); err != nil {
+		t.Fatalf("write subscribe: %v", err)
+	}
+	readWSMessage(t, conn, "Snapshot")
+
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	w := httptest.NewRecorder()
+	s.Router().ServeHTTP(w, signedRequest(t, http.MethodPost, "/v1/orders", []byte(
+		`{"symbol":"BTC-KRW","side":"BUY","type":"LIMIT","price":"100","qty":"1","timeInForce":"GTD","expireAt":`+strconv.FormatInt(expireAt, 10)+`}`,
+	), "ws-gtd"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("create GTD failed: %d body=%s", w.Code, w.Body.String())
+	}
+	if msg := readWSMessage(t, conn, "OrderUpdated"); msg.Data.(map[string]interface{})["status"] != "ACCEPTED" {
+		t.Fatalf("expected ACCEPTED update, got %+v", msg)
+	}
+
+	s.expireOrders(context.Background(), expireAt)
+	msg := readWSMessage(t, conn, "OrderUpdated")
+	data := msg.Data.(map[string]interface{})
+	if msg.Channel != "orders" || data["orderId"] != "ord_ws-gtd" || data["status"] != orderStatusExpired {
+		t.Fatalf("expected EXPIRED update, got %+v", msg)
+	}
+}
@@ -23,6 +23,23 @@ struct CoreGrpcService {
     publish_retries: usize,
 }
 
+impl CoreGrpcService {
+    /// Publishes what the last command left in the outbox; every command
+    /// that can emit events calls it before answering.
+    fn publish_pending(&self) -> Result<(), Status> {
+        let core = self
+            .core
+            .lock()
+            .map_err(|_| Status::internal("core lock poisoned"))?;
+        let mut publisher = self
+            .publisher
+            .lock()
+            .map_err(|_| Status::internal("publisher lock poisoned"))?;
+        core.publish_pending(&mut *publisher, self.publish_retries)
+            .map_err(|e| Status::internal(format!("publish pending: {e}")))
+    }
+}
+
 #[tonic::async_trait]
 impl TradingCoreService for CoreGrpcService {
     async fn place_order(
@@ -45,19 +62,7 @@ impl TradingCoreService for CoreGrpcService {
                 .map_err(|e| Status::internal(format!("place order: {e}")))?
         };
 
-        {
-            let core = self
-                .core
-                .lock()
-                .map_err(|_| Status::internal("core lock poisoned"))?;
-            let mut publisher = self
-                .publisher
-                .lock()
-                .map_err(|_| Status::internal("publisher lock poisoned"))?;
-            core.publish_pending(&mut *publisher, self.publish_retries)
-                .map_err(|e| Status::internal(format!("publish pending: {e}")))?;
-        }
-
+        self.publish_pending()?;
         Ok(Response::new(response))
     }
 
@@ -73,6 +78,7 @@ impl TradingCoreService for CoreGrpcService {
             core.cancel_order(request.into_inner())
                 .map_err(|e| Status::internal(format!("cancel order: {e}")))?
         };
+        self.publish_pending()?;
         Ok(Response::new(response))
     }
 
@@ -88,6 +94,7 @@ impl TradingCoreService for CoreGrpcService {
             core.set_symbol_mode(request.into_inner())
                 .map_err(|e| Status::internal(format!("set symbol mode: {e}")))?
         };
+        self.publish_pending()?;
         Ok(Response::new(response))
     }
 
@@ -103,6 +110,7 @@ impl TradingCoreService for CoreGrpcService {
             core.cancel_all(request.into_inner())
                 .map_err(|e| Status::internal(format!("cancel all: {e}")))?
         };
+        self.publish_pending()?;
         Ok(Response::new(response))
     }
 }
@@ -115,6 +123,7 @@ async fn main() -> Result<(), Box<dyn std::error::Error>> {
     let outbox_dir = getenv("CORE_OUTBOX_DIR", "/tmp/trading-core/outbox");
     let kafka_brokers = getenv("CORE_KAFKA_BROKERS", "localhost:29092");
     let kafka_topic = getenv("CORE_KAFKA_TRADE_TOPIC", "core.trade-events.v1");
+    let kafka_order_topic = getenv("CORE_KAFKA_ORDER_TOPIC", "core.order-events.v1");
     let publish_retries = getenv_usize("CORE_PUBLISH_RETRIES", 3);
     let stub_trades = getenv_bool("CORE_STUB_TRADES", false);
 
@@ -131,7 +140,12 @@ async fn main() -> Result<(), Box<dyn std::error::Error>> {
         core.last_state_hash(),
         core.symbol_mode(),
     );
-    let publisher = KafkaTradePublisher::new(&kafka_brokers, &kafka_topic, Duration::from_secs(2))?;
+    let publisher = KafkaTradePublisher::new(
+        &kafka_brokers,
+        &kafka_topic,
+        &kafka_order_topic,
+        Duration::from_secs(2),
+    )?;
 
     let service = CoreGrpcService {
         core: Arc::new(Mutex::new(core)),
@@ -2,10 +2,11 @@ use crate::contracts::exchange::v1 as proto;
 use crate::determinism::state_hash;
 use crate::leader::FencingCoordinator;
 use crate::model::{
-    build_envelope, from_proto_meta, now_timestamp, parse_u64, split_symbol, to_order_type,
-    to_side, to_symbol_mode, to_time_in_force, CancelRejectedEvent, CommandMeta, CoreEvent,
-    EngineCheckpointEvent, EventEnvelope, Order, OrderAcceptedEvent, OrderCanceledEvent,
-    OrderRejectedEvent, OrderType, RejectCode, Side, SymbolMode, TimeInForce, TradeExecutedEvent,
+    build_envelope, fee_amount, from_proto_meta, now_timestamp, parse_fee_bps, parse_u64,
+    split_symbol, to_order_type, to_self_trade_prevention, to_side, to_symbol_mode,
+    to_time_in_force, CancelRejectedEvent, CommandMeta, CoreEvent, EngineCheckpointEvent,
+    EventEnvelope, Order, OrderAcceptedEvent, OrderCanceledEvent, OrderRejectedEvent, OrderType,
+    RejectCode, Side, SymbolMode, TimeInForce, TradeExecutedEvent,
 };
 use crate::orderbook::OrderBook;
 use crate::outbox::{Outbox, OutboxRecord};
@@ -293,6 +294,24 @@ impl TradingCore {
                 return Ok(resp);
             }
         };
+        let stp = match to_self_trade_prevention(req.self_trade_prevention) {
+            Ok(v) => v,
+            Err(code) => {
+                let resp =
+                    self.make_place_reject_response(&req.order_id, &meta.correlation_id, code);
+                self.store_idempotent_place(&meta, &resp);
+                return Ok(resp);
+            }
+        };
+        if req.post_only && (order_type != OrderType::Limit || tif != TimeInForce::Gtc) {
+            let resp = self.make_place_reject_response(
+                &req.order_id,
+                &meta.correlation_id,
+                RejectCode::Validation,
+            );
+            self.store_idempotent_place(&meta, &resp);
+            return Ok(resp);
+        }
         let qty = match parse_u64(&req.quantity) {
             Ok(v) if v > 0 => v,
             _ => {
@@ -321,6 +340,21 @@ impl TradingCore {
         } else {
             None
         };
+        let (maker_fee_e8, taker_fee_e8) = match (
+            parse_fee_bps(&req.maker_fee_bps),
+            parse_fee_bps(&req.taker_fee_bps),
+        ) {
+            (Ok(maker), Ok(taker)) => (maker, taker),
+            _ => {
+                let resp = self.make_place_reject_response(
+                    &req.order_id,
+                    &meta.correlation_id,
+                    RejectCode::Validation,
+                );
+                self.store_idempotent_place(&meta, &resp);
+                return Ok(resp);
+            }
+        };
 
         let mut order = Order {
             order_id: req.order_id.clone(),
@@ -333,6 +367,8 @@ impl TradingCore {
             original_qty: qty,
             remaining_qty: qty,
             accepted_seq: 0,
+            maker_fee_e8,
+            taker_fee_e8,
         };
 
         // G1 minimal path: seed deterministic demo balances for unseen users.
@@ -364,10 +400,36 @@ impl TradingCore {
             self.transition_mode(SymbolMode::CancelOnly, "auto-volatility-guard");
         }
 
+        // Post-only is checked against the book after risk so halts and
+        // balance rejects keep their own codes.
+        if req.post_only && self.order_book.would_cross(&order) {
+            self.risk.release_reservation(&order);
+            let code = RejectCode::PostOnlyWouldTake;
+            let mut events = vec![self.event_order_rejected(
+                &order,
+                &meta,
+                code.clone(),
+                "post-only would take",
+            )];
+            self.append_checkpoint(&meta, &mut events);
+            self.persist_command(&meta.command_id, events)?;
+            let resp = self.make_place_reject_response(&req.order_id, &meta.correlation_id, code);
+            self.store_idempotent_place(&meta, &resp);
+            return Ok(resp);
+        }
+
         order.accepted_seq = self.next_seq();
         let mut events = vec![self.event_order_accepted(&order, &meta)];
 
-        let fills = self.order_book.match_order(&mut order);
+        let outcome = self.order_book.match_order_with_stp(&mut order, stp);
+        let taker_stp_canceled = outcome.taker_stp_canceled;
+        let mut stp_canceled_order_ids = Vec::with_capacity(outcome.stp_canceled_makers.len());
+        for maker in &outcome.stp_canceled_makers {
+            self.risk.release_reservation(maker);
+            events.push(self.event_order_canceled(maker, &meta));
+            stp_canceled_order_ids.push(maker.order_id.clone());
+        }
+        let fills = outcome.fills;
         let had_fill = !fills.is_empty();
         for (idx, fill) in fills.into_iter().enumerate() {
             let maker_side = opposite(order.side);
@@ -377,12 +439,20 @@ impl TradingCore {
             };
 
             let quote_amount = fill.price.saturating_mul(fill.quantity);
-            self.risk.on_trade(
+            let maker_fee = fee_amount(quote_amount, fill.maker_fee_e8);
+            let taker_fee = fee_amount(quote_amount, order.taker_fee_e8);
+            let (fee_buyer, fee_seller) = match order.side {
+                Side::Buy => (taker_fee, maker_fee),
+                Side::Sell => (maker_fee, taker_fee),
+            };
+            let fee_buyer = self.risk.on_trade(
                 &buyer_user_id,
                 &seller_user_id,
                 &self.cfg.symbol,
                 fill.price,
                 fill.quantity,
+                fee_buyer,
+                fee_seller,
             );
 
             let taker_consumed = match order.side {
@@ -403,6 +473,8 @@ impl TradingCore {
                 original_qty: fill.quantity,
                 remaining_qty: fill.maker_remaining_after,
                 accepted_seq: 0,
+                maker_fee_e8: fill.maker_fee_e8,
+                taker_fee_e8: 0,
             };
             let maker_consumed = match maker_side {
                 Side::Buy => i128::from(quote_amount),
@@ -423,11 +495,14 @@ impl TradingCore {
                 &seller_user_id,
                 fill.price,
                 fill.quantity,
+                fee_buyer,
+                fee_seller,
                 idx as u64,
             ));
         }
 
         let stub_trade = self.cfg.stub_trades
+            && !taker_stp_canceled
             && events
                 .iter()
                 .all(|e| !matches!(e, CoreEvent::TradeExecuted(_)));
@@ -439,6 +514,21 @@ impl TradingCore {
                 Side::Sell => ("stub-mm".to_string(), order.user_id.clone()),
             };
             order.remaining_qty = 0;
+            // The stub market maker pays no fee; the taker pays its rate.
+            let taker_fee = fee_amount(price.saturating_mul(quantity), order.taker_fee_e8);
+            let (fee_buyer, fee_seller) = match order.side {
+                Side::Buy => (taker_fee, 0),
+                Side::Sell => (0, taker_fee),
+            };
+            let fee_buyer = self.risk.on_trade(
+                &buyer_user_id,
+                &seller_user_id,
+                &self.cfg.symbol,
+                price,
+                quantity,
+                fee_buyer,
+                fee_seller,
+            );
             events.push(self.event_trade_executed(
                 &meta,
                 "stub-maker",
@@ -447,15 +537,10 @@ impl TradingCore {
                 &seller_user_id,
                 price,
                 quantity,
+                fee_buyer,
+                fee_seller,
                 0,
             ));
-            self.risk.on_trade(
-                &buyer_user_id,
-                &seller_user_id,
-                &self.cfg.symbol,
-                price,
-                quantity,
-            );
             let consumed = match order.side {
                 Side::Buy => i128::from(price.saturating_mul(quantity)),
                 Side::Sell => i128::from(quantity),
@@ -464,7 +549,9 @@ impl TradingCore {
         }
 
         if order.remaining_qty > 0 {
-            let can_rest = order.order_type == OrderType::Limit && order.tif == TimeInForce::Gtc;
+            let can_rest = order.order_type == OrderType::Limit
+                && order.tif == TimeInForce::Gtc
+                && !taker_stp_canceled;
             if can_rest {
                 self.order_book.insert(order.clone());
             } else {
@@ -477,10 +564,15 @@ impl TradingCore {
             }
         }
 
+        // A taker stopped by self-trade prevention after filling reports
+        // PARTIALLY_FILLED like an IOC remainder: its OrderCanceled event
+        // carries what is left, so the reserve its fills use stays held.
         let status = if order.remaining_qty == 0 {
             "FILLED".to_string()
         } else if had_fill {
             "PARTIALLY_FILLED".to_string()
+        } else if taker_stp_canceled {
+            "CANCELED".to_string()
         } else if order.order_type == OrderType::Limit && order.tif == TimeInForce::Gtc {
             "ACCEPTED".to_string()
         } else {
@@ -494,8 +586,13 @@ impl TradingCore {
             symbol: self.cfg.symbol.clone(),
             seq: self.seq,
             accepted_at: now_timestamp(),
-            reject_code: String::new(),
+            reject_code: if taker_stp_canceled {
+                RejectCode::SelfTradePrevented.as_str().to_string()
+            } else {
+                String::new()
+            },
             correlation_id: meta.correlation_id.clone(),
+            stp_canceled_order_ids,
         };
 
         self.append_checkpoint(&meta, &mut events);
@@ -683,6 +780,8 @@ impl TradingCore {
             order_type: order.order_type,
             price: order.price.unwrap_or_default().to_string(),
             quantity: order.original_qty.to_string(),
+            maker_fee_e8: order.maker_fee_e8,
+            taker_fee_e8: order.taker_fee_e8,
         })
     }
 
@@ -760,6 +859,8 @@ impl TradingCore {
         seller_user_id: &str,
         price: u64,
         quantity: u64,
+        fee_buyer: u64,
+        fee_seller: u64,
         trade_idx: u64,
     ) -> CoreEvent {
         let seq = self.next_seq();
@@ -781,8 +882,8 @@ impl TradingCore {
             price: price.to_string(),
             quantity: quantity.to_string(),
             quote_amount: quote_amount.to_string(),
-            fee_buyer: "0".to_string(),
-            fee_seller: "0".to_string(),
+            fee_buyer: fee_buyer.to_string(),
+            fee_seller: fee_seller.to_string(),
         })
     }
 
@@ -801,6 +902,7 @@ impl TradingCore {
             accepted_at: now_timestamp(),
             reject_code: code.as_str().to_string(),
             correlation_id: correlation_id.to_string(),
+            stp_canceled_order_ids: Vec::new(),
         }
     }
 
@@ -894,6 +996,8 @@ impl TradingCore {
                     original_qty: e.quantity.parse::<u64>().unwrap_or(0),
                     remaining_qty: e.quantity.parse::<u64>().unwrap_or(0),
                     accepted_seq: env.seq,
+                    maker_fee_e8: e.maker_fee_e8,
+                    taker_fee_e8: e.taker_fee_e8,
                 };
                 if order.order_type == OrderType::Limit {
                     self.order_book.insert(order);
@@ -7,9 +7,13 @@ use serde::Serialize;
 use time::format_description::well_known::Rfc3339;
 use time::OffsetDateTime;
 
+/// Publishes trades to the trade topic and order lifecycle events to the
+/// order topic. Order events are keyed by symbol so a consumer sees each
+/// symbol's events in seq order.
 pub struct KafkaTradePublisher {
     producer: BaseProducer,
     topic: String,
+    order_topic: String,
     flush_timeout: std::time::Duration,
 }
 
@@ -17,6 +21,7 @@ impl KafkaTradePublisher {
     pub fn new(
         brokers: &str,
         topic: &str,
+        order_topic: &str,
         flush_timeout: std::time::Duration,
     ) -> Result<Self, String> {
         let producer = ClientConfig::new()
@@ -27,26 +32,14 @@ impl KafkaTradePublisher {
         Ok(Self {
             producer,
             topic: topic.to_string(),
+            order_topic: order_topic.to_string(),
             flush_timeout,
         })
     }
-}
 
-impl EventSink for KafkaTradePublisher {
-    fn publish(&mut self, event: &CoreEvent) -> Result<(), String> {
-        let trade = match event {
-            CoreEvent::TradeExecuted(e) => e,
-            _ => return Ok(()),
-        };
-
-        let payload = TradeExecutedPayload::from(trade)?;
-        let json = serde_json::to_string(&payload).map_err(|e| e.to_string())?;
+    fn send(&mut self, topic: &str, key: &str, json: &str) -> Result<(), String> {
         self.producer
-            .send(
-                BaseRecord::to(&self.topic)
-                    .payload(&json)
-                    .key(&payload.trade_id),
-            )
+            .send(BaseRecord::to(topic).payload(json).key(key))
             .map_err(|(e, _)| e.to_string())?;
         self.producer
             .flush(Timeout::After(self.flush_timeout))
@@ -55,6 +48,93 @@ impl EventSink for KafkaTradePublisher {
     }
 }
 
+impl EventSink for KafkaTradePublisher {
+    fn publish(&mut self, event: &CoreEvent) -> Result<(), String> {
+        if let CoreEvent::TradeExecuted(trade) = event {
+            let payload = TradeExecutedPayload::from(trade)?;
+            let json = serde_json::to_string(&payload).map_err(|e| e.to_string())?;
+            let topic = self.topic.clone();
+            return self.send(&topic, &payload.trade_id, &json);
+        }
+        if self.order_topic.is_empty() {
+            return Ok(());
+        }
+        let Some(json) = encode_order_event(event)? else {
+            return Ok(());
+        };
+        let topic = self.order_topic.clone();
+        self.send(&topic, &event.envelope().symbol, &json)
+    }
+}
+
+/// Encodes an order lifecycle event the way the edge gateway reads the
+/// order topic. Events that are not order lifecycle events encode to None.
+pub fn encode_order_event(event: &CoreEvent) -> Result<Option<String>, String> {
+    let payload = match event {
+        CoreEvent::OrderAccepted(e) => {
+            OrderEventPayload::new(event, &e.envelope, &e.order_id, &e.user_id)?
+        }
+        CoreEvent::OrderRejected(e) => OrderEventPayload {
+            reject_code: e.reject_code.clone(),
+            detail: e.detail.clone(),
+            ..OrderEventPayload::new(event, &e.envelope, &e.order_id, &e.user_id)?
+        },
+        CoreEvent::OrderCanceled(e) => OrderEventPayload {
+            remaining_quantity: Some(parse_i64(&e.remaining_quantity)),
+            ..OrderEventPayload::new(event, &e.envelope, &e.order_id, &e.user_id)?
+        },
+        CoreEvent::CancelRejected(e) => OrderEventPayload {
+            reject_code: e.reject_code.clone(),
+            detail: e.detail.clone(),
+            ..OrderEventPayload::new(event, &e.envelope, &e.order_id, &e.user_id)?
+        },
+        _ => return Ok(None),
+    };
+    serde_json::to_string(&payload)
+        .map(Some)
+        .map_err(|e| e.to_string())
+}
+
+#[derive(Debug, Serialize)]
+#[serde(rename_all = "camelCase")]
+struct OrderEventPayload {
+    envelope: EventEnvelopePayload,
+    event_type: String,
+    symbol: String,
+    seq: u64,
+    ts: i64,
+    order_id: String,
+    user_id: String,
+    #[serde(skip_serializing_if = "String::is_empty")]
+    reject_code: String,
+    #[serde(skip_serializing_if = "String::is_empty")]
+    detail: String,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    remaining_quantity: Option<i64>,
+}
+
+impl OrderEventPayload {
+    fn new(
+        event: &CoreEvent,
+        envelope: &EventEnvelope,
+        order_id: &str,
+        user_id: &str,
+    ) -> Result<Self, String> {
+        Ok(Self {
+            envelope: EventEnvelopePayload::from(envelope)?,
+            event_type: event.kind().to_string(),
+            symbol: envelope.symbol.clone(),
+            seq: envelope.seq,
+            ts: envelope.occurred_at_ms,
+            order_id: order_id.to_string(),
+            user_id: user_id.to_string(),
+            reject_code: String::new(),
+            detail: String::new(),
+            remaining_quantity: None,
+        })
+    }
+}
+
 #[derive(Debug, Serialize)]
 #[serde(rename_all = "camelCase")]
 struct TradeExecutedPayload {
@@ -30,6 +30,14 @@ pub enum TimeInForce {
     Fok,
 }
 
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize, Hash)]
+pub enum SelfTradePrevention {
+    None,
+    CancelNewest,
+    CancelOldest,
+    CancelBoth,
+}
+
 #[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
 pub enum RejectCode {
     Validation,
@@ -41,6 +49,8 @@ pub enum RejectCode {
     CancelOnly,
     NoLiquidity,
     FencingToken,
+    PostOnlyWouldTake,
+    SelfTradePrevented,
 }
 
 impl RejectCode {
@@ -55,6 +65,8 @@ impl RejectCode {
             RejectCode::CancelOnly => "CANCEL_ONLY",
             RejectCode::NoLiquidity => "NO_LIQUIDITY",
             RejectCode::FencingToken => "FENCING_TOKEN",
+            RejectCode::PostOnlyWouldTake => "POST_ONLY_WOULD_TAKE",
+            RejectCode::SelfTradePrevented => "SELF_TRADE_PREVENTED",
         }
     }
 }
@@ -82,6 +94,13 @@ pub struct Order {
     pub original_qty: u64,
     pub remaining_qty: u64,
     pub accepted_seq: u64,
+    /// Fee rates the gateway stamped on the order, in 1e-8 of the quote
+    /// amount (basis points x 10^4). The maker rate stays with the order
+    /// while it rests.
+    #[serde(default, skip_serializing_if = "is_zero")]
+    pub maker_fee_e8: u64,
+    #[serde(default, skip_serializing_if = "is_zero")]
+    pub taker_fee_e8: u64,
 }
 
 #[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
@@ -104,6 +123,10 @@ pub struct OrderAcceptedEvent {
     pub order_type: OrderType,
     pub price: String,
     pub quantity: String,
+    #[serde(default, skip_serializing_if = "is_zero")]
+    pub maker_fee_e8: u64,
+    #[serde(default, skip_serializing_if = "is_zero")]
+    pub taker_fee_e8: u64,
 }
 
 #[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
@@ -215,6 +238,41 @@ pub fn parse_u64(value: &str) -> Result<u64, RejectCode> {
     value.parse::<u64>().map_err(|_| RejectCode::Validation)
 }
 
+/// Largest fee rate an order may carry, in basis points.
+pub const MAX_FEE_BPS: u64 = 1_000;
+
+/// Parses a fee rate in basis points with up to four decimals into 1e-8
+/// units. An empty rate is no fee.
+pub fn parse_fee_bps(value: &str) -> Result<u64, RejectCode> {
+    if value.is_empty() {
+        return Ok(0);
+    }
+    let (whole, frac) = value.split_once('.').unwrap_or((value, ""));
+    let digits = |s: &str| !s.is_empty() && s.bytes().all(|b| b.is_ascii_digit());
+    if !digits(whole) || (value.contains('.') && !digits(frac)) || frac.len() > 4 {
+        return Err(RejectCode::Validation);
+    }
+    let whole = parse_u64(whole)?;
+    if whole > MAX_FEE_BPS {
+        return Err(RejectCode::Validation);
+    }
+    let frac = format!("{frac:0<4}").parse::<u64>().unwrap_or(0);
+    let rate = whole * 10_000 + frac;
+    if rate > MAX_FEE_BPS * 10_000 {
+        return Err(RejectCode::Validation);
+    }
+    Ok(rate)
+}
+
+/// The fee on a quote amount at a rate in 1e-8 units, rounded down.
+pub fn fee_amount(quote_amount: u64, rate_e8: u64) -> u64 {
+    (u128::from(quote_amount) * u128::from(rate_e8) / 100_000_000) as u64
+}
+
+fn is_zero(v: &u64) -> bool {
+    *v == 0
+}
+
 pub fn split_symbol(symbol: &str) -> Result<(String, String), RejectCode> {
     let mut parts = symbol.split('-');
     let base = parts.next().unwrap_or_default();
@@ -250,6 +308,16 @@ pub fn to_time_in_force(value: i32) -> Result<TimeInForce, RejectCode> {
     }
 }
 
+pub fn to_self_trade_prevention(value: i32) -> Result<SelfTradePrevention, RejectCode> {
+    match proto::SelfTradePrevention::try_from(value).ok() {
+        Some(proto::SelfTradePrevention::Unspecified) => Ok(SelfTradePrevention::None),
+        Some(proto::SelfTradePrevention::CancelNewest) => Ok(SelfTradePrevention::CancelNewest),
+        Some(proto::SelfTradePrevention::CancelOldest) => Ok(SelfTradePrevention::CancelOldest),
+        Some(proto::SelfTradePrevention::CancelBoth) => Ok(SelfTradePrevention::CancelBoth),
+        _ => Err(RejectCode::Validation),
+    }
+}
+
 pub fn to_symbol_mode(value: i32) -> Result<SymbolMode, RejectCode> {
     match proto::SymbolMode::try_from(value).ok() {
         Some(proto::SymbolMode::Normal) => Ok(SymbolMode::Normal),
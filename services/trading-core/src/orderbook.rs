@@ -1,4 +1,4 @@
-use crate::model::{Order, OrderType, Side};
+use crate::model::{Order, OrderType, SelfTradePrevention, Side};
 use serde::{Deserialize, Serialize};
 use std::collections::{BTreeMap, HashMap, VecDeque};
 
@@ -11,6 +11,14 @@ pub struct TradeFill {
     pub price: u64,
     pub quantity: u64,
     pub maker_remaining_after: u64,
+    pub maker_fee_e8: u64,
+}
+
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct MatchOutcome {
+    pub fills: Vec<TradeFill>,
+    pub stp_canceled_makers: Vec<Order>,
+    pub taker_stp_canceled: bool,
 }
 
 #[derive(Debug, Clone, Default, Serialize, Deserialize)]
@@ -102,8 +110,24 @@ impl OrderBook {
         }
     }
 
+    pub fn would_cross(&self, incoming: &Order) -> bool {
+        match self.best_opposite(incoming.side) {
+            Some(price) => self.crosses(incoming, price),
+            None => false,
+        }
+    }
+
     pub fn match_order(&mut self, incoming: &mut Order) -> Vec<TradeFill> {
-        let mut fills = Vec::new();
+        self.match_order_with_stp(incoming, SelfTradePrevention::None)
+            .fills
+    }
+
+    pub fn match_order_with_stp(
+        &mut self,
+        incoming: &mut Order,
+        stp: SelfTradePrevention,
+    ) -> MatchOutcome {
+        let mut outcome = MatchOutcome::default();
 
         while incoming.remaining_qty > 0 {
             let price_level = match incoming.side {
@@ -132,14 +156,33 @@ impl OrderBook {
                 break;
             }
 
-            let (maker_user_id, maker_remaining) = match self.orders.get(&maker_id) {
-                Some(m) => (m.user_id.clone(), m.remaining_qty),
+            let (maker_user_id, maker_remaining, maker_fee_e8) = match self.orders.get(&maker_id) {
+                Some(m) => (m.user_id.clone(), m.remaining_qty, m.maker_fee_e8),
                 None => {
                     self.pop_front_maker(incoming.side, level_price);
                     continue;
                 }
             };
 
+            if stp != SelfTradePrevention::None && maker_user_id == incoming.user_id {
+                if matches!(
+                    stp,
+                    SelfTradePrevention::CancelOldest | SelfTradePrevention::CancelBoth
+                ) {
+                    if let Some(maker) = self.cancel(&maker_id) {
+                        outcome.stp_canceled_makers.push(maker);
+                    }
+                }
+                if matches!(
+                    stp,
+                    SelfTradePrevention::CancelNewest | SelfTradePrevention::CancelBoth
+                ) {
+                    outcome.taker_stp_canceled = true;
+                    break;
+                }
+                continue;
+            }
+
             let fill_qty = incoming.remaining_qty.min(maker_remaining);
             if fill_qty == 0 {
                 self.pop_front_maker(incoming.side, level_price);
@@ -158,7 +201,7 @@ impl OrderBook {
                 }
             }
 
-            fills.push(TradeFill {
+            outcome.fills.push(TradeFill {
                 maker_order_id: maker_id,
                 taker_order_id: incoming.order_id.clone(),
                 maker_user_id,
@@ -166,12 +209,13 @@ impl OrderBook {
                 price: level_price,
                 quantity: fill_qty,
                 maker_remaining_after,
+                maker_fee_e8,
             });
 
             self.cleanup_empty_level(incoming.side, level_price);
         }
 
-        fills
+        outcome
     }
 
     fn crosses(&self, incoming: &Order, resting_price: u64) -> bool {
@@ -230,6 +274,8 @@ mod tests {
             original_qty: qty,
             remaining_qty: qty,
             accepted_seq: 1,
+            maker_fee_e8: 0,
+            taker_fee_e8: 0,
         }
     }
 
@@ -276,6 +322,37 @@ mod tests {
         assert_eq!(remaining.remaining_qty, 5);
     }
 
+    #[test]
+    fn stp_cancel_oldest_removes_own_maker_and_keeps_matching() {
+        let mut book = OrderBook::default();
+        book.insert(mk("ask-own", Side::Sell, 100, 10, "mm"));
+        book.insert(mk("ask-other", Side::Sell, 100, 10, "m2"));
+
+        let mut taker = mk("buy", Side::Buy, 100, 10, "mm");
+        let outcome = book.match_order_with_stp(&mut taker, SelfTradePrevention::CancelOldest);
+
+        assert_eq!(outcome.stp_canceled_makers.len(), 1);
+        assert_eq!(outcome.stp_canceled_makers[0].order_id, "ask-own");
+        assert!(!outcome.taker_stp_canceled);
+        assert_eq!(outcome.fills.len(), 1);
+        assert_eq!(outcome.fills[0].maker_order_id, "ask-other");
+        assert_eq!(book.open_orders(), 0);
+    }
+
+    #[test]
+    fn stp_cancel_newest_stops_taker_and_keeps_maker() {
+        let mut book = OrderBook::default();
+        book.insert(mk("ask-own", Side::Sell, 100, 10, "mm"));
+
+        let mut taker = mk("buy", Side::Buy, 100, 10, "mm");
+        let outcome = book.match_order_with_stp(&mut taker, SelfTradePrevention::CancelNewest);
+
+        assert!(outcome.fills.is_empty());
+        assert!(outcome.taker_stp_canceled);
+        assert_eq!(taker.remaining_qty, 10);
+        assert!(book.get_order("ask-own").is_some());
+    }
+
     #[test]
     fn cancel_removes_order() {
         let mut book = OrderBook::default();
@@ -228,6 +228,11 @@ impl RiskManager {
         None
     }
 
+    /// Moves a trade between the two users. Fees are in the quote
+    /// currency: the buyer's comes out of available, since reserves do not
+    /// cover fees, and is capped at what available holds; the seller's is
+    /// taken from the proceeds. Returns the buyer fee actually charged.
+    #[allow(clippy::too_many_arguments)]
     pub fn on_trade(
         &mut self,
         buyer_user_id: &str,
@@ -235,10 +240,12 @@ impl RiskManager {
         symbol: &str,
         price: u64,
         quantity: u64,
-    ) {
+        fee_buyer: u64,
+        fee_seller: u64,
+    ) -> u64 {
         let (base, quote) = match split_symbol(symbol) {
             Ok(v) => v,
-            Err(_) => return,
+            Err(_) => return 0,
         };
         let quote_amount = i128::from(price.saturating_mul(quantity));
         let base_amount = i128::from(quantity);
@@ -249,6 +256,8 @@ impl RiskManager {
             .entry((buyer_user_id.to_string(), quote.clone()))
             .or_default();
         bq.hold -= quote_amount;
+        let charged = i128::from(fee_buyer).min(bq.available.max(0));
+        bq.available -= charged;
 
         let bb = self
             .balances
@@ -267,7 +276,8 @@ impl RiskManager {
             .balances
             .entry((seller_user_id.to_string(), quote))
             .or_default();
-        sq.available += quote_amount;
+        sq.available += quote_amount - i128::from(fee_seller);
+        charged as u64
     }
 
     pub fn release_reservation(&mut self, order: &Order) {
@@ -414,6 +424,8 @@ mod tests {
             original_qty: qty,
             remaining_qty: qty,
             accepted_seq: 1,
+            maker_fee_e8: 0,
+            taker_fee_e8: 0,
         }
     }
 
@@ -43,6 +43,10 @@ fn place_req(
         price: price.to_string(),
         quantity: qty.to_string(),
         time_in_force: proto::TimeInForce::Gtc as i32,
+        post_only: false,
+        self_trade_prevention: proto::SelfTradePrevention::Unspecified as i32,
+        maker_fee_bps: String::new(),
+        taker_fee_bps: String::new(),
     }
 }
 
@@ -131,6 +135,10 @@ fn validation_rejects_missing_meta() {
             price: "100".to_string(),
             quantity: "1".to_string(),
             time_in_force: proto::TimeInForce::Gtc as i32,
+            post_only: false,
+            self_trade_prevention: proto::SelfTradePrevention::Unspecified as i32,
+            maker_fee_bps: String::new(),
+            taker_fee_bps: String::new(),
         })
         .unwrap();
     assert!(!resp.accepted);
@@ -493,6 +501,212 @@ fn cancel_removes_order_and_rejects_second_cancel() {
     assert_eq!(second.reject_code, RejectCode::UnknownOrder.as_str());
 }
 
+#[test]
+fn post_only_rejects_when_it_would_take() {
+    let tmp = TempDir::new().unwrap();
+    let mut core = make_engine(&tmp, FencingCoordinator::new());
+
+    let _ = core
+        .place_order(place_req(
+            "m1",
+            "idem-m1",
+            "maker",
+            "ask-1",
+            proto::Side::Sell,
+            proto::OrderType::Limit,
+            "100",
+            "10",
+        ))
+        .unwrap();
+
+    let crossing = core
+        .place_order(proto::PlaceOrderRequest {
+            post_only: true,
+            ..place_req(
+                "p1",
+                "idem-p1",
+                "u1",
+                "bid-cross",
+                proto::Side::Buy,
+                proto::OrderType::Limit,
+                "100",
+                "10",
+            )
+        })
+        .unwrap();
+    assert!(!crossing.accepted);
+    assert_eq!(crossing.reject_code, RejectCode::PostOnlyWouldTake.as_str());
+
+    let passive = core
+        .place_order(proto::PlaceOrderRequest {
+            post_only: true,
+            ..place_req(
+                "p2",
+                "idem-p2",
+                "u1",
+                "bid-passive",
+                proto::Side::Buy,
+                proto::OrderType::Limit,
+                "99",
+                "10",
+            )
+        })
+        .unwrap();
+    assert!(passive.accepted);
+    assert_eq!(passive.status, "ACCEPTED");
+}
+
+#[test]
+fn self_trade_prevention_modes_cancel_expected_side() {
+    let tmp = TempDir::new().unwrap();
+    let mut core = make_engine(&tmp, FencingCoordinator::new());
+
+    let _ = core
+        .place_order(place_req(
+            "m1",
+            "idem-m1",
+            "u1",
+            "ask-own",
+            proto::Side::Sell,
+            proto::OrderType::Limit,
+            "100",
+            "10",
+        ))
+        .unwrap();
+
+    let newest = core
+        .place_order(proto::PlaceOrderRequest {
+            self_trade_prevention: proto::SelfTradePrevention::CancelNewest as i32,
+            ..place_req(
+                "t1",
+                "idem-t1",
+                "u1",
+                "bid-newest",
+                proto::Side::Buy,
+                proto::OrderType::Limit,
+                "100",
+                "10",
+            )
+        })
+        .unwrap();
+    assert!(newest.accepted);
+    assert_eq!(newest.status, "CANCELED");
+    assert_eq!(newest.reject_code, RejectCode::SelfTradePrevented.as_str());
+    assert!(newest.stp_canceled_order_ids.is_empty());
+    assert_eq!(core.open_order_count(), 1);
+
+    let oldest = core
+        .place_order(proto::PlaceOrderRequest {
+            self_trade_prevention: proto::SelfTradePrevention::CancelOldest as i32,
+            ..place_req(
+                "t2",
+                "idem-t2",
+                "u1",
+                "bid-oldest",
+                proto::Side::Buy,
+                proto::OrderType::Limit,
+                "100",
+                "10",
+            )
+        })
+        .unwrap();
+    assert!(oldest.accepted);
+    assert_eq!(oldest.status, "ACCEPTED");
+    assert_eq!(oldest.stp_canceled_order_ids, vec!["ask-own".to_string()]);
+    assert_eq!(core.open_order_count(), 1);
+}
+
+#[test]
+fn self_trade_prevention_leaves_other_users_orders_to_match() {
+    let tmp = TempDir::new().unwrap();
+    let mut core = make_engine(&tmp, FencingCoordinator::new());
+
+    let _ = core
+        .place_order(place_req(
+            "m1",
+            "idem-m1",
+            "u2",
+            "ask-other",
+            proto::Side::Sell,
+            proto::OrderType::Limit,
+            "100",
+            "10",
+        ))
+        .unwrap();
+
+    for (cmd, mode) in [
+        ("t1", proto::SelfTradePrevention::CancelNewest),
+        ("t2", proto::SelfTradePrevention::CancelOldest),
+        ("t3", proto::SelfTradePrevention::CancelBoth),
+    ] {
+        let taker = core
+            .place_order(proto::PlaceOrderRequest {
+                self_trade_prevention: mode as i32,
+                ..place_req(
+                    cmd,
+                    &format!("idem-{cmd}"),
+                    "u1",
+                    &format!("bid-{cmd}"),
+                    proto::Side::Buy,
+                    proto::OrderType::Limit,
+                    "100",
+                    "3",
+                )
+            })
+            .unwrap();
+        assert!(taker.accepted);
+        assert_eq!(taker.status, "FILLED");
+        assert!(taker.reject_code.is_empty());
+        assert!(taker.stp_canceled_order_ids.is_empty());
+    }
+    assert_eq!(core.open_order_count(), 1);
+}
+
+#[test]
+fn self_trade_prevention_after_a_fill_reports_partial_fill() {
+    let tmp = TempDir::new().unwrap();
+    let mut core = make_engine(&tmp, FencingCoordinator::new());
+
+    for (cmd, user, order_id, price, qty) in [
+        ("m1", "u2", "ask-other", "100", "4"),
+        ("m2", "u1", "ask-own", "101", "10"),
+    ] {
+        let resp = core
+            .place_order(place_req(
+                cmd,
+                &format!("idem-{cmd}"),
+                user,
+                order_id,
+                proto::Side::Sell,
+                proto::OrderType::Limit,
+                price,
+                qty,
+            ))
+            .unwrap();
+        assert!(resp.accepted);
+    }
+
+    let taker = core
+        .place_order(proto::PlaceOrderRequest {
+            self_trade_prevention: proto::SelfTradePrevention::CancelNewest as i32,
+            ..place_req(
+                "t1",
+                "idem-t1",
+                "u1",
+                "bid-newest",
+                proto::Side::Buy,
+                proto::OrderType::Limit,
+                "101",
+                "10",
+            )
+        })
+        .unwrap();
+    assert!(taker.accepted);
+    assert_eq!(taker.status, "PARTIALLY_FILLED");
+    assert_eq!(taker.reject_code, RejectCode::SelfTradePrevented.as_str());
+    assert_eq!(core.open_order_count(), 1);
+}
+
 #[test]
 fn risk_rejects_overspend() {
     let tmp = TempDir::new().unwrap();
@@ -679,6 +893,90 @@ fn deterministic_replay_same_hash_for_10_runs() {
     }
 }
 
+#[test]
+fn trades_charge_the_fee_rates_stamped_on_each_order() {
+    let tmp = TempDir::new().unwrap();
+    let mut core = make_engine(&tmp, FencingCoordinator::new());
+
+    for (cmd, rate) in [("f1", "1000.0001"), ("f2", "2.34567"), ("f3", "-1")] {
+        let resp = core
+            .place_order(proto::PlaceOrderRequest {
+                taker_fee_bps: rate.to_string(),
+                ..place_req(
+                    cmd,
+                    &format!("idem-{cmd}"),
+                    "u1",
+                    &format!("bad-{cmd}"),
+                    proto::Side::Buy,
+                    proto::OrderType::Limit,
+                    "1000",
+                    "1",
+                )
+            })
+            .unwrap();
+        assert!(!resp.accepted);
+        assert_eq!(resp.reject_code, RejectCode::Validation.as_str());
+    }
+
+    let _ = core
+        .place_order(proto::PlaceOrderRequest {
+            maker_fee_bps: "10".to_string(),
+            taker_fee_bps: "20".to_string(),
+            ..place_req(
+                "m1",
+                "idem-m1",
+                "maker",
+                "ask-fee",
+                proto::Side::Sell,
+                proto::OrderType::Limit,
+                "1000",
+                "200",
+            )
+        })
+        .unwrap();
+
+    // The resting order keeps its maker rate across a restart.
+    let mut core = make_engine(&tmp, FencingCoordinator::new());
+    for (cmd, qty) in [("t1", "100"), ("t2", "100")] {
+        let resp = core
+            .place_order(proto::PlaceOrderRequest {
+                maker_fee_bps: "1".to_string(),
+                taker_fee_bps: "2.5".to_string(),
+                ..place_req(
+                    cmd,
+                    &format!("idem-{cmd}"),
+                    "taker",
+                    &format!("bid-{cmd}"),
+                    proto::Side::Buy,
+                    proto::OrderType::Limit,
+                    "1000",
+                    qty,
+                )
+            })
+            .unwrap();
+        assert_eq!(resp.status, "FILLED");
+    }
+
+    let fees: Vec<(String, String)> = core
+        .recent_events()
+        .iter()
+        .filter_map(|e| match e {
+            crate::model::CoreEvent::TradeExecuted(t) => {
+                Some((t.fee_buyer.clone(), t.fee_seller.clone()))
+            }
+            _ => None,
+        })
+        .collect();
+    // 2.5 bps of 100000 from the buyer, 10 bps from the resting seller.
+    assert_eq!(
+        fees,
+        vec![
+            ("25".to_string(), "100".to_string()),
+            ("25".to_string(), "100".to_string()),
+        ]
+    );
+}
+
 #[test]
 fn recover_from_wal_keeps_seq_hash_and_mode() {
     let tmp = TempDir::new().unwrap();
@@ -881,6 +1179,66 @@ fn outbox_republish_is_idempotent() {
     assert_eq!(sink.0, first_calls);
 }
 
+#[test]
+fn order_events_encode_for_the_gateway_order_topic() {
+    let tmp = TempDir::new().unwrap();
+    let mut core = make_engine(&tmp, FencingCoordinator::new());
+
+    let _ = core
+        .place_order(place_req(
+            "oe1",
+            "idem-oe1",
+            "maker",
+            "ord-oe1",
+            proto::Side::Buy,
+            proto::OrderType::Limit,
+            "100",
+            "3",
+        ))
+        .unwrap();
+    let _ = core
+        .cancel_order(cancel_req("oe2", "idem-oe2", "maker", "ord-oe1"))
+        .unwrap();
+    let _ = core
+        .cancel_order(cancel_req("oe3", "idem-oe3", "maker", "ord-missing"))
+        .unwrap();
+
+    struct Sink(Vec<serde_json::Value>);
+    impl EventSink for Sink {
+        fn publish(&mut self, event: &crate::model::CoreEvent) -> Result<(), String> {
+            if let Some(json) = crate::kafka::encode_order_event(event)? {
+                self.0.push(serde_json::from_str(&json).unwrap());
+            }
+            Ok(())
+        }
+    }
+    let mut sink = Sink(Vec::new());
+    core.publish_pending(&mut sink, 1).unwrap();
+
+    let kinds: Vec<&str> = sink
+        .0
+        .iter()
+        .map(|v| v["eventType"].as_str().unwrap())
+        .collect();
+    assert_eq!(
+        kinds,
+        vec!["OrderAccepted", "OrderCanceled", "CancelRejected"]
+    );
+
+    let canceled = &sink.0[1];
+    assert_eq!(canceled["orderId"], "ord-oe1");
+    assert_eq!(canceled["userId"], "maker");
+    assert_eq!(canceled["remainingQuantity"], 3);
+    assert_eq!(canceled["envelope"]["symbol"], "BTC-KRW");
+    assert_eq!(canceled["seq"], canceled["envelope"]["seq"]);
+    assert!(canceled["envelope"]["eventId"]
+        .as_str()
+        .unwrap()
+        .ends_with("-OrderCanceled"));
+    assert_eq!(sink.0[2]["rejectCode"], RejectCode::UnknownOrder.as_str());
+    assert!(sink.0[0].get("rejectCode").is_none());
+}
+
 #[test]
 fn golden_vectors_cover_20_cases() {
     let tmp = TempDir::new().unwrap();
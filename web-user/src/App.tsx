@@ -352,7 +352,8 @@ function buildAreaPath(values: number[], width: number, height: number): string
   return `${line} L ${width} ${height} L 0 ${height} Z`;
 }
 
-function formatAssetAmount(currency: string, value: number): string {
+function formatAssetAmount(currency: string, amount: string): string {
+  const value = Number(amount);
   if (currency === "KRW") {
     return formatPrice(value);
   }
@@ -1701,7 +1702,7 @@ export default function App() {
     }
 
     const assetRows: BalanceItem[] = portfolio?.assets ?? [];
-    const totalAssetValue = portfolio?.totalAssetValue ?? 0;
+    const totalAssetValue = Number(portfolio?.totalAssetValue ?? 0);
 
     return (
       <>
@@ -1756,8 +1757,8 @@ export default function App() {
                 <span>{formatAssetAmount(asset.currency, asset.available)}</span>
                 <span>{formatAssetAmount(asset.currency, asset.hold)}</span>
                 <span>{formatAssetAmount(asset.currency, asset.total)}</span>
-                <span>{asset.priceKrw !== undefined ? formatPrice(asset.priceKrw) : "-"}</span>
-                <span>{asset.valueKrw !== undefined ? formatPrice(asset.valueKrw) : "-"}</span>
+                <span>{asset.priceKrw !== undefined ? formatPrice(Number(asset.priceKrw)) : "-"}</span>
+                <span>{asset.valueKrw !== undefined ? formatPrice(Number(asset.valueKrw)) : "-"}</span>
               </div>
             ))}
             {assetRows.length === 0 ? <p className="placeholder">아직 보유 자산 정보가 없습니다.</p> : null}
@@ -72,13 +72,14 @@ export type AuthMeResponse = {
   user: AuthUser;
 };
 
+// Amounts are exact decimal strings at the asset's precision.
 export type BalanceItem = {
   currency: string;
-  available: number;
-  hold: number;
-  total: number;
-  priceKrw?: number;
-  valueKrw?: number;
+  available: string;
+  hold: string;
+  total: string;
+  priceKrw?: string;
+  valueKrw?: string;
 };
 
 export type BalancesResponse = {
@@ -89,6 +90,6 @@ export type BalancesResponse = {
 export type PortfolioResponse = {
   userId: string;
   assets: BalanceItem[];
-  totalAssetValue: number;
+  totalAssetValue: string;
   updatedAt: number;
 };